@@ -1,6 +1,7 @@
 package api
 
 import (
+	"crypto"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -14,7 +15,7 @@ type AccountService service
 func (a *AccountService) New(req acme.Account) (acme.ExtendedAccount, error) {
 	var account acme.Account
 
-	resp, err := a.core.post(a.core.GetDirectory().NewAccountURL, req, &account)
+	resp, err := a.core.post(resourceNewAccount, a.core.GetDirectory().NewAccountURL, req, &account)
 	location := getLocation(resp)
 
 	if location != "" {
@@ -53,7 +54,7 @@ func (a *AccountService) Get(accountURL string) (acme.Account, error) {
 
 	var account acme.Account
 
-	_, err := a.core.postAsGet(accountURL, &account)
+	_, err := a.core.postAsGet(resourceAccount, accountURL, &account)
 	if err != nil {
 		return acme.Account{}, err
 	}
@@ -69,7 +70,7 @@ func (a *AccountService) Update(accountURL string, req acme.Account) (acme.Accou
 
 	var account acme.Account
 
-	_, err := a.core.post(accountURL, req, &account)
+	_, err := a.core.post(resourceAccount, accountURL, req, &account)
 	if err != nil {
 		return acme.Account{}, err
 	}
@@ -84,11 +85,46 @@ func (a *AccountService) Deactivate(accountURL string) error {
 	}
 
 	req := acme.Account{Status: acme.StatusDeactivated}
-	_, err := a.core.post(accountURL, req, nil)
+	_, err := a.core.post(resourceAccount, accountURL, req, nil)
 
 	return err
 }
 
+// KeyChange Changes the key of an account (account key rollover).
+// On success, the following requests are signed with the new key.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+func (a *AccountService) KeyChange(accountURL string, newKey crypto.PrivateKey) error {
+	if accountURL == "" {
+		return errors.New("account[keyChange]: empty URL")
+	}
+
+	if newKey == nil {
+		return errors.New("account[keyChange]: the new key cannot be nil")
+	}
+
+	keyChangeURL := a.core.GetDirectory().KeyChangeURL
+	if keyChangeURL == "" {
+		return errors.New("account[keyChange]: server does not advertise a key change endpoint")
+	}
+
+	// The inner JWS is the payload of the outer JWS, which is signed with the current key.
+	a.core.jws.SetKid(accountURL)
+
+	content, err := a.core.signKeyChangeContent(keyChangeURL, newKey)
+	if err != nil {
+		return fmt.Errorf("acme: error signing key change content: %w", err)
+	}
+
+	_, err = a.core.retrievablePost(resourceKeyChange, keyChangeURL, content, nil)
+	if err != nil {
+		return err
+	}
+
+	a.core.jws.SetPrivateKey(newKey)
+
+	return nil
+}
+
 func decodeEABHmac(hmacEncoded string) ([]byte, error) {
 	hmac, errRaw := base64.RawURLEncoding.DecodeString(hmacEncoded)
 	if errRaw == nil {
@@ -16,6 +16,24 @@ import (
 	"github.com/go-acme/lego/v4/acme/api/internal/secure"
 	"github.com/go-acme/lego/v4/acme/api/internal/sender"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+)
+
+// Names of the ACME resources, used to label the metrics.
+const (
+	resourceDirectory   = "directory"
+	resourceNewAccount  = "newAccount"
+	resourceAccount     = "account"
+	resourceKeyChange   = "keyChange"
+	resourceNewOrder    = "newOrder"
+	resourceOrder       = "order"
+	resourceFinalize    = "finalize"
+	resourceNewAuthz    = "newAuthz"
+	resourceAuthz       = "authz"
+	resourceChallenge   = "challenge"
+	resourceCertificate = "certificate"
+	resourceRevokeCert  = "revokeCert"
+	resourceRenewalInfo = "renewalInfo"
 )
 
 // Core ACME/LE core API.
@@ -26,6 +44,8 @@ type Core struct {
 	directory    acme.Directory
 	HTTPClient   *http.Client
 
+	ctx context.Context
+
 	common         service // Reuse a single struct instead of allocating one for each service on the heap.
 	Accounts       *AccountService
 	Authorizations *AuthorizationService
@@ -38,7 +58,7 @@ type Core struct {
 func New(httpClient *http.Client, userAgent, caDirURL, kid string, privateKey crypto.PrivateKey) (*Core, error) {
 	doer := sender.NewDoer(httpClient, userAgent)
 
-	dir, err := getDirectory(doer, caDirURL)
+	dir, err := getDirectory(context.Background(), doer, caDirURL)
 	if err != nil {
 		return nil, err
 	}
@@ -49,35 +69,89 @@ func New(httpClient *http.Client, userAgent, caDirURL, kid string, privateKey cr
 
 	c := &Core{doer: doer, nonceManager: nonceManager, jws: jws, directory: dir, HTTPClient: httpClient}
 
-	c.common.core = c
-	c.Accounts = (*AccountService)(&c.common)
-	c.Authorizations = (*AuthorizationService)(&c.common)
-	c.Certificates = (*CertificateService)(&c.common)
-	c.Challenges = (*ChallengeService)(&c.common)
-	c.Orders = (*OrderService)(&c.common)
+	c.initServices()
 
 	return c, nil
 }
 
+// WithContext returns a shallow copy of the Core with its context changed to ctx.
+// All the requests made through the services of the returned Core use ctx.
+// The provided ctx must be non-nil.
+func (a *Core) WithContext(ctx context.Context) *Core {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	c := &Core{
+		doer:         a.doer,
+		nonceManager: a.nonceManager,
+		jws:          a.jws,
+		directory:    a.directory,
+		HTTPClient:   a.HTTPClient,
+		ctx:          ctx,
+	}
+
+	c.initServices()
+
+	return c
+}
+
+// withKey returns a shallow copy of the Core which signs the requests with privateKey,
+// the public key is embedded in the requests (no key identifier).
+func (a *Core) withKey(privateKey crypto.PrivateKey) *Core {
+	c := &Core{
+		doer:         a.doer,
+		nonceManager: a.nonceManager,
+		jws:          secure.NewJWS(privateKey, "", a.nonceManager),
+		directory:    a.directory,
+		HTTPClient:   a.HTTPClient,
+		ctx:          a.ctx,
+	}
+
+	c.initServices()
+
+	return c
+}
+
+// Context returns the context of the Core.
+// To change the context, use WithContext.
+// The returned context is always non-nil; it defaults to the background context.
+func (a *Core) Context() context.Context {
+	if a.ctx != nil {
+		return a.ctx
+	}
+
+	return context.Background()
+}
+
+func (a *Core) initServices() {
+	a.common.core = a
+	a.Accounts = (*AccountService)(&a.common)
+	a.Authorizations = (*AuthorizationService)(&a.common)
+	a.Certificates = (*CertificateService)(&a.common)
+	a.Challenges = (*ChallengeService)(&a.common)
+	a.Orders = (*OrderService)(&a.common)
+}
+
 // post performs an HTTP POST request and parses the response body as JSON,
 // into the provided respBody object.
-func (a *Core) post(uri string, reqBody, response any) (*http.Response, error) {
+func (a *Core) post(resource, uri string, reqBody, response any) (*http.Response, error) {
 	content, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, errors.New("failed to marshal message")
 	}
 
-	return a.retrievablePost(uri, content, response)
+	return a.retrievablePost(resource, uri, content, response)
 }
 
 // postAsGet performs an HTTP POST ("POST-as-GET") request.
 // https://www.rfc-editor.org/rfc/rfc8555.html#section-6.3
-func (a *Core) postAsGet(uri string, response any) (*http.Response, error) {
-	return a.retrievablePost(uri, []byte{}, response)
+func (a *Core) postAsGet(resource, uri string, response any) (*http.Response, error) {
+	return a.retrievablePost(resource, uri, []byte{}, response)
 }
 
-func (a *Core) retrievablePost(uri string, content []byte, response any) (*http.Response, error) {
-	ctx := context.Background()
+func (a *Core) retrievablePost(resource, uri string, content []byte, response any) (*http.Response, error) {
+	ctx := sender.WithResource(a.Context(), resource)
 
 	// during tests, allow to support ~90% of bad nonce with a minimum of attempts.
 	bo := backoff.NewExponentialBackOff()
@@ -85,7 +159,7 @@ func (a *Core) retrievablePost(uri string, content []byte, response any) (*http.
 	bo.MaxInterval = 5 * time.Second
 
 	operation := func() (*http.Response, error) {
-		resp, err := a.signedPost(uri, content, response)
+		resp, err := a.signedPost(ctx, uri, content, response)
 		if err != nil {
 			// Retry if the nonce was invalidated
 			var e *acme.NonceError
@@ -100,6 +174,8 @@ func (a *Core) retrievablePost(uri string, content []byte, response any) (*http.
 	}
 
 	notify := func(err error, duration time.Duration) {
+		metrics.BadNonceRetry()
+
 		log.Infof("retry due to: %v", err)
 	}
 
@@ -109,15 +185,15 @@ func (a *Core) retrievablePost(uri string, content []byte, response any) (*http.
 		backoff.WithNotify(notify))
 }
 
-func (a *Core) signedPost(uri string, content []byte, response any) (*http.Response, error) {
-	signedContent, err := a.jws.SignContent(uri, content)
+func (a *Core) signedPost(ctx context.Context, uri string, content []byte, response any) (*http.Response, error) {
+	signedContent, err := a.jws.SignContent(ctx, uri, content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post JWS message: failed to sign content: %w", err)
 	}
 
 	signedBody := bytes.NewBufferString(signedContent.FullSerialize())
 
-	resp, err := a.doer.Post(uri, signedBody, "application/jose+json", response)
+	resp, err := a.doer.Post(ctx, uri, signedBody, "application/jose+json", response)
 
 	// nonceErr is ignored to keep the root error.
 	nonce, nonceErr := nonces.GetFromResponse(resp)
@@ -137,18 +213,33 @@ func (a *Core) signEABContent(newAccountURL, kid string, hmac []byte) ([]byte, e
 	return []byte(eabJWS.FullSerialize()), nil
 }
 
+func (a *Core) signKeyChangeContent(keyChangeURL string, newKey crypto.PrivateKey) ([]byte, error) {
+	keyChangeJWS, err := a.jws.SignKeyChangeContent(keyChangeURL, newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(keyChangeJWS.FullSerialize()), nil
+}
+
 // GetKeyAuthorization Gets the key authorization.
 func (a *Core) GetKeyAuthorization(token string) (string, error) {
 	return a.jws.GetKeyAuthorization(token)
 }
 
+// GetAccountURL Gets the URL of the account, used as the key identifier of the requests.
+// It is empty until the account is registered or resolved.
+func (a *Core) GetAccountURL() string {
+	return a.jws.GetKid()
+}
+
 func (a *Core) GetDirectory() acme.Directory {
 	return a.directory
 }
 
-func getDirectory(do *sender.Doer, caDirURL string) (acme.Directory, error) {
+func getDirectory(ctx context.Context, do *sender.Doer, caDirURL string) (acme.Directory, error) {
 	var dir acme.Directory
-	if _, err := do.Get(caDirURL, &dir); err != nil {
+	if _, err := do.Get(sender.WithResource(ctx, resourceDirectory), caDirURL, &dir); err != nil {
 		return dir, fmt.Errorf("get directory at '%s': %w", caDirURL, err)
 	}
 
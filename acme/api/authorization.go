@@ -2,12 +2,42 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/go-acme/lego/v4/acme"
 )
 
 type AuthorizationService service
 
+// New Creates a new authorization for a domain (pre-authorization).
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.1
+func (c *AuthorizationService) New(domain string) (acme.ExtendedAuthorization, error) {
+	newAuthzURL := c.core.GetDirectory().NewAuthzURL
+	if newAuthzURL == "" {
+		return acme.ExtendedAuthorization{}, errors.New("authorization[new]: server does not advertise a newAuthz endpoint")
+	}
+
+	// The server MUST NOT issue a pre-authorization for a wildcard domain name.
+	if strings.HasPrefix(domain, "*.") {
+		return acme.ExtendedAuthorization{}, fmt.Errorf("authorization[new]: a wildcard domain cannot be pre-authorized: %s", domain)
+	}
+
+	req := acme.NewAuthzMessage{Identifier: createIdentifiers([]string{domain})[0]}
+
+	var authz acme.Authorization
+
+	resp, err := c.core.post(resourceNewAuthz, newAuthzURL, req, &authz)
+	if err != nil {
+		return acme.ExtendedAuthorization{}, err
+	}
+
+	return acme.ExtendedAuthorization{
+		Authorization: authz,
+		Location:      resp.Header.Get("Location"),
+	}, nil
+}
+
 // Get Gets an authorization.
 func (c *AuthorizationService) Get(authzURL string) (acme.Authorization, error) {
 	if authzURL == "" {
@@ -16,7 +46,7 @@ func (c *AuthorizationService) Get(authzURL string) (acme.Authorization, error)
 
 	var authz acme.Authorization
 
-	_, err := c.core.postAsGet(authzURL, &authz)
+	_, err := c.core.postAsGet(resourceAuthz, authzURL, &authz)
 	if err != nil {
 		return acme.Authorization{}, err
 	}
@@ -32,7 +62,7 @@ func (c *AuthorizationService) Deactivate(authzURL string) error {
 
 	var disabledAuth acme.Authorization
 
-	_, err := c.core.post(authzURL, acme.Authorization{Status: acme.StatusDeactivated}, &disabledAuth)
+	_, err := c.core.post(resourceAuthz, authzURL, acme.Authorization{Status: acme.StatusDeactivated}, &disabledAuth)
 
 	return err
 }
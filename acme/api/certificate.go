@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"crypto"
 	"encoding/pem"
 	"errors"
 	"io"
@@ -54,7 +55,20 @@ func (c *CertificateService) GetAll(certURL string, bundle bool) (map[string]*ac
 
 // Revoke Revokes a certificate.
 func (c *CertificateService) Revoke(req acme.RevokeCertMessage) error {
-	_, err := c.core.post(c.core.GetDirectory().RevokeCertURL, req, nil)
+	_, err := c.core.post(resourceRevokeCert, c.core.GetDirectory().RevokeCertURL, req, nil)
+	return err
+}
+
+// RevokeWithKey Revokes a certificate with a request signed by the private key of the certificate,
+// instead of the account key.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.6
+func (c *CertificateService) RevokeWithKey(req acme.RevokeCertMessage, certKey crypto.Signer) error {
+	if certKey == nil {
+		return errors.New("certificate[revoke]: the certificate key cannot be nil")
+	}
+
+	_, err := c.core.withKey(certKey).post(resourceRevokeCert, c.core.GetDirectory().RevokeCertURL, req, nil)
+
 	return err
 }
 
@@ -64,7 +78,7 @@ func (c *CertificateService) get(certURL string, bundle bool) (*acme.RawCertific
 		return nil, nil, errors.New("certificate[get]: empty URL")
 	}
 
-	resp, err := c.core.postAsGet(certURL, nil)
+	resp, err := c.core.postAsGet(resourceCertificate, certURL, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1,12 +1,20 @@
 package api
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
 	"testing"
 
+	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/platform/tester"
 	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -105,3 +113,67 @@ func TestCertificateService_Get_embeddedIssuer(t *testing.T) {
 	assert.Equal(t, certResponseMock, string(cert), "Certificate")
 	assert.Equal(t, issuerMock, string(issuer), "IssuerCertificate")
 }
+
+// opaqueSigner a crypto.Signer which is not an RSA or ECDSA private key (like an HSM key).
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestCertificateService_RevokeWithKey(t *testing.T) {
+	accountKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	server := tester.MockACMEServer().
+		Route("POST /revokeCert",
+			http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				raw, err := io.ReadAll(req.Body)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				jws, err := jose.ParseSigned(string(raw), []jose.SignatureAlgorithm{jose.ES256})
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				header := jws.Signatures[0].Protected
+				if header.KeyID != "" || header.JSONWebKey == nil {
+					http.Error(rw, "the JWK must be embedded", http.StatusBadRequest)
+					return
+				}
+
+				body, err := jws.Verify(certKey.Public())
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				var msg acme.RevokeCertMessage
+
+				err = json.Unmarshal(body, &msg)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if msg.Certificate != "cert" {
+					http.Error(rw, "unexpected certificate: "+msg.Certificate, http.StatusBadRequest)
+					return
+				}
+			})).
+		BuildHTTPS(t)
+
+	core, err := New(server.Client(), "lego-test", server.URL+"/dir", "https://example.com/acct/1", accountKey)
+	require.NoError(t, err)
+
+	err = core.Certificates.RevokeWithKey(acme.RevokeCertMessage{Certificate: "cert"}, opaqueSigner{Signer: certKey})
+	require.NoError(t, err)
+
+	// The account key is still used by the other requests.
+	assert.Equal(t, "https://example.com/acct/1", core.GetAccountURL())
+}
@@ -18,7 +18,7 @@ func (c *ChallengeService) New(chlgURL string) (acme.ExtendedChallenge, error) {
 	// We use an empty struct instance as the postJSON payload here to achieve this result.
 	var chlng acme.ExtendedChallenge
 
-	resp, err := c.core.post(chlgURL, struct{}{}, &chlng)
+	resp, err := c.core.post(resourceChallenge, chlgURL, struct{}{}, &chlng)
 	if err != nil {
 		return acme.ExtendedChallenge{}, err
 	}
@@ -37,7 +37,7 @@ func (c *ChallengeService) Get(chlgURL string) (acme.ExtendedChallenge, error) {
 
 	var chlng acme.ExtendedChallenge
 
-	resp, err := c.core.postAsGet(chlgURL, &chlng)
+	resp, err := c.core.postAsGet(resourceChallenge, chlgURL, &chlng)
 	if err != nil {
 		return acme.ExtendedChallenge{}, err
 	}
@@ -1,6 +1,7 @@
 package nonces
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -51,15 +52,24 @@ func (n *Manager) Push(nonce string) {
 
 // Nonce implement jose.NonceSource.
 func (n *Manager) Nonce() (string, error) {
+	return n.nonce(context.Background())
+}
+
+// WithContext returns a jose.NonceSource which uses the context to get a new nonce.
+func (n *Manager) WithContext(ctx context.Context) *ContextSource {
+	return &ContextSource{manager: n, ctx: ctx}
+}
+
+func (n *Manager) nonce(ctx context.Context) (string, error) {
 	if nonce, ok := n.Pop(); ok {
 		return nonce, nil
 	}
 
-	return n.getNonce()
+	return n.getNonce(ctx)
 }
 
-func (n *Manager) getNonce() (string, error) {
-	resp, err := n.do.Head(n.nonceURL)
+func (n *Manager) getNonce(ctx context.Context) (string, error) {
+	resp, err := n.do.Head(sender.WithResource(ctx, "newNonce"), n.nonceURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to get nonce from HTTP HEAD: %w", err)
 	}
@@ -80,3 +90,14 @@ func GetFromResponse(resp *http.Response) (string, error) {
 
 	return nonce, nil
 }
+
+// ContextSource a jose.NonceSource bound to a context.
+type ContextSource struct {
+	manager *Manager
+	ctx     context.Context
+}
+
+// Nonce implement jose.NonceSource.
+func (s *ContextSource) Nonce() (string, error) {
+	return s.manager.nonce(s.ctx)
+}
@@ -1,15 +1,19 @@
 package secure
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 
+	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/acme/api/internal/nonces"
 	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/cryptosigner"
 )
 
 // JWS Represents a JWS.
@@ -33,28 +37,25 @@ func (j *JWS) SetKid(kid string) {
 	j.kid = kid
 }
 
-// SignContent Signs a content with the JWS.
-func (j *JWS) SignContent(url string, content []byte) (*jose.JSONWebSignature, error) {
-	var alg jose.SignatureAlgorithm
+// GetKid Gets the key identifier.
+func (j *JWS) GetKid() string {
+	return j.kid
+}
 
-	switch k := j.privKey.(type) {
-	case *rsa.PrivateKey:
-		alg = jose.RS256
-	case *ecdsa.PrivateKey:
-		if k.Curve == elliptic.P256() {
-			alg = jose.ES256
-		} else if k.Curve == elliptic.P384() {
-			alg = jose.ES384
-		}
-	}
+// SetPrivateKey Sets the private key used to sign the content.
+func (j *JWS) SetPrivateKey(privateKey crypto.PrivateKey) {
+	j.privKey = privateKey
+}
 
+// SignContent Signs a content with the JWS.
+func (j *JWS) SignContent(ctx context.Context, url string, content []byte) (*jose.JSONWebSignature, error) {
 	signKey := jose.SigningKey{
-		Algorithm: alg,
-		Key:       jose.JSONWebKey{Key: j.privKey, KeyID: j.kid},
+		Algorithm: getSignatureAlgorithm(j.privKey),
+		Key:       jose.JSONWebKey{Key: getSigningKey(j.privKey), KeyID: j.kid},
 	}
 
 	options := jose.SignerOptions{
-		NonceSource: j.nonces,
+		NonceSource: j.nonces.WithContext(ctx),
 		ExtraHeaders: map[jose.HeaderKey]any{
 			"url": url,
 		},
@@ -108,6 +109,48 @@ func (j *JWS) SignEABContent(url, kid string, hmac []byte) (*jose.JSONWebSignatu
 	return signed, nil
 }
 
+// SignKeyChangeContent Signs the inner JWS of an account key rollover request with the new key.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+func (j *JWS) SignKeyChangeContent(url string, newKey crypto.PrivateKey) (*jose.JSONWebSignature, error) {
+	oldKey := jose.JSONWebKey{Key: j.privKey}
+
+	oldKeyJSON, err := oldKey.Public().MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("acme: error encoding old jwk key: %w", err)
+	}
+
+	content, err := json.Marshal(acme.KeyChangeMessage{
+		Account: j.kid,
+		OldKey:  oldKeyJSON,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("acme: error encoding key change message: %w", err)
+	}
+
+	// The inner JWS MUST have a "jwk" header parameter containing the public key of the new key,
+	// MUST have the same "url" header parameter as the outer JWS,
+	// and MUST omit the "nonce" header parameter.
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: getSignatureAlgorithm(newKey), Key: newKey},
+		&jose.SignerOptions{
+			EmbedJWK: true,
+			ExtraHeaders: map[jose.HeaderKey]any{
+				"url": url,
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key change jose signer: %w", err)
+	}
+
+	signed, err := signer.Sign(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign key change content: %w", err)
+	}
+
+	return signed, nil
+}
+
 // GetKeyAuthorization Gets the key authorization for a token.
 func (j *JWS) GetKeyAuthorization(token string) (string, error) {
 	var publicKey crypto.PublicKey
@@ -132,3 +175,36 @@ func (j *JWS) GetKeyAuthorization(token string) (string, error) {
 
 	return token + "." + keyThumb, nil
 }
+
+func getSignatureAlgorithm(privateKey crypto.PrivateKey) jose.SignatureAlgorithm {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return ""
+	}
+
+	switch k := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return jose.RS256
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return jose.ES256
+		} else if k.Curve == elliptic.P384() {
+			return jose.ES384
+		}
+	}
+
+	return ""
+}
+
+// getSigningKey returns a key usable by jose:
+// a crypto.Signer other than an RSA or ECDSA private key (HSM, KMS, etc.) is wrapped into an opaque signer.
+func getSigningKey(privateKey crypto.PrivateKey) any {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return k
+	case crypto.Signer:
+		return cryptosigner.Opaque(k)
+	}
+
+	return privateKey
+}
@@ -1,16 +1,36 @@
 package sender
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/metrics"
 )
 
+type resourceKey struct{}
+
+// WithResource returns a copy of ctx associated with the name of the requested ACME resource.
+// The name is used to label the metrics of the requests.
+func WithResource(ctx context.Context, resource string) context.Context {
+	return context.WithValue(ctx, resourceKey{}, resource)
+}
+
+func getResource(ctx context.Context) string {
+	resource, ok := ctx.Value(resourceKey{}).(string)
+	if !ok {
+		return "unknown"
+	}
+
+	return resource
+}
+
 type RequestOption func(*http.Request) error
 
 func contentType(ct string) RequestOption {
@@ -37,8 +57,8 @@ func NewDoer(client *http.Client, userAgent string) *Doer {
 
 // Get performs a GET request with a proper User-Agent string.
 // If "response" is not provided, callers should close resp.Body when done reading from it.
-func (d *Doer) Get(url string, response any) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodGet, url, nil)
+func (d *Doer) Get(ctx context.Context, url string, response any) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -48,8 +68,8 @@ func (d *Doer) Get(url string, response any) (*http.Response, error) {
 
 // Head performs a HEAD request with a proper User-Agent string.
 // The response body (resp.Body) is already closed when this function returns.
-func (d *Doer) Head(url string) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodHead, url, nil)
+func (d *Doer) Head(ctx context.Context, url string) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +79,8 @@ func (d *Doer) Head(url string) (*http.Response, error) {
 
 // Post performs a POST request with a proper User-Agent string.
 // If "response" is not provided, callers should close resp.Body when done reading from it.
-func (d *Doer) Post(url string, body io.Reader, bodyType string, response any) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodPost, url, body, contentType(bodyType))
+func (d *Doer) Post(ctx context.Context, url string, body io.Reader, bodyType string, response any) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodPost, url, body, contentType(bodyType))
 	if err != nil {
 		return nil, err
 	}
@@ -68,8 +88,8 @@ func (d *Doer) Post(url string, body io.Reader, bodyType string, response any) (
 	return d.do(req, response)
 }
 
-func (d *Doer) newRequest(method, uri string, body io.Reader, opts ...RequestOption) (*http.Request, error) {
-	req, err := http.NewRequest(method, uri, body)
+func (d *Doer) newRequest(ctx context.Context, method, uri string, body io.Reader, opts ...RequestOption) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -87,11 +107,19 @@ func (d *Doer) newRequest(method, uri string, body io.Reader, opts ...RequestOpt
 }
 
 func (d *Doer) do(req *http.Request, response any) (*http.Response, error) {
+	resource := getResource(req.Context())
+
+	start := time.Now()
+
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
+		metrics.ACMERequest(resource, 0, time.Since(start))
+
 		return nil, err
 	}
 
+	metrics.ACMERequest(resource, resp.StatusCode, time.Since(start))
+
 	if err = checkError(req, resp); err != nil {
 		return resp, err
 	}
@@ -139,6 +167,8 @@ func checkError(req *http.Request, resp *http.Response) error {
 	errorDetails.Method = req.Method
 	errorDetails.URL = req.URL.String()
 
+	metrics.ACMEError(getResource(req.Context()), errorDetails.Type)
+
 	if errorDetails.HTTPStatus == 0 {
 		errorDetails.HTTPStatus = resp.StatusCode
 	}
@@ -31,17 +31,19 @@ func TestDo_UserAgentOnAllHTTPMethod(t *testing.T) {
 		{
 			method: http.MethodGet,
 			call: func(u string) (*http.Response, error) {
-				return doer.Get(u, nil)
+				return doer.Get(t.Context(), u, nil)
 			},
 		},
 		{
 			method: http.MethodHead,
-			call:   doer.Head,
+			call: func(u string) (*http.Response, error) {
+				return doer.Head(t.Context(), u)
+			},
 		},
 		{
 			method: http.MethodPost,
 			call: func(u string) (*http.Response, error) {
-				return doer.Post(u, strings.NewReader("falalalala"), "text/plain", nil)
+				return doer.Post(t.Context(), u, strings.NewReader("falalalala"), "text/plain", nil)
 			},
 		},
 	}
@@ -78,7 +80,7 @@ func TestDo_failWithHTTP(t *testing.T) {
 
 	sender := NewDoer(server.Client(), "test")
 
-	_, err := sender.Post(server.URL, strings.NewReader("data"), "text/plain", nil)
+	_, err := sender.Post(t.Context(), server.URL, strings.NewReader("data"), "text/plain", nil)
 	require.ErrorContains(t, err, "HTTPS is required: http://")
 }
 
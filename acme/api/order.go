@@ -57,7 +57,7 @@ func (o *OrderService) NewWithOptions(domains []string, opts *OrderOptions) (acm
 
 	var order acme.Order
 
-	resp, err := o.core.post(o.core.GetDirectory().NewOrderURL, orderReq, &order)
+	resp, err := o.core.post(resourceNewOrder, o.core.GetDirectory().NewOrderURL, orderReq, &order)
 	if err != nil {
 		are := &acme.AlreadyReplacedError{}
 		if !errors.As(err, &are) {
@@ -69,7 +69,7 @@ func (o *OrderService) NewWithOptions(domains []string, opts *OrderOptions) (acm
 		// https://www.rfc-editor.org/rfc/rfc9773.html#section-5
 		orderReq.Replaces = ""
 
-		resp, err = o.core.post(o.core.GetDirectory().NewOrderURL, orderReq, &order)
+		resp, err = o.core.post(resourceNewOrder, o.core.GetDirectory().NewOrderURL, orderReq, &order)
 		if err != nil {
 			return acme.ExtendedOrder{}, err
 		}
@@ -109,7 +109,7 @@ func (o *OrderService) Get(orderURL string) (acme.ExtendedOrder, error) {
 
 	var order acme.Order
 
-	_, err := o.core.postAsGet(orderURL, &order)
+	_, err := o.core.postAsGet(resourceOrder, orderURL, &order)
 	if err != nil {
 		return acme.ExtendedOrder{}, err
 	}
@@ -125,7 +125,7 @@ func (o *OrderService) UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedO
 
 	var order acme.Order
 
-	_, err := o.core.post(orderURL, csrMsg, &order)
+	_, err := o.core.post(resourceFinalize, orderURL, csrMsg, &order)
 	if err != nil {
 		return acme.ExtendedOrder{}, err
 	}
@@ -3,6 +3,9 @@ package api
 import (
 	"errors"
 	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/metrics"
 )
 
 // ErrNoARI is returned when the server does not advertise a renewal info endpoint.
@@ -24,5 +27,21 @@ func (c *CertificateService) GetRenewalInfo(certID string) (*http.Response, erro
 		return nil, errors.New("renewalInfo[get]: 'certID' cannot be empty")
 	}
 
-	return c.core.HTTPClient.Get(c.core.GetDirectory().RenewalInfo + "/" + certID)
+	req, err := http.NewRequestWithContext(c.core.Context(), http.MethodGet, c.core.GetDirectory().RenewalInfo+"/"+certID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	resp, err := c.core.HTTPClient.Do(req)
+	if err != nil {
+		metrics.ACMERequest(resourceRenewalInfo, 0, time.Since(start))
+
+		return nil, err
+	}
+
+	metrics.ACMERequest(resourceRenewalInfo, resp.StatusCode, time.Since(start))
+
+	return resp, nil
 }
@@ -209,6 +209,22 @@ func (r *Order) Err() error {
 	return nil
 }
 
+// ExtendedAuthorization a extended Authorization.
+type ExtendedAuthorization struct {
+	Authorization
+
+	// The authorization URL, contains the value of the response header `Location`
+	Location string `json:"-"`
+}
+
+// NewAuthzMessage a pre-authorization message.
+// - https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.1
+type NewAuthzMessage struct {
+	// identifier (required, object):
+	// The identifier that the account wishes to be authorized to represent.
+	Identifier Identifier `json:"identifier"`
+}
+
 // Authorization the ACME authorization object.
 // - https://www.rfc-editor.org/rfc/rfc8555.html#section-7.1.4
 type Authorization struct {
@@ -293,6 +309,11 @@ type Challenge struct {
 
 	// https://www.rfc-editor.org/rfc/rfc8555.html#section-8.1
 	KeyAuthorization string `json:"keyAuthorization"`
+
+	// issuer-domain-names (required for dns-persist-01, array of string):
+	// The issuer domain names accepted by the CA in the persistent TXT record.
+	// https://datatracker.ietf.org/doc/draft-ietf-acme-dns-persist/
+	IssuerDomainNames []string `json:"issuer-domain-names,omitempty"`
 }
 
 func (c *Challenge) Err() error {
@@ -338,6 +359,20 @@ type RevokeCertMessage struct {
 	Reason *uint `json:"reason,omitempty"`
 }
 
+// KeyChangeMessage the payload of the inner JWS of an account key rollover request.
+// - https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+type KeyChangeMessage struct {
+	// account (required, string):
+	// The URL for the account being modified.
+	// The content of this field MUST be the exact string provided in the Location header field
+	// in response to the newAccount request that created the account.
+	Account string `json:"account"`
+
+	// oldKey (required, JWK):
+	// The JWK representation of the old key.
+	OldKey json.RawMessage `json:"oldKey"`
+}
+
 // RawCertificate raw data of a certificate.
 type RawCertificate struct {
 	Cert   []byte
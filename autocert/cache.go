@@ -0,0 +1,84 @@
+package autocert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrCacheMiss is returned by a Cache when the data is not found.
+var ErrCacheMiss = errors.New("autocert: certificate cache miss")
+
+// Cache is used by the Manager to store and retrieve the certificates and their private keys.
+// The data are PEM encoded: the private key followed by the certificate chain.
+//
+// The implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the data associated with the key, or ErrCacheMiss if the key doesn't exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores the data under the key.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Delete removes the data associated with the key.
+	// It is not an error if the key doesn't exist.
+	Delete(ctx context.Context, key string) error
+}
+
+// DirCache implements Cache using a directory on the local filesystem.
+// The directory is created if it doesn't exist.
+type DirCache string
+
+// Get implements Cache.
+func (d DirCache) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+
+	return data, err
+}
+
+// Put implements Cache.
+// The file is written atomically (through a temporary file).
+func (d DirCache) Put(_ context.Context, key string, data []byte) error {
+	err := os.MkdirAll(string(d), 0o700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(string(d), "tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), d.path(key))
+}
+
+// Delete implements Cache.
+func (d DirCache) Delete(_ context.Context, key string) error {
+	err := os.Remove(d.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (d DirCache) path(key string) string {
+	return filepath.Join(string(d), filepath.Clean("/"+key))
+}
@@ -0,0 +1,581 @@
+// Package autocert obtains, caches and renews certificates on demand, from a tls.Config.
+//
+// It works like golang.org/x/crypto/acme/autocert, but uses a lego client:
+// all the challenges and the DNS providers (so the wildcard certificates), the profiles, and the ARI renewal windows.
+package autocert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge/tlsalpn01"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/wait"
+)
+
+const (
+	// DefaultRenewBefore is the default duration before the expiration of a certificate when it is renewed,
+	// when the ACME server doesn't provide a renewal window (ARI).
+	DefaultRenewBefore = 30 * 24 * time.Hour
+
+	// DefaultCheckInterval is the default interval between two checks of the renewal window (ARI).
+	DefaultCheckInterval = 6 * time.Hour
+
+	// retryInterval is the interval between two attempts to renew a certificate after a failure.
+	retryInterval = time.Hour
+
+	// minObtainBackoff is the delay before a new attempt to obtain a certificate after the first failure:
+	// the delay is doubled after each failure, up to retryInterval.
+	minObtainBackoff = time.Minute
+)
+
+// HostPolicy specifies which host names the Manager is allowed to obtain a certificate for.
+// It returns an error to deny the host.
+type HostPolicy func(ctx context.Context, host string) error
+
+// HostWhitelist returns a policy where only the specified host names are allowed.
+// The wildcards are not supported: use a DomainsFunc to obtain wildcard certificates.
+func HostWhitelist(hosts ...string) HostPolicy {
+	allowed := make(map[string]struct{}, len(hosts))
+	for _, h := range hosts {
+		allowed[normalizeHost(h)] = struct{}{}
+	}
+
+	return func(_ context.Context, host string) error {
+		if _, ok := allowed[host]; !ok {
+			return fmt.Errorf("autocert: host %q not configured in HostWhitelist", host)
+		}
+
+		return nil
+	}
+}
+
+// DomainsFunc returns the domains of the certificate used for a host name.
+// The first domain identifies the certificate:
+// all the host names with the same first domain share the same certificate.
+type DomainsFunc func(host string) []string
+
+// WildcardDomains returns a DomainsFunc where the subdomains of the zone share a wildcard certificate:
+// `*.zone` and `zone`.
+// The other host names have their own certificate.
+func WildcardDomains(zone string) DomainsFunc {
+	zone = normalizeHost(zone)
+
+	return func(host string) []string {
+		if host == zone || strings.HasSuffix(host, "."+zone) && !strings.Contains(strings.TrimSuffix(host, "."+zone), ".") {
+			return []string{"*." + zone, zone}
+		}
+
+		return []string{host}
+	}
+}
+
+// Options are the options of the Manager.
+type Options struct {
+	// HostPolicy controls which host names the Manager obtains certificates for.
+	// If nil, all the host names are allowed: this is not recommended (see HostWhitelist).
+	HostPolicy HostPolicy
+
+	// Cache stores the certificates.
+	// If nil, the certificates are only kept in memory.
+	Cache Cache
+
+	// Domains returns the domains of the certificate used for a host name.
+	// By default, each host name has its own certificate.
+	Domains DomainsFunc
+
+	// KeyType is the type of the private keys of the certificates.
+	// By default, certcrypto.EC256.
+	KeyType certcrypto.KeyType
+
+	// Profile is the certificate profile (draft-ietf-acme-profiles).
+	Profile string
+
+	// PreferredChain is the preferred certificate chain (Subject Common Name of the issuer).
+	PreferredChain string
+
+	// RenewBefore is the duration before the expiration of a certificate when it is renewed,
+	// when the ACME server doesn't provide a renewal window (ARI).
+	// By default, DefaultRenewBefore, or a third of the lifetime of short-lived certificates.
+	RenewBefore time.Duration
+
+	// DisableARI disables the use of the renewal windows (ARI).
+	DisableARI bool
+
+	// CheckInterval is the interval between two checks of the renewal window (ARI).
+	// By default, DefaultCheckInterval.
+	CheckInterval time.Duration
+}
+
+// Manager obtains, caches, and renews certificates on demand, through GetCertificate.
+// The certificates are renewed in the background until Stop is called.
+//
+// TLS-ALPN-01 challenges are answered by GetCertificate: see TLSALPN01Provider.
+type Manager struct {
+	client  *lego.Client
+	options Options
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	certs map[string]*certState
+
+	challengeMu    sync.RWMutex
+	challengeCerts map[string]*tls.Certificate
+}
+
+// certState is the state of a certificate.
+type certState struct {
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	renewing bool
+
+	// pending is the in-flight loading of the certificate (from the cache, or from the ACME server), if any.
+	pending *pendingCert
+
+	// err is the error of the last failed loading, returned to the handshakes until retryAt.
+	err      error
+	retryAt  time.Time
+	failures int
+}
+
+// pendingCert is the result of an in-flight loading of a certificate:
+// the concurrent requests wait for it.
+type pendingCert struct {
+	done chan struct{}
+	cert *tls.Certificate
+	err  error
+}
+
+// NewManager creates a new Manager.
+// The account of the client must be registered.
+func NewManager(client *lego.Client, options Options) *Manager {
+	if options.KeyType == "" {
+		options.KeyType = certcrypto.EC256
+	}
+
+	if options.CheckInterval <= 0 {
+		options.CheckInterval = DefaultCheckInterval
+	}
+
+	if options.Domains == nil {
+		options.Domains = func(host string) []string { return []string{host} }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		client:         client,
+		options:        options,
+		ctx:            ctx,
+		cancel:         cancel,
+		certs:          make(map[string]*certState),
+		challengeCerts: make(map[string]*tls.Certificate),
+	}
+}
+
+// TLSConfig returns a tls.Config using the Manager, with the TLS-ALPN-01 protocol enabled.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", tlsalpn01.ACMETLS1Protocol},
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// Stop stops the background renewals.
+func (m *Manager) Stop() {
+	m.cancel()
+}
+
+// GetCertificate implements the tls.Config.GetCertificate hook.
+// It returns the certificate of the host name (SNI), and obtains it if needed.
+// It also answers the TLS-ALPN-01 challenges.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := normalizeHost(hello.ServerName)
+	if host == "" {
+		return nil, errors.New("autocert: missing server name")
+	}
+
+	if strings.ContainsAny(host, `*/\`) || strings.HasPrefix(host, ".") {
+		return nil, fmt.Errorf("autocert: invalid server name %q", host)
+	}
+
+	if slices.Contains(hello.SupportedProtos, tlsalpn01.ACMETLS1Protocol) {
+		return m.getChallengeCert(host)
+	}
+
+	ctx := hello.Context()
+	if ctx == nil {
+		ctx = m.ctx
+	}
+
+	// The host policy is checked before any state or cache access:
+	// the denied host names must not consume memory or storage reads.
+	if m.options.HostPolicy != nil {
+		err := m.options.HostPolicy(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	domains := m.options.Domains(host)
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("autocert: no domains for %q", host)
+	}
+
+	state := m.getState(domains[0])
+
+	state.mu.Lock()
+
+	if isValid(state.cert, host, time.Now()) {
+		cert := state.cert
+		state.mu.Unlock()
+
+		return cert, nil
+	}
+
+	// The failures are cached: the handshakes don't start a new ACME order before the retry time.
+	if state.err != nil && time.Now().Before(state.retryAt) {
+		err := state.err
+		state.mu.Unlock()
+
+		return nil, err
+	}
+
+	// The certificate is loaded in the background (not bound to the handshake):
+	// a canceled handshake doesn't cancel the loading, and the concurrent handshakes wait for the same result.
+	pending := state.pending
+	if pending == nil {
+		pending = &pendingCert{done: make(chan struct{})}
+		state.pending = pending
+
+		go m.load(host, domains, state, pending)
+	}
+
+	state.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-pending.done:
+	}
+
+	if pending.err != nil {
+		return nil, pending.err
+	}
+
+	if !isValid(pending.cert, host, time.Now()) {
+		return nil, fmt.Errorf("autocert: the certificate of %q is not valid for %q", domains[0], host)
+	}
+
+	return pending.cert, nil
+}
+
+// load loads the certificate from the cache, or obtains it, and starts the background renewals.
+// The result is published through the pending loading.
+func (m *Manager) load(host string, domains []string, state *certState, pending *pendingCert) {
+	cert, cacheErr := m.loadFromCache(m.ctx, domains[0])
+	if cacheErr != nil {
+		log.Warn("autocert: could not read the certificate from the cache.", log.AttrDomain, domains[0], log.AttrError, cacheErr)
+	}
+
+	var err error
+
+	if !isValid(cert, host, time.Now()) {
+		cert, err = m.obtain(m.ctx, domains, nil)
+	}
+
+	state.mu.Lock()
+
+	state.pending = nil
+
+	if err == nil {
+		state.cert = cert
+		state.err = nil
+		state.failures = 0
+
+		if !state.renewing {
+			state.renewing = true
+
+			go m.renewLoop(domains, state)
+		}
+	} else {
+		state.err = err
+		state.failures++
+		state.retryAt = time.Now().Add(obtainBackoff(state.failures))
+
+		log.Warn("autocert: could not obtain the certificate.",
+			log.AttrDomains, domains, log.AttrError, err, "retry", state.retryAt)
+	}
+
+	state.mu.Unlock()
+
+	pending.cert, pending.err = cert, err
+	close(pending.done)
+}
+
+func (m *Manager) getState(key string) *certState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.certs[key]
+	if !ok {
+		state = &certState{}
+		m.certs[key] = state
+	}
+
+	return state
+}
+
+// obtain obtains a certificate, and stores it in the cache.
+func (m *Manager) obtain(ctx context.Context, domains []string, previous *x509.Certificate) (*tls.Certificate, error) {
+	privateKey, err := certcrypto.GeneratePrivateKey(m.options.KeyType)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: could not generate the private key: %w", err)
+	}
+
+	request := certificate.ObtainRequest{
+		Domains:        domains,
+		PrivateKey:     privateKey,
+		Bundle:         true,
+		PreferredChain: m.options.PreferredChain,
+		Profile:        m.options.Profile,
+	}
+
+	if previous != nil && !m.options.DisableARI {
+		request.ReplacesCertID, err = certificate.MakeARICertID(previous)
+		if err != nil {
+			log.Warn("autocert: could not compute the ARI certificate ID.", log.AttrDomains, domains, log.AttrError, err)
+		}
+	}
+
+	res, err := m.client.Certificate.ObtainWithContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: %w", err)
+	}
+
+	data := slices.Concat(res.PrivateKey, res.Certificate)
+
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: %w", err)
+	}
+
+	if m.options.Cache != nil {
+		err = m.options.Cache.Put(ctx, cacheKey(domains[0]), data)
+		if err != nil {
+			log.Warn("autocert: could not store the certificate in the cache.", log.AttrDomains, domains, log.AttrError, err)
+		}
+	}
+
+	return cert, nil
+}
+
+func (m *Manager) loadFromCache(ctx context.Context, key string) (*tls.Certificate, error) {
+	if m.options.Cache == nil {
+		return nil, nil
+	}
+
+	data, err := m.options.Cache.Get(ctx, cacheKey(key))
+	if errors.Is(err, ErrCacheMiss) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCertificate(data)
+}
+
+// renewLoop renews the certificate in the background, until the Manager is stopped.
+func (m *Manager) renewLoop(domains []string, state *certState) {
+	for {
+		state.mu.Lock()
+		leaf := state.cert.Leaf
+		state.mu.Unlock()
+
+		next, renew := m.nextRenewal(m.ctx, leaf, time.Now())
+
+		if wait.Sleep(m.ctx, time.Until(next)) != nil {
+			return
+		}
+
+		if !renew {
+			// Checks the renewal window (ARI) again.
+			continue
+		}
+
+		cert, err := m.obtain(m.ctx, domains, leaf)
+		if err != nil {
+			log.Error("autocert: could not renew the certificate.", log.AttrDomains, domains, log.AttrError, err)
+
+			if wait.Sleep(m.ctx, retryInterval) != nil {
+				return
+			}
+
+			continue
+		}
+
+		log.Info("autocert: the certificate has been renewed.", log.AttrDomains, domains)
+
+		state.mu.Lock()
+		state.cert = cert
+		state.mu.Unlock()
+	}
+}
+
+// nextRenewal returns the time of the next renewal,
+// or the time of the next check of the renewal window (ARI) if renew is false.
+func (m *Manager) nextRenewal(ctx context.Context, leaf *x509.Certificate, now time.Time) (next time.Time, renew bool) {
+	if !m.options.DisableARI {
+		info, err := m.client.Certificate.GetRenewalInfoWithContext(ctx, certificate.RenewalInfoRequest{Cert: leaf})
+		switch {
+		case err == nil:
+			renewAt := info.ShouldRenewAt(now, m.options.CheckInterval)
+			if renewAt != nil {
+				return *renewAt, true
+			}
+
+			return now.Add(m.options.CheckInterval), false
+
+		case !errors.Is(err, api.ErrNoARI):
+			log.Warn("autocert: could not get the renewal information.", log.AttrDomain, leaf.Subject.CommonName, log.AttrError, err)
+		}
+	}
+
+	return renewalTime(leaf, m.options.RenewBefore), true
+}
+
+// obtainBackoff returns the delay before a new attempt to obtain a certificate, after a number of failures.
+func obtainBackoff(failures int) time.Duration {
+	backoff := minObtainBackoff
+
+	for range failures - 1 {
+		backoff *= 2
+
+		if backoff >= retryInterval {
+			return retryInterval
+		}
+	}
+
+	return backoff
+}
+
+// renewalTime returns the time of the renewal of a certificate, without renewal window (ARI).
+func renewalTime(leaf *x509.Certificate, renewBefore time.Duration) time.Time {
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+
+	if renewBefore <= 0 {
+		renewBefore = DefaultRenewBefore
+
+		// Short-lived certificates.
+		if renewBefore > lifetime/3 {
+			renewBefore = lifetime / 3
+		}
+	}
+
+	return leaf.NotAfter.Add(-renewBefore)
+}
+
+// TLSALPN01Provider returns a provider for the TLS-ALPN-01 challenge:
+// the challenge certificates are served by GetCertificate.
+//
+//	err := client.Challenge.SetTLSALPN01Provider(manager.TLSALPN01Provider())
+func (m *Manager) TLSALPN01Provider() *TLSALPN01Provider {
+	return &TLSALPN01Provider{manager: m}
+}
+
+func (m *Manager) getChallengeCert(host string) (*tls.Certificate, error) {
+	m.challengeMu.RLock()
+	defer m.challengeMu.RUnlock()
+
+	cert, ok := m.challengeCerts[host]
+	if !ok {
+		return nil, fmt.Errorf("autocert: no TLS-ALPN-01 challenge for %q", host)
+	}
+
+	return cert, nil
+}
+
+// TLSALPN01Provider implements challenge.Provider for the TLS-ALPN-01 challenge:
+// the challenge certificates are served by Manager.GetCertificate.
+type TLSALPN01Provider struct {
+	manager *Manager
+}
+
+// Present generates the challenge certificate.
+func (p *TLSALPN01Provider) Present(domain, _, keyAuth string) error {
+	cert, err := tlsalpn01.ChallengeCert(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	p.manager.challengeMu.Lock()
+	defer p.manager.challengeMu.Unlock()
+
+	p.manager.challengeCerts[normalizeHost(domain)] = cert
+
+	return nil
+}
+
+// CleanUp removes the challenge certificate.
+func (p *TLSALPN01Provider) CleanUp(domain, _, _ string) error {
+	p.manager.challengeMu.Lock()
+	defer p.manager.challengeMu.Unlock()
+
+	delete(p.manager.challengeCerts, normalizeHost(domain))
+
+	return nil
+}
+
+// parseCertificate parses PEM data containing a private key and a certificate chain.
+func parseCertificate(data []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+	}
+
+	return &cert, nil
+}
+
+// isValid checks if the certificate is usable for the host.
+func isValid(cert *tls.Certificate, host string, now time.Time) bool {
+	if cert == nil || cert.Leaf == nil {
+		return false
+	}
+
+	if now.Before(cert.Leaf.NotBefore) || now.After(cert.Leaf.NotAfter) {
+		return false
+	}
+
+	return cert.Leaf.VerifyHostname(host) == nil
+}
+
+func cacheKey(domain string) string {
+	return strings.ReplaceAll(domain, "*", "_")
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,343 @@
+package autocert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/challenge/tlsalpn01"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostWhitelist(t *testing.T) {
+	policy := HostWhitelist("example.com", "WWW.example.com.")
+
+	testCases := []struct {
+		host    string
+		allowed bool
+	}{
+		{host: "example.com", allowed: true},
+		{host: "www.example.com", allowed: true},
+		{host: "example.org", allowed: false},
+		{host: "sub.example.com", allowed: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.host, func(t *testing.T) {
+			err := policy(t.Context(), test.host)
+			if test.allowed {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestWildcardDomains(t *testing.T) {
+	domains := WildcardDomains("example.com")
+
+	assert.Equal(t, []string{"*.example.com", "example.com"}, domains("example.com"))
+	assert.Equal(t, []string{"*.example.com", "example.com"}, domains("www.example.com"))
+	assert.Equal(t, []string{"a.b.example.com"}, domains("a.b.example.com"))
+	assert.Equal(t, []string{"example.org"}, domains("example.org"))
+}
+
+func TestDirCache(t *testing.T) {
+	cache := DirCache(t.TempDir())
+
+	_, err := cache.Get(t.Context(), "example.com")
+	require.ErrorIs(t, err, ErrCacheMiss)
+
+	err = cache.Put(t.Context(), "example.com", []byte("data"))
+	require.NoError(t, err)
+
+	data, err := cache.Get(t.Context(), "example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("data"), data)
+
+	err = cache.Delete(t.Context(), "example.com")
+	require.NoError(t, err)
+
+	err = cache.Delete(t.Context(), "example.com")
+	require.NoError(t, err)
+
+	_, err = cache.Get(t.Context(), "example.com")
+	require.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestManager_GetCertificate_cache(t *testing.T) {
+	cache := DirCache(t.TempDir())
+
+	err := cache.Put(t.Context(), "example.com", generateCertificate(t, "example.com"))
+	require.NoError(t, err)
+
+	manager := NewManager(nil, Options{
+		HostPolicy: HostWhitelist("example.com"),
+		Cache:      cache,
+		DisableARI: true,
+	})
+	t.Cleanup(manager.Stop)
+
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "Example.com."})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"example.com"}, cert.Leaf.DNSNames)
+
+	// From memory.
+	again, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+
+	assert.Same(t, cert, again)
+}
+
+func TestManager_GetCertificate_hostPolicy(t *testing.T) {
+	cache := &blockingCache{Cache: DirCache(t.TempDir())}
+
+	manager := NewManager(nil, Options{
+		HostPolicy: HostWhitelist("example.com"),
+		Cache:      cache,
+	})
+	t.Cleanup(manager.Stop)
+
+	_, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+	require.EqualError(t, err, `autocert: host "example.org" not configured in HostWhitelist`)
+
+	// The denied host names are not tracked, and the cache is not read.
+	assert.Empty(t, manager.certs)
+	assert.Zero(t, cache.gets.Load())
+}
+
+func TestManager_GetCertificate_concurrent(t *testing.T) {
+	cache := &blockingCache{
+		Cache:   DirCache(t.TempDir()),
+		release: make(chan struct{}),
+	}
+
+	err := cache.Put(t.Context(), "example.com", generateCertificate(t, "example.com"))
+	require.NoError(t, err)
+
+	manager := NewManager(nil, Options{
+		HostPolicy: HostWhitelist("example.com"),
+		Cache:      cache,
+		DisableARI: true,
+	})
+	t.Cleanup(manager.Stop)
+
+	certs := make([]*tls.Certificate, 3)
+
+	var wg sync.WaitGroup
+
+	for i := range certs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			cert, errG := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+			assert.NoError(t, errG)
+
+			certs[i] = cert
+		}()
+	}
+
+	close(cache.release)
+
+	wg.Wait()
+
+	// The concurrent handshakes wait for the same loading.
+	assert.Equal(t, int32(1), cache.gets.Load())
+
+	require.NotNil(t, certs[0])
+
+	for _, cert := range certs {
+		assert.Same(t, certs[0], cert)
+	}
+}
+
+func TestManager_GetCertificate_cachedFailure(t *testing.T) {
+	cache := &blockingCache{Cache: DirCache(t.TempDir())}
+
+	manager := NewManager(nil, Options{
+		HostPolicy: HostWhitelist("example.com"),
+		Cache:      cache,
+	})
+	t.Cleanup(manager.Stop)
+
+	state := manager.getState("example.com")
+	state.err = errors.New("autocert: oops")
+	state.retryAt = time.Now().Add(time.Minute)
+
+	_, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.EqualError(t, err, "autocert: oops")
+
+	// No new loading before the retry time.
+	assert.Zero(t, cache.gets.Load())
+	assert.Nil(t, state.pending)
+}
+
+func Test_obtainBackoff(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		failures int
+		expected time.Duration
+	}{
+		{
+			desc:     "first failure",
+			failures: 1,
+			expected: time.Minute,
+		},
+		{
+			desc:     "doubled",
+			failures: 4,
+			expected: 8 * time.Minute,
+		},
+		{
+			desc:     "capped",
+			failures: 100,
+			expected: time.Hour,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, obtainBackoff(test.failures))
+		})
+	}
+}
+
+func TestManager_GetCertificate_invalidServerName(t *testing.T) {
+	manager := NewManager(nil, Options{})
+	t.Cleanup(manager.Stop)
+
+	testCases := []string{"", "*.example.com", "../example.com", `a\b`}
+
+	for _, serverName := range testCases {
+		_, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		require.Error(t, err, serverName)
+	}
+}
+
+func TestManager_GetCertificate_tlsalpn01(t *testing.T) {
+	manager := NewManager(nil, Options{})
+	t.Cleanup(manager.Stop)
+
+	provider := manager.TLSALPN01Provider()
+
+	hello := &tls.ClientHelloInfo{
+		ServerName:      "example.com",
+		SupportedProtos: []string{tlsalpn01.ACMETLS1Protocol},
+	}
+
+	_, err := manager.GetCertificate(hello)
+	require.Error(t, err)
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	cert, err := manager.GetCertificate(hello)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"example.com"}, leaf.DNSNames)
+	assert.True(t, slices.ContainsFunc(leaf.Extensions, func(ext pkix.Extension) bool {
+		return ext.Id.String() == "1.3.6.1.5.5.7.1.31"
+	}))
+
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	_, err = manager.GetCertificate(hello)
+	require.Error(t, err)
+}
+
+func Test_renewalTime(t *testing.T) {
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc        string
+		lifetime    time.Duration
+		renewBefore time.Duration
+		expected    time.Time
+	}{
+		{
+			desc:     "default",
+			lifetime: 90 * 24 * time.Hour,
+			expected: notBefore.Add(60 * 24 * time.Hour),
+		},
+		{
+			desc:     "short-lived",
+			lifetime: 6 * 24 * time.Hour,
+			expected: notBefore.Add(4 * 24 * time.Hour),
+		},
+		{
+			desc:        "custom",
+			lifetime:    90 * 24 * time.Hour,
+			renewBefore: 10 * 24 * time.Hour,
+			expected:    notBefore.Add(80 * 24 * time.Hour),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			leaf := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(test.lifetime)}
+
+			assert.Equal(t, test.expected, renewalTime(leaf, test.renewBefore))
+		})
+	}
+}
+
+func TestManager_TLSConfig(t *testing.T) {
+	manager := NewManager(nil, Options{})
+	t.Cleanup(manager.Stop)
+
+	config := manager.TLSConfig()
+
+	assert.Contains(t, config.NextProtos, tlsalpn01.ACMETLS1Protocol)
+	assert.NotNil(t, config.GetCertificate)
+}
+
+// generateCertificate generates a self-signed certificate, in the cache format.
+func generateCertificate(t *testing.T, domain string) []byte {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cert, err := certcrypto.GeneratePemCert(privateKey, domain, nil)
+	require.NoError(t, err)
+
+	return slices.Concat(certcrypto.PEMEncode(privateKey), cert)
+}
+
+// blockingCache a Cache which counts the reads, and blocks them until the release channel is closed (if any).
+type blockingCache struct {
+	Cache
+
+	release chan struct{}
+	gets    atomic.Int32
+}
+
+func (c *blockingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.gets.Add(1)
+
+	if c.release != nil {
+		<-c.release
+	}
+
+	return c.Cache.Get(ctx, key)
+}
@@ -1,13 +1,90 @@
 package certificate
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/log"
 )
 
-func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authorization, error) {
+// Authorize creates authorizations for the domains ahead of time (pre-authorization), and solves them.
+// Wildcard domains cannot be pre-authorized.
+//
+// The valid authorizations are reused by the ACME server for the next orders of the account:
+// the challenges of these orders are already solved.
+// The ACME server must support pre-authorization (newAuthz).
+// - https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.1
+func (c *Certifier) Authorize(ctx context.Context, domains []string) ([]acme.ExtendedAuthorization, error) {
+	if len(domains) == 0 {
+		return nil, errors.New("no domains to authorize")
+	}
+
+	if c.core.GetDirectory().NewAuthzURL == "" {
+		return nil, errors.New("the ACME server does not support pre-authorization (newAuthz)")
+	}
+
+	domains = sanitizeDomain(domains)
+
+	log.Info("acme: Pre-authorizing the domains", log.AttrDomains, domains)
+
+	if c.options.CheckCAA {
+		var err error
+
+		ctx, err = c.checkCAA(ctx, domains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	core := c.core.WithContext(ctx)
+
+	var (
+		authzURLs []string
+		authz     []acme.Authorization
+	)
+
+	for _, domain := range domains {
+		auth, err := core.Authorizations.New(domain)
+		if err != nil {
+			c.deactivateAuthorizationURLs(ctx, authzURLs, false)
+			return nil, err
+		}
+
+		log.Info("acme: authorization", log.AttrDomain, domain, log.AttrAuthzURL, auth.Location)
+
+		authzURLs = append(authzURLs, auth.Location)
+		authz = append(authz, auth.Authorization)
+	}
+
+	err := c.solve(ctx, authz)
+	if err != nil {
+		c.recordFailedValidations(ctx, authzURLs)
+
+		c.deactivateAuthorizationURLs(ctx, authzURLs, false)
+		return nil, err
+	}
+
+	var authorizations []acme.ExtendedAuthorization
+
+	for _, authzURL := range authzURLs {
+		auth, err := core.Authorizations.Get(authzURL)
+		if err != nil {
+			return nil, err
+		}
+
+		authorizations = append(authorizations, acme.ExtendedAuthorization{Authorization: auth, Location: authzURL})
+	}
+
+	log.Info("acme: Pre-authorizations succeeded", log.AttrDomains, domains)
+
+	return authorizations, nil
+}
+
+func (c *Certifier) getAuthorizations(ctx context.Context, order acme.ExtendedOrder) ([]acme.Authorization, error) {
+	core := c.core.WithContext(ctx)
+
 	resc, errc := make(chan acme.Authorization), make(chan domainError)
 
 	delay := time.Second / time.Duration(c.overallRequestLimit)
@@ -16,7 +93,7 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 		time.Sleep(delay)
 
 		go func(authzURL string) {
-			authz, err := c.core.Authorizations.Get(authzURL)
+			authz, err := core.Authorizations.Get(authzURL)
 			if err != nil {
 				errc <- domainError{Domain: authz.Identifier.Value, Error: err}
 				return
@@ -40,7 +117,7 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 	}
 
 	for i, auth := range order.Authorizations {
-		log.Infof("[%s] AuthURL: %s", order.Identifiers[i].Value, auth)
+		log.Info("acme: authorization", log.AttrDomain, order.Identifiers[i].Value, log.AttrAuthzURL, auth)
 	}
 
 	close(resc)
@@ -49,23 +126,30 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 	return responses, failures.Join()
 }
 
-func (c *Certifier) deactivateAuthorizations(order acme.ExtendedOrder, force bool) {
-	for _, authzURL := range order.Authorizations {
-		auth, err := c.core.Authorizations.Get(authzURL)
+func (c *Certifier) deactivateAuthorizations(ctx context.Context, order acme.ExtendedOrder, force bool) {
+	c.deactivateAuthorizationURLs(ctx, order.Authorizations, force)
+}
+
+func (c *Certifier) deactivateAuthorizationURLs(ctx context.Context, authzURLs []string, force bool) {
+	// The authorizations are deactivated even if the context is canceled.
+	core := c.core.WithContext(context.WithoutCancel(ctx))
+
+	for _, authzURL := range authzURLs {
+		auth, err := core.Authorizations.Get(authzURL)
 		if err != nil {
-			log.Infof("Unable to get the authorization for %s: %v", authzURL, err)
+			log.Warn("Unable to get the authorization.", log.AttrAuthzURL, authzURL, log.AttrError, err)
 			continue
 		}
 
 		if auth.Status == acme.StatusValid && !force {
-			log.Infof("Skipping deactivating of valid auth: %s", authzURL)
+			log.Info("Skipping deactivating of valid authorization.", log.AttrAuthzURL, authzURL)
 			continue
 		}
 
-		log.Infof("Deactivating auth: %s", authzURL)
+		log.Info("Deactivating authorization.", log.AttrAuthzURL, authzURL)
 
-		if c.core.Authorizations.Deactivate(authzURL) != nil {
-			log.Infof("Unable to deactivate the authorization: %s", authzURL)
+		if core.Authorizations.Deactivate(authzURL) != nil {
+			log.Warn("Unable to deactivate the authorization.", log.AttrAuthzURL, authzURL)
 		}
 	}
 }
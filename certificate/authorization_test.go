@@ -0,0 +1,173 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertifier_Authorize(t *testing.T) {
+	core, deactivated := newPreAuthorizationCore(t, acme.StatusValid)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	authorizations, err := certifier.Authorize(t.Context(), []string{"example.com"})
+	require.NoError(t, err)
+
+	require.Len(t, authorizations, 1)
+
+	assert.Equal(t, acme.StatusValid, authorizations[0].Status)
+	assert.Equal(t, "example.com", authorizations[0].Identifier.Value)
+	assert.Regexp(t, `/authz/example.com$`, authorizations[0].Location)
+
+	assert.Empty(t, deactivated())
+}
+
+func TestCertifier_Authorize_solveError(t *testing.T) {
+	core, deactivated := newPreAuthorizationCore(t, acme.StatusPending)
+
+	certifier := NewCertifier(core, &resolverMock{error: errors.New("oops")}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	_, err := certifier.Authorize(t.Context(), []string{"example.com", "example.org"})
+	require.EqualError(t, err, "oops")
+
+	// The authorizations are not valid: they are deactivated.
+	assert.Len(t, deactivated(), 2)
+}
+
+func TestCertifier_Authorize_wildcard(t *testing.T) {
+	core, _ := newPreAuthorizationCore(t, acme.StatusValid)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	_, err := certifier.Authorize(t.Context(), []string{"*.example.com"})
+	require.EqualError(t, err, "authorization[new]: a wildcard domain cannot be pre-authorized: *.example.com")
+}
+
+func TestCertifier_Authorize_unsupported(t *testing.T) {
+	server := servermock.NewBuilder(func(server *httptest.Server) (*httptest.Server, error) {
+		return server, nil
+	}).
+		Route("GET /dir", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			serverURL := fmt.Sprintf("https://%s", req.Context().Value(http.LocalAddrContextKey))
+
+			servermock.JSONEncode(acme.Directory{
+				NewNonceURL:   serverURL + "/nonce",
+				NewAccountURL: serverURL + "/account",
+				NewOrderURL:   serverURL + "/newOrder",
+			}).ServeHTTP(rw, req)
+		})).
+		BuildHTTPS(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	_, err = certifier.Authorize(t.Context(), []string{"example.com"})
+	require.EqualError(t, err, "the ACME server does not support pre-authorization (newAuthz)")
+}
+
+// newPreAuthorizationCore creates a Core with an ACME server supporting pre-authorization.
+// The status is the status of the authorizations after their creation.
+// The returned function returns the domains of the deactivated authorizations.
+func newPreAuthorizationCore(t *testing.T, status string) (*api.Core, func() []string) {
+	t.Helper()
+
+	var (
+		mu          sync.Mutex
+		deactivated []string
+	)
+
+	server := tester.MockACMEServer().
+		Route("POST /newAuthz", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := readJWSPayload(req)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var msg acme.NewAuthzMessage
+
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			rw.Header().Set("Location", fmt.Sprintf("https://%s/authz/%s", req.Context().Value(http.LocalAddrContextKey), msg.Identifier.Value))
+			rw.WriteHeader(http.StatusCreated)
+
+			_ = json.NewEncoder(rw).Encode(acme.Authorization{
+				Status:     acme.StatusPending,
+				Identifier: msg.Identifier,
+				Challenges: []acme.Challenge{{Type: "http-01", Token: "token"}},
+			})
+		})).
+		Route("POST /authz/{domain}", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := readJWSPayload(req)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if len(body) > 0 {
+				mu.Lock()
+				deactivated = append(deactivated, req.PathValue("domain"))
+				mu.Unlock()
+			}
+
+			servermock.JSONEncode(acme.Authorization{
+				Status:     status,
+				Identifier: acme.Identifier{Type: "dns", Value: req.PathValue("domain")},
+			}).ServeHTTP(rw, req)
+		})).
+		BuildHTTPS(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", key)
+	require.NoError(t, err)
+
+	return core, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return deactivated
+	}
+}
+
+// readJWSPayload reads the payload of a JWS request, without verifying the signature.
+func readJWSPayload(req *http.Request) ([]byte, error) {
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	jws, err := jose.ParseSigned(string(raw), []jose.SignatureAlgorithm{jose.RS256})
+	if err != nil {
+		return nil, err
+	}
+
+	return jws.UnsafePayloadWithoutVerification(), nil
+}
@@ -0,0 +1,211 @@
+package certificate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	challengeresolver "github.com/go-acme/lego/v4/challenge/resolver"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/miekg/dns"
+)
+
+// caaFlagCritical the issuer critical flag (RFC 8659, section 4.1).
+const caaFlagCritical = 128
+
+// The CAA property tags.
+const (
+	caaTagIssue     = "issue"
+	caaTagIssueWild = "issuewild"
+)
+
+// The parameters of the issue and issuewild properties (RFC 8657).
+const (
+	caaParamAccountURI        = "accounturi"
+	caaParamValidationMethods = "validationmethods"
+)
+
+// caaKnownTags the property tags which can be marked as critical without preventing the issuance.
+var caaKnownTags = []string{caaTagIssue, caaTagIssueWild, "iodef", "contactemail", "contactphone", "issuemail", "issuevmc"}
+
+// lookupCAA is used to mock the DNS lookups in the tests.
+var lookupCAA = dns01.LookupCAA
+
+// challengeTypesResolver a resolver which can provide the challenge types used to solve the authorizations.
+type challengeTypesResolver interface {
+	ChallengeTypes(ctx context.Context) []challenge.Type
+}
+
+// checkCAA checks the CAA records of the domains (RFC 8659) against the CAA identities of the ACME server,
+// the account URL, and the challenge types (RFC 8657).
+// If the CAA records restrict the validation methods,
+// the returned context restricts the challenge types used to solve the authorizations (see resolver.WithChallengeTypes).
+func (c *Certifier) checkCAA(ctx context.Context, domains []string) (context.Context, error) {
+	identities := c.core.GetDirectory().Meta.CaaIdentities
+	if len(identities) == 0 {
+		log.Warn("acme: the server doesn't provide CAA identities, the CAA records are not checked.", log.AttrDomains, domains)
+		return ctx, nil
+	}
+
+	var types []challenge.Type
+	if r, ok := c.resolver.(challengeTypesResolver); ok {
+		types = r.ChallengeTypes(ctx)
+	}
+
+	check := caaCheck{
+		identities: identities,
+		accountURL: c.core.GetAccountURL(),
+		types:      types,
+	}
+
+	allowed := types
+
+	var restricted bool
+
+	for _, domain := range domains {
+		if net.ParseIP(domain) != nil {
+			continue
+		}
+
+		records, err := lookupCAA(ctx, domain)
+		if err != nil {
+			return ctx, fmt.Errorf("CAA: %s: %w", domain, err)
+		}
+
+		domainTypes, err := check.verify(domain, records)
+		if err != nil {
+			return ctx, fmt.Errorf("CAA: %s: %w", domain, err)
+		}
+
+		if domainTypes == nil {
+			continue
+		}
+
+		restricted = true
+
+		allowed = slices.DeleteFunc(slices.Clone(allowed), func(t challenge.Type) bool {
+			return !slices.Contains(domainTypes, t)
+		})
+	}
+
+	if !restricted {
+		return ctx, nil
+	}
+
+	if len(allowed) == 0 {
+		return ctx, fmt.Errorf("CAA: the validation methods allowed by the CAA records of %s are not available", strings.Join(domains, ", "))
+	}
+
+	log.Info("acme: the CAA records restrict the validation methods.", log.AttrDomains, domains, log.AttrChallengeType, allowed)
+
+	return challengeresolver.WithChallengeTypes(ctx, allowed...), nil
+}
+
+// caaCheck the values against which the CAA records are checked.
+type caaCheck struct {
+	identities []string
+	accountURL string
+
+	// types the challenge types which can be used.
+	// If nil, the validation methods are not checked.
+	types []challenge.Type
+}
+
+// verify checks the relevant CAA record set of a domain.
+// It returns the challenge types allowed by the records, or nil if the records don't restrict the challenge types.
+func (c caaCheck) verify(domain string, records []*dns.CAA) ([]challenge.Type, error) {
+	for _, record := range records {
+		if record.Flag&caaFlagCritical != 0 && !slices.Contains(caaKnownTags, strings.ToLower(record.Tag)) {
+			return nil, fmt.Errorf("unknown critical property %q", record.Tag)
+		}
+	}
+
+	issues := filterCAA(records, caaTagIssue)
+
+	// The issuewild properties take precedence over the issue properties for the wildcard domains.
+	if strings.HasPrefix(domain, "*.") {
+		if wild := filterCAA(records, caaTagIssueWild); len(wild) > 0 {
+			issues = wild
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil, nil
+	}
+
+	var (
+		allowed []challenge.Type
+		matched bool
+	)
+
+	for _, record := range issues {
+		issuer, params := parseCAAValue(record.Value)
+
+		if !slices.ContainsFunc(c.identities, func(id string) bool { return strings.EqualFold(id, issuer) }) {
+			continue
+		}
+
+		if uri, ok := params[caaParamAccountURI]; ok && uri != c.accountURL {
+			continue
+		}
+
+		methods, ok := params[caaParamValidationMethods]
+		if !ok || c.types == nil {
+			// No restriction.
+			return nil, nil
+		}
+
+		for method := range strings.SplitSeq(methods, ",") {
+			t := challenge.Type(strings.TrimSpace(method))
+
+			if slices.Contains(c.types, t) && !slices.Contains(allowed, t) {
+				matched = true
+
+				allowed = append(allowed, t)
+			}
+		}
+	}
+
+	if !matched {
+		return nil, fmt.Errorf("the CAA records don't allow the issuance by %s with the account %q and the challenges %v",
+			strings.Join(c.identities, ", "), c.accountURL, c.types)
+	}
+
+	return allowed, nil
+}
+
+func filterCAA(records []*dns.CAA, tag string) []*dns.CAA {
+	var filtered []*dns.CAA
+
+	for _, record := range records {
+		if strings.EqualFold(record.Tag, tag) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered
+}
+
+// parseCAAValue parses the value of an issue or issuewild property (RFC 8659, section 4.2):
+// `issuer-domain-name; key1=value1; key2=value2`.
+// The issuer domain name is empty if the property forbids the issuance.
+func parseCAAValue(value string) (string, map[string]string) {
+	parts := strings.Split(value, ";")
+
+	params := make(map[string]string)
+
+	for _, part := range parts[1:] {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(val)
+	}
+
+	return strings.TrimSpace(parts[0]), params
+}
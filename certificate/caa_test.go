@@ -0,0 +1,325 @@
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	challengeresolver "github.com/go-acme/lego/v4/challenge/resolver"
+	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const caaAccountURL = "https://ca.example/acme/acct/123"
+
+func Test_caaCheck_verify(t *testing.T) {
+	check := caaCheck{
+		identities: []string{"ca.example"},
+		accountURL: caaAccountURL,
+		types:      []challenge.Type{challenge.DNS01, challenge.HTTP01},
+	}
+
+	testCases := []struct {
+		desc       string
+		domain     string
+		records    []*dns.CAA
+		expected   []challenge.Type
+		requireErr require.ErrorAssertionFunc
+	}{
+		{
+			desc:       "no records",
+			domain:     "example.com",
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "no issue property",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "iodef", "mailto:admin@example.com")},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "allowed",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "other.example"), fakeCAA(0, "issue", "CA.example")},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "other CA",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "other.example")},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "issuance forbidden",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", ";")},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "account URI",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "ca.example; accounturi="+caaAccountURL)},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "other account URI",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "ca.example; accounturi=https://ca.example/acme/acct/456")},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "validation methods",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "ca.example; validationmethods=tls-alpn-01,dns-01")},
+			expected:   []challenge.Type{challenge.DNS01},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "validation methods not available",
+			domain:     "example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "ca.example; validationmethods=tls-alpn-01")},
+			requireErr: require.Error,
+		},
+		{
+			desc:   "validation methods and unrestricted record",
+			domain: "example.com",
+			records: []*dns.CAA{
+				fakeCAA(0, "issue", "ca.example; validationmethods=dns-01"),
+				fakeCAA(0, "issue", "ca.example"),
+			},
+			requireErr: require.NoError,
+		},
+		{
+			desc:   "wildcard: issuewild",
+			domain: "*.example.com",
+			records: []*dns.CAA{
+				fakeCAA(0, "issue", "ca.example"),
+				fakeCAA(0, "issuewild", "other.example"),
+			},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "wildcard: issue",
+			domain:     "*.example.com",
+			records:    []*dns.CAA{fakeCAA(0, "issue", "ca.example")},
+			requireErr: require.NoError,
+		},
+		{
+			desc:   "not wildcard: issuewild ignored",
+			domain: "example.com",
+			records: []*dns.CAA{
+				fakeCAA(0, "issue", "ca.example"),
+				fakeCAA(0, "issuewild", ";"),
+			},
+			requireErr: require.NoError,
+		},
+		{
+			desc:   "unknown critical property",
+			domain: "example.com",
+			records: []*dns.CAA{
+				fakeCAA(0, "issue", "ca.example"),
+				fakeCAA(caaFlagCritical, "tbs", "unknown"),
+			},
+			requireErr: require.Error,
+		},
+		{
+			desc:   "unknown non-critical property",
+			domain: "example.com",
+			records: []*dns.CAA{
+				fakeCAA(0, "issue", "ca.example"),
+				fakeCAA(0, "tbs", "unknown"),
+			},
+			requireErr: require.NoError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			types, err := check.verify(test.domain, test.records)
+			test.requireErr(t, err)
+
+			assert.Equal(t, test.expected, types)
+		})
+	}
+}
+
+func Test_parseCAAValue(t *testing.T) {
+	testCases := []struct {
+		value          string
+		expectedIssuer string
+		expectedParams map[string]string
+	}{
+		{
+			value:          "ca.example",
+			expectedIssuer: "ca.example",
+			expectedParams: map[string]string{},
+		},
+		{
+			value:          ";",
+			expectedIssuer: "",
+			expectedParams: map[string]string{},
+		},
+		{
+			value:          " ca.example ; accounturi=https://ca.example/acct/1;validationmethods = dns-01,http-01 ",
+			expectedIssuer: "ca.example",
+			expectedParams: map[string]string{
+				"accounturi":        "https://ca.example/acct/1",
+				"validationmethods": "dns-01,http-01",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			issuer, params := parseCAAValue(test.value)
+
+			assert.Equal(t, test.expectedIssuer, issuer)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestCertifier_checkCAA(t *testing.T) {
+	records := map[string][]*dns.CAA{
+		"example.com":     {fakeCAA(0, "issue", "ca.example; validationmethods=dns-01,http-01")},
+		"www.example.com": {fakeCAA(0, "issue", "ca.example; validationmethods=http-01")},
+		"example.org":     {fakeCAA(0, "issue", "other.example")},
+	}
+
+	mockLookupCAA(t, records)
+
+	core := newCAACore(t, []string{"ca.example"})
+
+	testCases := []struct {
+		desc       string
+		domains    []string
+		expected   []challenge.Type
+		requireErr require.ErrorAssertionFunc
+	}{
+		{
+			desc:       "no restriction",
+			domains:    []string{"example.net"},
+			expected:   []challenge.Type{challenge.DNS01, challenge.HTTP01},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "restricted",
+			domains:    []string{"example.com", "www.example.com"},
+			expected:   []challenge.Type{challenge.HTTP01},
+			requireErr: require.NoError,
+		},
+		{
+			desc:       "not allowed",
+			domains:    []string{"example.com", "example.org"},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "IP address",
+			domains:    []string{"192.0.2.1"},
+			expected:   []challenge.Type{challenge.DNS01, challenge.HTTP01},
+			requireErr: require.NoError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			manager := challengeresolver.NewSolversManager(core)
+			require.NoError(t, manager.SetHTTP01Provider(&providerMock{}))
+			require.NoError(t, manager.SetDNS01Provider(&providerMock{}))
+
+			certifier := NewCertifier(core, challengeresolver.NewProber(manager), CertifierOptions{CheckCAA: true})
+
+			ctx, err := certifier.checkCAA(t.Context(), test.domains)
+			test.requireErr(t, err)
+
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, test.expected, manager.ChallengeTypes(ctx))
+		})
+	}
+}
+
+func TestCertifier_checkCAA_noIdentities(t *testing.T) {
+	mockLookupCAA(t, map[string][]*dns.CAA{
+		"example.org": {fakeCAA(0, "issue", "other.example")},
+	})
+
+	core := newCAACore(t, nil)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{CheckCAA: true})
+
+	_, err := certifier.checkCAA(t.Context(), []string{"example.org"})
+	require.NoError(t, err)
+}
+
+type providerMock struct{}
+
+func (p *providerMock) Present(_, _, _ string) error { return nil }
+
+func (p *providerMock) CleanUp(_, _, _ string) error { return nil }
+
+func fakeCAA(flag uint8, tag, value string) *dns.CAA {
+	return &dns.CAA{
+		Hdr:   dns.RR_Header{Rrtype: dns.TypeCAA, Class: dns.ClassINET},
+		Flag:  flag,
+		Tag:   tag,
+		Value: value,
+	}
+}
+
+func mockLookupCAA(t *testing.T, records map[string][]*dns.CAA) {
+	t.Helper()
+
+	original := lookupCAA
+
+	t.Cleanup(func() { lookupCAA = original })
+
+	lookupCAA = func(_ context.Context, domain string) ([]*dns.CAA, error) {
+		return records[domain], nil
+	}
+}
+
+func newCAACore(t *testing.T, identities []string) *api.Core {
+	t.Helper()
+
+	server := servermock.NewBuilder(
+		func(server *httptest.Server) (*httptest.Server, error) {
+			return server, nil
+		}).
+		Route("GET /dir", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			serverURL := fmt.Sprintf("https://%s", req.Context().Value(http.LocalAddrContextKey))
+
+			servermock.JSONEncode(acme.Directory{
+				NewNonceURL:   serverURL + "/nonce",
+				NewAccountURL: serverURL + "/account",
+				NewOrderURL:   serverURL + "/newOrder",
+				RevokeCertURL: serverURL + "/revokeCert",
+				KeyChangeURL:  serverURL + "/keyChange",
+				Meta:          acme.Meta{CaaIdentities: identities},
+			}).ServeHTTP(rw, req)
+		})).
+		BuildHTTPS(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", caaAccountURL, key)
+	require.NoError(t, err)
+
+	return core
+}
@@ -2,6 +2,8 @@ package certificate
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"crypto"
 	"crypto/x509"
 	"encoding/base64"
@@ -9,7 +11,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/go-acme/lego/v4/acme"
@@ -17,7 +18,9 @@ import (
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
 	"github.com/go-acme/lego/v4/platform/wait"
+	"github.com/go-acme/lego/v4/ratelimit"
 	"golang.org/x/crypto/ocsp"
 	"golang.org/x/net/idna"
 )
@@ -49,6 +52,9 @@ type Resource struct {
 	Certificate       []byte `json:"-"`
 	IssuerCertificate []byte `json:"-"`
 	CSR               []byte `json:"-"`
+
+	// Profile the profile used to issue the certificate (draft-ietf-acme-profiles).
+	Profile string `json:"profile,omitempty"`
 }
 
 // ObtainRequest The request to obtain certificate.
@@ -121,11 +127,22 @@ type resolver interface {
 	Solve(authorizations []acme.Authorization) error
 }
 
+// contextResolver a resolver which can be canceled through a context.
+type contextResolver interface {
+	SolveWithContext(ctx context.Context, authorizations []acme.Authorization) error
+}
+
 type CertifierOptions struct {
 	KeyType             certcrypto.KeyType
 	Timeout             time.Duration
 	OverallRequestLimit int
 	DisableCommonName   bool
+
+	// CheckCAA enables the check of the CAA records of the domains before the creation of the orders.
+	CheckCAA bool
+
+	// RateLimiter checks the known rate limits of the ACME server before the creation of the orders (optional).
+	RateLimiter *ratelimit.Limiter
 }
 
 // Certifier A service to obtain/renew/revoke certificates.
@@ -157,6 +174,22 @@ func NewCertifier(core *api.Core, resolver resolver, options CertifierOptions) *
 // This function will never return a partial certificate.
 // If one domain in the list fails, the whole certificate will fail.
 func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
+	return c.ObtainWithContext(context.Background(), request)
+}
+
+// ObtainWithContext is like Obtain, but the context is used to cancel the process.
+// The challenges already presented are cleaned up even if the context is canceled.
+func (c *Certifier) ObtainWithContext(ctx context.Context, request ObtainRequest) (*Resource, error) {
+	start := time.Now()
+
+	cert, err := c.obtain(ctx, request)
+
+	observeOrder(cert, err, start)
+
+	return cert, err
+}
+
+func (c *Certifier) obtain(ctx context.Context, request ObtainRequest) (*Resource, error) {
 	if len(request.Domains) == 0 {
 		return nil, errors.New("no domains to obtain a certificate for")
 	}
@@ -164,9 +197,9 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 	domains := sanitizeDomain(request.Domains)
 
 	if request.Bundle {
-		log.Infof("[%s] acme: Obtaining bundled SAN certificate", strings.Join(domains, ", "))
+		log.Info("acme: Obtaining bundled SAN certificate", log.AttrDomains, domains)
 	} else {
-		log.Infof("[%s] acme: Obtaining SAN certificate", strings.Join(domains, ", "))
+		log.Info("acme: Obtaining SAN certificate", log.AttrDomains, domains)
 	}
 
 	orderOpts := &api.OrderOptions{
@@ -176,30 +209,41 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 		ReplacesCertID: request.ReplacesCertID,
 	}
 
-	order, err := c.core.Orders.NewWithOptions(domains, orderOpts)
+	if c.options.CheckCAA {
+		var err error
+
+		ctx, err = c.checkCAA(ctx, domains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := c.newOrder(ctx, domains, orderOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	authz, err := c.getAuthorizations(order)
+	authz, err := c.getAuthorizations(ctx, order)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	err = c.resolver.Solve(authz)
+	err = c.solve(ctx, authz)
 	if err != nil {
+		c.recordFailedValidations(ctx, order.Authorizations)
+
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	log.Infof("[%s] acme: Validations succeeded; requesting certificates", strings.Join(domains, ", "))
+	log.Info("acme: Validations succeeded; requesting certificates", log.AttrDomains, domains, log.AttrOrderURL, order.Location)
 
 	failures := newObtainError()
 
-	cert, err := c.getForOrder(domains, order, request)
+	cert, err := c.getForOrder(ctx, domains, order, request)
 	if err != nil {
 		for _, auth := range authz {
 			failures.Add(challenge.GetTargetedDomain(auth), err)
@@ -207,7 +251,7 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 	}
 
 	if request.AlwaysDeactivateAuthorizations {
-		c.deactivateAuthorizations(order, true)
+		c.deactivateAuthorizations(ctx, order, true)
 	}
 
 	return cert, failures.Join()
@@ -223,6 +267,22 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 // This function will never return a partial certificate.
 // If one domain in the list fails, the whole certificate will fail.
 func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error) {
+	return c.ObtainForCSRWithContext(context.Background(), request)
+}
+
+// ObtainForCSRWithContext is like ObtainForCSR, but the context is used to cancel the process.
+// The challenges already presented are cleaned up even if the context is canceled.
+func (c *Certifier) ObtainForCSRWithContext(ctx context.Context, request ObtainForCSRRequest) (*Resource, error) {
+	start := time.Now()
+
+	cert, err := c.obtainForCSR(ctx, request)
+
+	observeOrder(cert, err, start)
+
+	return cert, err
+}
+
+func (c *Certifier) obtainForCSR(ctx context.Context, request ObtainForCSRRequest) (*Resource, error) {
 	if request.CSR == nil {
 		return nil, errors.New("cannot obtain resource for CSR: CSR is missing")
 	}
@@ -232,9 +292,9 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	domains := certcrypto.ExtractDomainsCSR(request.CSR)
 
 	if request.Bundle {
-		log.Infof("[%s] acme: Obtaining bundled SAN certificate given a CSR", strings.Join(domains, ", "))
+		log.Info("acme: Obtaining bundled SAN certificate given a CSR", log.AttrDomains, domains)
 	} else {
-		log.Infof("[%s] acme: Obtaining SAN certificate given a CSR", strings.Join(domains, ", "))
+		log.Info("acme: Obtaining SAN certificate given a CSR", log.AttrDomains, domains)
 	}
 
 	orderOpts := &api.OrderOptions{
@@ -244,26 +304,37 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 		ReplacesCertID: request.ReplacesCertID,
 	}
 
-	order, err := c.core.Orders.NewWithOptions(domains, orderOpts)
+	if c.options.CheckCAA {
+		var err error
+
+		ctx, err = c.checkCAA(ctx, domains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := c.newOrder(ctx, domains, orderOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	authz, err := c.getAuthorizations(order)
+	authz, err := c.getAuthorizations(ctx, order)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	err = c.resolver.Solve(authz)
+	err = c.solve(ctx, authz)
 	if err != nil {
+		c.recordFailedValidations(ctx, order.Authorizations)
+
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	log.Infof("[%s] acme: Validations succeeded; requesting certificates", strings.Join(domains, ", "))
+	log.Info("acme: Validations succeeded; requesting certificates", log.AttrDomains, domains, log.AttrOrderURL, order.Location)
 
 	failures := newObtainError()
 
@@ -272,7 +343,7 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 		privateKey = certcrypto.PEMEncode(request.PrivateKey)
 	}
 
-	cert, err := c.getForCSR(domains, order, request.Bundle, request.CSR.Raw, privateKey, request.PreferredChain)
+	cert, err := c.getForCSR(ctx, domains, order, request.Bundle, request.CSR.Raw, privateKey, request.PreferredChain)
 	if err != nil {
 		for _, auth := range authz {
 			failures.Add(challenge.GetTargetedDomain(auth), err)
@@ -280,7 +351,7 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	}
 
 	if request.AlwaysDeactivateAuthorizations {
-		c.deactivateAuthorizations(order, true)
+		c.deactivateAuthorizations(ctx, order, true)
 	}
 
 	if cert != nil {
@@ -291,7 +362,7 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	return cert, failures.Join()
 }
 
-func (c *Certifier) getForOrder(domains []string, order acme.ExtendedOrder, request ObtainRequest) (*Resource, error) {
+func (c *Certifier) getForOrder(ctx context.Context, domains []string, order acme.ExtendedOrder, request ObtainRequest) (*Resource, error) {
 	privateKey := request.PrivateKey
 
 	if privateKey == nil {
@@ -338,24 +409,30 @@ func (c *Certifier) getForOrder(domains []string, order acme.ExtendedOrder, requ
 		return nil, err
 	}
 
-	return c.getForCSR(domains, order, request.Bundle, csr, certcrypto.PEMEncode(privateKey), request.PreferredChain)
+	return c.getForCSR(ctx, domains, order, request.Bundle, csr, certcrypto.PEMEncode(privateKey), request.PreferredChain)
 }
 
-func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle bool, csr, privateKeyPem []byte, preferredChain string) (*Resource, error) {
-	respOrder, err := c.core.Orders.UpdateForCSR(order.Finalize, csr)
+func (c *Certifier) getForCSR(ctx context.Context, domains []string, order acme.ExtendedOrder, bundle bool, csr, privateKeyPem []byte, preferredChain string) (*Resource, error) {
+	core := c.core.WithContext(ctx)
+
+	respOrder, err := core.Orders.UpdateForCSR(order.Finalize, csr)
 	if err != nil {
 		return nil, err
 	}
 
+	// The certificate is counted by the rate limits once the order is finalized.
+	c.options.RateLimiter.RecordCertificate(c.core.GetAccountURL(), domains)
+
 	certRes := &Resource{
 		Domain:     domains[0],
 		CertURL:    respOrder.Certificate,
 		PrivateKey: privateKeyPem,
+		Profile:    cmp.Or(respOrder.Profile, order.Profile),
 	}
 
 	if respOrder.Status == acme.StatusValid {
 		// if the certificate is available right away, shortcut!
-		ok, errR := c.checkResponse(respOrder, certRes, bundle, preferredChain)
+		ok, errR := c.checkResponse(ctx, respOrder, certRes, bundle, preferredChain)
 		if errR != nil {
 			return nil, errR
 		}
@@ -370,13 +447,13 @@ func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle
 		timeout = 30 * time.Second
 	}
 
-	err = wait.For("certificate", timeout, timeout/60, func() (bool, error) {
-		ord, errW := c.core.Orders.Get(order.Location)
+	err = wait.ForWithContext(ctx, "certificate", timeout, timeout/60, func() (bool, error) {
+		ord, errW := core.Orders.Get(order.Location)
 		if errW != nil {
 			return false, errW
 		}
 
-		done, errW := c.checkResponse(ord, certRes, bundle, preferredChain)
+		done, errW := c.checkResponse(ctx, ord, certRes, bundle, preferredChain)
 		if errW != nil {
 			return false, errW
 		}
@@ -395,13 +472,13 @@ func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle
 // The certRes input should already have the Domain (common name) field populated.
 //
 // If bundle is true, the certificate will be bundled with the issuer's cert.
-func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, bundle bool, preferredChain string) (bool, error) {
+func (c *Certifier) checkResponse(ctx context.Context, order acme.ExtendedOrder, certRes *Resource, bundle bool, preferredChain string) (bool, error) {
 	valid, err := checkOrderStatus(order)
 	if err != nil || !valid {
 		return valid, err
 	}
 
-	certs, err := c.core.Certificates.GetAll(order.Certificate, bundle)
+	certs, err := c.core.WithContext(ctx).Certificates.GetAll(order.Certificate, bundle)
 	if err != nil {
 		return false, err
 	}
@@ -413,7 +490,7 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 	certRes.CertStableURL = order.Certificate
 
 	if preferredChain == "" {
-		log.Infof("[%s] Server responded with a certificate.", certRes.Domain)
+		log.Info("Server responded with a certificate.", log.AttrDomain, certRes.Domain)
 
 		return true, nil
 	}
@@ -425,7 +502,8 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 		}
 
 		if ok {
-			log.Infof("[%s] Server responded with a certificate for the preferred certificate chains %q.", certRes.Domain, preferredChain)
+			log.Info("Server responded with a certificate for the preferred certificate chains.",
+				log.AttrDomain, certRes.Domain, "preferredChain", preferredChain)
 
 			certRes.IssuerCertificate = cert.Issuer
 			certRes.Certificate = cert.Cert
@@ -436,7 +514,8 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 		}
 	}
 
-	log.Infof("lego has been configured to prefer certificate chains with issuer %q, but no chain from the CA matched this issuer. Using the default certificate chain instead.", preferredChain)
+	log.Info("lego has been configured to prefer certificate chains with a specific issuer, but no chain from the CA matched this issuer. Using the default certificate chain instead.",
+		log.AttrDomain, certRes.Domain, "preferredChain", preferredChain)
 
 	return true, nil
 }
@@ -448,6 +527,11 @@ func (c *Certifier) Revoke(cert []byte) error {
 
 // RevokeWithReason takes a PEM encoded certificate or bundle and tries to revoke it at the CA.
 func (c *Certifier) RevokeWithReason(cert []byte, reason *uint) error {
+	return c.RevokeWithReasonWithContext(context.Background(), cert, reason)
+}
+
+// RevokeWithReasonWithContext is like RevokeWithReason, but the context is used to cancel the request.
+func (c *Certifier) RevokeWithReasonWithContext(ctx context.Context, cert []byte, reason *uint) error {
 	certificates, err := certcrypto.ParsePEMBundle(cert)
 	if err != nil {
 		return err
@@ -463,7 +547,39 @@ func (c *Certifier) RevokeWithReason(cert []byte, reason *uint) error {
 		Reason:      reason,
 	}
 
-	return c.core.Certificates.Revoke(revokeMsg)
+	return c.core.WithContext(ctx).Certificates.Revoke(revokeMsg)
+}
+
+// RevokeWithCertificateKey takes a PEM encoded certificate or bundle and tries to revoke it at the CA,
+// with a request signed by the private key of the certificate instead of the account key.
+// No account is required: it allows revoking a certificate after a key compromise,
+// when the account which obtained the certificate is not available.
+func (c *Certifier) RevokeWithCertificateKey(ctx context.Context, cert []byte, certKey crypto.Signer, reason *uint) error {
+	certificates, err := certcrypto.ParsePEMBundle(cert)
+	if err != nil {
+		return err
+	}
+
+	x509Cert := certificates[0]
+	if x509Cert.IsCA {
+		return errors.New("certificate bundle starts with a CA certificate")
+	}
+
+	if certKey == nil {
+		return errors.New("the certificate key cannot be nil")
+	}
+
+	pub, ok := x509Cert.PublicKey.(interface{ Equal(x crypto.PublicKey) bool })
+	if !ok || !pub.Equal(certKey.Public()) {
+		return errors.New("the key does not match the certificate")
+	}
+
+	revokeMsg := acme.RevokeCertMessage{
+		Certificate: base64.RawURLEncoding.EncodeToString(x509Cert.Raw),
+		Reason:      reason,
+	}
+
+	return c.core.WithContext(ctx).Certificates.RevokeWithKey(revokeMsg, certKey)
 }
 
 // RenewOptions options used by Certifier.RenewWithOptions.
@@ -513,6 +629,12 @@ func (c *Certifier) Renew(certRes Resource, bundle, mustStaple bool, preferredCh
 //
 // For private key reuse the PrivateKey property of the passed in Resource should be non-nil.
 func (c *Certifier) RenewWithOptions(certRes Resource, options *RenewOptions) (*Resource, error) {
+	return c.RenewWithOptionsWithContext(context.Background(), certRes, options)
+}
+
+// RenewWithOptionsWithContext is like RenewWithOptions, but the context is used to cancel the process.
+// The challenges already presented are cleaned up even if the context is canceled.
+func (c *Certifier) RenewWithOptionsWithContext(ctx context.Context, certRes Resource, options *RenewOptions) (*Resource, error) {
 	// Input certificate is PEM encoded.
 	// Decode it here as we may need the decoded cert later on in the renewal process.
 	// The input may be a bundle or a single certificate.
@@ -528,7 +650,7 @@ func (c *Certifier) RenewWithOptions(certRes Resource, options *RenewOptions) (*
 
 	// This is just meant to be informal for the user.
 	timeLeft := x509Cert.NotAfter.Sub(time.Now().UTC())
-	log.Infof("[%s] acme: Trying renewal with %d hours remaining", certRes.Domain, int(timeLeft.Hours()))
+	log.Info("acme: Trying renewal", log.AttrDomain, certRes.Domain, "hoursRemaining", int(timeLeft.Hours()))
 
 	// We always need to request a new certificate to renew.
 	// Start by checking to see if the certificate was based off a CSR,
@@ -550,7 +672,7 @@ func (c *Certifier) RenewWithOptions(certRes Resource, options *RenewOptions) (*
 			request.AlwaysDeactivateAuthorizations = options.AlwaysDeactivateAuthorizations
 		}
 
-		return c.ObtainForCSR(request)
+		return c.ObtainForCSRWithContext(ctx, request)
 	}
 
 	var privateKey crypto.PrivateKey
@@ -577,7 +699,7 @@ func (c *Certifier) RenewWithOptions(certRes Resource, options *RenewOptions) (*
 		request.AlwaysDeactivateAuthorizations = options.AlwaysDeactivateAuthorizations
 	}
 
-	return c.Obtain(request)
+	return c.ObtainWithContext(ctx, request)
 }
 
 // GetOCSP takes a PEM encoded cert or cert bundle returning the raw OCSP response,
@@ -692,6 +814,14 @@ func (c *Certifier) Get(url string, bundle bool) (*Resource, error) {
 	}, nil
 }
 
+func (c *Certifier) solve(ctx context.Context, authorizations []acme.Authorization) error {
+	if r, ok := c.resolver.(contextResolver); ok {
+		return r.SolveWithContext(ctx, authorizations)
+	}
+
+	return c.resolver.Solve(authorizations)
+}
+
 func hasPreferredChain(issuer []byte, preferredChain string) (bool, error) {
 	certs, err := certcrypto.ParsePEMBundle(issuer)
 	if err != nil {
@@ -707,6 +837,22 @@ func hasPreferredChain(issuer []byte, preferredChain string) (bool, error) {
 	return false, nil
 }
 
+// observeOrder records the metrics related to an order.
+func observeOrder(cert *Resource, err error, start time.Time) {
+	metrics.Order(err == nil, time.Since(start))
+
+	if err != nil || cert == nil {
+		return
+	}
+
+	x509Cert, err := certcrypto.ParsePEMCertificate(cert.Certificate)
+	if err != nil {
+		return
+	}
+
+	metrics.CertificateExpiry(cert.Domain, x509Cert.NotAfter)
+}
+
 func checkOrderStatus(order acme.ExtendedOrder) (bool, error) {
 	switch order.Status {
 	case acme.StatusValid:
@@ -729,7 +875,7 @@ func sanitizeDomain(domains []string) []string {
 	for _, domain := range domains {
 		sanitizedDomain, err := idna.ToASCII(domain)
 		if err != nil {
-			log.Infof("skip domain %q: unable to sanitize (punnycode): %v", domain, err)
+			log.Warn("skip domain: unable to sanitize (punnycode).", log.AttrDomain, domain, log.AttrError, err)
 		} else {
 			sanitizedDomains = append(sanitizedDomains, sanitizedDomain)
 		}
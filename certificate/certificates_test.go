@@ -195,7 +195,7 @@ func Test_checkResponse(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "")
+	valid, err := certifier.checkResponse(t.Context(), order, certRes, true, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -229,7 +229,7 @@ func Test_checkResponse_issuerRelUp(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "")
+	valid, err := certifier.checkResponse(t.Context(), order, certRes, true, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -263,7 +263,7 @@ func Test_checkResponse_no_bundle(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, false, "")
+	valid, err := certifier.checkResponse(t.Context(), order, certRes, false, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -306,7 +306,7 @@ func Test_checkResponse_alternate(t *testing.T) {
 		Domain: "example.com",
 	}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "DST Root CA X3")
+	valid, err := certifier.checkResponse(t.Context(), order, certRes, true, "DST Root CA X3")
 	require.NoError(t, err)
 
 	assert.True(t, valid)
@@ -398,3 +398,34 @@ type resolverMock struct {
 func (r *resolverMock) Solve(_ []acme.Authorization) error {
 	return r.error
 }
+
+func TestCertifier_RevokeWithCertificateKey(t *testing.T) {
+	server := tester.MockACMEServer().
+		Route("POST /revokeCert", http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})).
+		BuildHTTPS(t)
+
+	accountKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", accountKey)
+	require.NoError(t, err)
+
+	certKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	cert, err := certcrypto.GeneratePemCert(certKey, "example.com", nil)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.RSA2048})
+
+	err = certifier.RevokeWithCertificateKey(t.Context(), cert, otherKey, nil)
+	require.EqualError(t, err, "the key does not match the certificate")
+
+	err = certifier.RevokeWithCertificateKey(t.Context(), cert, certKey, nil)
+	require.NoError(t, err)
+}
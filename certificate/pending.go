@@ -0,0 +1,212 @@
+package certificate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	challengeresolver "github.com/go-acme/lego/v4/challenge/resolver"
+	"github.com/go-acme/lego/v4/log"
+)
+
+// ErrChallengesNotCleanedUp is returned (wrapped) by ResumeOrder
+// when the order has not been resumed, and the challenges have not been cleaned up (e.g. a temporary network error):
+// the pending order must be kept, to be resumed later.
+var ErrChallengesNotCleanedUp = errors.New("the challenges of the pending order have not been cleaned up")
+
+// presenter a resolver which can present the challenges, and validate them later.
+type presenter interface {
+	Present(ctx context.Context, authorizations []acme.Authorization) (*challengeresolver.State, error)
+	Resume(ctx context.Context, authorizations []acme.Authorization, state *challengeresolver.State) error
+}
+
+// PendingOrder an order with presented, but not validated, challenges (see Certifier.PresentOrder).
+// A PendingOrder is serializable (JSON):
+// the order can be resumed later (see Certifier.ResumeOrder), by another process.
+type PendingOrder struct {
+	Domains        []string                 `json:"domains"`
+	OrderURL       string                   `json:"orderUrl"`
+	Authorizations []string                 `json:"authorizations"`
+	Challenges     *challengeresolver.State `json:"challenges"`
+	CreatedAt      time.Time                `json:"createdAt"`
+}
+
+// PresentOrder creates an order, and presents the challenges of its authorizations, without validating them.
+// It's the first step of a split issuance:
+// the pending order is then resumed by ResumeOrder, to validate the challenges, and to obtain the certificate.
+//
+// Only the challenges which can be presented in advance (dns-01, dns-account-01) are supported.
+// The fields of the request related to the order (Domains, NotBefore, NotAfter, Profile, ReplacesCertID) are used.
+func (c *Certifier) PresentOrder(ctx context.Context, request ObtainRequest) (*PendingOrder, error) {
+	p, ok := c.resolver.(presenter)
+	if !ok {
+		return nil, errors.New("the resolver cannot present the challenges in advance")
+	}
+
+	if len(request.Domains) == 0 {
+		return nil, errors.New("no domains to obtain a certificate for")
+	}
+
+	domains := sanitizeDomain(request.Domains)
+
+	log.Info("acme: Presenting the challenges of a SAN certificate", log.AttrDomains, domains)
+
+	orderOpts := &api.OrderOptions{
+		NotBefore:      request.NotBefore,
+		NotAfter:       request.NotAfter,
+		Profile:        request.Profile,
+		ReplacesCertID: request.ReplacesCertID,
+	}
+
+	if c.options.CheckCAA {
+		var err error
+
+		ctx, err = c.checkCAA(ctx, domains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := c.newOrder(ctx, domains, orderOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	authz, err := c.getAuthorizations(ctx, order)
+	if err != nil {
+		c.deactivateAuthorizations(ctx, order, false)
+		return nil, err
+	}
+
+	state, err := p.Present(ctx, authz)
+	if err != nil {
+		c.deactivateAuthorizations(ctx, order, false)
+		return nil, err
+	}
+
+	log.Info("acme: Challenges presented; the order can be resumed", log.AttrDomains, domains, log.AttrOrderURL, order.Location)
+
+	return &PendingOrder{
+		Domains:        domains,
+		OrderURL:       order.Location,
+		Authorizations: order.Authorizations,
+		Challenges:     state,
+		CreatedAt:      time.Now().UTC(),
+	}, nil
+}
+
+// ResumeOrder resumes an order created by PresentOrder:
+// the challenges are validated and cleaned up, then the order is finalized and the certificate is downloaded.
+//
+// The fields of the request related to the certificate (PrivateKey, MustStaple, EmailAddresses, Bundle, PreferredChain,
+// AlwaysDeactivateAuthorizations) are used.
+// The domains of the request, if any, must be the domains of the pending order.
+//
+// If the challenges have not been cleaned up, the error wraps ErrChallengesNotCleanedUp: the order can be resumed again.
+// Otherwise, the pending order cannot be resumed twice.
+func (c *Certifier) ResumeOrder(ctx context.Context, pending *PendingOrder, request ObtainRequest) (*Resource, error) {
+	start := time.Now()
+
+	cert, err := c.resumeOrder(ctx, pending, request)
+
+	observeOrder(cert, err, start)
+
+	return cert, err
+}
+
+func (c *Certifier) resumeOrder(ctx context.Context, pending *PendingOrder, request ObtainRequest) (*Resource, error) {
+	p, ok := c.resolver.(presenter)
+	if !ok {
+		return nil, fmt.Errorf("%w: the resolver cannot present the challenges in advance", ErrChallengesNotCleanedUp)
+	}
+
+	if pending == nil || pending.OrderURL == "" || len(pending.Domains) == 0 {
+		return nil, errors.New("invalid pending order")
+	}
+
+	if len(request.Domains) > 0 && !slices.Equal(sanitizeDomain(request.Domains), pending.Domains) {
+		return nil, fmt.Errorf("%w: the domains %v are not the domains of the pending order %v",
+			ErrChallengesNotCleanedUp, request.Domains, pending.Domains)
+	}
+
+	domains := pending.Domains
+
+	log.Info("acme: Resuming the order", log.AttrDomains, domains, log.AttrOrderURL, pending.OrderURL)
+
+	order, err := c.core.WithContext(ctx).Orders.Get(pending.OrderURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChallengesNotCleanedUp, err)
+	}
+
+	// The location is not returned when the order is fetched.
+	order.Location = pending.OrderURL
+
+	switch order.Status {
+	case acme.StatusPending, acme.StatusReady:
+	case acme.StatusInvalid:
+		return nil, c.cleanUpPending(ctx, p, order, pending, fmt.Errorf("invalid order: %w", order.Err()))
+	default:
+		return nil, c.cleanUpPending(ctx, p, order, pending, fmt.Errorf("the order cannot be resumed (status=%s)", order.Status))
+	}
+
+	authz, err := c.getAuthorizations(ctx, order)
+	if err != nil {
+		// The authorizations are not deactivated: the order can be resumed again.
+		return nil, fmt.Errorf("%w: %w", ErrChallengesNotCleanedUp, err)
+	}
+
+	err = p.Resume(ctx, authz, pending.Challenges)
+	if err != nil {
+		c.recordFailedValidations(ctx, order.Authorizations)
+
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
+		return nil, err
+	}
+
+	log.Info("acme: Validations succeeded; requesting certificates", log.AttrDomains, domains, log.AttrOrderURL, order.Location)
+
+	failures := newObtainError()
+
+	cert, err := c.getForOrder(ctx, domains, order, request)
+	if err != nil {
+		for _, auth := range authz {
+			failures.Add(challenge.GetTargetedDomain(auth), err)
+		}
+	}
+
+	if request.AlwaysDeactivateAuthorizations {
+		c.deactivateAuthorizations(ctx, order, true)
+	}
+
+	return cert, failures.Join()
+}
+
+// cleanUpPending cleans up the challenges of an order which cannot be resumed, and returns the cause.
+// If the authorizations cannot be fetched, the cause wraps ErrChallengesNotCleanedUp.
+func (c *Certifier) cleanUpPending(ctx context.Context, p presenter, order acme.ExtendedOrder, pending *PendingOrder, cause error) error {
+	// The challenges are cleaned up even if the context is canceled.
+	ctx = context.WithoutCancel(ctx)
+
+	authz, err := c.getAuthorizations(ctx, order)
+	if err != nil {
+		log.Warn("acme: Unable to get the authorizations to clean up the challenges.", log.AttrOrderURL, order.Location, log.AttrError, err)
+		return fmt.Errorf("%w: %w", ErrChallengesNotCleanedUp, cause)
+	}
+
+	for i := range authz {
+		// Only the cleanup is done: the valid authorizations are not validated again.
+		authz[i].Status = acme.StatusValid
+	}
+
+	err = p.Resume(ctx, authz, pending.Challenges)
+	if err != nil {
+		log.Warn("acme: Unable to clean up the challenges.", log.AttrOrderURL, order.Location, log.AttrError, err)
+	}
+
+	return cause
+}
@@ -0,0 +1,199 @@
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/challenge"
+	challengeresolver "github.com/go-acme/lego/v4/challenge/resolver"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type presenterMock struct {
+	resolverMock
+
+	presented []string
+	resumed   []string
+}
+
+func (p *presenterMock) Present(_ context.Context, authorizations []acme.Authorization) (*challengeresolver.State, error) {
+	state := &challengeresolver.State{}
+
+	for _, authz := range authorizations {
+		p.presented = append(p.presented, authz.Identifier.Value)
+
+		state.Challenges = append(state.Challenges, challengeresolver.ChallengeState{
+			Domain:        authz.Identifier.Value,
+			Type:          challenge.DNS01,
+			Token:         "token",
+			ProviderState: "id-" + authz.Identifier.Value,
+		})
+	}
+
+	return state, nil
+}
+
+func (p *presenterMock) Resume(_ context.Context, _ []acme.Authorization, state *challengeresolver.State) error {
+	for _, chlg := range state.Challenges {
+		p.resumed = append(p.resumed, chlg.ProviderState)
+	}
+
+	return nil
+}
+
+func newPendingOrderCore(t *testing.T, orderStatus string) *api.Core {
+	t.Helper()
+
+	order := func(req *http.Request, status string) acme.Order {
+		serverURL := fmt.Sprintf("https://%s", req.Context().Value(http.LocalAddrContextKey))
+
+		return acme.Order{
+			Status:         status,
+			Identifiers:    []acme.Identifier{{Type: "dns", Value: "example.com"}},
+			Authorizations: []string{serverURL + "/authz/1"},
+			Finalize:       serverURL + "/finalize/1",
+			Certificate:    serverURL + "/certificate",
+		}
+	}
+
+	server := tester.MockACMEServer().
+		Route("POST /newOrder", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Location", fmt.Sprintf("https://%s/order/1", req.Context().Value(http.LocalAddrContextKey)))
+			rw.WriteHeader(http.StatusCreated)
+
+			_ = json.NewEncoder(rw).Encode(order(req, acme.StatusPending))
+		})).
+		Route("POST /order/1", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			servermock.JSONEncode(order(req, orderStatus)).ServeHTTP(rw, req)
+		})).
+		Route("POST /authz/1", servermock.JSONEncode(acme.Authorization{
+			Status:     acme.StatusPending,
+			Identifier: acme.Identifier{Type: "dns", Value: "example.com"},
+			Challenges: []acme.Challenge{{Type: challenge.DNS01.String(), Token: "token"}},
+		})).
+		Route("POST /finalize/1", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			servermock.JSONEncode(order(req, acme.StatusValid)).ServeHTTP(rw, req)
+		})).
+		Route("POST /certificate", servermock.RawStringResponse(certResponseMock)).
+		BuildHTTPS(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", key)
+	require.NoError(t, err)
+
+	return core
+}
+
+func TestCertifier_PresentOrder_ResumeOrder(t *testing.T) {
+	core := newPendingOrderCore(t, acme.StatusPending)
+
+	resolver := &presenterMock{}
+
+	certifier := NewCertifier(core, resolver, CertifierOptions{KeyType: certcrypto.EC256})
+
+	pending, err := certifier.PresentOrder(t.Context(), ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"example.com"}, resolver.presented)
+	assert.Equal(t, []string{"example.com"}, pending.Domains)
+	assert.Equal(t, strings.Replace(core.GetDirectory().NewOrderURL, "newOrder", "order/1", 1), pending.OrderURL)
+
+	// The pending order is saved, then resumed by another process.
+	raw, err := json.Marshal(pending)
+	require.NoError(t, err)
+
+	var restored PendingOrder
+
+	err = json.Unmarshal(raw, &restored)
+	require.NoError(t, err)
+
+	certifier = NewCertifier(core, resolver, CertifierOptions{KeyType: certcrypto.EC256})
+
+	cert, err := certifier.ResumeOrder(t.Context(), &restored, ObtainRequest{Bundle: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"id-example.com"}, resolver.resumed)
+
+	assert.Equal(t, "example.com", cert.Domain)
+	assert.Equal(t, certResponseMock, string(cert.Certificate))
+	assert.NotEmpty(t, cert.PrivateKey)
+}
+
+func TestCertifier_ResumeOrder_invalid(t *testing.T) {
+	core := newPendingOrderCore(t, acme.StatusInvalid)
+
+	resolver := &presenterMock{}
+
+	certifier := NewCertifier(core, resolver, CertifierOptions{KeyType: certcrypto.EC256})
+
+	pending := &PendingOrder{
+		Domains:  []string{"example.com"},
+		OrderURL: strings.Replace(core.GetDirectory().NewOrderURL, "newOrder", "order/1", 1),
+		Challenges: &challengeresolver.State{Challenges: []challengeresolver.ChallengeState{
+			{Domain: "example.com", Type: challenge.DNS01, Token: "token", ProviderState: "id-example.com"},
+		}},
+	}
+
+	_, err := certifier.ResumeOrder(t.Context(), pending, ObtainRequest{})
+	require.ErrorContains(t, err, "invalid order")
+	require.NotErrorIs(t, err, ErrChallengesNotCleanedUp)
+
+	// The challenges are cleaned up.
+	assert.Equal(t, []string{"id-example.com"}, resolver.resumed)
+}
+
+func TestCertifier_ResumeOrder_domains(t *testing.T) {
+	certifier := NewCertifier(nil, &presenterMock{}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	pending := &PendingOrder{
+		Domains:  []string{"example.com"},
+		OrderURL: "https://ca.example/order/1",
+	}
+
+	_, err := certifier.ResumeOrder(t.Context(), pending, ObtainRequest{Domains: []string{"example.org"}})
+	require.EqualError(t, err, "the challenges of the pending order have not been cleaned up: "+
+		"the domains [example.org] are not the domains of the pending order [example.com]")
+}
+
+func TestCertifier_ResumeOrder_orderError(t *testing.T) {
+	core := newPendingOrderCore(t, acme.StatusPending)
+
+	resolver := &presenterMock{}
+
+	certifier := NewCertifier(core, resolver, CertifierOptions{KeyType: certcrypto.EC256})
+
+	pending := &PendingOrder{
+		Domains:  []string{"example.com"},
+		OrderURL: strings.Replace(core.GetDirectory().NewOrderURL, "newOrder", "order/unknown", 1),
+		Challenges: &challengeresolver.State{Challenges: []challengeresolver.ChallengeState{
+			{Domain: "example.com", Type: challenge.DNS01, Token: "token", ProviderState: "id-example.com"},
+		}},
+	}
+
+	_, err := certifier.ResumeOrder(t.Context(), pending, ObtainRequest{})
+	require.ErrorIs(t, err, ErrChallengesNotCleanedUp)
+
+	// The challenges are not cleaned up: the order can be resumed again.
+	assert.Empty(t, resolver.resumed)
+}
+
+func TestCertifier_PresentOrder_unsupported(t *testing.T) {
+	certifier := NewCertifier(nil, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256})
+
+	_, err := certifier.PresentOrder(t.Context(), ObtainRequest{Domains: []string{"example.com"}})
+	require.EqualError(t, err, "the resolver cannot present the challenges in advance")
+}
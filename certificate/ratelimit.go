@@ -0,0 +1,69 @@
+package certificate
+
+import (
+	"context"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/wait"
+)
+
+// maxRateLimitRetries the maximum number of retries of an order creation rejected with a rateLimited error.
+const maxRateLimitRetries = 3
+
+// newOrder creates an order according to the rate limits (see CertifierOptions.RateLimiter):
+// it waits, or fails, if the order would exceed a known limit,
+// and it retries the order creation rejected with a rateLimited error, after the time requested by the server.
+func (c *Certifier) newOrder(ctx context.Context, domains []string, opts *api.OrderOptions) (acme.ExtendedOrder, error) {
+	limiter := c.options.RateLimiter
+	account := c.core.GetAccountURL()
+
+	err := limiter.Wait(ctx, account, domains)
+	if err != nil {
+		return acme.ExtendedOrder{}, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		order, err := c.core.WithContext(ctx).Orders.NewWithOptions(domains, opts)
+		if err == nil {
+			limiter.RecordOrder(account, domains)
+
+			return order, nil
+		}
+
+		delay, retry := limiter.RetryAfter(err)
+		if !retry || attempt >= maxRateLimitRetries {
+			return acme.ExtendedOrder{}, err
+		}
+
+		log.Info("acme: Rate limited; waiting before retrying.", log.AttrDomains, domains, "delay", delay)
+
+		err = wait.Sleep(ctx, delay)
+		if err != nil {
+			return acme.ExtendedOrder{}, err
+		}
+	}
+}
+
+// recordFailedValidations records the invalid authorizations in the ledger of the rate limits.
+func (c *Certifier) recordFailedValidations(ctx context.Context, authzURLs []string) {
+	limiter := c.options.RateLimiter
+	if limiter == nil {
+		return
+	}
+
+	core := c.core.WithContext(context.WithoutCancel(ctx))
+
+	for _, authzURL := range authzURLs {
+		authz, err := core.Authorizations.Get(authzURL)
+		if err != nil {
+			log.Warn("Unable to get the authorization.", log.AttrAuthzURL, authzURL, log.AttrError, err)
+			continue
+		}
+
+		if authz.Status == acme.StatusInvalid {
+			limiter.RecordFailedValidation(c.core.GetAccountURL(), authz.Identifier.Value)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/go-acme/lego/v4/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertifier_newOrder_retryAfter(t *testing.T) {
+	var calls atomic.Int32
+
+	server := tester.MockACMEServer().
+		Route("POST /newOrder", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if calls.Add(1) == 1 {
+				rw.Header().Set("Content-Type", "application/problem+json")
+				rw.Header().Set("Retry-After", "1")
+				rw.WriteHeader(http.StatusTooManyRequests)
+				_, _ = fmt.Fprintf(rw, `{"type":%q,"status":429,"detail":"too many orders"}`, acme.RateLimitedErr)
+
+				return
+			}
+
+			rw.Header().Set("Location", fmt.Sprintf("https://%s/order/1", req.Context().Value(http.LocalAddrContextKey)))
+			rw.WriteHeader(http.StatusCreated)
+
+			servermock.JSONEncode(acme.Order{
+				Status:      acme.StatusPending,
+				Identifiers: []acme.Identifier{{Type: "dns", Value: "example.com"}},
+			}).ServeHTTP(rw, req)
+		})).
+		BuildHTTPS(t)
+
+	core := newRateLimitCore(t, server)
+
+	ledger := ratelimit.NewLedger(ratelimit.NewFileStore(filepath.Join(t.TempDir(), "ledger.json")))
+
+	limiter := ratelimit.NewLimiter(ledger, ratelimit.Config{Limits: ratelimit.LetsEncrypt, MaxRetryAfter: 5 * time.Second})
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256, RateLimiter: limiter})
+
+	order, err := certifier.newOrder(t.Context(), []string{"example.com"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, acme.StatusPending, order.Status)
+
+	events, err := ledger.Events()
+	require.NoError(t, err)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, ratelimit.KindOrder, events[0].Kind)
+	assert.Equal(t, []string{"example.com"}, events[0].Domains)
+}
+
+func TestCertifier_newOrder_exceeded(t *testing.T) {
+	server := tester.MockACMEServer().
+		Route("POST /newOrder", http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			http.Error(rw, "unexpected order", http.StatusInternalServerError)
+		})).
+		BuildHTTPS(t)
+
+	core := newRateLimitCore(t, server)
+
+	ledger := ratelimit.NewLedger(ratelimit.NewFileStore(filepath.Join(t.TempDir(), "ledger.json")))
+
+	for range 5 {
+		event := ratelimit.Event{Kind: ratelimit.KindCertificate, Domains: []string{"example.com"}, Time: time.Now().Add(-time.Hour)}
+		require.NoError(t, ledger.Add(event, 7*24*time.Hour))
+	}
+
+	limiter := ratelimit.NewLimiter(ledger, ratelimit.Config{Limits: ratelimit.LetsEncrypt})
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.EC256, RateLimiter: limiter})
+
+	_, err := certifier.newOrder(t.Context(), []string{"example.com"}, nil)
+
+	var errE *ratelimit.ExceededError
+
+	require.ErrorAs(t, err, &errE)
+
+	assert.Equal(t, ratelimit.LimitDuplicateCertificates, errE.Name)
+}
+
+func newRateLimitCore(t *testing.T, server *httptest.Server) *api.Core {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "https://ca.example/acct/1", key)
+	require.NoError(t, err)
+
+	return core
+}
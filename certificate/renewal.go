@@ -1,6 +1,7 @@
 package certificate
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/base64"
@@ -74,12 +75,17 @@ func (r *RenewalInfoResponse) ShouldRenewAt(now time.Time, willingToSleep time.D
 //
 // https://www.rfc-editor.org/rfc/rfc9773.html
 func (c *Certifier) GetRenewalInfo(req RenewalInfoRequest) (*RenewalInfoResponse, error) {
+	return c.GetRenewalInfoWithContext(context.Background(), req)
+}
+
+// GetRenewalInfoWithContext is like GetRenewalInfo, but the context is used to cancel the request.
+func (c *Certifier) GetRenewalInfoWithContext(ctx context.Context, req RenewalInfoRequest) (*RenewalInfoResponse, error) {
 	certID, err := MakeARICertID(req.Cert)
 	if err != nil {
 		return nil, fmt.Errorf("error making certID: %w", err)
 	}
 
-	resp, err := c.core.Certificates.GetRenewalInfo(certID)
+	resp, err := c.core.WithContext(ctx).Certificates.GetRenewalInfo(certID)
 	if err != nil {
 		return nil, err
 	}
@@ -20,6 +20,14 @@ const (
 
 	// TLSALPN01 is the "tls-alpn-01" ACME challenge https://www.rfc-editor.org/rfc/rfc8737.html
 	TLSALPN01 = Type("tls-alpn-01")
+
+	// DNSAccount01 is the "dns-account-01" ACME challenge https://datatracker.ietf.org/doc/draft-ietf-acme-dns-account-label/
+	// Note: the TXT record is account-scoped (i.e. `_<label>._acme-challenge.[domain].`).
+	DNSAccount01 = Type("dns-account-01")
+
+	// DNSPersist01 is the "dns-persist-01" ACME challenge https://datatracker.ietf.org/doc/draft-ietf-acme-dns-persist/
+	// Note: the TXT record is persistent (i.e. `_validation-persist.[domain].`), and binds the domain to an ACME account.
+	DNSPersist01 = Type("dns-persist-01")
 )
 
 func (t Type) String() string {
@@ -0,0 +1,64 @@
+package dns01
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// LookupCAA returns the relevant CAA record set of the domain (RFC 8659, section 3),
+// using the recursive nameservers of the default resolver:
+// the CAA records of the domain, or of the closest parent domain with CAA records.
+// The CNAME records are followed by the recursive nameservers.
+//
+// An empty record set means that any CA is allowed to issue certificates for the domain.
+func LookupCAA(ctx context.Context, domain string) ([]*dns.CAA, error) {
+	return defaultResolver.LookupCAA(ctx, domain)
+}
+
+// LookupCAA returns the relevant CAA record set of the domain (RFC 8659, section 3):
+// the CAA records of the domain, or of the closest parent domain with CAA records.
+// See the package-level LookupCAA.
+func (r *Resolver) LookupCAA(ctx context.Context, domain string) ([]*dns.CAA, error) {
+	fqdn := ToFqdn(strings.TrimPrefix(domain, "*."))
+
+	for _, index := range dns.Split(fqdn) {
+		name := fqdn[index:]
+
+		records, err := r.lookupCAA(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(records) > 0 {
+			return records, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *Resolver) lookupCAA(ctx context.Context, fqdn string) ([]*dns.CAA, error) {
+	msg, err := r.query(ctx, fqdn, dns.TypeCAA, r.Nameservers(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+	default:
+		return nil, &DNSError{Message: fmt.Sprintf("unexpected response for '%s'", fqdn), MsgOut: msg}
+	}
+
+	var records []*dns.CAA
+
+	for _, rr := range msg.Answer {
+		if caa, ok := rr.(*dns.CAA); ok {
+			records = append(records, caa)
+		}
+	}
+
+	return records, nil
+}
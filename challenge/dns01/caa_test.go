@@ -0,0 +1,79 @@
+package dns01
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/platform/tester/dnsmock"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCAA(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("sub.www.example.com. CAA", dnsmock.Noop).
+		Query("www.example.com. CAA", dnsmock.Error(dns.RcodeNameError)).
+		Query("example.com. CAA",
+			dnsmock.Answer(
+				fakeCAA("example.com.", "issue", "ca.example"),
+				fakeCAA("example.com.", "iodef", "mailto:admin@example.com"),
+			),
+		).
+		Build(t)
+
+	useAsNameserver(t, addr)
+
+	testCases := []struct {
+		desc   string
+		domain string
+	}{
+		{desc: "domain", domain: "example.com"},
+		{desc: "parent domain", domain: "sub.www.example.com"},
+		{desc: "wildcard", domain: "*.www.example.com"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			records, err := LookupCAA(t.Context(), test.domain)
+			require.NoError(t, err)
+
+			require.Len(t, records, 2)
+
+			assert.Equal(t, "issue", records[0].Tag)
+			assert.Equal(t, "ca.example", records[0].Value)
+		})
+	}
+}
+
+func TestLookupCAA_noRecords(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("example.com. CAA", dnsmock.Noop).
+		Query("com. CAA", dnsmock.Noop).
+		Build(t)
+
+	useAsNameserver(t, addr)
+
+	records, err := LookupCAA(t.Context(), "example.com")
+	require.NoError(t, err)
+
+	assert.Empty(t, records)
+}
+
+func TestLookupCAA_error(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("example.com. CAA", dnsmock.Error(dns.RcodeServerFailure)).
+		Build(t)
+
+	useAsNameserver(t, addr)
+
+	_, err := LookupCAA(t.Context(), "example.com")
+	require.Error(t, err)
+}
+
+func fakeCAA(name, tag, value string) *dns.CAA {
+	return &dns.CAA{
+		Hdr:   dns.RR_Header{Name: name, Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 10},
+		Tag:   tag,
+		Value: value,
+	}
+}
@@ -0,0 +1,42 @@
+package dns01
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+// NewAccountChallenge creates a solver for the dns-account-01 challenge.
+// https://datatracker.ietf.org/doc/draft-ietf-acme-dns-account-label/
+//
+// The challenge works like dns-01 (same propagation checks),
+// but the TXT record is account-scoped: `_[label]._acme-challenge.[domain].`.
+// Several ACME accounts (or clients) can validate the same domain concurrently without overwriting their records.
+//
+// The provider must implement RecordProvider: the record is not the record computed by GetChallengeInfo.
+func NewAccountChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
+	return newChallenge(challenge.DNSAccount01, core, validate, provider, opts...)
+}
+
+// AccountLabel returns the label of the dns-account-01 records of an account:
+// the lowercase base32 encoding of the first 10 bytes of the SHA-256 digest of the account URL.
+func AccountLabel(accountURL string) string {
+	sum := sha256.Sum256([]byte(accountURL))
+
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:10]))
+}
+
+// GetAccountChallengeInfo returns information used to create a DNS record which will fulfill the `dns-account-01` challenge.
+func GetAccountChallengeInfo(domain, accountURL, keyAuth string) ChallengeInfo {
+	fqdn := getAccountChallengeFQDN(domain, AccountLabel(accountURL))
+
+	return defaultResolver.newChallengeInfo(fqdn, getChallengeValue(keyAuth))
+}
+
+func getAccountChallengeFQDN(domain, accountLabel string) string {
+	return fmt.Sprintf("_%s._acme-challenge.%s.", accountLabel, domain)
+}
@@ -0,0 +1,140 @@
+package dns01
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerRecorder records the challenge info computed by the provider, like the DNS providers do.
+type providerRecorder struct {
+	present, cleanUp []ChallengeInfo
+}
+
+func (p *providerRecorder) Present(domain, _, keyAuth string) error {
+	p.present = append(p.present, GetChallengeInfo(domain, keyAuth))
+	return nil
+}
+
+func (p *providerRecorder) CleanUp(domain, _, keyAuth string) error {
+	p.cleanUp = append(p.cleanUp, GetChallengeInfo(domain, keyAuth))
+	return nil
+}
+
+func TestAccountLabel(t *testing.T) {
+	// Example from draft-ietf-acme-dns-account-label.
+	assert.Equal(t, "ujmmovf2vn55tgye", AccountLabel("https://example.com/acme/acct/ExampleAccount"))
+}
+
+func TestGetAccountChallengeInfo(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	info := GetAccountChallengeInfo("example.org", "https://example.com/acme/acct/ExampleAccount", "123")
+
+	expected := ChallengeInfo{
+		FQDN:          "_ujmmovf2vn55tgye._acme-challenge.example.org.",
+		EffectiveFQDN: "_ujmmovf2vn55tgye._acme-challenge.example.org.",
+		Value:         "pmWkWSBCL51Bfkhn79xPuKBKHz__H6B-mY6G9_eieuM",
+	}
+
+	assert.Equal(t, expected, info)
+}
+
+func TestAccountChallenge_PreSolve_CleanUp(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	server := tester.MockACMEServer().BuildHTTPS(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "https://example.com/acme/acct/ExampleAccount", privateKey)
+	require.NoError(t, err)
+
+	provider := &recordProviderMock{}
+
+	chlg := NewAccountChallenge(core, nil, provider)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{
+			Value: "example.org",
+		},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "abc"},
+		},
+	}
+
+	require.NoError(t, chlg.PreSolve(authz))
+	require.NoError(t, chlg.CleanUp(authz))
+
+	keyAuth, err := core.GetKeyAuthorization("abc")
+	require.NoError(t, err)
+
+	expected := GetAccountChallengeInfo("example.org", "https://example.com/acme/acct/ExampleAccount", keyAuth)
+
+	assert.Equal(t, "_ujmmovf2vn55tgye._acme-challenge.example.org.", expected.EffectiveFQDN)
+	assert.Equal(t, []ChallengeInfo{expected}, provider.presentRecords)
+	assert.Equal(t, []ChallengeInfo{expected}, provider.cleanUpRecords)
+
+	// The dns-01 record is not used.
+	assert.Empty(t, provider.present)
+	assert.Empty(t, provider.cleanUp)
+}
+
+func TestAccountChallenge_PreSolve_unsupportedProvider(t *testing.T) {
+	server := tester.MockACMEServer().BuildHTTPS(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "https://example.com/acme/acct/ExampleAccount", privateKey)
+	require.NoError(t, err)
+
+	provider := &providerRecorder{}
+
+	chlg := NewAccountChallenge(core, nil, provider)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{
+			Value: "example.org",
+		},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "abc"},
+		},
+	}
+
+	require.EqualError(t, chlg.PreSolve(authz),
+		"[example.org] acme: error presenting token: dns01: the provider dns01 doesn't support arbitrary records")
+
+	assert.Empty(t, provider.present)
+}
+
+func TestAccountChallenge_PreSolve_noAccount(t *testing.T) {
+	server := tester.MockACMEServer().BuildHTTPS(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	chlg := NewAccountChallenge(core, nil, &providerRecorder{})
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{
+			Value: "example.org",
+		},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "abc"},
+		},
+	}
+
+	require.EqualError(t, chlg.PreSolve(authz), "acme: the account URL is required by the dns-account-01 challenge")
+}
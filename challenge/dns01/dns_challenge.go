@@ -1,8 +1,10 @@
 package dns01
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -13,6 +15,7 @@ import (
 	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
 	"github.com/go-acme/lego/v4/platform/wait"
 	"github.com/miekg/dns"
 )
@@ -44,31 +47,71 @@ func CondOption(condition bool, opt ChallengeOption) ChallengeOption {
 	return opt
 }
 
-// Challenge implements the dns-01 challenge.
+// Challenge implements the dns-01 challenge, and the dns-account-01 challenge (see NewAccountChallenge).
 type Challenge struct {
 	core       *api.Core
 	validate   ValidateFunc
 	provider   challenge.Provider
 	preCheck   preCheck
+	resolver   *Resolver
 	dnsTimeout time.Duration
+	chlgType   challenge.Type
+
+	// resolverOpts the modifications of the resolver of the challenge (see WithRecursiveNameservers, WithDNSTimeout).
+	resolverOpts []func(*Resolver)
+}
+
+// WithResolver defines the resolver used by the challenge: the CNAME resolution, and the propagation checks.
+// By default, the default resolver is used (see DefaultResolver).
+//
+// The records are computed with the resolver for the providers implementing RecordProvider.
+// The other providers compute the records with GetChallengeInfo, so with the default resolver.
+// The zone lookups of the providers always use the default resolver (see AddRecursiveNameservers, AddDNSTimeout).
+func WithResolver(resolver *Resolver) ChallengeOption {
+	return func(chlg *Challenge) error {
+		if resolver == nil {
+			return errors.New("dns01: the resolver is nil")
+		}
+
+		chlg.resolver = resolver
+
+		return nil
+	}
 }
 
 func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
+	return newChallenge(challenge.DNS01, core, validate, provider, opts...)
+}
+
+func newChallenge(chlgType challenge.Type, core *api.Core, validate ValidateFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
 	chlg := &Challenge{
 		core:       core,
 		validate:   validate,
 		provider:   provider,
 		preCheck:   newPreCheck(),
+		resolver:   defaultResolver,
 		dnsTimeout: 10 * time.Second,
+		chlgType:   chlgType,
 	}
 
 	for _, opt := range opts {
 		err := opt(chlg)
 		if err != nil {
-			log.Infof("challenge option error: %v", err)
+			log.Warn("challenge option error.", log.AttrChallengeType, chlgType, log.AttrError, err)
 		}
 	}
 
+	if len(chlg.resolverOpts) > 0 {
+		// The default resolver, or the resolver of WithResolver, can be shared: the challenge uses its own copy.
+		chlg.resolver = chlg.resolver.clone()
+
+		for _, opt := range chlg.resolverOpts {
+			opt(chlg.resolver)
+		}
+	}
+
+	chlg.preCheck.resolver = chlg.resolver
+
 	return chlg
 }
 
@@ -76,9 +119,9 @@ func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Prov
 // It does not validate record propagation, or do anything at all with the acme server.
 func (c *Challenge) PreSolve(authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Preparing to solve DNS-01", domain)
+	log.Info("acme: Preparing to solve "+c.name(), log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(c.provider))
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
@@ -88,12 +131,12 @@ func (c *Challenge) PreSolve(authz acme.Authorization) error {
 	}
 
 	// Generate the Key Authorization for the challenge
-	keyAuth, err := c.core.GetKeyAuthorization(chlng.Token)
+	keyAuth, err := c.getKeyAuthorization(chlng.Token)
 	if err != nil {
 		return err
 	}
 
-	err = c.provider.Present(authz.Identifier.Value, chlng.Token, keyAuth)
+	err = c.present(authz.Identifier.Value, chlng.Token, keyAuth)
 	if err != nil {
 		return fmt.Errorf("[%s] acme: error presenting token: %w", domain, err)
 	}
@@ -102,21 +145,26 @@ func (c *Challenge) PreSolve(authz acme.Authorization) error {
 }
 
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveWithContext(context.Background(), authz)
+}
+
+// SolveWithContext is like Solve, but the context is used to cancel the propagation checks and the validation.
+func (c *Challenge) SolveWithContext(ctx context.Context, authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Trying to solve DNS-01", domain)
+	log.Info("acme: Trying to solve "+c.name(), log.AttrDomain, domain)
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
 
 	// Generate the Key Authorization for the challenge
-	keyAuth, err := c.core.GetKeyAuthorization(chlng.Token)
+	keyAuth, err := c.getKeyAuthorization(chlng.Token)
 	if err != nil {
 		return err
 	}
 
-	info := GetChallengeInfo(authz.Identifier.Value, keyAuth)
+	info := c.getChallengeInfo(authz.Identifier.Value, keyAuth)
 
 	var timeout, interval time.Duration
 
@@ -127,14 +175,18 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		timeout, interval = DefaultPropagationTimeout, DefaultPollingInterval
 	}
 
-	log.Infof("[%s] acme: Checking DNS record propagation. [nameservers=%s]", domain, strings.Join(recursiveNameservers, ","))
+	log.Info("acme: Checking DNS record propagation.",
+		log.AttrDomain, domain, "fqdn", info.EffectiveFQDN, "nameservers", strings.Join(c.resolver.Nameservers(), ","))
 
-	time.Sleep(interval)
+	err = wait.Sleep(ctx, interval)
+	if err != nil {
+		return err
+	}
 
-	err = wait.For("propagation", timeout, interval, func() (bool, error) {
-		stop, errP := c.preCheck.call(domain, info.EffectiveFQDN, info.Value)
+	err = wait.ForWithContext(ctx, "propagation", timeout, interval, func() (bool, error) {
+		stop, errP := c.preCheck.call(ctx, domain, info.EffectiveFQDN, info.Value)
 		if !stop || errP != nil {
-			log.Infof("[%s] acme: Waiting for DNS record propagation.", domain)
+			log.Info("acme: Waiting for DNS record propagation.", log.AttrDomain, domain)
 		}
 
 		return stop, errP
@@ -145,24 +197,114 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 
 	chlng.KeyAuthorization = keyAuth
 
-	return c.validate(c.core, domain, chlng)
+	return c.validate(c.core.WithContext(ctx), domain, chlng)
 }
 
 // CleanUp cleans the challenge.
 func (c *Challenge) CleanUp(authz acme.Authorization) error {
-	log.Infof("[%s] acme: Cleaning DNS-01 challenge", challenge.GetTargetedDomain(authz))
+	log.Info("acme: Cleaning "+c.name()+" challenge", log.AttrDomain, challenge.GetTargetedDomain(authz), log.AttrProvider, metrics.ProviderName(c.provider))
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
 
-	keyAuth, err := c.core.GetKeyAuthorization(chlng.Token)
+	keyAuth, err := c.getKeyAuthorization(chlng.Token)
 	if err != nil {
 		return err
 	}
 
-	return c.provider.CleanUp(authz.Identifier.Value, chlng.Token, keyAuth)
+	return c.cleanUp(authz.Identifier.Value, chlng.Token, keyAuth)
+}
+
+// present presents the record of the challenge.
+// The providers implementing RecordProvider receive the record computed with the resolver of the challenge.
+// The dns-account-01 challenge requires a RecordProvider.
+func (c *Challenge) present(domain, token, keyAuth string) error {
+	if p, ok := c.provider.(RecordProvider); ok {
+		return callPresentRecord(p, domain, c.getChallengeInfo(domain, keyAuth))
+	}
+
+	if c.chlgType == challenge.DNSAccount01 {
+		return errRecordsUnsupported(c.provider)
+	}
+
+	return challenge.CallPresent(c.provider, domain, token, keyAuth)
+}
+
+// cleanUp removes the record of the challenge (see present).
+func (c *Challenge) cleanUp(domain, token, keyAuth string) error {
+	if p, ok := c.provider.(RecordProvider); ok {
+		return callCleanUpRecord(p, domain, c.getChallengeInfo(domain, keyAuth))
+	}
+
+	if c.chlgType == challenge.DNSAccount01 {
+		return errRecordsUnsupported(c.provider)
+	}
+
+	return challenge.CallCleanUp(c.provider, domain, token, keyAuth)
+}
+
+// getChallengeInfo returns the record of the challenge, as created by the provider:
+// the CNAMEs are followed by the resolver of the challenge for the providers implementing RecordProvider,
+// by the default resolver for the other providers (see GetChallengeInfo).
+func (c *Challenge) getChallengeInfo(domain, keyAuth string) ChallengeInfo {
+	if c.chlgType == challenge.DNSAccount01 {
+		fqdn := getAccountChallengeFQDN(domain, AccountLabel(c.core.GetAccountURL()))
+
+		return c.resolver.newChallengeInfo(fqdn, getChallengeValue(keyAuth))
+	}
+
+	if _, ok := c.provider.(RecordProvider); ok {
+		return getChallengeInfo(c.resolver, domain, keyAuth)
+	}
+
+	return getChallengeInfo(defaultResolver, domain, keyAuth)
+}
+
+// SaveState returns the state of the provider related to the challenge (see challenge.ProviderState), after PreSolve.
+// The state is empty if the provider has no state.
+func (c *Challenge) SaveState(authz acme.Authorization) (string, error) {
+	provider, ok := c.provider.(challenge.ProviderState)
+	if !ok {
+		return "", nil
+	}
+
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.SaveState(chlng.Token)
+}
+
+// RestoreState restores the state of the provider related to the challenge (see challenge.ProviderState), before CleanUp.
+func (c *Challenge) RestoreState(authz acme.Authorization, state string) error {
+	provider, ok := c.provider.(challenge.ProviderState)
+	if !ok || state == "" {
+		return nil
+	}
+
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
+	if err != nil {
+		return err
+	}
+
+	return provider.RestoreState(chlng.Token, state)
+}
+
+// getKeyAuthorization gets the key authorization of a token.
+// The dns-account-01 challenge also requires the account URL (see AccountLabel).
+func (c *Challenge) getKeyAuthorization(token string) (string, error) {
+	if c.chlgType == challenge.DNSAccount01 && c.core.GetAccountURL() == "" {
+		return "", errors.New("acme: the account URL is required by the dns-account-01 challenge")
+	}
+
+	return c.core.GetKeyAuthorization(token)
+}
+
+func (c *Challenge) name() string {
+	return strings.ToUpper(string(c.chlgType))
 }
 
 func (c *Challenge) Sequential() (bool, time.Duration) {
@@ -188,7 +330,8 @@ func GetRecord(domain, keyAuth string) (fqdn, value string) {
 
 // ChallengeInfo contains the information use to create the TXT record.
 type ChallengeInfo struct {
-	// FQDN is the full-qualified challenge domain (i.e. `_acme-challenge.[domain].`)
+	// FQDN is the full-qualified challenge domain
+	// (i.e. `_acme-challenge.[domain].`, or `_[label]._acme-challenge.[domain].` with the dns-account-01 challenge)
 	FQDN string
 
 	// EffectiveFQDN contains the resulting FQDN after the CNAMEs resolutions.
@@ -199,44 +342,66 @@ type ChallengeInfo struct {
 }
 
 // GetChallengeInfo returns information used to create a DNS record which will fulfill the `dns-01` challenge.
+// The record of the `dns-account-01` challenge is returned by GetAccountChallengeInfo.
+// The CNAMEs are resolved by the default resolver.
 func GetChallengeInfo(domain, keyAuth string) ChallengeInfo {
-	keyAuthShaBytes := sha256.Sum256([]byte(keyAuth))
-	// base64URL encoding without padding
-	value := base64.RawURLEncoding.EncodeToString(keyAuthShaBytes[:sha256.Size])
+	return getChallengeInfo(defaultResolver, domain, keyAuth)
+}
 
-	ok, _ := strconv.ParseBool(os.Getenv("LEGO_DISABLE_CNAME_SUPPORT"))
+// getChallengeInfo computes the challenge information, the CNAMEs are resolved by the resolver.
+func getChallengeInfo(resolver *Resolver, domain, keyAuth string) ChallengeInfo {
+	fqdn := fmt.Sprintf("_acme-challenge.%s.", domain)
+
+	return resolver.newChallengeInfo(fqdn, getChallengeValue(keyAuth))
+}
+
+func (r *Resolver) newChallengeInfo(fqdn, value string) ChallengeInfo {
+	effectiveFQDN := fqdn
+	if !cnameSupportDisabled() {
+		effectiveFQDN = r.followCNAMEs(fqdn)
+	}
 
 	return ChallengeInfo{
 		Value:         value,
-		FQDN:          getChallengeFQDN(domain, false),
-		EffectiveFQDN: getChallengeFQDN(domain, !ok),
+		FQDN:          fqdn,
+		EffectiveFQDN: effectiveFQDN,
 	}
 }
 
-func getChallengeFQDN(domain string, followCNAME bool) string {
-	fqdn := fmt.Sprintf("_acme-challenge.%s.", domain)
+func getChallengeValue(keyAuth string) string {
+	keyAuthShaBytes := sha256.Sum256([]byte(keyAuth))
 
-	if !followCNAME {
-		return fqdn
-	}
+	// base64URL encoding without padding
+	return base64.RawURLEncoding.EncodeToString(keyAuthShaBytes[:sha256.Size])
+}
+
+func cnameSupportDisabled() bool {
+	ok, _ := strconv.ParseBool(os.Getenv("LEGO_DISABLE_CNAME_SUPPORT"))
+
+	return ok
+}
+
+// followCNAMEs follows the CNAME records of the FQDN.
+func (r *Resolver) followCNAMEs(fqdn string) string {
+	nameservers := r.Nameservers()
 
 	// recursion counter so it doesn't spin out of control
 	for range 50 {
 		// Keep following CNAMEs
-		r, err := dnsQuery(fqdn, dns.TypeCNAME, recursiveNameservers, true)
+		msg, err := r.query(context.Background(), fqdn, dns.TypeCNAME, nameservers, true)
 
-		if err != nil || r.Rcode != dns.RcodeSuccess {
+		if err != nil || msg.Rcode != dns.RcodeSuccess {
 			// No more CNAME records to follow, exit
 			break
 		}
 
 		// Check if the domain has CNAME then use that
-		cname := updateDomainWithCName(r, fqdn)
+		cname := updateDomainWithCName(msg, fqdn)
 		if cname == fqdn {
 			break
 		}
 
-		log.Infof("Found CNAME entry for %q: %q", fqdn, cname)
+		log.Info("Found CNAME entry", "fqdn", fqdn, "cname", cname)
 
 		fqdn = cname
 	}
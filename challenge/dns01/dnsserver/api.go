@@ -0,0 +1,363 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The key authorizations digests are 43 characters long (base64url of a SHA-256).
+const txtLength = 43
+
+// maxTXTValues is the number of values kept for a subdomain:
+// a certificate for a domain and its wildcard uses the same subdomain twice.
+const maxTXTValues = 2
+
+// API implements the HTTP API of acme-dns (https://github.com/joohoi/acme-dns#api),
+// the records are created in the store of a Server:
+// the acme-dns clients (e.g. the `acme-dns` DNS provider of lego) can be used with the Server.
+//
+// The routes are:
+//   - POST /register: registers an account, and its subdomain inside the challenge zone.
+//   - POST /update: updates the TXT record of the subdomain of an account.
+//   - GET /health: health check.
+type API struct {
+	zone  string
+	store Store
+
+	mux *http.ServeMux
+
+	mu           sync.Mutex
+	accounts     map[string]*apiAccount
+	accountsFile string
+}
+
+// apiAccount an acme-dns account.
+type apiAccount struct {
+	Username     string   `json:"username"`
+	PasswordHash []byte   `json:"passwordHash"`
+	Subdomain    string   `json:"subdomain"`
+	AllowFrom    []string `json:"allowFrom,omitempty"`
+	Values       []string `json:"values,omitempty"`
+}
+
+type registerRequest struct {
+	AllowFrom []string `json:"allowfrom"`
+}
+
+type registerResponse struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	FullDomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+type updateRequest struct {
+	Subdomain string `json:"subdomain"`
+	TXT       string `json:"txt"`
+}
+
+type updateResponse struct {
+	TXT string `json:"txt"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// NewAPI creates a new API.
+// The accounts are persisted in the accountsFile (JSON), or only kept in memory if accountsFile is empty.
+func NewAPI(zone string, store Store, accountsFile string) (*API, error) {
+	if store == nil {
+		return nil, errors.New("dnsserver: the store is nil")
+	}
+
+	a := &API{
+		zone:         normalize(zone),
+		store:        store,
+		accounts:     make(map[string]*apiAccount),
+		accountsFile: accountsFile,
+	}
+
+	if accountsFile != "" {
+		err := a.load()
+		if err != nil {
+			return nil, fmt.Errorf("dnsserver: could not load the accounts: %w", err)
+		}
+	}
+
+	a.mux = http.NewServeMux()
+	a.mux.HandleFunc("POST /register", a.register)
+	a.mux.HandleFunc("POST /update", a.update)
+	a.mux.HandleFunc("GET /health", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	return a, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (a *API) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	a.mux.ServeHTTP(rw, req)
+}
+
+func (a *API) register(rw http.ResponseWriter, req *http.Request) {
+	reg := registerRequest{}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, 64*1024))
+	if err != nil {
+		writeJSON(rw, http.StatusBadRequest, apiError{Error: "malformed_json_payload"})
+		return
+	}
+
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &reg)
+		if err != nil {
+			writeJSON(rw, http.StatusBadRequest, apiError{Error: "malformed_json_payload"})
+			return
+		}
+	}
+
+	if reg.AllowFrom == nil {
+		reg.AllowFrom = []string{}
+	}
+
+	for _, cidr := range reg.AllowFrom {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			writeJSON(rw, http.StatusBadRequest, apiError{Error: "invalid_allowfrom_cidr"})
+			return
+		}
+	}
+
+	// 40 characters, like acme-dns.
+	password := rand.Text() + rand.Text()[:14]
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		writeJSON(rw, http.StatusInternalServerError, apiError{Error: "internal_error"})
+		return
+	}
+
+	account := &apiAccount{
+		Username:     uuid.NewString(),
+		PasswordHash: hash,
+		Subdomain:    uuid.NewString(),
+		AllowFrom:    reg.AllowFrom,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.accounts[account.Username] = account
+
+	err = a.save()
+	if err != nil {
+		delete(a.accounts, account.Username)
+
+		log.Error("dnsserver: could not save the accounts.", log.AttrError, err)
+		writeJSON(rw, http.StatusInternalServerError, apiError{Error: "internal_error"})
+
+		return
+	}
+
+	log.Info("dnsserver: new account.", "subdomain", account.Subdomain)
+
+	writeJSON(rw, http.StatusCreated, registerResponse{
+		Username:   account.Username,
+		Password:   password,
+		FullDomain: account.Subdomain + "." + trimDot(a.zone),
+		Subdomain:  account.Subdomain,
+		AllowFrom:  account.AllowFrom,
+	})
+}
+
+func (a *API) update(rw http.ResponseWriter, req *http.Request) {
+	upd := updateRequest{}
+
+	err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&upd)
+	if err != nil {
+		writeJSON(rw, http.StatusBadRequest, apiError{Error: "malformed_json_payload"})
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	account, ok := a.accounts[req.Header.Get("X-Api-User")]
+	if !ok || bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(req.Header.Get("X-Api-Key"))) != nil {
+		writeJSON(rw, http.StatusUnauthorized, apiError{Error: "forbidden"})
+		return
+	}
+
+	if !account.allowed(req.RemoteAddr) {
+		writeJSON(rw, http.StatusUnauthorized, apiError{Error: "forbidden"})
+		return
+	}
+
+	if upd.Subdomain != account.Subdomain {
+		writeJSON(rw, http.StatusUnauthorized, apiError{Error: "forbidden"})
+		return
+	}
+
+	if len(upd.TXT) != txtLength {
+		writeJSON(rw, http.StatusBadRequest, apiError{Error: "bad_txt"})
+		return
+	}
+
+	err = a.setTXT(req.Context(), account, upd.TXT)
+	if err != nil {
+		log.Error("dnsserver: could not update the record.", "subdomain", account.Subdomain, log.AttrError, err)
+		writeJSON(rw, http.StatusInternalServerError, apiError{Error: "internal_error"})
+
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, updateResponse{TXT: upd.TXT})
+}
+
+// setTXT adds the value to the subdomain, and removes the oldest values.
+func (a *API) setTXT(ctx context.Context, account *apiAccount, value string) error {
+	fqdn := account.Subdomain + "." + a.zone
+
+	if slices.Contains(account.Values, value) {
+		return nil
+	}
+
+	err := a.store.Add(ctx, fqdn, value)
+	if err != nil {
+		return err
+	}
+
+	account.Values = append(account.Values, value)
+
+	for len(account.Values) > maxTXTValues {
+		err = a.store.Remove(ctx, fqdn, account.Values[0])
+		if err != nil {
+			return err
+		}
+
+		account.Values = account.Values[1:]
+	}
+
+	return a.save()
+}
+
+func (a *API) load() error {
+	data, err := os.ReadFile(a.accountsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var accounts []*apiAccount
+
+	err = json.Unmarshal(data, &accounts)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		a.accounts[account.Username] = account
+	}
+
+	return nil
+}
+
+func (a *API) save() error {
+	if a.accountsFile == "" {
+		return nil
+	}
+
+	accounts := make([]*apiAccount, 0, len(a.accounts))
+	for _, account := range a.accounts {
+		accounts = append(accounts, account)
+	}
+
+	slices.SortFunc(accounts, func(x, y *apiAccount) int {
+		switch {
+		case x.Username < y.Username:
+			return -1
+		case x.Username > y.Username:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	data, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := a.accountsFile + ".tmp"
+
+	err = os.MkdirAll(filepath.Dir(a.accountsFile), 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(tmp, data, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, a.accountsFile)
+}
+
+// allowed checks the source address of the request against the allowed networks.
+func (a *apiAccount) allowed(remoteAddr string) bool {
+	if len(a.AllowFrom) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range a.AllowFrom {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	_ = json.NewEncoder(rw).Encode(body)
+}
+
+func trimDot(fqdn string) string {
+	if len(fqdn) > 0 && fqdn[len(fqdn)-1] == '.' {
+		return fqdn[:len(fqdn)-1]
+	}
+
+	return fqdn
+}
@@ -0,0 +1,148 @@
+package dnsserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nrdcg/goacmedns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAPI(t *testing.T, store Store, accountsFile string) (*goacmedns.Client, string) {
+	t.Helper()
+
+	api, err := NewAPI("acme.example.com", store, accountsFile)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client, err := goacmedns.NewClient(server.URL)
+	require.NoError(t, err)
+
+	return client, server.URL
+}
+
+func TestAPI(t *testing.T) {
+	store := NewMemoryStore()
+
+	client, _ := setupAPI(t, store, "")
+
+	account, err := client.RegisterAccount(t.Context(), nil)
+	require.NoError(t, err)
+
+	assert.Len(t, account.Password, 40)
+	assert.Equal(t, account.SubDomain+".acme.example.com", account.FullDomain)
+
+	values := []string{
+		strings.Repeat("a", txtLength),
+		strings.Repeat("b", txtLength),
+		strings.Repeat("c", txtLength),
+	}
+
+	for _, value := range values {
+		err = client.UpdateTXTRecord(t.Context(), account, value)
+		require.NoError(t, err)
+	}
+
+	// Only the 2 last values are kept.
+	records, err := store.Get(t.Context(), account.FullDomain)
+	require.NoError(t, err)
+	assert.Equal(t, values[1:], records)
+}
+
+func TestAPI_update_errors(t *testing.T) {
+	client, _ := setupAPI(t, NewMemoryStore(), "")
+
+	account, err := client.RegisterAccount(t.Context(), nil)
+	require.NoError(t, err)
+
+	other, err := client.RegisterAccount(t.Context(), []string{"192.0.2.0/24"})
+	require.NoError(t, err)
+
+	value := strings.Repeat("a", txtLength)
+
+	testCases := []struct {
+		desc     string
+		account  goacmedns.Account
+		value    string
+		expected string
+	}{
+		{
+			desc: "invalid password",
+			account: goacmedns.Account{
+				Username:  account.Username,
+				Password:  "invalid",
+				SubDomain: account.SubDomain,
+			},
+			value:    value,
+			expected: "forbidden",
+		},
+		{
+			desc: "subdomain of another account",
+			account: goacmedns.Account{
+				Username:  account.Username,
+				Password:  account.Password,
+				SubDomain: other.SubDomain,
+			},
+			value:    value,
+			expected: "forbidden",
+		},
+		{
+			desc:     "not allowed source address",
+			account:  other,
+			value:    value,
+			expected: "forbidden",
+		},
+		{
+			desc:     "invalid TXT",
+			account:  account,
+			value:    "invalid",
+			expected: "bad_txt",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := client.UpdateTXTRecord(t.Context(), test.account, test.value)
+			require.ErrorContains(t, err, test.expected)
+		})
+	}
+}
+
+func TestAPI_register_invalidAllowFrom(t *testing.T) {
+	_, serverURL := setupAPI(t, NewMemoryStore(), "")
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, serverURL+"/register", bytes.NewBufferString(`{"allowfrom":["invalid"]}`))
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_accountsFile(t *testing.T) {
+	store := NewMemoryStore()
+	accountsFile := filepath.Join(t.TempDir(), "accounts.json")
+
+	client, _ := setupAPI(t, store, accountsFile)
+
+	account, err := client.RegisterAccount(t.Context(), nil)
+	require.NoError(t, err)
+
+	// A new API, with the same accounts file.
+	client, _ = setupAPI(t, store, accountsFile)
+
+	err = client.UpdateTXTRecord(t.Context(), account, strings.Repeat("a", txtLength))
+	require.NoError(t, err)
+}
@@ -0,0 +1,81 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+var (
+	_ challenge.Provider   = (*Provider)(nil)
+	_ dns01.RecordProvider = (*Provider)(nil)
+)
+
+// Provider implements challenge.Provider for the DNS-01 challenge:
+// the TXT records are created in the store of a Server.
+//
+// The `_acme-challenge` records of the domains must be CNAMEs pointing inside the challenge zone:
+// the records are created at the targets of the CNAMEs.
+type Provider struct {
+	zone  string
+	store Store
+}
+
+// NewProvider creates a new Provider.
+// The store can be the store of a Server running in the same process (e.g. MemoryStore),
+// or a store shared with a Server running in another process (e.g. DirStore).
+func NewProvider(zone string, store Store) (*Provider, error) {
+	if store == nil {
+		return nil, errors.New("dnsserver: the store is nil")
+	}
+
+	fqdn := normalize(zone)
+	if _, ok := dns.IsDomainName(fqdn); !ok || fqdn == "." {
+		return nil, fmt.Errorf("dnsserver: invalid zone: %q", zone)
+	}
+
+	return &Provider{zone: fqdn, store: store}, nil
+}
+
+// Present creates the TXT record in the challenge zone.
+func (p *Provider) Present(domain, _, keyAuth string) error {
+	return p.PresentRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// CleanUp removes the TXT record from the challenge zone.
+func (p *Provider) CleanUp(domain, _, keyAuth string) error {
+	return p.CleanUpRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentRecord creates an arbitrary TXT record in the challenge zone (see dns01.RecordProvider).
+func (p *Provider) PresentRecord(domain string, info dns01.ChallengeInfo) error {
+	if !inZone(p.zone, info.EffectiveFQDN) {
+		return fmt.Errorf("dnsserver: the challenge of %s is not delegated to the zone %s: create a CNAME record from %s to a name inside the zone",
+			domain, p.zone, info.FQDN)
+	}
+
+	err := p.store.Add(context.Background(), info.EffectiveFQDN, info.Value)
+	if err != nil {
+		return fmt.Errorf("dnsserver: %w", err)
+	}
+
+	return nil
+}
+
+// CleanUpRecord removes an arbitrary TXT record from the challenge zone (see dns01.RecordProvider).
+func (p *Provider) CleanUpRecord(_ string, info dns01.ChallengeInfo) error {
+	if !inZone(p.zone, info.EffectiveFQDN) {
+		return nil
+	}
+
+	err := p.store.Remove(context.Background(), info.EffectiveFQDN, info.Value)
+	if err != nil {
+		return fmt.Errorf("dnsserver: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package dnsserver
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/platform/tester/dnsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.CNAME("abc.acme.example.org.")).
+		Query("_acme-challenge.example.net. CNAME", dnsmock.Noop).
+		Build(t)
+
+	dns01.SetRecursiveNameservers([]string{addr.String()})
+
+	dns01.ClearFqdnCache()
+	t.Cleanup(dns01.ClearFqdnCache)
+
+	store := NewMemoryStore()
+
+	provider, err := NewProvider("acme.example.org", store)
+	require.NoError(t, err)
+
+	info := dns01.GetChallengeInfo("example.com", "keyAuth")
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	values, err := store.Get(t.Context(), "abc.acme.example.org.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{info.Value}, values)
+
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	values, err = store.Get(t.Context(), "abc.acme.example.org.")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	err = provider.Present("example.net", "token", "keyAuth")
+	require.EqualError(t, err, "dnsserver: the challenge of example.net is not delegated to the zone acme.example.org.: "+
+		"create a CNAME record from _acme-challenge.example.net. to a name inside the zone")
+}
+
+func TestNewProvider_errors(t *testing.T) {
+	_, err := NewProvider("acme.example.org", nil)
+	require.EqualError(t, err, "dnsserver: the store is nil")
+
+	_, err = NewProvider(".", NewMemoryStore())
+	require.EqualError(t, err, `dnsserver: invalid zone: "."`)
+}
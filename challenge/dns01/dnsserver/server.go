@@ -0,0 +1,234 @@
+// Package dnsserver implements a small authoritative DNS server for a delegated challenge zone.
+//
+// The `_acme-challenge` records of the domains are CNAMEs pointing inside the challenge zone,
+// and the zone is delegated (NS records) to the server:
+// the TXT records of the challenges are created in the zone by the Provider, or by the acme-dns compatible API (see API),
+// without the API of the DNS hosts of the domains.
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/miekg/dns"
+)
+
+// DefaultTTL is the default TTL of the records.
+const DefaultTTL = 1
+
+// maxTXTStringLength is the maximum length of a character string of a TXT record.
+const maxTXTStringLength = 255
+
+// Options are the options of the Server.
+type Options struct {
+	// Zone is the challenge zone (e.g. `acme.example.com`), delegated to the server.
+	Zone string
+
+	// Nameserver is the hostname of the server, used in the NS and SOA records.
+	// By default, `ns.<zone>`.
+	Nameserver string
+
+	// Admin is the mailbox of the administrator, used in the SOA record.
+	// By default, `hostmaster.<zone>`.
+	Admin string
+
+	// TTL is the TTL of the records.
+	// By default, DefaultTTL.
+	TTL uint32
+}
+
+// Server is an authoritative DNS server for a challenge zone.
+// It only answers the queries for the zone: the SOA and NS records of the zone, and the TXT records of the store.
+type Server struct {
+	zone       string
+	nameserver string
+	admin      string
+	ttl        uint32
+
+	store Store
+}
+
+// NewServer creates a new Server.
+func NewServer(store Store, options Options) (*Server, error) {
+	if store == nil {
+		return nil, errors.New("dnsserver: the store is nil")
+	}
+
+	zone := normalize(options.Zone)
+	if _, ok := dns.IsDomainName(zone); !ok || zone == "." {
+		return nil, fmt.Errorf("dnsserver: invalid zone: %q", options.Zone)
+	}
+
+	s := &Server{
+		zone:       zone,
+		nameserver: dns.Fqdn(options.Nameserver),
+		admin:      dns.Fqdn(options.Admin),
+		ttl:        options.TTL,
+		store:      store,
+	}
+
+	if options.Nameserver == "" {
+		s.nameserver = "ns." + zone
+	}
+
+	if options.Admin == "" {
+		s.admin = "hostmaster." + zone
+	}
+
+	if s.ttl == 0 {
+		s.ttl = DefaultTTL
+	}
+
+	return s, nil
+}
+
+// Zone returns the challenge zone (FQDN).
+func (s *Server) Zone() string {
+	return s.zone
+}
+
+// ListenAndServe serves the DNS queries on the address, in UDP and TCP, until the context is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("dnsserver: %w", err)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = pc.Close()
+		return fmt.Errorf("dnsserver: %w", err)
+	}
+
+	servers := []*dns.Server{
+		{PacketConn: pc, Handler: s},
+		{Listener: ln, Handler: s},
+	}
+
+	errCh := make(chan error, len(servers))
+
+	for _, server := range servers {
+		go func() {
+			errCh <- server.ActivateAndServe()
+		}()
+	}
+
+	log.Info("dnsserver: serving the challenge zone.", log.AttrDomain, s.zone, "addr", addr)
+
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+	}
+
+	for _, server := range servers {
+		_ = server.Shutdown()
+	}
+
+	return err
+}
+
+// ServeDNS implements dns.Handler.
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	_ = w.WriteMsg(s.answer(context.Background(), req))
+}
+
+func (s *Server) answer(ctx context.Context, req *dns.Msg) *dns.Msg {
+	m := new(dns.Msg).SetReply(req)
+
+	if req.Opcode != dns.OpcodeQuery {
+		return m.SetRcode(req, dns.RcodeNotImplemented)
+	}
+
+	if len(req.Question) != 1 {
+		return m.SetRcode(req, dns.RcodeFormatError)
+	}
+
+	if opt := req.IsEdns0(); opt != nil {
+		m.SetEdns0(opt.UDPSize(), false)
+	}
+
+	q := req.Question[0]
+	name := normalize(q.Name)
+
+	if q.Qclass != dns.ClassINET || !dns.IsSubDomain(s.zone, name) {
+		return m.SetRcode(req, dns.RcodeRefused)
+	}
+
+	m.Authoritative = true
+
+	switch {
+	case q.Qtype == dns.TypeSOA && name == s.zone:
+		m.Answer = append(m.Answer, s.soa())
+
+	case q.Qtype == dns.TypeNS && name == s.zone:
+		m.Answer = append(m.Answer, s.ns())
+
+	case q.Qtype == dns.TypeTXT:
+		values, err := s.store.Get(ctx, name)
+		if err != nil {
+			log.Error("dnsserver: could not read the records.", log.AttrDomain, name, log.AttrError, err)
+
+			return m.SetRcode(req, dns.RcodeServerFailure)
+		}
+
+		for _, value := range values {
+			m.Answer = append(m.Answer, s.txt(name, value))
+		}
+	}
+
+	if len(m.Answer) == 0 {
+		// NODATA: the names of the zone always exist, to avoid the caching of NXDOMAIN for the names of the next challenges.
+		m.Ns = append(m.Ns, s.soa())
+	}
+
+	return m
+}
+
+func (s *Server) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: s.zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: s.ttl},
+		Ns:      s.nameserver,
+		Mbox:    s.admin,
+		Serial:  uint32(time.Now().Unix()), //nolint:gosec // the serial is a 32 bits value.
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  s.ttl,
+	}
+}
+
+func (s *Server) ns() *dns.NS {
+	return &dns.NS{
+		Hdr: dns.RR_Header{Name: s.zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: s.ttl},
+		Ns:  s.nameserver,
+	}
+}
+
+func (s *Server) txt(name, value string) *dns.TXT {
+	return &dns.TXT{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: s.ttl},
+		Txt: splitTXT(value),
+	}
+}
+
+// splitTXT splits a value into character strings of 255 bytes maximum.
+func splitTXT(value string) []string {
+	var chunks []string
+
+	for len(value) > maxTXTStringLength {
+		chunks = append(chunks, value[:maxTXTStringLength])
+		value = value[maxTXTStringLength:]
+	}
+
+	return append(chunks, value)
+}
+
+// inZone checks if the FQDN is inside the zone.
+func inZone(zone, fqdn string) bool {
+	return dns.IsSubDomain(zone, normalize(fqdn)) && !strings.EqualFold(normalize(fqdn), zone)
+}
@@ -0,0 +1,177 @@
+package dnsserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		options  Options
+		expected *Server
+	}{
+		{
+			desc:    "defaults",
+			options: Options{Zone: "Acme.Example.com"},
+			expected: &Server{
+				zone:       "acme.example.com.",
+				nameserver: "ns.acme.example.com.",
+				admin:      "hostmaster.acme.example.com.",
+				ttl:        DefaultTTL,
+			},
+		},
+		{
+			desc: "all options",
+			options: Options{
+				Zone:       "acme.example.com.",
+				Nameserver: "dns.example.com",
+				Admin:      "admin.example.com",
+				TTL:        60,
+			},
+			expected: &Server{
+				zone:       "acme.example.com.",
+				nameserver: "dns.example.com.",
+				admin:      "admin.example.com.",
+				ttl:        60,
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			store := NewMemoryStore()
+
+			server, err := NewServer(store, test.options)
+			require.NoError(t, err)
+
+			test.expected.store = store
+
+			assert.Equal(t, test.expected, server)
+		})
+	}
+}
+
+func TestNewServer_errors(t *testing.T) {
+	_, err := NewServer(nil, Options{Zone: "acme.example.com"})
+	require.EqualError(t, err, "dnsserver: the store is nil")
+
+	_, err = NewServer(NewMemoryStore(), Options{})
+	require.EqualError(t, err, `dnsserver: invalid zone: ""`)
+}
+
+func TestServer_answer(t *testing.T) {
+	store := NewMemoryStore()
+
+	require.NoError(t, store.Add(t.Context(), "a.acme.example.com.", "foo"))
+	require.NoError(t, store.Add(t.Context(), "a.acme.example.com.", "bar"))
+	require.NoError(t, store.Add(t.Context(), "long.acme.example.com.", strings.Repeat("x", 300)))
+
+	server, err := NewServer(store, Options{Zone: "acme.example.com"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc          string
+		name          string
+		qType         uint16
+		expectedRcode int
+		expectedTypes []uint16
+		expectedTXT   [][]string
+		expectedNs    bool
+	}{
+		{
+			desc:          "SOA of the zone",
+			name:          "acme.example.com.",
+			qType:         dns.TypeSOA,
+			expectedRcode: dns.RcodeSuccess,
+			expectedTypes: []uint16{dns.TypeSOA},
+		},
+		{
+			desc:          "NS of the zone",
+			name:          "ACME.example.com.",
+			qType:         dns.TypeNS,
+			expectedRcode: dns.RcodeSuccess,
+			expectedTypes: []uint16{dns.TypeNS},
+		},
+		{
+			desc:          "TXT records",
+			name:          "A.acme.example.com.",
+			qType:         dns.TypeTXT,
+			expectedRcode: dns.RcodeSuccess,
+			expectedTypes: []uint16{dns.TypeTXT, dns.TypeTXT},
+			expectedTXT:   [][]string{{"foo"}, {"bar"}},
+		},
+		{
+			desc:          "long TXT record",
+			name:          "long.acme.example.com.",
+			qType:         dns.TypeTXT,
+			expectedRcode: dns.RcodeSuccess,
+			expectedTypes: []uint16{dns.TypeTXT},
+			expectedTXT:   [][]string{{strings.Repeat("x", 255), strings.Repeat("x", 45)}},
+		},
+		{
+			desc:          "no TXT records",
+			name:          "b.acme.example.com.",
+			qType:         dns.TypeTXT,
+			expectedRcode: dns.RcodeSuccess,
+			expectedNs:    true,
+		},
+		{
+			desc:          "other type",
+			name:          "a.acme.example.com.",
+			qType:         dns.TypeA,
+			expectedRcode: dns.RcodeSuccess,
+			expectedNs:    true,
+		},
+		{
+			desc:          "outside the zone",
+			name:          "example.com.",
+			qType:         dns.TypeTXT,
+			expectedRcode: dns.RcodeRefused,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := new(dns.Msg).SetQuestion(test.name, test.qType)
+
+			resp := server.answer(t.Context(), req)
+
+			assert.Equal(t, test.expectedRcode, resp.Rcode)
+
+			var types []uint16
+
+			var txt [][]string
+
+			for _, rr := range resp.Answer {
+				types = append(types, rr.Header().Rrtype)
+
+				if record, ok := rr.(*dns.TXT); ok {
+					txt = append(txt, record.Txt)
+				}
+			}
+
+			assert.Equal(t, test.expectedTypes, types)
+			assert.Equal(t, test.expectedTXT, txt)
+
+			if test.expectedNs {
+				require.Len(t, resp.Ns, 1)
+				assert.IsType(t, &dns.SOA{}, resp.Ns[0])
+			} else {
+				assert.Empty(t, resp.Ns)
+			}
+
+			if test.expectedRcode == dns.RcodeSuccess {
+				assert.True(t, resp.Authoritative)
+			}
+		})
+	}
+}
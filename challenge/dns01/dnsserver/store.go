@@ -0,0 +1,198 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Store stores the TXT records served by the Server.
+// The FQDNs are normalized (lower case, with a trailing dot).
+//
+// The implementations must be safe for concurrent use.
+type Store interface {
+	// Add adds a TXT record.
+	// Adding an existing record is not an error.
+	Add(ctx context.Context, fqdn, value string) error
+
+	// Remove removes a TXT record.
+	// Removing a missing record is not an error.
+	Remove(ctx context.Context, fqdn, value string) error
+
+	// Get returns the values of the TXT records of the FQDN.
+	Get(ctx context.Context, fqdn string) ([]string, error)
+}
+
+// MemoryStore implements Store in memory.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string][]string
+}
+
+// NewMemoryStore creates a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string][]string)}
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(_ context.Context, fqdn, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fqdn = normalize(fqdn)
+
+	if !slices.Contains(s.records[fqdn], value) {
+		s.records[fqdn] = append(s.records[fqdn], value)
+	}
+
+	return nil
+}
+
+// Remove implements Store.
+func (s *MemoryStore) Remove(_ context.Context, fqdn, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fqdn = normalize(fqdn)
+
+	s.records[fqdn] = slices.DeleteFunc(s.records[fqdn], func(v string) bool { return v == value })
+
+	if len(s.records[fqdn]) == 0 {
+		delete(s.records, fqdn)
+	}
+
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, fqdn string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.records[normalize(fqdn)]), nil
+}
+
+// DirStore implements Store using a directory on the local filesystem (or a shared filesystem):
+// the records can be written by a process (e.g. Provider), and served by another one (e.g. `lego dns serve`).
+// Each record is a file, inside a directory named after the FQDN.
+type DirStore string
+
+// Add implements Store.
+func (d DirStore) Add(_ context.Context, fqdn, value string) error {
+	dir, err := d.recordDir(fqdn)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.WriteString(value)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	// The rename is atomic: the readers never see a partial record.
+	return os.Rename(tmp.Name(), filepath.Join(dir, recordFilename(value)))
+}
+
+// Remove implements Store.
+func (d DirStore) Remove(_ context.Context, fqdn, value string) error {
+	dir, err := d.recordDir(fqdn)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, recordFilename(value)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// Removes the directory if it's empty.
+	_ = os.Remove(dir)
+
+	return nil
+}
+
+// Get implements Store.
+func (d DirStore) Get(_ context.Context, fqdn string) ([]string, error) {
+	dir, err := d.recordDir(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed concurrently.
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, string(data))
+	}
+
+	return values, nil
+}
+
+func (d DirStore) recordDir(fqdn string) (string, error) {
+	name := strings.TrimSuffix(normalize(fqdn), ".")
+
+	if _, ok := dns.IsDomainName(name); !ok || name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid FQDN: %q", fqdn)
+	}
+
+	return filepath.Join(string(d), name), nil
+}
+
+func recordFilename(value string) string {
+	hash := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(hash[:]) + ".txt"
+}
+
+func normalize(fqdn string) string {
+	return dns.CanonicalName(fqdn)
+}
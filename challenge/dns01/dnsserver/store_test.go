@@ -0,0 +1,84 @@
+package dnsserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		store func(t *testing.T) Store
+	}{
+		{
+			desc: "memory",
+			store: func(_ *testing.T) Store {
+				return NewMemoryStore()
+			},
+		},
+		{
+			desc: "directory",
+			store: func(t *testing.T) Store {
+				t.Helper()
+
+				return DirStore(t.TempDir())
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			store := test.store(t)
+
+			ctx := t.Context()
+
+			values, err := store.Get(ctx, "a.acme.example.com.")
+			require.NoError(t, err)
+			assert.Empty(t, values)
+
+			require.NoError(t, store.Add(ctx, "a.acme.example.com.", "foo"))
+			require.NoError(t, store.Add(ctx, "A.Acme.Example.com", "bar"))
+			require.NoError(t, store.Add(ctx, "a.acme.example.com.", "foo"))
+			require.NoError(t, store.Add(ctx, "b.acme.example.com.", "baz"))
+
+			values, err = store.Get(ctx, "a.acme.example.com.")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"foo", "bar"}, values)
+
+			require.NoError(t, store.Remove(ctx, "a.acme.example.com.", "foo"))
+			require.NoError(t, store.Remove(ctx, "a.acme.example.com.", "missing"))
+
+			values, err = store.Get(ctx, "a.acme.example.com")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bar"}, values)
+
+			values, err = store.Get(ctx, "b.acme.example.com.")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"baz"}, values)
+		})
+	}
+}
+
+func TestDirStore_invalidFQDN(t *testing.T) {
+	store := DirStore(t.TempDir())
+
+	testCases := []string{
+		".",
+		"",
+		"../a.example.com.",
+		"a/b.example.com.",
+	}
+
+	for _, fqdn := range testCases {
+		t.Run(fqdn, func(t *testing.T) {
+			t.Parallel()
+
+			err := store.Add(t.Context(), fqdn, "foo")
+			require.Error(t, err)
+		})
+	}
+}
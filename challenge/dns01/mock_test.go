@@ -71,11 +71,11 @@ func useAsNameserver(t *testing.T, addr net.Addr) {
 		ClearFqdnCache()
 	})
 
-	originalRecursiveNameservers := recursiveNameservers
+	originalNameservers := defaultResolver.Nameservers()
 
 	t.Cleanup(func() {
-		recursiveNameservers = originalRecursiveNameservers
+		defaultResolver.SetNameservers(originalNameservers)
 	})
 
-	recursiveNameservers = ParseNameservers([]string{addr.String()})
+	defaultResolver.SetNameservers([]string{addr.String()})
 }
@@ -1,14 +1,13 @@
 package dns01
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/miekg/dns"
@@ -16,16 +15,11 @@ import (
 
 const defaultResolvConf = "/etc/resolv.conf"
 
-var fqdnSoaCache = &sync.Map{}
-
 var defaultNameservers = []string{
 	"google-public-dns-a.google.com:53",
 	"google-public-dns-b.google.com:53",
 }
 
-// recursiveNameservers are used to pre-check DNS propagation.
-var recursiveNameservers = getNameservers(defaultResolvConf, defaultNameservers)
-
 // soaCacheEntry holds a cached SOA record (only selected fields).
 type soaCacheEntry struct {
 	zone      string    // zone apex (a domain name)
@@ -46,29 +40,66 @@ func (cache *soaCacheEntry) isExpired() bool {
 	return time.Now().After(cache.expires)
 }
 
-// ClearFqdnCache clears the cache of fqdn to zone mappings. Primarily used in testing.
+// ClearFqdnCache clears the cache of fqdn to zone mappings of the default resolver. Primarily used in testing.
 func ClearFqdnCache() {
-	// TODO(ldez): use `fqdnSoaCache.Clear()` when updating to go1.23
-	fqdnSoaCache.Range(func(k, v any) bool {
-		fqdnSoaCache.Delete(k)
-		return true
-	})
+	defaultResolver.ClearCache()
 }
 
+// AddDNSTimeout sets the timeout of the DNS queries of the default resolver (see DefaultResolver):
+// the resolver used by the challenges without a resolver, and by the zone lookups of the DNS providers.
+// To set the timeout of a single challenge, see WithDNSTimeout.
 func AddDNSTimeout(timeout time.Duration) ChallengeOption {
 	return func(_ *Challenge) error {
-		dnsTimeout = timeout
+		defaultResolver.SetTimeout(timeout)
 		return nil
 	}
 }
 
+// AddRecursiveNameservers sets the recursive nameservers of the default resolver (see DefaultResolver):
+// the resolver used by the challenges without a resolver, and by the zone lookups of the DNS providers.
+// To set the nameservers of a single challenge, see WithRecursiveNameservers.
 func AddRecursiveNameservers(nameservers []string) ChallengeOption {
 	return func(_ *Challenge) error {
-		recursiveNameservers = ParseNameservers(nameservers)
+		defaultResolver.SetNameservers(nameservers)
+		return nil
+	}
+}
+
+// WithDNSTimeout sets the timeout of the DNS queries of the challenge only.
+// The timeout is applied to a copy of the resolver of the challenge (see WithResolver):
+// the default resolver, or the resolver given to WithResolver, is not modified.
+func WithDNSTimeout(timeout time.Duration) ChallengeOption {
+	return func(chlg *Challenge) error {
+		chlg.resolverOpts = append(chlg.resolverOpts, func(r *Resolver) {
+			r.SetTimeout(timeout)
+		})
+
 		return nil
 	}
 }
 
+// WithRecursiveNameservers sets the recursive nameservers of the challenge only.
+// The nameservers are applied to a copy of the resolver of the challenge (see WithResolver):
+// the default resolver, or the resolver given to WithResolver, is not modified.
+//
+// The zone lookups of the DNS providers which don't implement RecordProvider use the default resolver:
+// see AddRecursiveNameservers.
+func WithRecursiveNameservers(nameservers []string) ChallengeOption {
+	return func(chlg *Challenge) error {
+		chlg.resolverOpts = append(chlg.resolverOpts, func(r *Resolver) {
+			r.SetNameservers(nameservers)
+		})
+
+		return nil
+	}
+}
+
+// SetRecursiveNameservers sets the recursive nameservers of the default resolver,
+// used by the lookups (CNAME, SOA, TXT, CAA), outside a DNS challenge (e.g. for the CAA checks).
+func SetRecursiveNameservers(nameservers []string) {
+	defaultResolver.SetNameservers(nameservers)
+}
+
 // getNameservers attempts to get systems nameservers before falling back to the defaults.
 func getNameservers(path string, defaults []string) []string {
 	config, err := dns.ClientConfigFromFile(path)
@@ -79,36 +110,40 @@ func getNameservers(path string, defaults []string) []string {
 	return ParseNameservers(config.Servers)
 }
 
+// ParseNameservers ensures all the nameservers have a port number.
+// The nameservers can be:
+//   - plain DNS: `192.0.2.1`, `192.0.2.1:53`, `dns.example.com:5353`.
+//   - DNS over TLS (RFC 7858): `tls://dns.example.com`, `tls://192.0.2.1:853`.
+//   - DNS over HTTPS (RFC 8484): `https://dns.example.com/dns-query`.
 func ParseNameservers(servers []string) []string {
 	var resolvers []string
 
 	for _, resolver := range servers {
-		// ensure all servers have a port number
-		if _, _, err := net.SplitHostPort(resolver); err != nil {
-			resolvers = append(resolvers, net.JoinHostPort(resolver, "53"))
-		} else {
-			resolvers = append(resolvers, resolver)
-		}
+		resolvers = append(resolvers, parseNameserver(resolver))
 	}
 
 	return resolvers
 }
 
 // lookupNameservers returns the authoritative nameservers for the given fqdn.
-func lookupNameservers(fqdn string) ([]string, error) {
+func (r *Resolver) lookupNameservers(ctx context.Context, fqdn string) ([]string, error) {
 	var authoritativeNss []string
 
-	zone, err := FindZoneByFqdn(fqdn)
+	nameservers := r.Nameservers()
+
+	soa, err := r.lookupSoaByFqdn(ctx, fqdn, nameservers)
 	if err != nil {
-		return nil, fmt.Errorf("could not find zone: %w", err)
+		return nil, fmt.Errorf("could not find zone: [fqdn=%s] %w", fqdn, err)
 	}
 
-	r, err := dnsQuery(zone, dns.TypeNS, recursiveNameservers, true)
+	zone := soa.zone
+
+	msg, err := r.query(ctx, zone, dns.TypeNS, nameservers, true)
 	if err != nil {
 		return nil, fmt.Errorf("NS call failed: %w", err)
 	}
 
-	for _, rr := range r.Answer {
+	for _, rr := range msg.Answer {
 		if ns, ok := rr.(*dns.NS); ok {
 			authoritativeNss = append(authoritativeNss, strings.ToLower(ns.Ns))
 		}
@@ -124,13 +159,13 @@ func lookupNameservers(fqdn string) ([]string, error) {
 // FindPrimaryNsByFqdn determines the primary nameserver of the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
 func FindPrimaryNsByFqdn(fqdn string) (string, error) {
-	return FindPrimaryNsByFqdnCustom(fqdn, recursiveNameservers)
+	return defaultResolver.FindPrimaryNsByFqdn(context.Background(), fqdn)
 }
 
 // FindPrimaryNsByFqdnCustom determines the primary nameserver of the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
 func FindPrimaryNsByFqdnCustom(fqdn string, nameservers []string) (string, error) {
-	soa, err := lookupSoaByFqdn(fqdn, nameservers)
+	soa, err := defaultResolver.lookupSoaByFqdn(context.Background(), fqdn, nameservers)
 	if err != nil {
 		return "", fmt.Errorf("[fqdn=%s] %w", fqdn, err)
 	}
@@ -141,13 +176,13 @@ func FindPrimaryNsByFqdnCustom(fqdn string, nameservers []string) (string, error
 // FindZoneByFqdn determines the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
 func FindZoneByFqdn(fqdn string) (string, error) {
-	return FindZoneByFqdnCustom(fqdn, recursiveNameservers)
+	return defaultResolver.FindZoneByFqdn(context.Background(), fqdn)
 }
 
 // FindZoneByFqdnCustom determines the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
 func FindZoneByFqdnCustom(fqdn string, nameservers []string) (string, error) {
-	soa, err := lookupSoaByFqdn(fqdn, nameservers)
+	soa, err := defaultResolver.lookupSoaByFqdn(context.Background(), fqdn, nameservers)
 	if err != nil {
 		return "", fmt.Errorf("[fqdn=%s] %w", fqdn, err)
 	}
@@ -155,9 +190,9 @@ func FindZoneByFqdnCustom(fqdn string, nameservers []string) (string, error) {
 	return soa.zone, nil
 }
 
-func lookupSoaByFqdn(fqdn string, nameservers []string) (*soaCacheEntry, error) {
+func (r *Resolver) lookupSoaByFqdn(ctx context.Context, fqdn string, nameservers []string) (*soaCacheEntry, error) {
 	// Do we have it cached and is it still fresh?
-	entAny, ok := fqdnSoaCache.Load(fqdn)
+	entAny, ok := r.soaCache.Load(fqdn)
 	if ok && entAny != nil {
 		ent, ok1 := entAny.(*soaCacheEntry)
 		if ok1 && !ent.isExpired() {
@@ -165,46 +200,46 @@ func lookupSoaByFqdn(fqdn string, nameservers []string) (*soaCacheEntry, error)
 		}
 	}
 
-	ent, err := fetchSoaByFqdn(fqdn, nameservers)
+	ent, err := r.fetchSoaByFqdn(ctx, fqdn, nameservers)
 	if err != nil {
 		return nil, err
 	}
 
-	fqdnSoaCache.Store(fqdn, ent)
+	r.soaCache.Store(fqdn, ent)
 
 	return ent, nil
 }
 
-func fetchSoaByFqdn(fqdn string, nameservers []string) (*soaCacheEntry, error) {
+func (r *Resolver) fetchSoaByFqdn(ctx context.Context, fqdn string, nameservers []string) (*soaCacheEntry, error) {
 	var (
 		err error
-		r   *dns.Msg
+		msg *dns.Msg
 	)
 
 	for domain := range DomainsSeq(fqdn) {
-		r, err = dnsQuery(domain, dns.TypeSOA, nameservers, true)
+		msg, err = r.query(ctx, domain, dns.TypeSOA, nameservers, true)
 		if err != nil {
 			continue
 		}
 
-		if r == nil {
+		if msg == nil {
 			continue
 		}
 
-		switch r.Rcode {
+		switch msg.Rcode {
 		case dns.RcodeSuccess:
 			// Check if we got a SOA RR in the answer section
-			if len(r.Answer) == 0 {
+			if len(msg.Answer) == 0 {
 				continue
 			}
 
 			// CNAME records cannot/should not exist at the root of a zone.
 			// So we skip a domain when a CNAME is found.
-			if dnsMsgContainsCNAME(r) {
+			if dnsMsgContainsCNAME(msg) {
 				continue
 			}
 
-			for _, ans := range r.Answer {
+			for _, ans := range msg.Answer {
 				if soa, ok := ans.(*dns.SOA); ok {
 					return newSoaCacheEntry(soa), nil
 				}
@@ -213,11 +248,17 @@ func fetchSoaByFqdn(fqdn string, nameservers []string) (*soaCacheEntry, error) {
 			// NXDOMAIN
 		default:
 			// Any response code other than NOERROR and NXDOMAIN is treated as error
-			return nil, &DNSError{Message: fmt.Sprintf("unexpected response for '%s'", domain), MsgOut: r}
+			return nil, &DNSError{Message: fmt.Sprintf("unexpected response for '%s'", domain), MsgOut: msg}
 		}
 	}
 
-	return nil, &DNSError{Message: fmt.Sprintf("could not find the start of authority for '%s'", fqdn), MsgOut: r, Err: err}
+	return nil, &DNSError{Message: fmt.Sprintf("could not find the start of authority for '%s'", fqdn), MsgOut: msg, Err: err}
+}
+
+// LookupTXT returns the values of the TXT records of the FQDN, using the recursive nameservers of the default resolver.
+// The CNAME records are followed by the recursive nameservers.
+func LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	return defaultResolver.LookupTXT(ctx, fqdn)
 }
 
 // dnsMsgContainsCNAME checks for a CNAME answer in msg.
@@ -228,7 +269,7 @@ func dnsMsgContainsCNAME(msg *dns.Msg) bool {
 	})
 }
 
-func dnsQuery(fqdn string, rtype uint16, nameservers []string, recursive bool) (*dns.Msg, error) {
+func (r *Resolver) query(ctx context.Context, fqdn string, rtype uint16, nameservers []string, recursive bool) (*dns.Msg, error) {
 	m := createDNSMsg(fqdn, rtype, recursive)
 
 	if len(nameservers) == 0 {
@@ -236,14 +277,14 @@ func dnsQuery(fqdn string, rtype uint16, nameservers []string, recursive bool) (
 	}
 
 	var (
-		r      *dns.Msg
+		msg    *dns.Msg
 		err    error
 		errAll error
 	)
 
 	for _, ns := range nameservers {
-		r, err = sendDNSQuery(m, ns)
-		if err == nil && len(r.Answer) > 0 {
+		msg, err = r.send(ctx, m, ns)
+		if err == nil && len(msg.Answer) > 0 {
 			break
 		}
 
@@ -251,10 +292,10 @@ func dnsQuery(fqdn string, rtype uint16, nameservers []string, recursive bool) (
 	}
 
 	if err != nil {
-		return r, errAll
+		return msg, errAll
 	}
 
-	return r, nil
+	return msg, nil
 }
 
 func createDNSMsg(fqdn string, rtype uint16, recursive bool) *dns.Msg {
@@ -269,32 +310,48 @@ func createDNSMsg(fqdn string, rtype uint16, recursive bool) *dns.Msg {
 	return m
 }
 
-func sendDNSQuery(m *dns.Msg, ns string) (*dns.Msg, error) {
+func (r *Resolver) send(ctx context.Context, m *dns.Msg, ns string) (*dns.Msg, error) {
+	timeout := r.Timeout()
+
+	if isEncrypted(ns) {
+		send := sendDoTQuery
+		if hasPrefixFold(ns, dohPrefix) {
+			send = sendDoHQuery
+		}
+
+		msg, err := send(ctx, m, ns, timeout)
+		if err != nil {
+			return msg, &DNSError{Message: "DNS call error", MsgIn: m, NS: ns, Err: err}
+		}
+
+		return msg, nil
+	}
+
 	if ok, _ := strconv.ParseBool(os.Getenv("LEGO_EXPERIMENTAL_DNS_TCP_ONLY")); ok {
-		tcp := &dns.Client{Net: "tcp", Timeout: dnsTimeout}
+		tcp := &dns.Client{Net: "tcp", Timeout: timeout}
 
-		r, _, err := tcp.Exchange(m, ns)
+		msg, _, err := tcp.ExchangeContext(ctx, m, ns)
 		if err != nil {
-			return r, &DNSError{Message: "DNS call error", MsgIn: m, NS: ns, Err: err}
+			return msg, &DNSError{Message: "DNS call error", MsgIn: m, NS: ns, Err: err}
 		}
 
-		return r, nil
+		return msg, nil
 	}
 
-	udp := &dns.Client{Net: "udp", Timeout: dnsTimeout}
-	r, _, err := udp.Exchange(m, ns)
+	udp := &dns.Client{Net: "udp", Timeout: timeout}
+	msg, _, err := udp.ExchangeContext(ctx, m, ns)
 
-	if r != nil && r.Truncated {
-		tcp := &dns.Client{Net: "tcp", Timeout: dnsTimeout}
+	if msg != nil && msg.Truncated {
+		tcp := &dns.Client{Net: "tcp", Timeout: timeout}
 		// If the TCP request succeeds, the "err" will reset to nil
-		r, _, err = tcp.Exchange(m, ns)
+		msg, _, err = tcp.ExchangeContext(ctx, m, ns)
 	}
 
 	if err != nil {
-		return r, &DNSError{Message: "DNS call error", MsgIn: m, NS: ns, Err: err}
+		return msg, &DNSError{Message: "DNS call error", MsgIn: m, NS: ns, Err: err}
 	}
 
-	return r, nil
+	return msg, nil
 }
 
 // DNSError error related to DNS calls.
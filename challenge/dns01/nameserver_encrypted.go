@@ -0,0 +1,202 @@
+package dns01
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Prefixes of the encrypted nameservers.
+const (
+	// DNS over HTTPS (RFC 8484): `https://dns.example.com/dns-query`.
+	dohPrefix = "https://"
+
+	// DNS over TLS (RFC 7858): `tls://dns.example.com` or `tls://192.0.2.1:853`.
+	dotPrefix = "tls://"
+)
+
+// defaultNameserverTLSPort used by DNS over TLS.
+const defaultNameserverTLSPort = "853"
+
+// dotNameserverPort used by the authoritative NS with DNS over TLS.
+// This is for tests only.
+var dotNameserverPort = defaultNameserverTLSPort
+
+// rootCAs are the root certificates used to verify the encrypted nameservers.
+// By default (nil), the system root certificates are used.
+// This is for tests only.
+var rootCAs *x509.CertPool
+
+// parseNameserver ensures the nameserver has a port number (53, or 853 for DNS over TLS).
+// The DNS over HTTPS nameservers are URLs, kept as is.
+func parseNameserver(nameserver string) string {
+	switch {
+	case hasPrefixFold(nameserver, dohPrefix):
+		return nameserver
+
+	case hasPrefixFold(nameserver, dotPrefix):
+		host := nameserver[len(dotPrefix):]
+
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultNameserverTLSPort)
+		}
+
+		return dotPrefix + host
+
+	default:
+		if _, _, err := net.SplitHostPort(nameserver); err != nil {
+			return net.JoinHostPort(nameserver, "53")
+		}
+
+		return nameserver
+	}
+}
+
+// isEncrypted checks if the nameserver uses DNS over HTTPS or DNS over TLS.
+func isEncrypted(nameserver string) bool {
+	return hasPrefixFold(nameserver, dohPrefix) || hasPrefixFold(nameserver, dotPrefix)
+}
+
+// onlyEncrypted checks if all the nameservers use DNS over HTTPS or DNS over TLS:
+// the plain DNS traffic is probably blocked.
+func onlyEncrypted(nameservers []string) bool {
+	if len(nameservers) == 0 {
+		return false
+	}
+
+	for _, ns := range nameservers {
+		if !isEncrypted(ns) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sendDoHQuery sends the query with DNS over HTTPS (RFC 8484).
+func sendDoHQuery(ctx context.Context, m *dns.Msg, endpoint string, timeout time.Duration) (*dns.Msg, error) {
+	// The ID should be 0 to maximize the HTTP cache friendliness (RFC 8484, section 4.1).
+	query := m.Copy()
+	query.Id = 0
+
+	data, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	client := &http.Client{Timeout: timeout}
+
+	if rootCAs != nil {
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if ok {
+			transport = transport.Clone()
+			transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+
+			client.Transport = transport
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(dns.Msg)
+
+	err = r.Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Id = m.Id
+
+	return r, nil
+}
+
+// sendDoTQuery sends the query with DNS over TLS (RFC 7858).
+func sendDoTQuery(ctx context.Context, m *dns.Msg, nameserver string, timeout time.Duration) (*dns.Msg, error) {
+	addr := nameserver[len(dotPrefix):]
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &dns.Client{
+		Net:     "tcp-tls",
+		Timeout: timeout,
+		TLSConfig: &tls.Config{
+			ServerName: strings.TrimSuffix(host, "."),
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+
+	msg, _, err := client.ExchangeContext(ctx, m, addr)
+
+	return msg, err
+}
+
+// queryAuthoritativeNameserver queries an authoritative nameserver.
+// When only encrypted recursive nameservers are used (the plain DNS traffic is probably blocked),
+// DNS over TLS is tried first, and plain DNS is used if the nameserver doesn't support DNS over TLS.
+func (r *Resolver) queryAuthoritativeNameserver(ctx context.Context, fqdn string, rtype uint16, ns string) (*dns.Msg, string, error) {
+	plain := net.JoinHostPort(ns, defaultNameserverPort)
+
+	if !onlyEncrypted(r.Nameservers()) {
+		msg, err := r.query(ctx, fqdn, rtype, []string{plain}, false)
+
+		return msg, plain, err
+	}
+
+	if _, unavailable := r.dotUnavailable.Load(ns); !unavailable {
+		dot := dotPrefix + net.JoinHostPort(ns, dotNameserverPort)
+
+		msg, err := r.query(ctx, fqdn, rtype, []string{dot}, false)
+		if err == nil {
+			return msg, dot, nil
+		}
+
+		if ctx.Err() != nil {
+			return msg, dot, err
+		}
+
+		r.dotUnavailable.Store(ns, struct{}{})
+	}
+
+	msg, err := r.query(ctx, fqdn, rtype, []string{plain}, false)
+
+	return msg, plain, err
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
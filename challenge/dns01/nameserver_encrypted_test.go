@@ -0,0 +1,234 @@
+package dns01
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-acme/lego/v4/platform/tester/dnsmock"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNameservers(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		servers  []string
+		expected []string
+	}{
+		{
+			desc:     "plain DNS",
+			servers:  []string{"192.0.2.1", "192.0.2.2:5353", "2001:db8::1", "dns.example.com"},
+			expected: []string{"192.0.2.1:53", "192.0.2.2:5353", "[2001:db8::1]:53", "dns.example.com:53"},
+		},
+		{
+			desc:     "DNS over TLS",
+			servers:  []string{"tls://dns.example.com", "tls://192.0.2.1:8853", "tls://[2001:db8::1]", "TLS://dns.example.com"},
+			expected: []string{"tls://dns.example.com:853", "tls://192.0.2.1:8853", "tls://[2001:db8::1]:853", "tls://dns.example.com:853"},
+		},
+		{
+			desc:     "DNS over HTTPS",
+			servers:  []string{"https://dns.example.com/dns-query", "https://dns.example.com:8443/dns-query"},
+			expected: []string{"https://dns.example.com/dns-query", "https://dns.example.com:8443/dns-query"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ParseNameservers(test.servers))
+		})
+	}
+}
+
+func TestLookupTXT_doh(t *testing.T) {
+	handler := dnsmock.Answer(fakeTXT("_acme-challenge.example.com.", "foo"))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(rw, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		m := new(dns.Msg)
+
+		err = m.Unpack(body)
+		if err != nil || m.Id != 0 {
+			http.Error(rw, "invalid message", http.StatusBadRequest)
+			return
+		}
+
+		w := &recorderWriter{}
+		handler(w, m)
+
+		data, err := w.msg.Pack()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/dns-message")
+		_, _ = rw.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	useRootCA(t, server.Certificate())
+
+	resolver := NewResolver([]string{server.URL + "/dns-query"}, 0)
+
+	values, err := resolver.LookupTXT(t.Context(), "_acme-challenge.example.com.")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo"}, values)
+}
+
+func TestLookupTXT_dot(t *testing.T) {
+	addr := startDoTServer(t, dnsmock.Answer(fakeTXT("_acme-challenge.example.com.", "foo")))
+
+	resolver := NewResolver([]string{"tls://" + addr}, 0)
+
+	values, err := resolver.LookupTXT(t.Context(), "_acme-challenge.example.com.")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo"}, values)
+}
+
+func Test_queryAuthoritativeNameserver(t *testing.T) {
+	plainAddr := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. TXT", dnsmock.Answer(fakeTXT("_acme-challenge.example.com.", "plain"))).
+		Build(t)
+
+	_, plainPort, err := net.SplitHostPort(plainAddr.String())
+	require.NoError(t, err)
+
+	dotAddr := startDoTServer(t, dnsmock.Answer(fakeTXT("_acme-challenge.example.com.", "dot")))
+
+	_, dotPort, err := net.SplitHostPort(dotAddr)
+	require.NoError(t, err)
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	_, closedPort, err := net.SplitHostPort(closed.Addr().String())
+	require.NoError(t, err)
+
+	_ = closed.Close()
+
+	testCases := []struct {
+		desc          string
+		nameservers   []string
+		dotPort       string
+		expectedNS    string
+		expectedValue string
+	}{
+		{
+			desc:          "plain recursive nameservers",
+			nameservers:   []string{"127.0.0.1:53"},
+			dotPort:       dotPort,
+			expectedNS:    net.JoinHostPort("127.0.0.1", plainPort),
+			expectedValue: "plain",
+		},
+		{
+			desc:          "encrypted recursive nameservers",
+			nameservers:   []string{"tls://127.0.0.1:853"},
+			dotPort:       dotPort,
+			expectedNS:    "tls://" + net.JoinHostPort("127.0.0.1", dotPort),
+			expectedValue: "dot",
+		},
+		{
+			desc:          "encrypted recursive nameservers, DNS over TLS not available",
+			nameservers:   []string{"https://dns.example.com/dns-query"},
+			dotPort:       closedPort,
+			expectedNS:    net.JoinHostPort("127.0.0.1", plainPort),
+			expectedValue: "plain",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			originalDefaultNameserverPort := defaultNameserverPort
+			originalDoTNameserverPort := dotNameserverPort
+
+			t.Cleanup(func() {
+				defaultNameserverPort = originalDefaultNameserverPort
+				dotNameserverPort = originalDoTNameserverPort
+			})
+
+			defaultNameserverPort = plainPort
+			dotNameserverPort = test.dotPort
+
+			resolver := NewResolver(test.nameservers, 0)
+
+			r, ns, err := resolver.queryAuthoritativeNameserver(t.Context(), "_acme-challenge.example.com.", dns.TypeTXT, "127.0.0.1")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedNS, ns)
+
+			require.Len(t, r.Answer, 1)
+			assert.Equal(t, []string{test.expectedValue}, r.Answer[0].(*dns.TXT).Txt)
+		})
+	}
+}
+
+// startDoTServer starts a DNS over TLS server, and returns its address.
+func startDoTServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+
+	// Reuses the certificate of httptest (valid for 127.0.0.1).
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(certServer.Close)
+
+	useRootCA(t, certServer.Certificate())
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: certServer.TLS.Certificates,
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: handler}
+
+	go func() { _ = server.ActivateAndServe() }()
+
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return listener.Addr().String()
+}
+
+func useRootCA(t *testing.T, cert *x509.Certificate) {
+	t.Helper()
+
+	originalRootCAs := rootCAs
+
+	t.Cleanup(func() {
+		rootCAs = originalRootCAs
+	})
+
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(cert)
+}
+
+// recorderWriter is a dns.ResponseWriter keeping the response.
+type recorderWriter struct {
+	dns.ResponseWriter
+
+	msg *dns.Msg
+}
+
+func (w *recorderWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+
+	return nil
+}
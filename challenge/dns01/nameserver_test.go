@@ -67,7 +67,7 @@ func Test_lookupNameserversOK(t *testing.T) {
 		t.Run(test.fqdn, func(t *testing.T) {
 			useAsNameserver(t, test.fakeDNSServer.Build(t))
 
-			nss, err := lookupNameservers(test.fqdn)
+			nss, err := defaultResolver.lookupNameservers(t.Context(), test.fqdn)
 			require.NoError(t, err)
 
 			sort.Strings(nss)
@@ -114,7 +114,7 @@ func Test_lookupNameserversErr(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			useAsNameserver(t, test.fakeDNSServer.Build(t))
 
-			_, err := lookupNameservers(test.fqdn)
+			_, err := defaultResolver.lookupNameservers(t.Context(), test.fqdn)
 			require.Error(t, err)
 			assert.EqualError(t, err, test.error)
 		})
@@ -268,6 +268,40 @@ func TestFindPrimaryNsByFqdnCustom(t *testing.T) {
 	}
 }
 
+func TestLookupTXT(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("_validation-persist.example.com. TXT",
+			dnsmock.Answer(
+				fakeTXT("_validation-persist.example.com.", "ca.example; accounturi=https://ca.example/acct/1"),
+				fakeTXT("_validation-persist.example.com.", "other.example; accounturi=https://other.example/acct/2"),
+			),
+		).
+		Build(t)
+
+	useAsNameserver(t, addr)
+
+	values, err := LookupTXT(t.Context(), "_validation-persist.example.com")
+	require.NoError(t, err)
+
+	expected := []string{
+		"ca.example; accounturi=https://ca.example/acct/1",
+		"other.example; accounturi=https://other.example/acct/2",
+	}
+
+	assert.Equal(t, expected, values)
+}
+
+func TestLookupTXT_error(t *testing.T) {
+	addr := dnsmock.NewServer().
+		Query("_validation-persist.example.com. TXT", dnsmock.Error(dns.RcodeServerFailure)).
+		Build(t)
+
+	useAsNameserver(t, addr)
+
+	_, err := LookupTXT(t.Context(), "_validation-persist.example.com.")
+	require.Error(t, err)
+}
+
 func Test_getNameservers_ResolveConfServers(t *testing.T) {
 	testCases := []struct {
 		fixture  string
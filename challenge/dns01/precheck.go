@@ -1,11 +1,13 @@
 package dns01
 
 import (
+	"context"
 	"fmt"
-	"net"
 	"strings"
 	"time"
 
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
 	"github.com/miekg/dns"
 )
 
@@ -65,6 +67,9 @@ type preCheck struct {
 	// checks DNS propagation before notifying ACME that the DNS challenge is ready.
 	checkFunc WrapPreCheckFunc
 
+	// resolver used by the checks.
+	resolver *Resolver
+
 	// require the TXT record to be propagated to all authoritative name servers
 	requireAuthoritativeNssPropagation bool
 
@@ -74,22 +79,37 @@ type preCheck struct {
 
 func newPreCheck() preCheck {
 	return preCheck{
+		resolver:                           defaultResolver,
 		requireAuthoritativeNssPropagation: true,
 	}
 }
 
-func (p preCheck) call(domain, fqdn, value string) (bool, error) {
+func (p preCheck) call(ctx context.Context, domain, fqdn, value string) (bool, error) {
+	check := func(fqdn, value string) (bool, error) {
+		start := time.Now()
+
+		found, err := p.checkDNSPropagation(ctx, fqdn, value)
+
+		log.Debug("acme: DNS record propagation checked.", "fqdn", fqdn, "found", found, log.AttrError, err)
+
+		metrics.PropagationCheck(found && err == nil, time.Since(start))
+
+		return found, err
+	}
+
 	if p.checkFunc == nil {
-		return p.checkDNSPropagation(fqdn, value)
+		return check(fqdn, value)
 	}
 
-	return p.checkFunc(domain, fqdn, value, p.checkDNSPropagation)
+	return p.checkFunc(domain, fqdn, value, check)
 }
 
 // checkDNSPropagation checks if the expected TXT record has been propagated to all authoritative nameservers.
-func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
+func (p preCheck) checkDNSPropagation(ctx context.Context, fqdn, value string) (bool, error) {
+	nameservers := p.resolver.Nameservers()
+
 	// Initial attempt to resolve at the recursive NS (require to get CNAME)
-	r, err := dnsQuery(fqdn, dns.TypeTXT, recursiveNameservers, true)
+	r, err := p.resolver.query(ctx, fqdn, dns.TypeTXT, nameservers, true)
 	if err != nil {
 		return false, fmt.Errorf("initial recursive nameserver: %w", err)
 	}
@@ -99,7 +119,7 @@ func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 	}
 
 	if p.requireRecursiveNssPropagation {
-		_, err = checkNameserversPropagation(fqdn, value, recursiveNameservers, false)
+		_, err = p.resolver.checkNameserversPropagation(ctx, fqdn, value, nameservers, false)
 		if err != nil {
 			return false, fmt.Errorf("recursive nameservers: %w", err)
 		}
@@ -109,12 +129,12 @@ func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 		return true, nil
 	}
 
-	authoritativeNss, err := lookupNameservers(fqdn)
+	authoritativeNss, err := p.resolver.lookupNameservers(ctx, fqdn)
 	if err != nil {
 		return false, err
 	}
 
-	found, err := checkNameserversPropagation(fqdn, value, authoritativeNss, true)
+	found, err := p.resolver.checkNameserversPropagation(ctx, fqdn, value, authoritativeNss, true)
 	if err != nil {
 		return found, fmt.Errorf("authoritative nameservers: %w", err)
 	}
@@ -123,26 +143,32 @@ func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 }
 
 // checkNameserversPropagation queries each of the given nameservers for the expected TXT record.
-func checkNameserversPropagation(fqdn, value string, nameservers []string, addPort bool) (bool, error) {
+func (r *Resolver) checkNameserversPropagation(ctx context.Context, fqdn, value string, nameservers []string, addPort bool) (bool, error) {
 	for _, ns := range nameservers {
+		var (
+			msg *dns.Msg
+			err error
+		)
+
 		if addPort {
-			ns = net.JoinHostPort(ns, defaultNameserverPort)
+			msg, ns, err = r.queryAuthoritativeNameserver(ctx, fqdn, dns.TypeTXT, ns)
+		} else {
+			msg, err = r.query(ctx, fqdn, dns.TypeTXT, []string{ns}, false)
 		}
 
-		r, err := dnsQuery(fqdn, dns.TypeTXT, []string{ns}, false)
 		if err != nil {
 			return false, err
 		}
 
-		if r.Rcode != dns.RcodeSuccess {
-			return false, fmt.Errorf("NS %s returned %s for %s", ns, dns.RcodeToString[r.Rcode], fqdn)
+		if msg.Rcode != dns.RcodeSuccess {
+			return false, fmt.Errorf("NS %s returned %s for %s", ns, dns.RcodeToString[msg.Rcode], fqdn)
 		}
 
 		var records []string
 
 		var found bool
 
-		for _, rr := range r.Answer {
+		for _, rr := range msg.Answer {
 			if txt, ok := rr.(*dns.TXT); ok {
 				record := strings.Join(txt.Txt, "")
 
@@ -67,7 +67,7 @@ func Test_preCheck_checkDNSPropagation(t *testing.T) {
 
 			check := newPreCheck()
 
-			ok, err := check.checkDNSPropagation(test.fqdn, test.value)
+			ok, err := check.checkDNSPropagation(t.Context(), test.fqdn, test.value)
 			if test.expectedError != "" {
 				assert.ErrorContainsf(t, err, test.expectedError, "PreCheckDNS must fail for %s", test.fqdn)
 				assert.False(t, ok, "PreCheckDNS must fail for %s", test.fqdn)
@@ -130,7 +130,7 @@ func Test_checkNameserversPropagation_authoritativeNss(t *testing.T) {
 
 			addr := test.fakeDNSServer.Build(t)
 
-			ok, err := checkNameserversPropagation(test.fqdn, test.value, []string{addr.String()}, false)
+			ok, err := defaultResolver.checkNameserversPropagation(t.Context(), test.fqdn, test.value, []string{addr.String()}, false)
 
 			if test.expectedError == "" {
 				require.NoError(t, err)
@@ -0,0 +1,82 @@
+package dns01
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+)
+
+// RecordProvider is implemented by the DNS providers able to manage arbitrary TXT records,
+// i.e. records which are not computed from a key authorization (see PresentRecord, and the dns-account-01 challenge).
+// The provider creates (or removes) a TXT record at the info.EffectiveFQDN with the info.Value.
+type RecordProvider interface {
+	PresentRecord(domain string, info ChallengeInfo) error
+	CleanUpRecord(domain string, info ChallengeInfo) error
+}
+
+// Record is a TXT record.
+type Record struct {
+	FQDN  string
+	Value string
+}
+
+// PresentRecord creates an arbitrary TXT record through a DNS-01 provider.
+// The provider must implement RecordProvider.
+func PresentRecord(provider challenge.Provider, domain string, record Record) error {
+	p, ok := provider.(RecordProvider)
+	if !ok {
+		return errRecordsUnsupported(provider)
+	}
+
+	return callPresentRecord(p, domain, defaultResolver.newChallengeInfo(ToFqdn(record.FQDN), record.Value))
+}
+
+// CleanUpRecord removes an arbitrary TXT record through a DNS-01 provider.
+// The provider must implement RecordProvider.
+func CleanUpRecord(provider challenge.Provider, domain string, record Record) error {
+	p, ok := provider.(RecordProvider)
+	if !ok {
+		return errRecordsUnsupported(provider)
+	}
+
+	return callCleanUpRecord(p, domain, defaultResolver.newChallengeInfo(ToFqdn(record.FQDN), record.Value))
+}
+
+// callPresentRecord calls the PresentRecord method of the provider, and records the duration of the call in the metrics.
+func callPresentRecord(p RecordProvider, domain string, info ChallengeInfo) error {
+	start := time.Now()
+
+	err := p.PresentRecord(domain, info)
+
+	duration := time.Since(start)
+
+	log.Debug("acme: provider PresentRecord called.",
+		log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(p), "fqdn", info.EffectiveFQDN, "duration", duration, log.AttrError, err)
+
+	metrics.ProviderCall(metrics.ProviderName(p), metrics.OperationPresent, err == nil, duration)
+
+	return err
+}
+
+// callCleanUpRecord calls the CleanUpRecord method of the provider, and records the duration of the call in the metrics.
+func callCleanUpRecord(p RecordProvider, domain string, info ChallengeInfo) error {
+	start := time.Now()
+
+	err := p.CleanUpRecord(domain, info)
+
+	duration := time.Since(start)
+
+	log.Debug("acme: provider CleanUpRecord called.",
+		log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(p), "fqdn", info.EffectiveFQDN, "duration", duration, log.AttrError, err)
+
+	metrics.ProviderCall(metrics.ProviderName(p), metrics.OperationCleanUp, err == nil, duration)
+
+	return err
+}
+
+func errRecordsUnsupported(provider challenge.Provider) error {
+	return fmt.Errorf("dns01: the provider %s doesn't support arbitrary records", metrics.ProviderName(provider))
+}
@@ -0,0 +1,59 @@
+package dns01
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordProviderMock records the arbitrary records.
+type recordProviderMock struct {
+	providerRecorder
+
+	presentRecords, cleanUpRecords []ChallengeInfo
+}
+
+func (p *recordProviderMock) PresentRecord(_ string, info ChallengeInfo) error {
+	p.presentRecords = append(p.presentRecords, info)
+	return nil
+}
+
+func (p *recordProviderMock) CleanUpRecord(_ string, info ChallengeInfo) error {
+	p.cleanUpRecords = append(p.cleanUpRecords, info)
+	return nil
+}
+
+func TestPresentRecord(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	provider := &recordProviderMock{}
+
+	record := Record{FQDN: "_validation-persist.example.com", Value: "ca.example; accounturi=https://ca.example/acct/1"}
+
+	require.NoError(t, PresentRecord(provider, "example.com", record))
+	require.NoError(t, CleanUpRecord(provider, "example.com", record))
+
+	expected := ChallengeInfo{
+		FQDN:          "_validation-persist.example.com.",
+		EffectiveFQDN: "_validation-persist.example.com.",
+		Value:         "ca.example; accounturi=https://ca.example/acct/1",
+	}
+
+	assert.Equal(t, []ChallengeInfo{expected}, provider.presentRecords)
+	assert.Equal(t, []ChallengeInfo{expected}, provider.cleanUpRecords)
+
+	// The provider methods of the dns-01 challenge are not used.
+	assert.Empty(t, provider.present)
+	assert.Empty(t, provider.cleanUp)
+}
+
+func TestPresentRecord_unsupported(t *testing.T) {
+	record := Record{FQDN: "_validation-persist.example.com", Value: "ca.example"}
+
+	err := PresentRecord(&providerRecorder{}, "example.com", record)
+	require.EqualError(t, err, "dns01: the provider dns01 doesn't support arbitrary records")
+
+	err = CleanUpRecord(&providerRecorder{}, "example.com", record)
+	require.EqualError(t, err, "dns01: the provider dns01 doesn't support arbitrary records")
+}
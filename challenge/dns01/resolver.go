@@ -0,0 +1,169 @@
+package dns01
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// defaultResolver is used by the package-level functions (FindZoneByFqdn, LookupTXT, GetChallengeInfo, etc.),
+// and by the challenges without a resolver (see WithResolver).
+var defaultResolver = NewResolver(nil, 0)
+
+// Resolver performs the DNS lookups of the DNS challenges:
+// the zone apex determination, the CNAME resolution, the propagation checks, and the CAA and TXT lookups.
+//
+// A Resolver holds its own recursive nameservers, timeout, and cache of the SOA records:
+// several resolvers (e.g. one per tenant) can be used concurrently without sharing their settings.
+// A Resolver is safe for concurrent use.
+type Resolver struct {
+	mu          sync.RWMutex
+	nameservers []string
+	timeout     time.Duration
+
+	// soaCache holds the zone apexes of the FQDNs (fqdn -> *soaCacheEntry).
+	soaCache *sync.Map
+
+	// dotUnavailable holds the authoritative nameservers without DNS over TLS.
+	dotUnavailable *sync.Map
+}
+
+// NewResolver creates a new Resolver.
+// The nameservers are parsed by ParseNameservers:
+// by default (empty list), the nameservers of the system are used, or the Google's DNS resolvers if the system's cannot be determined.
+// By default (0), the timeout of the DNS queries is 10 seconds (20 seconds on Windows).
+func NewResolver(nameservers []string, timeout time.Duration) *Resolver {
+	r := &Resolver{
+		soaCache:       &sync.Map{},
+		dotUnavailable: &sync.Map{},
+	}
+
+	r.SetNameservers(nameservers)
+	r.SetTimeout(timeout)
+
+	return r
+}
+
+// clone returns a copy of the resolver, with its own cache.
+func (r *Resolver) clone() *Resolver {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &Resolver{
+		nameservers:    slices.Clone(r.nameservers),
+		timeout:        r.timeout,
+		soaCache:       &sync.Map{},
+		dotUnavailable: &sync.Map{},
+	}
+}
+
+// DefaultResolver returns the resolver used by the package-level functions.
+func DefaultResolver() *Resolver {
+	return defaultResolver
+}
+
+// Nameservers returns the recursive nameservers.
+func (r *Resolver) Nameservers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.nameservers)
+}
+
+// SetNameservers sets the recursive nameservers.
+// By default (empty list), the nameservers of the system are used.
+func (r *Resolver) SetNameservers(nameservers []string) {
+	parsed := ParseNameservers(nameservers)
+	if len(parsed) == 0 {
+		parsed = getNameservers(defaultResolvConf, defaultNameservers)
+	}
+
+	r.mu.Lock()
+	r.nameservers = parsed
+	r.mu.Unlock()
+}
+
+// Timeout returns the timeout of the DNS queries.
+func (r *Resolver) Timeout() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.timeout
+}
+
+// SetTimeout sets the timeout of the DNS queries.
+// By default (0), the timeout is 10 seconds (20 seconds on Windows).
+func (r *Resolver) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+
+	r.mu.Lock()
+	r.timeout = timeout
+	r.mu.Unlock()
+}
+
+// ClearCache clears the cache of fqdn to zone mappings.
+func (r *Resolver) ClearCache() {
+	r.soaCache.Clear()
+	r.dotUnavailable.Clear()
+}
+
+// FindZoneByFqdn determines the zone apex for the given fqdn
+// by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
+func (r *Resolver) FindZoneByFqdn(ctx context.Context, fqdn string) (string, error) {
+	soa, err := r.lookupSoaByFqdn(ctx, fqdn, r.Nameservers())
+	if err != nil {
+		return "", fmt.Errorf("[fqdn=%s] %w", fqdn, err)
+	}
+
+	return soa.zone, nil
+}
+
+// FindPrimaryNsByFqdn determines the primary nameserver of the zone apex for the given fqdn
+// by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
+func (r *Resolver) FindPrimaryNsByFqdn(ctx context.Context, fqdn string) (string, error) {
+	soa, err := r.lookupSoaByFqdn(ctx, fqdn, r.Nameservers())
+	if err != nil {
+		return "", fmt.Errorf("[fqdn=%s] %w", fqdn, err)
+	}
+
+	return soa.primaryNs, nil
+}
+
+// LookupTXT returns the values of the TXT records of the FQDN.
+// The CNAME records are followed by the recursive nameservers.
+func (r *Resolver) LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	msg, err := r.query(ctx, ToFqdn(fqdn), dns.TypeTXT, r.Nameservers(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+	default:
+		return nil, &DNSError{Message: fmt.Sprintf("unexpected response for '%s'", fqdn), MsgOut: msg}
+	}
+
+	var values []string
+
+	for _, rr := range msg.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			values = append(values, strings.Join(txt.Txt, ""))
+		}
+	}
+
+	return values, nil
+}
+
+// GetChallengeInfo returns information used to create a DNS record which will fulfill the `dns-01` challenge,
+// the CNAMEs are resolved by the resolver.
+// See the package-level GetChallengeInfo.
+func (r *Resolver) GetChallengeInfo(domain, keyAuth string) ChallengeInfo {
+	return getChallengeInfo(r, domain, keyAuth)
+}
@@ -0,0 +1,166 @@
+package dns01
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/go-acme/lego/v4/platform/tester/dnsmock"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolver(t *testing.T) {
+	resolver := NewResolver([]string{"192.0.2.1", "tls://dns.example.com"}, 0)
+
+	assert.Equal(t, []string{"192.0.2.1:53", "tls://dns.example.com:853"}, resolver.Nameservers())
+	assert.Equal(t, defaultDNSTimeout, resolver.Timeout())
+
+	resolver.SetTimeout(5 * time.Second)
+
+	assert.Equal(t, 5*time.Second, resolver.Timeout())
+
+	resolver = NewResolver(nil, 0)
+
+	assert.NotEmpty(t, resolver.Nameservers())
+}
+
+func TestResolver_isolation(t *testing.T) {
+	addrA := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.CNAME("a.example.net.")).
+		Query("a.example.net. CNAME", dnsmock.Noop).
+		Query("example.com. SOA", dnsmock.SOA("")).
+		Build(t)
+
+	addrB := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.CNAME("b.example.net.")).
+		Query("b.example.net. CNAME", dnsmock.Noop).
+		Query("_acme-challenge.example.com. SOA", dnsmock.Error(dns.RcodeNameError)).
+		Query("example.com. SOA", dnsmock.Error(dns.RcodeNameError)).
+		Query("com. SOA", dnsmock.SOA("")).
+		Build(t)
+
+	resolverA := NewResolver([]string{addrA.String()}, time.Second)
+	resolverB := NewResolver([]string{addrB.String()}, time.Second)
+
+	assert.Equal(t, "a.example.net.", resolverA.GetChallengeInfo("example.com", "123").EffectiveFQDN)
+	assert.Equal(t, "b.example.net.", resolverB.GetChallengeInfo("example.com", "123").EffectiveFQDN)
+
+	// The SOA caches are not shared.
+	zone, err := resolverA.FindZoneByFqdn(t.Context(), "example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com.", zone)
+
+	zone, err = resolverB.FindZoneByFqdn(t.Context(), "example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, "com.", zone)
+
+	resolverA.ClearCache()
+
+	_, found := resolverB.soaCache.Load("example.com.")
+	assert.True(t, found)
+}
+
+func TestChallenge_WithResolver(t *testing.T) {
+	useAsNameserver(t, dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.Noop).
+		Build(t))
+
+	addr := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.CNAME("tenant.example.net.")).
+		Query("tenant.example.net. CNAME", dnsmock.Noop).
+		Build(t)
+
+	server := tester.MockACMEServer().BuildHTTPS(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	resolver := NewResolver([]string{addr.String()}, time.Second)
+
+	provider := &providerInfoMock{}
+
+	// The options modifying the resolver can be before WithResolver.
+	chlg := NewChallenge(core, nil, provider, WithDNSTimeout(2*time.Second), WithResolver(resolver))
+
+	assert.Equal(t, 2*time.Second, chlg.resolver.Timeout())
+	assert.Equal(t, resolver.Nameservers(), chlg.resolver.Nameservers())
+
+	// The shared resolvers are not modified.
+	assert.Equal(t, time.Second, resolver.Timeout())
+	assert.Equal(t, defaultDNSTimeout, defaultResolver.Timeout())
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.com"},
+		Challenges: []acme.Challenge{{Type: challenge.DNS01.String(), Token: "token"}},
+	}
+
+	require.NoError(t, chlg.PreSolve(authz))
+
+	// The record is computed with the resolver of the challenge.
+	assert.Equal(t, "tenant.example.net.", provider.info.EffectiveFQDN)
+
+	require.NoError(t, chlg.CleanUp(authz))
+
+	assert.Equal(t, "tenant.example.net.", provider.info.EffectiveFQDN)
+
+	// The other challenges use the default resolver.
+	keyAuth, err := core.GetKeyAuthorization("token")
+	require.NoError(t, err)
+
+	assert.Equal(t, "_acme-challenge.example.com.", GetChallengeInfo("example.com", keyAuth).EffectiveFQDN)
+}
+
+func TestChallenge_WithRecursiveNameservers(t *testing.T) {
+	nameservers := defaultResolver.Nameservers()
+
+	chlg := NewChallenge(nil, nil, &providerRecorder{}, WithRecursiveNameservers([]string{"192.0.2.1"}))
+
+	assert.Equal(t, []string{"192.0.2.1:53"}, chlg.resolver.Nameservers())
+	assert.Equal(t, nameservers, defaultResolver.Nameservers())
+}
+
+func TestChallenge_AddRecursiveNameservers(t *testing.T) {
+	nameservers := defaultResolver.Nameservers()
+	t.Cleanup(func() { defaultResolver.SetNameservers(nameservers) })
+
+	chlg := NewChallenge(nil, nil, &providerRecorder{}, AddRecursiveNameservers([]string{"192.0.2.1"}))
+
+	// The default resolver is used by the zone lookups of the providers.
+	assert.Equal(t, []string{"192.0.2.1:53"}, defaultResolver.Nameservers())
+	assert.Same(t, defaultResolver, chlg.resolver)
+}
+
+// providerInfoMock records the records received through RecordProvider.
+type providerInfoMock struct {
+	info ChallengeInfo
+}
+
+func (p *providerInfoMock) Present(domain, _, keyAuth string) error {
+	p.info = GetChallengeInfo(domain, keyAuth)
+	return nil
+}
+
+func (p *providerInfoMock) CleanUp(domain, _, keyAuth string) error {
+	p.info = GetChallengeInfo(domain, keyAuth)
+	return nil
+}
+
+func (p *providerInfoMock) PresentRecord(_ string, info ChallengeInfo) error {
+	p.info = info
+	return nil
+}
+
+func (p *providerInfoMock) CleanUpRecord(_ string, info ChallengeInfo) error {
+	p.info = info
+	return nil
+}
@@ -0,0 +1,183 @@
+package dnspersist01
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/log"
+)
+
+type ValidateFunc func(core *api.Core, domain string, chlng acme.Challenge) error
+
+type ChallengeOption func(*Challenge) error
+
+// CondOption Conditional challenge option.
+func CondOption(condition bool, opt ChallengeOption) ChallengeOption {
+	if !condition {
+		// NoOp options
+		return func(*Challenge) error {
+			return nil
+		}
+	}
+
+	return opt
+}
+
+// SetIssuerDomainName defines the issuer domain name used in the TXT record.
+// By default, the issuer domain names offered by the CA are used.
+func SetIssuerDomainName(name string) ChallengeOption {
+	return func(chlg *Challenge) error {
+		if name == "" {
+			return errors.New("dns-persist-01: empty issuer domain name")
+		}
+
+		chlg.issuerDomainName = name
+
+		return nil
+	}
+}
+
+// DisableRecordCheck disables the check of the TXT record before the validation.
+func DisableRecordCheck() ChallengeOption {
+	return func(chlg *Challenge) error {
+		chlg.recordCheck = false
+		return nil
+	}
+}
+
+// WithResolver defines the DNS resolver used to check the TXT record.
+// By default, the default resolver of the dns01 package is used.
+func WithResolver(resolver *dns01.Resolver) ChallengeOption {
+	return func(chlg *Challenge) error {
+		if resolver == nil {
+			return errors.New("dns-persist-01: nil resolver")
+		}
+
+		chlg.lookupTXT = resolver.LookupTXT
+
+		return nil
+	}
+}
+
+// Challenge implements the dns-persist-01 challenge.
+// https://datatracker.ietf.org/doc/draft-ietf-acme-dns-persist/
+//
+// The TXT record is created once (see Record), and is reused by all the orders:
+// the challenge doesn't need a DNS provider, and the record is never cleaned up.
+type Challenge struct {
+	core             *api.Core
+	validate         ValidateFunc
+	issuerDomainName string
+	recordCheck      bool
+
+	lookupTXT func(ctx context.Context, fqdn string) ([]string, error)
+}
+
+func NewChallenge(core *api.Core, validate ValidateFunc, opts ...ChallengeOption) *Challenge {
+	chlg := &Challenge{
+		core:        core,
+		validate:    validate,
+		recordCheck: true,
+		lookupTXT:   dns01.LookupTXT,
+	}
+
+	for _, opt := range opts {
+		err := opt(chlg)
+		if err != nil {
+			log.Warn("challenge option error.", log.AttrChallengeType, challenge.DNSPersist01, log.AttrError, err)
+		}
+	}
+
+	return chlg
+}
+
+// Solve checks the TXT record, and validates the challenge.
+func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveWithContext(context.Background(), authz)
+}
+
+// SolveWithContext is like Solve, but the context is used to cancel the record check and the validation.
+func (c *Challenge) SolveWithContext(ctx context.Context, authz acme.Authorization) error {
+	domain := challenge.GetTargetedDomain(authz)
+	log.Info("acme: Trying to solve DNS-PERSIST-01", log.AttrDomain, domain)
+
+	chlng, err := challenge.FindChallenge(challenge.DNSPersist01, authz)
+	if err != nil {
+		return err
+	}
+
+	accountURI := c.core.GetAccountURL()
+	if accountURI == "" {
+		return errors.New("acme: the account URL is required by the dns-persist-01 challenge")
+	}
+
+	issuerDomainNames, err := c.getIssuerDomainNames(chlng)
+	if err != nil {
+		return fmt.Errorf("[%s] acme: %w", domain, err)
+	}
+
+	if c.recordCheck {
+		err = c.checkRecord(ctx, authz, issuerDomainNames, accountURI)
+		if err != nil {
+			return fmt.Errorf("[%s] acme: %w", domain, err)
+		}
+	}
+
+	return c.validate(c.core.WithContext(ctx), authz.Identifier.Value, chlng)
+}
+
+// getIssuerDomainNames returns the issuer domain names usable in the TXT record.
+func (c *Challenge) getIssuerDomainNames(chlng acme.Challenge) ([]string, error) {
+	if len(chlng.IssuerDomainNames) == 0 {
+		return nil, errors.New("the dns-persist-01 challenge doesn't contain issuer domain names")
+	}
+
+	if c.issuerDomainName == "" {
+		return chlng.IssuerDomainNames, nil
+	}
+
+	for _, name := range chlng.IssuerDomainNames {
+		if sameDomainName(name, c.issuerDomainName) {
+			return []string{name}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the issuer domain name %q is not accepted by the CA: %v", c.issuerDomainName, chlng.IssuerDomainNames)
+}
+
+// checkRecord checks that a TXT record allows the account to validate the domain.
+func (c *Challenge) checkRecord(ctx context.Context, authz acme.Authorization, issuerDomainNames []string, accountURI string) error {
+	fqdn := GetRecordFQDN(authz.Identifier.Value)
+
+	values, err := c.lookupTXT(ctx, fqdn)
+	if err != nil {
+		return fmt.Errorf("dns-persist-01: failed to look up the TXT record %s: %w", fqdn, err)
+	}
+
+	now := time.Now()
+
+	for _, value := range values {
+		record, err := ParseRecord(value)
+		if err != nil {
+			log.Debug("acme: ignored TXT record.", "fqdn", fqdn, "value", value, log.AttrError, err)
+			continue
+		}
+
+		if slices.ContainsFunc(issuerDomainNames, func(name string) bool {
+			return record.matches(name, accountURI, authz.Wildcard, now)
+		}) {
+			return nil
+		}
+	}
+
+	expected := Record{IssuerDomainName: issuerDomainNames[0], AccountURI: accountURI, Wildcard: authz.Wildcard}
+
+	return fmt.Errorf("dns-persist-01: no TXT record matching the account, expected: %s IN TXT %q", fqdn, expected.Value())
+}
@@ -0,0 +1,136 @@
+package dnspersist01
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountURL = "https://ca.example/acct/123"
+
+func TestChallenge_Solve(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		opts     []ChallengeOption
+		wildcard bool
+		records  []string
+		expected string
+	}{
+		{
+			desc:    "matching record",
+			records: []string{"other.example; accounturi=https://other.example/acct/1", "ca.example; accounturi=" + testAccountURL},
+		},
+		{
+			desc:     "wildcard",
+			wildcard: true,
+			records:  []string{"CA.example.; accounturi=" + testAccountURL + "; policy=wildcard"},
+		},
+		{
+			desc:     "wildcard without policy",
+			wildcard: true,
+			records:  []string{"ca.example; accounturi=" + testAccountURL},
+			expected: `[*.example.com] acme: dns-persist-01: no TXT record matching the account, expected: _validation-persist.example.com. IN TXT "ca.example; accounturi=https://ca.example/acct/123; policy=wildcard"`,
+		},
+		{
+			desc:     "expired record",
+			records:  []string{"ca.example; accounturi=" + testAccountURL + "; persistUntil=1"},
+			expected: `[example.com] acme: dns-persist-01: no TXT record matching the account, expected: _validation-persist.example.com. IN TXT "ca.example; accounturi=https://ca.example/acct/123"`,
+		},
+		{
+			desc:     "other account",
+			records:  []string{"ca.example; accounturi=https://ca.example/acct/456"},
+			expected: `[example.com] acme: dns-persist-01: no TXT record matching the account, expected: _validation-persist.example.com. IN TXT "ca.example; accounturi=https://ca.example/acct/123"`,
+		},
+		{
+			desc:    "issuer domain name",
+			opts:    []ChallengeOption{SetIssuerDomainName("alt.ca.example")},
+			records: []string{"alt.ca.example; accounturi=" + testAccountURL},
+		},
+		{
+			desc:     "issuer domain name not accepted",
+			opts:     []ChallengeOption{SetIssuerDomainName("other.example")},
+			expected: `[example.com] acme: the issuer domain name "other.example" is not accepted by the CA: [ca.example alt.ca.example]`,
+		},
+		{
+			desc: "record check disabled",
+			opts: []ChallengeOption{DisableRecordCheck()},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			core := newTestCore(t, testAccountURL)
+
+			var validated bool
+
+			chlg := NewChallenge(core, func(_ *api.Core, domain string, chlng acme.Challenge) error {
+				assert.Equal(t, "example.com", domain)
+				assert.Equal(t, challenge.DNSPersist01.String(), chlng.Type)
+
+				validated = true
+
+				return nil
+			}, test.opts...)
+
+			chlg.lookupTXT = func(_ context.Context, fqdn string) ([]string, error) {
+				assert.Equal(t, "_validation-persist.example.com.", fqdn)
+
+				return test.records, nil
+			}
+
+			authz := acme.Authorization{
+				Identifier: acme.Identifier{Value: "example.com"},
+				Wildcard:   test.wildcard,
+				Challenges: []acme.Challenge{
+					{Type: challenge.DNSPersist01.String(), IssuerDomainNames: []string{"ca.example", "alt.ca.example"}},
+				},
+			}
+
+			err := chlg.Solve(authz)
+			if test.expected != "" {
+				require.EqualError(t, err, test.expected)
+				assert.False(t, validated)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, validated)
+		})
+	}
+}
+
+func TestChallenge_Solve_noAccount(t *testing.T) {
+	chlg := NewChallenge(newTestCore(t, ""), nil)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.com"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSPersist01.String(), IssuerDomainNames: []string{"ca.example"}},
+		},
+	}
+
+	require.EqualError(t, chlg.Solve(authz), "acme: the account URL is required by the dns-persist-01 challenge")
+}
+
+func newTestCore(t *testing.T, accountURL string) *api.Core {
+	t.Helper()
+
+	server := tester.MockACMEServer().BuildHTTPS(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", accountURL, privateKey)
+	require.NoError(t, err)
+
+	return core
+}
@@ -0,0 +1,115 @@
+package dnspersist01
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+const (
+	paramAccountURI   = "accounturi"
+	paramPolicy       = "policy"
+	paramPersistUntil = "persistuntil"
+
+	policyWildcard = "wildcard"
+)
+
+// Record is the content of the TXT record of the `dns-persist-01` challenge:
+//
+//	_validation-persist.example.com. IN TXT "ca.example; accounturi=https://ca.example/acct/123; policy=wildcard; persistUntil=1767225600"
+type Record struct {
+	// IssuerDomainName is the domain name of the CA allowed to issue certificates.
+	IssuerDomainName string
+
+	// AccountURI is the URL of the ACME account allowed to issue certificates.
+	AccountURI string
+
+	// Wildcard allows the issuance of wildcard certificates (`policy=wildcard`).
+	Wildcard bool
+
+	// PersistUntil is the time after which the record is no longer valid (optional).
+	PersistUntil time.Time
+}
+
+// GetRecordFQDN returns the FQDN of the TXT record of the `dns-persist-01` challenge.
+func GetRecordFQDN(domain string) string {
+	return dns01.ToFqdn("_validation-persist." + strings.TrimPrefix(domain, "*."))
+}
+
+// Value returns the value of the TXT record.
+func (r Record) Value() string {
+	parts := []string{r.IssuerDomainName, paramAccountURI + "=" + r.AccountURI}
+
+	if r.Wildcard {
+		parts = append(parts, paramPolicy+"="+policyWildcard)
+	}
+
+	if !r.PersistUntil.IsZero() {
+		parts = append(parts, "persistUntil="+strconv.FormatInt(r.PersistUntil.Unix(), 10))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// ParseRecord parses the value of a TXT record of the `dns-persist-01` challenge.
+// The unknown parameters are ignored.
+func ParseRecord(value string) (Record, error) {
+	parts := strings.Split(value, ";")
+
+	record := Record{IssuerDomainName: strings.TrimSpace(parts[0])}
+	if record.IssuerDomainName == "" {
+		return Record{}, errors.New("missing issuer domain name")
+	}
+
+	for _, part := range parts[1:] {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Record{}, fmt.Errorf("malformed parameter: %q", part)
+		}
+
+		val = strings.TrimSpace(val)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case paramAccountURI:
+			record.AccountURI = val
+
+		case paramPolicy:
+			record.Wildcard = strings.EqualFold(val, policyWildcard)
+
+		case paramPersistUntil:
+			timestamp, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid persistUntil: %w", err)
+			}
+
+			record.PersistUntil = time.Unix(timestamp, 0)
+		}
+	}
+
+	if record.AccountURI == "" {
+		return Record{}, errors.New("missing accounturi")
+	}
+
+	return record, nil
+}
+
+// matches checks if the record allows the account to validate the domain.
+func (r Record) matches(issuerDomainName, accountURI string, wildcard bool, now time.Time) bool {
+	if !sameDomainName(r.IssuerDomainName, issuerDomainName) || r.AccountURI != accountURI {
+		return false
+	}
+
+	if wildcard && !r.Wildcard {
+		return false
+	}
+
+	return r.PersistUntil.IsZero() || now.Before(r.PersistUntil)
+}
+
+func sameDomainName(a, b string) bool {
+	return strings.EqualFold(dns01.UnFqdn(a), dns01.UnFqdn(b))
+}
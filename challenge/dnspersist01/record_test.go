@@ -0,0 +1,119 @@
+package dnspersist01
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRecordFQDN(t *testing.T) {
+	assert.Equal(t, "_validation-persist.example.com.", GetRecordFQDN("example.com"))
+	assert.Equal(t, "_validation-persist.example.com.", GetRecordFQDN("*.example.com"))
+}
+
+func TestRecord_Value(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		record   Record
+		expected string
+	}{
+		{
+			desc:     "minimal",
+			record:   Record{IssuerDomainName: "ca.example", AccountURI: "https://ca.example/acct/123"},
+			expected: "ca.example; accounturi=https://ca.example/acct/123",
+		},
+		{
+			desc: "all parameters",
+			record: Record{
+				IssuerDomainName: "ca.example",
+				AccountURI:       "https://ca.example/acct/123",
+				Wildcard:         true,
+				PersistUntil:     time.Unix(1767225600, 0),
+			},
+			expected: "ca.example; accounturi=https://ca.example/acct/123; policy=wildcard; persistUntil=1767225600",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.record.Value())
+		})
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected Record
+	}{
+		{
+			desc:     "minimal",
+			value:    "ca.example; accounturi=https://ca.example/acct/123",
+			expected: Record{IssuerDomainName: "ca.example", AccountURI: "https://ca.example/acct/123"},
+		},
+		{
+			desc:  "all parameters",
+			value: "ca.example;accounturi=https://ca.example/acct/123 ; Policy=Wildcard; persistUntil=1767225600; foo=bar",
+			expected: Record{
+				IssuerDomainName: "ca.example",
+				AccountURI:       "https://ca.example/acct/123",
+				Wildcard:         true,
+				PersistUntil:     time.Unix(1767225600, 0),
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			record, err := ParseRecord(test.value)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, record)
+		})
+	}
+}
+
+func TestParseRecord_error(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected string
+	}{
+		{
+			desc:     "empty",
+			value:    "",
+			expected: "missing issuer domain name",
+		},
+		{
+			desc:     "missing account URI",
+			value:    "ca.example; policy=wildcard",
+			expected: "missing accounturi",
+		},
+		{
+			desc:     "malformed parameter",
+			value:    "ca.example; accounturi",
+			expected: `malformed parameter: " accounturi"`,
+		},
+		{
+			desc:     "invalid persistUntil",
+			value:    "ca.example; accounturi=https://ca.example/acct/123; persistUntil=tomorrow",
+			expected: `invalid persistUntil: strconv.ParseInt: parsing "tomorrow": invalid syntax`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseRecord(test.value)
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
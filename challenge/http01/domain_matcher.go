@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/netip"
+	"net/textproto"
 	"strings"
 )
 
@@ -47,6 +48,18 @@ type domainMatcher interface {
 	name() string
 }
 
+// newDomainMatcher creates the domainMatcher associated with a header name (see ProviderServer.SetProxyHeader).
+func newDomainMatcher(headerName string) domainMatcher {
+	switch h := textproto.CanonicalMIMEHeaderKey(headerName); h {
+	case "", "Host":
+		return &hostMatcher{}
+	case "Forwarded":
+		return &forwardedMatcher{}
+	default:
+		return arbitraryMatcher(h)
+	}
+}
+
 // hostMatcher checks whether (*net/http).Request.Host starts with a domain name.
 type hostMatcher struct{}
 
@@ -1,6 +1,7 @@
 package http01
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -8,12 +9,26 @@ import (
 	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/go-acme/lego/v4/platform/wait"
 )
 
 type ValidateFunc func(core *api.Core, domain string, chlng acme.Challenge) error
 
 type ChallengeOption func(*Challenge) error
 
+// CondOption Conditional challenge option.
+func CondOption(condition bool, opt ChallengeOption) ChallengeOption {
+	if !condition {
+		// NoOp options
+		return func(*Challenge) error {
+			return nil
+		}
+	}
+
+	return opt
+}
+
 // SetDelay sets a delay between the start of the HTTP server and the challenge validation.
 func SetDelay(delay time.Duration) ChallengeOption {
 	return func(chlg *Challenge) error {
@@ -32,6 +47,8 @@ type Challenge struct {
 	validate ValidateFunc
 	provider challenge.Provider
 	delay    time.Duration
+
+	selfCheck *selfChecker
 }
 
 func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
@@ -44,7 +61,7 @@ func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Prov
 	for _, opt := range opts {
 		err := opt(chlg)
 		if err != nil {
-			log.Infof("challenge option error: %v", err)
+			log.Warn("challenge option error.", log.AttrChallengeType, challenge.HTTP01, log.AttrError, err)
 		}
 	}
 
@@ -56,8 +73,14 @@ func (c *Challenge) SetProvider(provider challenge.Provider) {
 }
 
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveWithContext(context.Background(), authz)
+}
+
+// SolveWithContext is like Solve, but the context is used to cancel the validation.
+// The token is cleaned up even if the context is canceled.
+func (c *Challenge) SolveWithContext(ctx context.Context, authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Trying to solve HTTP-01", domain)
+	log.Info("acme: Trying to solve HTTP-01", log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(c.provider))
 
 	chlng, err := challenge.FindChallenge(challenge.HTTP01, authz)
 	if err != nil {
@@ -70,23 +93,33 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		return err
 	}
 
-	err = c.provider.Present(authz.Identifier.Value, chlng.Token, keyAuth)
+	err = challenge.CallPresent(c.provider, authz.Identifier.Value, chlng.Token, keyAuth)
 	if err != nil {
 		return fmt.Errorf("[%s] acme: error presenting token: %w", domain, err)
 	}
 
 	defer func() {
-		err := c.provider.CleanUp(authz.Identifier.Value, chlng.Token, keyAuth)
+		err := challenge.CallCleanUp(c.provider, authz.Identifier.Value, chlng.Token, keyAuth)
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v", domain, err)
+			log.Warn("acme: cleaning up failed.", log.AttrDomain, domain, log.AttrError, err)
 		}
 	}()
 
 	if c.delay > 0 {
-		time.Sleep(c.delay)
+		err = wait.Sleep(ctx, c.delay)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.selfCheck != nil {
+		err = c.selfCheck.Wait(ctx, authz.Identifier.Value, chlng.Token, keyAuth)
+		if err != nil {
+			return fmt.Errorf("[%s] acme: %w", domain, err)
+		}
 	}
 
 	chlng.KeyAuthorization = keyAuth
 
-	return c.validate(c.core, domain, chlng)
+	return c.validate(c.core.WithContext(ctx), domain, chlng)
 }
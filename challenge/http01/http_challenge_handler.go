@@ -0,0 +1,113 @@
+package http01
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-acme/lego/v4/log"
+)
+
+// ProviderHandler implements ChallengeProvider for `http-01` challenge, and http.Handler.
+// The key authorizations are kept in memory, and served by the handler:
+// the handler can be mounted on the mux of an existing web server (see Middleware),
+// and the provider can be used by a lego client in the same process.
+type ProviderHandler struct {
+	mu       sync.RWMutex
+	matcher  domainMatcher
+	keyAuths map[string]tokenKeyAuth
+}
+
+type tokenKeyAuth struct {
+	domain  string
+	keyAuth string
+}
+
+// NewProviderHandler creates a new ProviderHandler.
+func NewProviderHandler() *ProviderHandler {
+	return &ProviderHandler{
+		matcher:  &hostMatcher{},
+		keyAuths: make(map[string]tokenKeyAuth),
+	}
+}
+
+// Present makes the token available at `ChallengePath(token)` for web requests.
+func (h *ProviderHandler) Present(domain, token, keyAuth string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.keyAuths[token] = tokenKeyAuth{domain: domain, keyAuth: keyAuth}
+
+	return nil
+}
+
+// CleanUp removes the token from `ChallengePath(token)`.
+func (h *ProviderHandler) CleanUp(_, token, _ string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.keyAuths, token)
+
+	return nil
+}
+
+// SetProxyHeader changes the validation of incoming requests.
+// By default, h matches the "Host" header value to the domain name.
+// See ProviderServer.SetProxyHeader for the details.
+func (h *ProviderHandler) SetProxyHeader(headerName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.matcher = newDomainMatcher(headerName)
+}
+
+// ServeHTTP serves the key authorizations on `ChallengePath(token)`.
+// The requests for unknown tokens, or with a domain not matching the token, receive a 404 response.
+func (h *ProviderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.URL.Path, ChallengePath(""))
+	if !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.RLock()
+	entry, found := h.keyAuths[token]
+	matcher := h.matcher
+	h.mu.RUnlock()
+
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The incoming request is validated to prevent DNS rebind attacks.
+	if !matcher.matches(r, entry.domain) {
+		log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure you are passing the %s header properly.", r.Host, r.Method, matcher.name())
+
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+
+	_, err := w.Write([]byte(entry.keyAuth))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Served key authentication", log.AttrDomain, entry.domain)
+}
+
+// Middleware serves the key authorizations on `ChallengePath(token)`, and forwards the other requests to the next handler.
+func (h *ProviderHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, ChallengePath("")) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
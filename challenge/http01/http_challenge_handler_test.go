@@ -0,0 +1,161 @@
+package http01
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderHandler(t *testing.T) {
+	handler := NewProviderHandler()
+
+	require.NoError(t, handler.Present("example.com", "token", "keyAuth"))
+
+	testCases := []struct {
+		desc           string
+		method         string
+		target         string
+		header         http.Header
+		proxyHeader    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			desc:           "match",
+			method:         http.MethodGet,
+			target:         "http://example.com/.well-known/acme-challenge/token",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "keyAuth",
+		},
+		{
+			desc:           "host with port",
+			method:         http.MethodGet,
+			target:         "http://example.com:8080/.well-known/acme-challenge/token",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "keyAuth",
+		},
+		{
+			desc:           "unknown token",
+			method:         http.MethodGet,
+			target:         "http://example.com/.well-known/acme-challenge/unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "other domain",
+			method:         http.MethodGet,
+			target:         "http://example.org/.well-known/acme-challenge/token",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "invalid method",
+			method:         http.MethodPost,
+			target:         "http://example.com/.well-known/acme-challenge/token",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "proxy header",
+			method:         http.MethodGet,
+			target:         "http://localhost/.well-known/acme-challenge/token",
+			header:         http.Header{"X-Forwarded-Host": []string{"example.com"}},
+			proxyHeader:    "X-Forwarded-Host",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "keyAuth",
+		},
+		{
+			desc:           "forwarded header",
+			method:         http.MethodGet,
+			target:         "http://localhost/.well-known/acme-challenge/token",
+			header:         http.Header{"Forwarded": []string{"host=example.com"}},
+			proxyHeader:    "Forwarded",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "keyAuth",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			handler.SetProxyHeader(test.proxyHeader)
+
+			req := httptest.NewRequest(test.method, test.target, http.NoBody)
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProviderHandler_CleanUp(t *testing.T) {
+	handler := NewProviderHandler()
+
+	require.NoError(t, handler.Present("example.com", "token", "keyAuth"))
+	require.NoError(t, handler.CleanUp("example.com", "token", "keyAuth"))
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com"+ChallengePath("token"), http.NoBody))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestProviderHandler_Middleware(t *testing.T) {
+	handler := NewProviderHandler()
+
+	require.NoError(t, handler.Present("example.com", "token", "keyAuth"))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("application"))
+	})
+
+	server := httptest.NewServer(handler.Middleware(next))
+	t.Cleanup(server.Close)
+
+	testCases := []struct {
+		desc     string
+		path     string
+		expected string
+	}{
+		{
+			desc:     "challenge",
+			path:     ChallengePath("token"),
+			expected: "keyAuth",
+		},
+		{
+			desc:     "application",
+			path:     "/index.html",
+			expected: "application",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+test.path, http.NoBody)
+			require.NoError(t, err)
+
+			req.Host = "example.com"
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, string(body))
+		})
+	}
+}
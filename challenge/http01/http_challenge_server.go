@@ -5,9 +5,9 @@ import (
 	"io/fs"
 	"net"
 	"net/http"
-	"net/textproto"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/go-acme/lego/v4/log"
 )
@@ -15,13 +15,18 @@ import (
 // ProviderServer implements ChallengeProvider for `http-01` challenge.
 // It may be instantiated without using the NewProviderServer function if
 // you want only to use the default values.
+//
+// The server is shared by the concurrent challenges:
+// it is started by the first challenge, and closed when the last challenge is cleaned up.
 type ProviderServer struct {
 	address string
 	network string // must be valid argument to net.Listen
 
 	socketMode fs.FileMode
 
+	mu       sync.Mutex
 	matcher  domainMatcher
+	keyAuths map[string]tokenKeyAuth
 	done     chan bool
 	listener net.Listener
 }
@@ -41,24 +46,23 @@ func NewUnixProviderServer(socketPath string, mode fs.FileMode) *ProviderServer
 	return &ProviderServer{network: "unix", address: socketPath, socketMode: mode, matcher: &hostMatcher{}}
 }
 
-// Present starts a web server and makes the token available at `ChallengePath(token)` for web requests.
+// Present starts a web server (if not already started) and makes the token available at `ChallengePath(token)` for web requests.
 func (s *ProviderServer) Present(domain, token, keyAuth string) error {
-	var err error
-
-	s.listener, err = net.Listen(s.network, s.GetAddress())
-	if err != nil {
-		return fmt.Errorf("could not start HTTP server for challenge: %w", err)
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if s.network == "unix" {
-		if err = os.Chmod(s.address, s.socketMode); err != nil {
-			return fmt.Errorf("chmod %s: %w", s.address, err)
+	if s.listener == nil {
+		err := s.start()
+		if err != nil {
+			return err
 		}
 	}
 
-	s.done = make(chan bool)
+	if s.keyAuths == nil {
+		s.keyAuths = make(map[string]tokenKeyAuth)
+	}
 
-	go s.serve(domain, token, keyAuth)
+	s.keyAuths[token] = tokenKeyAuth{domain: domain, keyAuth: keyAuth}
 
 	return nil
 }
@@ -67,9 +71,14 @@ func (s *ProviderServer) GetAddress() string {
 	return s.address
 }
 
-// CleanUp closes the HTTP server and removes the token from `ChallengePath(token)`.
+// CleanUp removes the token from `ChallengePath(token)`, and closes the HTTP server when no challenge remains.
 func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
-	if s.listener == nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keyAuths, token)
+
+	if s.listener == nil || len(s.keyAuths) > 0 {
 		return nil
 	}
 
@@ -77,6 +86,8 @@ func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
 
 	<-s.done
 
+	s.listener = nil
+
 	return nil
 }
 
@@ -93,39 +104,67 @@ func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
 // - "Forwarded" will look for a Forwarded header, and inspect it according to https://www.rfc-editor.org/rfc/rfc7239.html
 // - any other value will check the header value with the same name.
 func (s *ProviderServer) SetProxyHeader(headerName string) {
-	switch h := textproto.CanonicalMIMEHeaderKey(headerName); h {
-	case "", "Host":
-		s.matcher = &hostMatcher{}
-	case "Forwarded":
-		s.matcher = &forwardedMatcher{}
-	default:
-		s.matcher = arbitraryMatcher(h)
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.matcher = newDomainMatcher(headerName)
 }
 
-func (s *ProviderServer) serve(domain, token, keyAuth string) {
-	path := ChallengePath(token)
+// start starts the HTTP server.
+// The caller must hold the lock.
+func (s *ProviderServer) start() error {
+	listener, err := net.Listen(s.network, s.GetAddress())
+	if err != nil {
+		return fmt.Errorf("could not start HTTP server for challenge: %w", err)
+	}
+
+	if s.network == "unix" {
+		if err = os.Chmod(s.address, s.socketMode); err != nil {
+			listener.Close()
+
+			return fmt.Errorf("chmod %s: %w", s.address, err)
+		}
+	}
 
+	s.listener = listener
+	s.done = make(chan bool)
+
+	go s.serve(listener, s.done)
+
+	return nil
+}
+
+func (s *ProviderServer) serve(listener net.Listener, done chan<- bool) {
 	// The incoming request will be validated to prevent DNS rebind attacks.
 	// We only respond with the keyAuth, when we're receiving a GET requests with
 	// the "Host" header matching the domain (the latter is configurable though SetProxyHeader).
 	mux := http.NewServeMux()
-	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet && s.matcher.matches(r, domain) {
+	mux.HandleFunc(ChallengePath(""), func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		entry, found := s.keyAuths[strings.TrimPrefix(r.URL.Path, ChallengePath(""))]
+		matcher := s.matcher
+		s.mu.Unlock()
+
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Method == http.MethodGet && matcher.matches(r, entry.domain) {
 			w.Header().Set("Content-Type", "text/plain")
 
-			_, err := w.Write([]byte(keyAuth))
+			_, err := w.Write([]byte(entry.keyAuth))
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			log.Infof("[%s] Served key authentication", domain)
+			log.Infof("[%s] Served key authentication", entry.domain)
 
 			return
 		}
 
-		log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure you are passing the %s header properly.", r.Host, r.Method, s.matcher.name())
+		log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure you are passing the %s header properly.", r.Host, r.Method, matcher.name())
 
 		_, err := w.Write([]byte("TEST"))
 		if err != nil {
@@ -140,10 +179,10 @@ func (s *ProviderServer) serve(domain, token, keyAuth string) {
 	// we don't want any lingering connections, so disable KeepAlives.
 	httpServer.SetKeepAlivesEnabled(false)
 
-	err := httpServer.Serve(s.listener)
+	err := httpServer.Serve(listener)
 	if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
 		log.Println(err)
 	}
 
-	s.done <- true
+	done <- true
 }
@@ -66,6 +66,59 @@ func TestProviderServer_GetAddress(t *testing.T) {
 	}
 }
 
+func TestProviderServer_concurrent(t *testing.T) {
+	server := NewProviderServer("127.0.0.1", "0")
+
+	require.NoError(t, server.Present("a.example.com", "tokenA", "keyAuthA"))
+	require.NoError(t, server.Present("b.example.com", "tokenB", "keyAuthB"))
+
+	// The server is shared by the challenges.
+	address := server.listener.Addr().String()
+
+	get := func(domain, token string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "http://"+address+ChallengePath(token), http.NoBody)
+		require.NoError(t, err)
+
+		req.Host = domain
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("a.example.com", "tokenA")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "keyAuthA", body)
+
+	require.NoError(t, server.CleanUp("a.example.com", "tokenA", "keyAuthA"))
+
+	code, _ = get("a.example.com", "tokenA")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = get("b.example.com", "tokenB")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "keyAuthB", body)
+
+	// The server is closed with the last challenge.
+	require.NoError(t, server.CleanUp("b.example.com", "tokenB", "keyAuthB"))
+
+	assert.Nil(t, server.listener)
+
+	_, err := net.Dial("tcp", address)
+	require.Error(t, err)
+
+	// A clean-up without presented challenge is a no-op.
+	require.NoError(t, server.CleanUp("c.example.com", "tokenC", "keyAuthC"))
+}
+
 func TestChallenge(t *testing.T) {
 	server := tester.MockACMEServer().BuildHTTPS(t)
 
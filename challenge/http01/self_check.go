@@ -0,0 +1,175 @@
+package http01
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/wait"
+)
+
+const (
+	// DefaultSelfCheckTimeout is the default duration of the self-check retries.
+	DefaultSelfCheckTimeout = 2 * time.Minute
+
+	// DefaultSelfCheckInterval is the default interval between two self-check attempts.
+	DefaultSelfCheckInterval = 2 * time.Second
+)
+
+// maxKeyAuthSize is the maximum size of the key authorization body that we will read.
+const maxKeyAuthSize = 4 * 1024
+
+// SelfCheckOptions are the options of the self-check.
+type SelfCheckOptions struct {
+	// Target is the address (host:port) where the requests are sent, instead of the address of the domain.
+	// Useful to check a specific load balancer, or a specific backend.
+	// Only the requests to the port 80 are sent to the target (the redirections to HTTPS are followed normally).
+	Target string
+
+	// Resolver is the address (host:port) of the DNS resolver used to resolve the domain.
+	// By default, the system resolver is used.
+	Resolver string
+
+	// Timeout is the duration during which the self-check is retried.
+	// By default, DefaultSelfCheckTimeout.
+	Timeout time.Duration
+
+	// Interval is the interval between two attempts.
+	// By default, DefaultSelfCheckInterval.
+	Interval time.Duration
+}
+
+// SelfCheck enables a check of the key authorization from the local machine, before asking the CA to validate the challenge:
+// `http://<domain>/.well-known/acme-challenge/<token>` must serve the key authorization.
+// The check is retried until it succeeds, or until the timeout.
+// It catches the routing problems (load balancers, CDN) without failed validations on the CA side.
+func SelfCheck(options SelfCheckOptions) ChallengeOption {
+	return func(chlg *Challenge) error {
+		if options.Timeout <= 0 {
+			options.Timeout = DefaultSelfCheckTimeout
+		}
+
+		if options.Interval <= 0 {
+			options.Interval = DefaultSelfCheckInterval
+		}
+
+		if options.Target != "" {
+			if _, _, err := net.SplitHostPort(options.Target); err != nil {
+				return fmt.Errorf("self-check: invalid target: %w", err)
+			}
+		}
+
+		if options.Resolver != "" {
+			if _, _, err := net.SplitHostPort(options.Resolver); err != nil {
+				return fmt.Errorf("self-check: invalid resolver: %w", err)
+			}
+		}
+
+		chlg.selfCheck = newSelfChecker(options)
+
+		return nil
+	}
+}
+
+// selfChecker checks the key authorizations served for the domains.
+type selfChecker struct {
+	client   *http.Client
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func newSelfChecker(options SelfCheckOptions) *selfChecker {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	if options.Resolver != "" {
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, options.Resolver)
+			},
+		}
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if options.Target != "" {
+				if _, port, err := net.SplitHostPort(addr); err == nil && port == "80" {
+					addr = options.Target
+				}
+			}
+
+			return dialer.DialContext(ctx, network, addr)
+		},
+		// Like the CAs, the certificates are not verified when a redirection to HTTPS is followed.
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // the key authorization is not a secret.
+		DisableKeepAlives: true,
+	}
+
+	return &selfChecker{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		timeout:  options.Timeout,
+		interval: options.Interval,
+	}
+}
+
+// Wait waits until the key authorization is served for the domain, or until the timeout.
+func (s *selfChecker) Wait(ctx context.Context, domain, token, keyAuth string) error {
+	return wait.ForWithContext(ctx, fmt.Sprintf("self-check of the HTTP-01 challenge for %s", domain), s.timeout, s.interval,
+		func() (bool, error) {
+			err := s.check(ctx, domain, token, keyAuth)
+			if err != nil {
+				log.Info("acme: self-check failed.", log.AttrDomain, domain, log.AttrError, err)
+				return false, err
+			}
+
+			return true, nil
+		})
+}
+
+func (s *selfChecker) check(ctx context.Context, domain, token, keyAuth string) error {
+	host := domain
+	if ip := net.ParseIP(domain); ip != nil && ip.To4() == nil {
+		host = "[" + domain + "]"
+	}
+
+	endpoint := &url.URL{Scheme: "http", Host: host, Path: ChallengePath(token)}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeyAuthSize))
+	if err != nil {
+		return err
+	}
+
+	// The trailing whitespaces are ignored by the CAs (RFC 8555, section 8.3).
+	if strings.TrimRight(string(body), " \t\r\n") != keyAuth {
+		return errors.New("the body doesn't match the key authorization")
+	}
+
+	return nil
+}
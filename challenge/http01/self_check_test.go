@@ -0,0 +1,101 @@
+package http01
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfCheck(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		handler    http.HandlerFunc
+		requireErr require.ErrorAssertionFunc
+	}{
+		{
+			desc: "success",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				if req.Host != "example.com" || req.URL.Path != ChallengePath("token") {
+					http.NotFound(rw, req)
+					return
+				}
+
+				_, _ = rw.Write([]byte("keyAuth\n"))
+			},
+			requireErr: require.NoError,
+		},
+		{
+			desc: "redirect",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == ChallengePath("token") {
+					http.Redirect(rw, req, "/other", http.StatusFound)
+					return
+				}
+
+				_, _ = rw.Write([]byte("keyAuth"))
+			},
+			requireErr: require.NoError,
+		},
+		{
+			desc: "invalid body",
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				_, _ = rw.Write([]byte("other"))
+			},
+			requireErr: require.Error,
+		},
+		{
+			desc:       "not found",
+			handler:    http.NotFound,
+			requireErr: require.Error,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(test.handler)
+			t.Cleanup(server.Close)
+
+			chlg := &Challenge{}
+
+			err := SelfCheck(SelfCheckOptions{
+				Target:   server.Listener.Addr().String(),
+				Timeout:  200 * time.Millisecond,
+				Interval: 50 * time.Millisecond,
+			})(chlg)
+			require.NoError(t, err)
+
+			err = chlg.selfCheck.Wait(t.Context(), "example.com", "token", "keyAuth")
+			test.requireErr(t, err)
+		})
+	}
+}
+
+func TestSelfCheck_invalidOptions(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		options SelfCheckOptions
+	}{
+		{
+			desc:    "target without port",
+			options: SelfCheckOptions{Target: "127.0.0.1"},
+		},
+		{
+			desc:    "resolver without port",
+			options: SelfCheckOptions{Resolver: "1.1.1.1"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := SelfCheck(test.options)(&Challenge{})
+			require.Error(t, err)
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package challenge
 
-import "time"
+import (
+	"time"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+)
 
 // Provider enables implementing a custom challenge
 // provider. Present presents the solution to a challenge available to
@@ -26,3 +31,47 @@ type ProviderTimeout interface {
 	Provider
 	Timeout() (timeout, interval time.Duration)
 }
+
+// ProviderState allows for implementing a Provider
+// which keeps a state between Present and CleanUp (e.g. the ID of the created record).
+// When the challenges are presented and validated by different processes (see resolver.Prober.Present),
+// the state is saved after Present, and restored before CleanUp.
+type ProviderState interface {
+	Provider
+	// SaveState returns the state related to the token, after Present.
+	SaveState(token string) (string, error)
+	// RestoreState restores the state related to the token, before CleanUp.
+	RestoreState(token, state string) error
+}
+
+// CallPresent calls the Present method of the provider, and records the duration of the call in the metrics.
+func CallPresent(p Provider, domain, token, keyAuth string) error {
+	start := time.Now()
+
+	err := p.Present(domain, token, keyAuth)
+
+	duration := time.Since(start)
+
+	log.Debug("acme: provider Present called.",
+		log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(p), "duration", duration, log.AttrError, err)
+
+	metrics.ProviderCall(metrics.ProviderName(p), metrics.OperationPresent, err == nil, duration)
+
+	return err
+}
+
+// CallCleanUp calls the CleanUp method of the provider, and records the duration of the call in the metrics.
+func CallCleanUp(p Provider, domain, token, keyAuth string) error {
+	start := time.Now()
+
+	err := p.CleanUp(domain, token, keyAuth)
+
+	duration := time.Since(start)
+
+	log.Debug("acme: provider CleanUp called.",
+		log.AttrDomain, domain, log.AttrProvider, metrics.ProviderName(p), "duration", duration, log.AttrError, err)
+
+	metrics.ProviderCall(metrics.ProviderName(p), metrics.OperationCleanUp, err == nil, duration)
+
+	return err
+}
@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+// setDomainSolver defines the solver of a challenge type for the domains matching the pattern.
+func (c *SolverManager) setDomainSolver(pattern string, chlgType challenge.Type, solvr solver) error {
+	pattern = normalizeDomain(pattern)
+
+	if !isValidPattern(pattern) {
+		return fmt.Errorf("invalid domain pattern: %q", pattern)
+	}
+
+	if _, ok := c.domains[pattern]; !ok {
+		c.domains[pattern] = map[challenge.Type]solver{}
+	}
+
+	c.domains[pattern][chlgType] = solvr
+
+	return nil
+}
+
+// findDomainSolvers returns the solvers of the most specific pattern matching the domain:
+// the domain itself, then the longest zone (`*.example.com`).
+func (c *SolverManager) findDomainSolvers(domain string) (string, map[challenge.Type]solver) {
+	domain = normalizeDomain(domain)
+
+	if solvers, ok := c.domains[domain]; ok {
+		return domain, solvers
+	}
+
+	var best string
+
+	for pattern := range c.domains {
+		zone, ok := strings.CutPrefix(pattern, "*.")
+		if !ok || !strings.HasSuffix(domain, "."+zone) {
+			continue
+		}
+
+		if len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+
+	if best == "" {
+		return "", nil
+	}
+
+	return best, c.domains[best]
+}
+
+func isValidPattern(pattern string) bool {
+	zone := strings.TrimPrefix(pattern, "*.")
+
+	return zone != "" && !strings.Contains(zone, "*") && !strings.HasPrefix(zone, ".")
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolverManager_setDomainSolver_invalid(t *testing.T) {
+	manager := NewSolversManager(nil)
+
+	testCases := []string{"", "*.", "*", "a.*.example.com", "*.*.example.com", "*..example.com"}
+
+	for _, pattern := range testCases {
+		err := manager.setDomainSolver(pattern, challenge.DNS01, &preSolverMock{})
+		require.Error(t, err, pattern)
+	}
+}
+
+func TestSolverManager_chooseSolver_domains(t *testing.T) {
+	manager := NewSolversManager(nil)
+
+	global := &preSolverMock{}
+	manager.solvers[challenge.HTTP01] = global
+
+	zone := &preSolverMock{}
+	require.NoError(t, manager.setDomainSolver("*.example.com", challenge.DNS01, zone))
+
+	subZone := &preSolverMock{}
+	require.NoError(t, manager.setDomainSolver("*.sub.example.com", challenge.DNS01, subZone))
+
+	host := &preSolverMock{}
+	require.NoError(t, manager.setDomainSolver("App.Example.com.", challenge.TLSALPN01, host))
+
+	allChallenges := []acme.Challenge{
+		{Type: challenge.DNS01.String()},
+		{Type: challenge.HTTP01.String()},
+		{Type: challenge.TLSALPN01.String()},
+	}
+
+	testCases := []struct {
+		desc             string
+		domain           string
+		wildcard         bool
+		expectedType     challenge.Type
+		expectedSolver   solver
+		expectedNoSolver bool
+	}{
+		{
+			desc:           "no pattern",
+			domain:         "example.org",
+			expectedType:   challenge.HTTP01,
+			expectedSolver: global,
+		},
+		{
+			desc:           "zone apex is not matched",
+			domain:         "example.com",
+			expectedType:   challenge.HTTP01,
+			expectedSolver: global,
+		},
+		{
+			desc:           "zone",
+			domain:         "www.example.com",
+			expectedType:   challenge.DNS01,
+			expectedSolver: zone,
+		},
+		{
+			desc:           "wildcard",
+			domain:         "example.com",
+			wildcard:       true,
+			expectedType:   challenge.DNS01,
+			expectedSolver: zone,
+		},
+		{
+			desc:           "longest zone",
+			domain:         "a.sub.example.com",
+			expectedType:   challenge.DNS01,
+			expectedSolver: subZone,
+		},
+		{
+			desc:           "domain",
+			domain:         "app.example.com",
+			expectedType:   challenge.TLSALPN01,
+			expectedSolver: host,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			authz := acme.Authorization{
+				Identifier: acme.Identifier{Value: test.domain},
+				Wildcard:   test.wildcard,
+				Challenges: allChallenges,
+			}
+
+			chlgType, solvr := manager.chooseSolver(t.Context(), authz)
+
+			assert.Equal(t, test.expectedType, chlgType)
+			assert.Same(t, test.expectedSolver, solvr)
+		})
+	}
+}
+
+func TestSolverManager_chooseSolver_domains_noFallback(t *testing.T) {
+	manager := NewSolversManager(nil)
+	manager.solvers[challenge.HTTP01] = &preSolverMock{}
+
+	require.NoError(t, manager.setDomainSolver("*.example.com", challenge.DNS01, &preSolverMock{}))
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "www.example.com"},
+		Challenges: []acme.Challenge{{Type: challenge.HTTP01.String()}},
+	}
+
+	chlgType, solvr := manager.chooseSolver(t.Context(), authz)
+
+	assert.Empty(t, chlgType)
+	assert.Nil(t, solvr)
+}
+
+func TestSolverManager_ChallengeTypes_domains(t *testing.T) {
+	manager := NewSolversManager(nil)
+	manager.solvers[challenge.HTTP01] = &preSolverMock{}
+
+	require.NoError(t, manager.setDomainSolver("*.example.com", challenge.DNS01, &preSolverMock{}))
+	require.NoError(t, manager.setDomainSolver("example.org", challenge.HTTP01, &preSolverMock{}))
+
+	assert.Equal(t, []challenge.Type{challenge.DNS01, challenge.HTTP01}, manager.ChallengeTypes(t.Context()))
+}
@@ -1,12 +1,15 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/go-acme/lego/v4/platform/wait"
 )
 
 // Interface for all challenge solvers to implement.
@@ -14,6 +17,11 @@ type solver interface {
 	Solve(authorization acme.Authorization) error
 }
 
+// Interface for challenges which can be canceled through a context.
+type contextSolver interface {
+	SolveWithContext(ctx context.Context, authorization acme.Authorization) error
+}
+
 // Interface for challenges like dns, where we can set a record in advance for ALL challenges.
 // This saves quite a bit of time vs creating the records and solving them serially.
 type preSolver interface {
@@ -31,8 +39,9 @@ type sequential interface {
 
 // an authz with the solver we have chosen and the index of the challenge associated with it.
 type selectedAuthSolver struct {
-	authz  acme.Authorization
-	solver solver
+	authz    acme.Authorization
+	chlgType challenge.Type
+	solver   solver
 }
 
 type Prober struct {
@@ -45,9 +54,20 @@ func NewProber(solverManager *SolverManager) *Prober {
 	}
 }
 
+// ChallengeTypes returns the challenge types which can be used to solve the authorizations.
+func (p *Prober) ChallengeTypes(ctx context.Context) []challenge.Type {
+	return p.solverManager.ChallengeTypes(ctx)
+}
+
 // Solve Looks through the challenge combinations to find a solvable match.
 // Then solves the challenges in series and returns.
 func (p *Prober) Solve(authorizations []acme.Authorization) error {
+	return p.SolveWithContext(context.Background(), authorizations)
+}
+
+// SolveWithContext is like Solve, but the context is used to cancel the resolution.
+// The challenges already presented are cleaned up even if the context is canceled.
+func (p *Prober) SolveWithContext(ctx context.Context, authorizations []acme.Authorization) error {
 	failures := make(obtainError)
 
 	var (
@@ -62,12 +82,12 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 		domain := challenge.GetTargetedDomain(authz)
 		if authz.Status == acme.StatusValid {
 			// Boulder might recycle recent validated authz (see issue #267)
-			log.Infof("[%s] acme: authorization already valid; skipping challenge", domain)
+			log.Info("acme: authorization already valid; skipping challenge", log.AttrDomain, domain)
 			continue
 		}
 
-		if solvr := p.solverManager.chooseSolver(authz); solvr != nil {
-			authSolver := &selectedAuthSolver{authz: authz, solver: solvr}
+		if chlgType, solvr := p.solverManager.chooseSolver(ctx, authz); solvr != nil {
+			authSolver := &selectedAuthSolver{authz: authz, chlgType: chlgType, solver: solvr}
 
 			switch s := solvr.(type) {
 			case sequential:
@@ -84,9 +104,9 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 		}
 	}
 
-	parallelSolve(authSolvers, failures)
+	parallelSolve(ctx, authSolvers, failures)
 
-	sequentialSolve(authSolversSequential, failures)
+	sequentialSolve(ctx, authSolversSequential, failures)
 
 	// Be careful not to return an empty failures map,
 	// for even an empty obtainError is a non-nil error value
@@ -97,7 +117,7 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 	return nil
 }
 
-func sequentialSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
+func sequentialSolve(ctx context.Context, authSolvers []*selectedAuthSolver, failures obtainError) {
 	// Some CA are using the same token,
 	// this can be a problem with the DNS01 challenge when the DNS provider doesn't support duplicate TXT records.
 	// In the sequential mode, this is not a problem because we can solve the challenges in order.
@@ -108,11 +128,16 @@ func sequentialSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 		// Submit the challenge
 		domain := challenge.GetTargetedDomain(authSolver.authz)
 
-		chlg, _ := challenge.FindChallenge(challenge.DNS01, authSolver.authz)
+		if err := ctx.Err(); err != nil {
+			failures[domain] = err
+			continue
+		}
+
+		chlg, _ := findDNSChallenge(authSolver)
 
 		if solvr, ok := authSolver.solver.(preSolver); ok {
 			if _, ok := uniq[authSolver.authz.Identifier.Value+chlg.Token]; ok && chlg.Token != "" {
-				log.Infof("acme: duplicate token for %q (DNS-01); skipping pre-solve.", authSolver.authz.Identifier.Value)
+				log.Info("acme: duplicate token; skipping pre-solve.", log.AttrDomain, authSolver.authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
 				continue
 			}
 
@@ -129,7 +154,7 @@ func sequentialSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 		}
 
 		// Solve challenge
-		err := authSolver.solver.Solve(authSolver.authz)
+		err := solve(ctx, authSolver)
 		if err != nil {
 			failures[domain] = err
 
@@ -145,18 +170,19 @@ func sequentialSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 			if len(authSolvers)-1 > i {
 				solvr := authSolver.solver.(sequential)
 				_, interval := solvr.Sequential()
-				log.Infof("sequence: wait for %s", interval)
-				time.Sleep(interval)
+				log.Info("sequence: wait", "interval", interval)
+				// The cancellation of the context is handled at the beginning of the loop.
+				_ = wait.Sleep(ctx, interval)
 			}
 
 			delete(uniq, authSolver.authz.Identifier.Value+chlg.Token)
 		} else {
-			log.Infof("acme: duplicate token for %q (DNS-01); skipping cleanup.", authSolver.authz.Identifier.Value)
+			log.Info("acme: duplicate token; skipping cleanup.", log.AttrDomain, authSolver.authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
 		}
 	}
 }
 
-func parallelSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
+func parallelSolve(ctx context.Context, authSolvers []*selectedAuthSolver, failures obtainError) {
 	// Some CA are using the same token,
 	// this can be a problem with the DNS01 challenge when the DNS provider doesn't support duplicate TXT records.
 	uniq := make(map[string]struct{})
@@ -165,10 +191,15 @@ func parallelSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 	for _, authSolver := range authSolvers {
 		authz := authSolver.authz
 
-		chlg, err := challenge.FindChallenge(challenge.DNS01, authz)
+		if err := ctx.Err(); err != nil {
+			failures[challenge.GetTargetedDomain(authz)] = err
+			continue
+		}
+
+		chlg, err := findDNSChallenge(authSolver)
 		if err == nil {
 			if _, ok := uniq[authz.Identifier.Value+chlg.Token]; ok {
-				log.Infof("acme: duplicate token for %q (DNS-01); skipping pre-solve.", authSolver.authz.Identifier.Value)
+				log.Info("acme: duplicate token; skipping pre-solve.", log.AttrDomain, authSolver.authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
 				continue
 			}
 
@@ -186,12 +217,12 @@ func parallelSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 	defer func() {
 		// Clean all created TXT records
 		for _, authSolver := range authSolvers {
-			chlg, err := challenge.FindChallenge(challenge.DNS01, authSolver.authz)
+			chlg, err := findDNSChallenge(authSolver)
 			if err == nil {
 				if _, ok := uniq[authSolver.authz.Identifier.Value+chlg.Token]; ok {
 					delete(uniq, authSolver.authz.Identifier.Value+chlg.Token)
 				} else {
-					log.Infof("acme: duplicate token for %q (DNS-01); skipping cleanup.", authSolver.authz.Identifier.Value)
+					log.Info("acme: duplicate token; skipping cleanup.", log.AttrDomain, authSolver.authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
 					continue
 				}
 			}
@@ -210,20 +241,50 @@ func parallelSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
 			continue
 		}
 
-		err := authSolver.solver.Solve(authz)
+		if err := ctx.Err(); err != nil {
+			failures[domain] = err
+			continue
+		}
+
+		err := solve(ctx, authSolver)
 		if err != nil {
 			failures[domain] = err
 		}
 	}
 }
 
+// findDNSChallenge returns the DNS challenge (dns-01 or dns-account-01) of the authorization,
+// used to detect the duplicate tokens.
+func findDNSChallenge(authSolver *selectedAuthSolver) (acme.Challenge, error) {
+	if authSolver.chlgType == challenge.DNSAccount01 {
+		return challenge.FindChallenge(challenge.DNSAccount01, authSolver.authz)
+	}
+
+	return challenge.FindChallenge(challenge.DNS01, authSolver.authz)
+}
+
+func solve(ctx context.Context, authSolver *selectedAuthSolver) error {
+	start := time.Now()
+
+	var err error
+	if solvr, ok := authSolver.solver.(contextSolver); ok {
+		err = solvr.SolveWithContext(ctx, authSolver.authz)
+	} else {
+		err = authSolver.solver.Solve(authSolver.authz)
+	}
+
+	metrics.ChallengeAttempt(string(authSolver.chlgType), err == nil, time.Since(start))
+
+	return err
+}
+
 func cleanUp(solvr solver, authz acme.Authorization) {
 	if solvr, ok := solvr.(cleanup); ok {
 		domain := challenge.GetTargetedDomain(authz)
 
 		err := solvr.CleanUp(authz)
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v ", domain, err)
+			log.Warn("acme: cleaning up failed.", log.AttrDomain, domain, log.AttrError, err)
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -77,3 +78,18 @@ func createStubAuthorization(domain, status string, wildcard bool, chlgs ...acme
 		Challenges: chlgs,
 	}
 }
+
+// contextSolverMock cancels the context during the first call to SolveWithContext.
+type contextSolverMock struct {
+	preSolverMock
+
+	cancel context.CancelFunc
+}
+
+func (s *contextSolverMock) SolveWithContext(ctx context.Context, authorization acme.Authorization) error {
+	s.solveCounter++
+
+	s.cancel()
+
+	return ctx.Err()
+}
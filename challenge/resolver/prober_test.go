@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -155,3 +156,23 @@ func TestProber_Solve(t *testing.T) {
 		})
 	}
 }
+
+func TestProber_SolveWithContext_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	solvr := &contextSolverMock{cancel: cancel}
+
+	prober := &Prober{
+		solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.HTTP01: solvr}},
+	}
+
+	err := prober.SolveWithContext(ctx, []acme.Authorization{
+		createStubAuthorizationHTTP01("example.com", acme.StatusProcessing),
+		createStubAuthorizationHTTP01("example.org", acme.StatusProcessing),
+		createStubAuthorizationHTTP01("example.net", acme.StatusProcessing),
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// All the presented challenges must be cleaned up.
+	assert.Equal(t, "PreSolve: 3, Solve: 1, CleanUp: 3", solvr.String())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 
@@ -12,9 +13,11 @@ import (
 	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/challenge/dnspersist01"
 	"github.com/go-acme/lego/v4/challenge/http01"
 	"github.com/go-acme/lego/v4/challenge/tlsalpn01"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
 	"github.com/go-acme/lego/v4/platform/wait"
 )
 
@@ -27,11 +30,15 @@ func (a byType) Less(i, j int) bool { return a[i].Type > a[j].Type }
 type SolverManager struct {
 	core    *api.Core
 	solvers map[challenge.Type]solver
+
+	// domains the solvers of the domains matching a pattern (see SetHTTP01ProviderFor, etc.).
+	domains map[string]map[challenge.Type]solver
 }
 
 func NewSolversManager(core *api.Core) *SolverManager {
 	return &SolverManager{
 		solvers: map[challenge.Type]solver{},
+		domains: map[string]map[challenge.Type]solver{},
 		core:    core,
 	}
 }
@@ -54,27 +61,145 @@ func (c *SolverManager) SetDNS01Provider(p challenge.Provider, opts ...dns01.Cha
 	return nil
 }
 
+// SetDNSAccount01Provider specifies a custom provider p that can solve the given DNS-ACCOUNT-01 challenge.
+// The provider is a DNS-01 provider implementing dns01.RecordProvider: the TXT record is account-scoped.
+func (c *SolverManager) SetDNSAccount01Provider(p challenge.Provider, opts ...dns01.ChallengeOption) error {
+	err := checkRecordProvider(p)
+	if err != nil {
+		return err
+	}
+
+	c.solvers[challenge.DNSAccount01] = dns01.NewAccountChallenge(c.core, validate, p, opts...)
+	return nil
+}
+
+// SetDNSPersist01 enables the DNS-PERSIST-01 challenge.
+// The challenge doesn't need a provider: the persistent TXT record must be created beforehand (see dnspersist01.Record).
+func (c *SolverManager) SetDNSPersist01(opts ...dnspersist01.ChallengeOption) error {
+	c.solvers[challenge.DNSPersist01] = dnspersist01.NewChallenge(c.core, validate, opts...)
+	return nil
+}
+
+// SetHTTP01ProviderFor specifies a custom provider p that can solve the HTTP-01 challenge,
+// only for the domains matching the pattern.
+//
+// The pattern is a domain (`example.com`), or a zone (`*.example.com`) matching all the subdomains and the wildcard domain.
+// The most specific pattern matching a domain is used (the domain, then the longest zone),
+// and only the solvers of this pattern are used for the domain.
+// The other domains use the solvers defined without pattern (SetHTTP01Provider, etc.).
+func (c *SolverManager) SetHTTP01ProviderFor(pattern string, p challenge.Provider, opts ...http01.ChallengeOption) error {
+	return c.setDomainSolver(pattern, challenge.HTTP01, http01.NewChallenge(c.core, validate, p, opts...))
+}
+
+// SetTLSALPN01ProviderFor specifies a custom provider p that can solve the TLS-ALPN-01 challenge,
+// only for the domains matching the pattern (see SetHTTP01ProviderFor).
+func (c *SolverManager) SetTLSALPN01ProviderFor(pattern string, p challenge.Provider, opts ...tlsalpn01.ChallengeOption) error {
+	return c.setDomainSolver(pattern, challenge.TLSALPN01, tlsalpn01.NewChallenge(c.core, validate, p, opts...))
+}
+
+// SetDNS01ProviderFor specifies a custom provider p that can solve the DNS-01 challenge,
+// only for the domains matching the pattern (see SetHTTP01ProviderFor).
+func (c *SolverManager) SetDNS01ProviderFor(pattern string, p challenge.Provider, opts ...dns01.ChallengeOption) error {
+	return c.setDomainSolver(pattern, challenge.DNS01, dns01.NewChallenge(c.core, validate, p, opts...))
+}
+
+// SetDNSAccount01ProviderFor specifies a custom provider p that can solve the DNS-ACCOUNT-01 challenge,
+// only for the domains matching the pattern (see SetHTTP01ProviderFor).
+func (c *SolverManager) SetDNSAccount01ProviderFor(pattern string, p challenge.Provider, opts ...dns01.ChallengeOption) error {
+	err := checkRecordProvider(p)
+	if err != nil {
+		return err
+	}
+
+	return c.setDomainSolver(pattern, challenge.DNSAccount01, dns01.NewAccountChallenge(c.core, validate, p, opts...))
+}
+
+// checkRecordProvider checks that the provider can create the records of the DNS-ACCOUNT-01 challenge.
+func checkRecordProvider(p challenge.Provider) error {
+	if _, ok := p.(dns01.RecordProvider); !ok {
+		return fmt.Errorf("the provider %s doesn't support the dns-account-01 challenge (dns01.RecordProvider)", metrics.ProviderName(p))
+	}
+
+	return nil
+}
+
 // Remove removes a challenge type from the available solvers.
 func (c *SolverManager) Remove(chlgType challenge.Type) {
 	delete(c.solvers, chlgType)
 }
 
-// Checks all challenges from the server in order and returns the first matching solver.
-func (c *SolverManager) chooseSolver(authz acme.Authorization) solver {
+type challengeTypesKey struct{}
+
+// WithChallengeTypes restricts the challenge types used to solve the authorizations to the given types.
+// It allows to choose the challenge of an order, when the SolverManager is shared by several orders.
+func WithChallengeTypes(ctx context.Context, types ...challenge.Type) context.Context {
+	return context.WithValue(ctx, challengeTypesKey{}, types)
+}
+
+func getChallengeTypes(ctx context.Context) []challenge.Type {
+	types, _ := ctx.Value(challengeTypesKey{}).([]challenge.Type)
+
+	return types
+}
+
+// ChallengeTypes returns the challenge types which can be used to solve the authorizations:
+// the types with a solver, restricted through the context (see WithChallengeTypes).
+func (c *SolverManager) ChallengeTypes(ctx context.Context) []challenge.Type {
+	allowed := getChallengeTypes(ctx)
+
+	var types []challenge.Type
+
+	add := func(solvers map[challenge.Type]solver) {
+		for chlgType := range solvers {
+			if (len(allowed) == 0 || slices.Contains(allowed, chlgType)) && !slices.Contains(types, chlgType) {
+				types = append(types, chlgType)
+			}
+		}
+	}
+
+	add(c.solvers)
+
+	for _, solvers := range c.domains {
+		add(solvers)
+	}
+
+	slices.Sort(types)
+
+	return types
+}
+
+// Checks all challenges from the server in order and returns the first matching solver, and its challenge type.
+// The challenge types can be restricted through the context (see WithChallengeTypes).
+func (c *SolverManager) chooseSolver(ctx context.Context, authz acme.Authorization) (challenge.Type, solver) {
 	// Allow to have a deterministic challenge order
 	sort.Sort(byType(authz.Challenges))
 
+	allowed := getChallengeTypes(ctx)
+
 	domain := challenge.GetTargetedDomain(authz)
+
+	solvers := c.solvers
+	if pattern, domainSolvers := c.findDomainSolvers(domain); domainSolvers != nil {
+		log.Debug("acme: use the solvers of the domain pattern", log.AttrDomain, domain, "pattern", pattern)
+
+		solvers = domainSolvers
+	}
+
 	for _, chlg := range authz.Challenges {
-		if solvr, ok := c.solvers[challenge.Type(chlg.Type)]; ok {
-			log.Infof("[%s] acme: use %s solver", domain, chlg.Type)
-			return solvr
+		if len(allowed) > 0 && !slices.Contains(allowed, challenge.Type(chlg.Type)) {
+			log.Debug("acme: challenge not allowed", log.AttrDomain, domain, log.AttrChallengeType, chlg.Type)
+			continue
+		}
+
+		if solvr, ok := solvers[challenge.Type(chlg.Type)]; ok {
+			log.Info("acme: use solver", log.AttrDomain, domain, log.AttrChallengeType, chlg.Type)
+			return challenge.Type(chlg.Type), solvr
 		}
 
-		log.Infof("[%s] acme: Could not find solver for: %s", domain, chlg.Type)
+		log.Info("acme: Could not find solver", log.AttrDomain, domain, log.AttrChallengeType, chlg.Type)
 	}
 
-	return nil
+	return "", nil
 }
 
 func validate(core *api.Core, domain string, chlg acme.Challenge) error {
@@ -89,7 +214,7 @@ func validate(core *api.Core, domain string, chlg acme.Challenge) error {
 	}
 
 	if valid {
-		log.Infof("[%s] The server validated our request", domain)
+		log.Info("The server validated our request", log.AttrDomain, domain, log.AttrChallengeType, chlg.Type)
 		return nil
 	}
 
@@ -102,8 +227,6 @@ func validate(core *api.Core, domain string, chlg acme.Challenge) error {
 		retryAfter = 5 * time.Second
 	}
 
-	ctx := context.Background()
-
 	bo := backoff.NewExponentialBackOff()
 	bo.InitialInterval = retryAfter
 	bo.MaxInterval = 10 * retryAfter
@@ -122,14 +245,14 @@ func validate(core *api.Core, domain string, chlg acme.Challenge) error {
 		}
 
 		if valid {
-			log.Infof("[%s] The server validated our request", domain)
+			log.Info("The server validated our request", log.AttrDomain, domain, log.AttrChallengeType, chlg.Type)
 			return nil
 		}
 
 		return fmt.Errorf("the server didn't respond to our request (status=%s)", authz.Status)
 	}
 
-	return wait.Retry(ctx, operation,
+	return wait.Retry(core.Context(), operation,
 		backoff.WithBackOff(bo),
 		backoff.WithMaxElapsedTime(100*retryAfter))
 }
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
 	"github.com/go-acme/lego/v4/platform/tester"
 	"github.com/go-acme/lego/v4/platform/tester/servermock"
 	"github.com/go-jose/go-jose/v4"
@@ -32,6 +35,55 @@ func TestByType(t *testing.T) {
 	assert.Equal(t, expected, challenges)
 }
 
+func TestSolverManager_chooseSolver(t *testing.T) {
+	manager := NewSolversManager(nil)
+	manager.solvers[challenge.HTTP01] = &preSolverMock{}
+	manager.solvers[challenge.DNS01] = &preSolverMock{}
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.com"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNS01.String()}, {Type: challenge.HTTP01.String()},
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		ctx      context.Context
+		expected challenge.Type
+	}{
+		{
+			desc:     "no restriction",
+			ctx:      t.Context(),
+			expected: challenge.HTTP01,
+		},
+		{
+			desc:     "restricted",
+			ctx:      WithChallengeTypes(t.Context(), challenge.DNS01),
+			expected: challenge.DNS01,
+		},
+		{
+			desc:     "not available",
+			ctx:      WithChallengeTypes(t.Context(), challenge.TLSALPN01),
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			chlgType, solvr := manager.chooseSolver(test.ctx, authz)
+
+			assert.Equal(t, test.expected, chlgType)
+
+			if test.expected == "" {
+				assert.Nil(t, solvr)
+			} else {
+				assert.NotNil(t, solvr)
+			}
+		})
+	}
+}
+
 func TestValidate(t *testing.T) {
 	var statuses []string
 
@@ -280,3 +332,42 @@ func validateNoBody(privateKey *rsa.PrivateKey, r *http.Request) error {
 
 	return nil
 }
+
+func TestSolverManager_ChallengeTypes(t *testing.T) {
+	manager := NewSolversManager(nil)
+	manager.solvers[challenge.HTTP01] = &preSolverMock{}
+	manager.solvers[challenge.DNS01] = &preSolverMock{}
+
+	assert.Equal(t, []challenge.Type{challenge.DNS01, challenge.HTTP01}, manager.ChallengeTypes(t.Context()))
+
+	ctx := WithChallengeTypes(t.Context(), challenge.HTTP01, challenge.TLSALPN01)
+
+	assert.Equal(t, []challenge.Type{challenge.HTTP01}, manager.ChallengeTypes(ctx))
+}
+
+func TestSolverManager_SetDNSAccount01Provider(t *testing.T) {
+	manager := NewSolversManager(nil)
+
+	err := manager.SetDNSAccount01Provider(&dnsProviderMock{})
+	require.EqualError(t, err, "the provider resolver doesn't support the dns-account-01 challenge (dns01.RecordProvider)")
+
+	err = manager.SetDNSAccount01ProviderFor("example.com", &dnsProviderMock{})
+	require.EqualError(t, err, "the provider resolver doesn't support the dns-account-01 challenge (dns01.RecordProvider)")
+
+	err = manager.SetDNSAccount01Provider(&recordProviderMock{})
+	require.NoError(t, err)
+
+	assert.Contains(t, manager.solvers, challenge.DNSAccount01)
+}
+
+type dnsProviderMock struct{}
+
+func (*dnsProviderMock) Present(_, _, _ string) error { return nil }
+func (*dnsProviderMock) CleanUp(_, _, _ string) error { return nil }
+
+type recordProviderMock struct {
+	dnsProviderMock
+}
+
+func (*recordProviderMock) PresentRecord(_ string, _ dns01.ChallengeInfo) error { return nil }
+func (*recordProviderMock) CleanUpRecord(_ string, _ dns01.ChallengeInfo) error { return nil }
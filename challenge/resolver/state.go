@@ -0,0 +1,256 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/log"
+)
+
+// Interface for challenges where the state of the provider can be saved between the presentation and the cleanup.
+type statefulSolver interface {
+	SaveState(authorization acme.Authorization) (string, error)
+	RestoreState(authorization acme.Authorization, state string) error
+}
+
+// State the state of the challenges presented by Prober.Present.
+// The state is serializable (JSON):
+// the challenges can be validated and cleaned up later (Prober.Resume), by another process.
+type State struct {
+	Challenges []ChallengeState `json:"challenges,omitempty"`
+}
+
+// ChallengeState the state of a presented challenge.
+type ChallengeState struct {
+	// Domain the targeted domain of the authorization (`*.example.com` for a wildcard).
+	Domain string `json:"domain"`
+
+	// Type the type of the challenge.
+	Type challenge.Type `json:"type"`
+
+	// Token the token of the challenge.
+	Token string `json:"token"`
+
+	// ProviderState the state of the provider (see challenge.ProviderState).
+	ProviderState string `json:"providerState,omitempty"`
+}
+
+func (s *State) find(domain string) (ChallengeState, bool) {
+	if s == nil {
+		return ChallengeState{}, false
+	}
+
+	for _, chlg := range s.Challenges {
+		if chlg.Domain == domain {
+			return chlg, true
+		}
+	}
+
+	return ChallengeState{}, false
+}
+
+// Present presents the challenges of the authorizations, without validating them.
+// Only the challenges which can be presented in advance (dns-01, dns-account-01) are supported.
+// A sequential provider cannot hold several records at once:
+// only one challenge per sequential provider can be presented in advance.
+//
+// The returned state is used by Resume to validate and clean up the challenges.
+// If an error occurs, the challenges already presented are cleaned up.
+func (p *Prober) Present(ctx context.Context, authorizations []acme.Authorization) (*State, error) {
+	failures := make(obtainError)
+
+	var authSolvers []*selectedAuthSolver
+
+	// sequentials the first domain of each sequential solver.
+	sequentials := make(map[solver]string)
+
+	for _, authz := range authorizations {
+		domain := challenge.GetTargetedDomain(authz)
+		if authz.Status == acme.StatusValid {
+			log.Info("acme: authorization already valid; skipping challenge", log.AttrDomain, domain)
+			continue
+		}
+
+		chlgType, solvr := p.solverManager.chooseSolver(ctx, authz)
+		if solvr == nil {
+			failures[domain] = fmt.Errorf("[%s] acme: could not determine solvers", domain)
+			continue
+		}
+
+		if _, ok := solvr.(preSolver); !ok {
+			failures[domain] = fmt.Errorf("[%s] acme: the challenge %s cannot be presented in advance", domain, chlgType)
+			continue
+		}
+
+		if s, ok := solvr.(sequential); ok {
+			if seq, _ := s.Sequential(); seq {
+				if first, found := sequentials[solvr]; found {
+					failures[domain] = fmt.Errorf("[%s] acme: the challenges of a sequential provider cannot be presented in advance for several domains (%s, %s)", domain, first, domain)
+					continue
+				}
+
+				sequentials[solvr] = domain
+			}
+		}
+
+		authSolvers = append(authSolvers, &selectedAuthSolver{authz: authz, chlgType: chlgType, solver: solvr})
+	}
+
+	if len(failures) > 0 {
+		return nil, failures
+	}
+
+	state := &State{}
+
+	// Some CA are using the same token,
+	// this can be a problem with the DNS01 challenge when the DNS provider doesn't support duplicate TXT records.
+	uniq := make(map[string]struct{})
+
+	var presented []*selectedAuthSolver
+
+	for _, authSolver := range authSolvers {
+		authz := authSolver.authz
+		domain := challenge.GetTargetedDomain(authz)
+
+		if err := ctx.Err(); err != nil {
+			failures[domain] = err
+			break
+		}
+
+		chlg, err := findDNSChallenge(authSolver)
+		if err != nil {
+			failures[domain] = err
+			break
+		}
+
+		chlgState := ChallengeState{Domain: domain, Type: authSolver.chlgType, Token: chlg.Token}
+
+		if _, ok := uniq[authz.Identifier.Value+chlg.Token]; ok {
+			log.Info("acme: duplicate token; skipping pre-solve.", log.AttrDomain, authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
+
+			state.Challenges = append(state.Challenges, chlgState)
+
+			continue
+		}
+
+		err = authSolver.solver.(preSolver).PreSolve(authz)
+		if err != nil {
+			failures[domain] = err
+			break
+		}
+
+		uniq[authz.Identifier.Value+chlg.Token] = struct{}{}
+
+		presented = append(presented, authSolver)
+
+		if solvr, ok := authSolver.solver.(statefulSolver); ok {
+			chlgState.ProviderState, err = solvr.SaveState(authz)
+			if err != nil {
+				failures[domain] = fmt.Errorf("[%s] acme: save the state of the provider: %w", domain, err)
+				break
+			}
+		}
+
+		state.Challenges = append(state.Challenges, chlgState)
+	}
+
+	if len(failures) > 0 {
+		for _, authSolver := range presented {
+			cleanUp(authSolver.solver, authSolver.authz)
+		}
+
+		return nil, failures
+	}
+
+	return state, nil
+}
+
+// Resume validates and cleans up the challenges presented by Present.
+// The authorizations must be the current authorizations of the order (i.e. fetched after Present).
+// The challenges are always cleaned up, even if the validation fails or the context is canceled.
+func (p *Prober) Resume(ctx context.Context, authorizations []acme.Authorization, state *State) error {
+	failures := make(obtainError)
+
+	var authSolvers []*selectedAuthSolver
+
+	for _, authz := range authorizations {
+		domain := challenge.GetTargetedDomain(authz)
+
+		chlgState, ok := state.find(domain)
+		if !ok {
+			if authz.Status != acme.StatusValid {
+				failures[domain] = fmt.Errorf("[%s] acme: no presented challenge", domain)
+			}
+
+			continue
+		}
+
+		chlgType, solvr := p.solverManager.chooseSolver(WithChallengeTypes(ctx, chlgState.Type), authz)
+		if solvr == nil {
+			failures[domain] = fmt.Errorf("[%s] acme: could not determine solvers", domain)
+			continue
+		}
+
+		if solvr, ok := solvr.(statefulSolver); ok {
+			err := solvr.RestoreState(authz, chlgState.ProviderState)
+			if err != nil {
+				failures[domain] = fmt.Errorf("[%s] acme: restore the state of the provider: %w", domain, err)
+				continue
+			}
+		}
+
+		authSolvers = append(authSolvers, &selectedAuthSolver{authz: authz, chlgType: chlgType, solver: solvr})
+	}
+
+	uniq := make(map[string]struct{})
+
+	defer func() {
+		for _, authSolver := range authSolvers {
+			chlg, err := findDNSChallenge(authSolver)
+			if err == nil {
+				if _, ok := uniq[authSolver.authz.Identifier.Value+chlg.Token]; ok {
+					log.Info("acme: duplicate token; skipping cleanup.", log.AttrDomain, authSolver.authz.Identifier.Value, log.AttrChallengeType, authSolver.chlgType)
+					continue
+				}
+
+				uniq[authSolver.authz.Identifier.Value+chlg.Token] = struct{}{}
+			}
+
+			cleanUp(authSolver.solver, authSolver.authz)
+		}
+	}()
+
+	for _, authSolver := range authSolvers {
+		authz := authSolver.authz
+
+		domain := challenge.GetTargetedDomain(authz)
+		if failures[domain] != nil {
+			continue
+		}
+
+		if authz.Status == acme.StatusValid {
+			log.Info("acme: authorization already valid; skipping challenge", log.AttrDomain, domain)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			failures[domain] = err
+			continue
+		}
+
+		err := solve(ctx, authSolver)
+		if err != nil {
+			failures[domain] = err
+		}
+	}
+
+	// Be careful not to return an empty failures map,
+	// for even an empty obtainError is a non-nil error value
+	if len(failures) > 0 {
+		return failures
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statefulSolverMock struct {
+	preSolverMock
+
+	restored map[string]string
+}
+
+func (s *statefulSolverMock) SaveState(authorization acme.Authorization) (string, error) {
+	return "id-" + authorization.Identifier.Value, nil
+}
+
+func (s *statefulSolverMock) RestoreState(authorization acme.Authorization, state string) error {
+	s.restored[authorization.Identifier.Value] = state
+	return nil
+}
+
+type sequentialSolverMock struct {
+	preSolverMock
+}
+
+func (s *sequentialSolverMock) Sequential() (bool, time.Duration) {
+	return true, 0
+}
+
+type solverMock struct{}
+
+func (s *solverMock) Solve(_ acme.Authorization) error {
+	return nil
+}
+
+func newStatefulSolverMock() *statefulSolverMock {
+	return &statefulSolverMock{
+		preSolverMock: preSolverMock{
+			preSolve: map[string]error{},
+			solve:    map[string]error{},
+			cleanUp:  map[string]error{},
+		},
+		restored: map[string]string{},
+	}
+}
+
+func TestProber_Present_Resume(t *testing.T) {
+	authz := []acme.Authorization{
+		createStubAuthorizationDNS01("a.example", false),
+		createStubAuthorizationDNS01("a.example", true),
+		createStubAuthorizationDNS01("b.example", false),
+	}
+
+	presentSolver := newStatefulSolverMock()
+
+	prober := &Prober{solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: presentSolver}}}
+
+	state, err := prober.Present(t.Context(), authz)
+	require.NoError(t, err)
+
+	assert.Equal(t, "PreSolve: 2, Solve: 0, CleanUp: 0", presentSolver.String())
+
+	expected := &State{Challenges: []ChallengeState{
+		{Domain: "a.example", Type: challenge.DNS01, ProviderState: "id-a.example"},
+		{Domain: "*.a.example", Type: challenge.DNS01},
+		{Domain: "b.example", Type: challenge.DNS01, ProviderState: "id-b.example"},
+	}}
+
+	assert.Equal(t, expected, state)
+
+	// The state is saved, then resumed by another process.
+	raw, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	var restoredState State
+
+	err = json.Unmarshal(raw, &restoredState)
+	require.NoError(t, err)
+
+	resumeSolver := newStatefulSolverMock()
+
+	prober = &Prober{solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: resumeSolver}}}
+
+	err = prober.Resume(t.Context(), authz, &restoredState)
+	require.NoError(t, err)
+
+	assert.Equal(t, "PreSolve: 0, Solve: 3, CleanUp: 2", resumeSolver.String())
+	assert.Equal(t, map[string]string{"a.example": "", "b.example": "id-b.example"}, resumeSolver.restored)
+}
+
+func TestProber_Present_sequential(t *testing.T) {
+	solvr := &sequentialSolverMock{
+		preSolverMock: preSolverMock{
+			preSolve: map[string]error{},
+			solve:    map[string]error{},
+			cleanUp:  map[string]error{},
+		},
+	}
+
+	prober := &Prober{solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: solvr}}}
+
+	state, err := prober.Present(t.Context(), []acme.Authorization{createStubAuthorizationDNS01("a.example", false)})
+	require.NoError(t, err)
+
+	assert.Equal(t, "PreSolve: 1, Solve: 0, CleanUp: 0", solvr.String())
+	assert.Len(t, state.Challenges, 1)
+}
+
+func TestProber_Present_sequential_severalDomains(t *testing.T) {
+	authz := []acme.Authorization{
+		createStubAuthorizationDNS01("a.example", false),
+		createStubAuthorizationDNS01("b.example", false),
+	}
+
+	solvr := &sequentialSolverMock{
+		preSolverMock: preSolverMock{
+			preSolve: map[string]error{},
+			solve:    map[string]error{},
+			cleanUp:  map[string]error{},
+		},
+	}
+
+	prober := &Prober{solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: solvr}}}
+
+	state, err := prober.Present(t.Context(), authz)
+	require.EqualError(t, err, "error: one or more domains had a problem:\n"+
+		"[b.example] [b.example] acme: the challenges of a sequential provider cannot be presented in advance for several domains (a.example, b.example)\n")
+
+	assert.Nil(t, state)
+
+	// Nothing is presented.
+	assert.Equal(t, "PreSolve: 0, Solve: 0, CleanUp: 0", solvr.String())
+}
+
+func TestProber_Present_errors(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		solvers          map[challenge.Type]solver
+		expectedError    string
+		expectedCounters string
+	}{
+		{
+			desc: "PreSolve error",
+			solvers: map[challenge.Type]solver{
+				challenge.DNS01: &preSolverMock{
+					preSolve: map[string]error{"b.example": errors.New("preSolve error")},
+					solve:    map[string]error{},
+					cleanUp:  map[string]error{},
+				},
+			},
+			expectedError:    "error: one or more domains had a problem:\n[b.example] preSolve error\n",
+			expectedCounters: "PreSolve: 2, Solve: 0, CleanUp: 1",
+		},
+		{
+			desc: "not presentable",
+			solvers: map[challenge.Type]solver{
+				challenge.DNS01: &solverMock{},
+			},
+			expectedError: "error: one or more domains had a problem:\n" +
+				"[a.example] [a.example] acme: the challenge dns-01 cannot be presented in advance\n" +
+				"[b.example] [b.example] acme: the challenge dns-01 cannot be presented in advance\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			prober := &Prober{solverManager: &SolverManager{solvers: test.solvers}}
+
+			authz := []acme.Authorization{
+				createStubAuthorizationDNS01("a.example", false),
+				createStubAuthorizationDNS01("b.example", false),
+			}
+
+			state, err := prober.Present(t.Context(), authz)
+			require.EqualError(t, err, test.expectedError)
+
+			assert.Nil(t, state)
+
+			if test.expectedCounters != "" {
+				assert.Equal(t, test.expectedCounters, test.solvers[challenge.DNS01].(*preSolverMock).String())
+			}
+		})
+	}
+}
+
+func TestProber_Resume_error(t *testing.T) {
+	authz := []acme.Authorization{
+		createStubAuthorizationDNS01("a.example", false),
+		createStubAuthorizationDNS01("b.example", false),
+		createStubAuthorizationDNS01("c.example", false),
+	}
+
+	solvr := newStatefulSolverMock()
+	solvr.solve["a.example"] = errors.New("solve error")
+
+	prober := &Prober{solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: solvr}}}
+
+	state := &State{Challenges: []ChallengeState{
+		{Domain: "a.example", Type: challenge.DNS01},
+		{Domain: "b.example", Type: challenge.DNS01},
+	}}
+
+	err := prober.Resume(t.Context(), authz, state)
+	require.EqualError(t, err, "error: one or more domains had a problem:\n"+
+		"[a.example] solve error\n"+
+		"[c.example] [c.example] acme: no presented challenge\n")
+
+	// The challenges are cleaned up even if the validation fails.
+	assert.Equal(t, "PreSolve: 0, Solve: 2, CleanUp: 2", solvr.String())
+}
@@ -1,6 +1,7 @@
 package tlsalpn01
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/tls"
@@ -14,6 +15,8 @@ import (
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/go-acme/lego/v4/platform/wait"
 )
 
 // idPeAcmeIdentifierV1 is the SMI Security for PKIX Certification Extension OID referencing the ACME extension.
@@ -49,7 +52,7 @@ func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Prov
 	for _, opt := range opts {
 		err := opt(chlg)
 		if err != nil {
-			log.Infof("challenge option error: %v", err)
+			log.Warn("challenge option error.", log.AttrChallengeType, challenge.TLSALPN01, log.AttrError, err)
 		}
 	}
 
@@ -62,8 +65,14 @@ func (c *Challenge) SetProvider(provider challenge.Provider) {
 
 // Solve manages the provider to validate and solve the challenge.
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveWithContext(context.Background(), authz)
+}
+
+// SolveWithContext is like Solve, but the context is used to cancel the validation.
+// The token is cleaned up even if the context is canceled.
+func (c *Challenge) SolveWithContext(ctx context.Context, authz acme.Authorization) error {
 	domain := authz.Identifier.Value
-	log.Infof("[%s] acme: Trying to solve TLS-ALPN-01", challenge.GetTargetedDomain(authz))
+	log.Info("acme: Trying to solve TLS-ALPN-01", log.AttrDomain, challenge.GetTargetedDomain(authz), log.AttrProvider, metrics.ProviderName(c.provider))
 
 	chlng, err := challenge.FindChallenge(challenge.TLSALPN01, authz)
 	if err != nil {
@@ -76,25 +85,28 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		return err
 	}
 
-	err = c.provider.Present(domain, chlng.Token, keyAuth)
+	err = challenge.CallPresent(c.provider, domain, chlng.Token, keyAuth)
 	if err != nil {
 		return fmt.Errorf("[%s] acme: error presenting token: %w", challenge.GetTargetedDomain(authz), err)
 	}
 
 	defer func() {
-		err := c.provider.CleanUp(domain, chlng.Token, keyAuth)
+		err := challenge.CallCleanUp(c.provider, domain, chlng.Token, keyAuth)
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v", challenge.GetTargetedDomain(authz), err)
+			log.Warn("acme: cleaning up failed.", log.AttrDomain, challenge.GetTargetedDomain(authz), log.AttrError, err)
 		}
 	}()
 
 	if c.delay > 0 {
-		time.Sleep(c.delay)
+		err = wait.Sleep(ctx, c.delay)
+		if err != nil {
+			return err
+		}
 	}
 
 	chlng.KeyAuthorization = keyAuth
 
-	return c.validate(c.core, domain, chlng)
+	return c.validate(c.core.WithContext(ctx), domain, chlng)
 }
 
 // ChallengeBlocks returns PEM blocks (certPEMBlock, keyPEMBlock) with the acmeValidation-v1 extension
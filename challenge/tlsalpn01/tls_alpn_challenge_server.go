@@ -7,8 +7,10 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/go-acme/lego/v4/log"
+	"github.com/miekg/dns"
 )
 
 const (
@@ -23,12 +25,25 @@ const (
 // ProviderServer implements ChallengeProvider for `TLS-ALPN-01` challenge.
 // It may be instantiated without using the NewProviderServer
 // if you want only to use the default values.
+//
+// The server is shared by the concurrent challenges:
+// it is started by the first challenge, and closed when the last challenge is cleaned up.
+// The challenge certificate is selected by the server name (SNI).
 type ProviderServer struct {
-	iface    string
-	port     string
+	iface string
+	port  string
+
+	mu       sync.Mutex
+	certs    map[string]challengeCert
 	listener net.Listener
 }
 
+// challengeCert is the certificate of a challenge, with the server name (SNI) used by the validation.
+type challengeCert struct {
+	serverName string
+	cert       *tls.Certificate
+}
+
 // NewProviderServer creates a new ProviderServer on the selected interface and port.
 // Setting iface and / or port to an empty string will make the server fall back to
 // the "any" interface and port 443 respectively.
@@ -41,38 +56,85 @@ func (s *ProviderServer) GetAddress() string {
 }
 
 // Present generates a certificate with an SHA-256 digest of the keyAuth provided
-// as the acmeValidation-v1 extension value to conform to the ACME-TLS-ALPN spec.
+// as the acmeValidation-v1 extension value to conform to the ACME-TLS-ALPN spec,
+// and starts the HTTPS server (if not already started).
 func (s *ProviderServer) Present(domain, token, keyAuth string) error {
+	// Generate the challenge certificate using the provided keyAuth and domain.
+	cert, err := ChallengeCert(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.port == "" {
 		// Fallback to port 443 if the port was not provided.
 		s.port = defaultTLSPort
 	}
 
-	// Generate the challenge certificate using the provided keyAuth and domain.
-	cert, err := ChallengeCert(domain, keyAuth)
-	if err != nil {
+	if s.listener == nil {
+		err = s.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.certs == nil {
+		s.certs = make(map[string]challengeCert)
+	}
+
+	s.certs[token] = challengeCert{serverName: serverName(domain), cert: cert}
+
+	return nil
+}
+
+// CleanUp removes the challenge certificate, and closes the HTTPS server when no challenge remains.
+func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.certs, token)
+
+	if s.listener == nil || len(s.certs) > 0 {
+		return nil
+	}
+
+	// Server was created, close it.
+	err := s.listener.Close()
+
+	s.listener = nil
+
+	if err != nil && errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
-	// Place the generated certificate with the extension into the TLS config
-	// so that it can serve the correct details.
-	tlsConf := new(tls.Config)
-	tlsConf.Certificates = []tls.Certificate{*cert}
+	return nil
+}
 
-	// We must set that the `acme-tls/1` application level protocol is supported
-	// so that the protocol negotiation can succeed. Reference:
-	// https://www.rfc-editor.org/rfc/rfc8737.html#section-6.2
-	tlsConf.NextProtos = []string{ACMETLS1Protocol}
+// start starts the HTTPS server.
+// The caller must hold the lock.
+func (s *ProviderServer) start() error {
+	tlsConf := &tls.Config{
+		// The certificate of the challenge is selected by the server name.
+		GetCertificate: s.getCertificate,
+		// We must set that the `acme-tls/1` application level protocol is supported
+		// so that the protocol negotiation can succeed. Reference:
+		// https://www.rfc-editor.org/rfc/rfc8737.html#section-6.2
+		NextProtos: []string{ACMETLS1Protocol},
+	}
 
 	// Create the listener with the created tls.Config.
-	s.listener, err = tls.Listen("tcp", s.GetAddress(), tlsConf)
+	listener, err := tls.Listen("tcp", s.GetAddress(), tlsConf)
 	if err != nil {
 		return fmt.Errorf("could not start HTTPS server for challenge: %w", err)
 	}
 
+	s.listener = listener
+
 	// Shut the server down when we're finished.
 	go func() {
-		err := http.Serve(s.listener, nil)
+		err := http.Serve(listener, nil)
 		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
 			log.Println(err)
 		}
@@ -81,16 +143,30 @@ func (s *ProviderServer) Present(domain, token, keyAuth string) error {
 	return nil
 }
 
-// CleanUp closes the HTTPS server.
-func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
-	if s.listener == nil {
-		return nil
+func (s *ProviderServer) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.certs {
+		if c.serverName == name {
+			return c.cert, nil
+		}
 	}
 
-	// Server was created, close it.
-	if err := s.listener.Close(); err != nil && errors.Is(err, http.ErrServerClosed) {
-		return err
+	return nil, fmt.Errorf("no TLS-ALPN-01 challenge for %q", hello.ServerName)
+}
+
+// serverName returns the server name (SNI) used to validate the challenge of a domain:
+// the reverse mapping name for the IP addresses (RFC 8738).
+func serverName(domain string) string {
+	if net.ParseIP(domain) != nil {
+		rd, err := dns.ReverseAddr(domain)
+		if err == nil {
+			domain = rd
+		}
 	}
 
-	return nil
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
 }
@@ -19,6 +19,56 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestProviderServer_concurrent(t *testing.T) {
+	server := NewProviderServer("127.0.0.1", "0")
+
+	require.NoError(t, server.Present("a.example.com", "tokenA", "keyAuthA"))
+	require.NoError(t, server.Present("127.0.0.2", "tokenB", "keyAuthB"))
+
+	// The server is shared by the challenges.
+	address := server.listener.Addr().String()
+
+	rd, err := dns.ReverseAddr("127.0.0.2")
+	require.NoError(t, err)
+
+	dial := func(serverName string) (*tls.Conn, error) {
+		return tls.Dial("tcp", address, &tls.Config{
+			ServerName:         serverName,
+			NextProtos:         []string{ACMETLS1Protocol},
+			InsecureSkipVerify: true,
+		})
+	}
+
+	conn, err := dial("a.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a.example.com"}, conn.ConnectionState().PeerCertificates[0].DNSNames)
+	require.NoError(t, conn.Close())
+
+	conn, err = dial(rd)
+	require.NoError(t, err)
+
+	require.Len(t, conn.ConnectionState().PeerCertificates[0].IPAddresses, 1)
+	assert.Equal(t, "127.0.0.2", conn.ConnectionState().PeerCertificates[0].IPAddresses[0].String())
+	require.NoError(t, conn.Close())
+
+	_, err = dial("c.example.com")
+	require.Error(t, err)
+
+	require.NoError(t, server.CleanUp("a.example.com", "tokenA", "keyAuthA"))
+
+	_, err = dial("a.example.com")
+	require.Error(t, err)
+
+	// The server is closed with the last challenge.
+	require.NoError(t, server.CleanUp("127.0.0.2", "tokenB", "keyAuthB"))
+
+	assert.Nil(t, server.listener)
+
+	// A clean-up without presented challenge is a no-op.
+	require.NoError(t, server.CleanUp("c.example.com", "tokenC", "keyAuthC"))
+}
+
 func TestChallenge(t *testing.T) {
 	server := tester.MockACMEServer().BuildHTTPS(t)
 
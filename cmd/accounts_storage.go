@@ -3,13 +3,16 @@ package cmd
 import (
 	"crypto"
 	"encoding/json"
-	"encoding/pem"
+	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/go-acme/lego/v4/log"
 	"github.com/go-acme/lego/v4/registration"
@@ -26,6 +29,8 @@ const (
 
 // AccountsStorage A storage for account data.
 //
+// The paths are relative to the root of the storage backend ("path" option for the file storage).
+//
 // rootPath:
 //
 //	./.lego/accounts/
@@ -49,6 +54,15 @@ const (
 //	     │      └── root accounts directory
 //	     └── "path" option
 //
+// archivesPath:
+//
+//	./.lego/accounts/localhost_14000/foo@example.com/archives/
+//	     │      │             │             │           └── archived account keys directory
+//	     │      │             │             └── userID ("email" option)
+//	     │      │             └── CA server ("server" option)
+//	     │      └── root accounts directory
+//	     └── "path" option
+//
 // accountFilePath:
 //
 //	./.lego/accounts/localhost_14000/foo@example.com/account.json
@@ -58,63 +72,88 @@ const (
 //	     │      └── root accounts directory
 //	     └── "path" option
 type AccountsStorage struct {
+	backend         storage.Backend
 	userID          string
 	email           string
 	rootPath        string
 	rootUserPath    string
 	keysPath        string
+	archivesPath    string
 	accountFilePath string
 	ctx             *cli.Context
 }
 
 // NewAccountsStorage Creates a new AccountsStorage.
 func NewAccountsStorage(ctx *cli.Context) *AccountsStorage {
+	accountsStorage, err := tryNewAccountsStorage(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return accountsStorage
+}
+
+// tryNewAccountsStorage creates a new AccountsStorage.
+// Unlike NewAccountsStorage, it returns the errors (e.g. the reload of the daemon).
+func tryNewAccountsStorage(ctx *cli.Context) (*AccountsStorage, error) {
 	// TODO: move to account struct?
-	email := ctx.String(flgEmail)
+	backend, err := tryNewStorageBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	return newAccountsStorage(ctx, backend, ctx.String(flgServer), ctx.String(flgEmail))
+}
+
+// newAccountsStorage creates an AccountsStorage for the account of the given email on the given server.
+func newAccountsStorage(ctx *cli.Context, backend storage.Backend, server, email string) (*AccountsStorage, error) {
 	userID := email
 	if userID == "" {
 		userID = userIDPlaceholder
 	}
 
-	serverURL, err := url.Parse(ctx.String(flgServer))
+	serverURL, err := url.Parse(server)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	rootPath := filepath.Join(ctx.String(flgPath), baseAccountsRootFolderName)
-	serverPath := strings.NewReplacer(":", "_", "/", string(os.PathSeparator)).Replace(serverURL.Host)
-	accountsPath := filepath.Join(rootPath, serverPath)
-	rootUserPath := filepath.Join(accountsPath, userID)
+	rootPath := baseAccountsRootFolderName
+	serverPath := strings.NewReplacer(":", "_").Replace(serverURL.Host)
+	accountsPath := path.Join(rootPath, serverPath)
+	rootUserPath := path.Join(accountsPath, userID)
 
 	return &AccountsStorage{
+		backend:         backend,
 		userID:          userID,
 		email:           email,
 		rootPath:        rootPath,
 		rootUserPath:    rootUserPath,
-		keysPath:        filepath.Join(rootUserPath, baseKeysFolderName),
-		accountFilePath: filepath.Join(rootUserPath, accountFileName),
+		keysPath:        path.Join(rootUserPath, baseKeysFolderName),
+		archivesPath:    path.Join(rootUserPath, baseArchivesFolderName),
+		accountFilePath: path.Join(rootUserPath, accountFileName),
 		ctx:             ctx,
-	}
+	}, nil
 }
 
 func (s *AccountsStorage) ExistsAccountFilePath() bool {
-	accountFile := filepath.Join(s.rootUserPath, accountFileName)
-	if _, err := os.Stat(accountFile); os.IsNotExist(err) {
-		return false
-	} else if err != nil {
+	exists, err := s.tryExistsAccountFilePath()
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	return true
+	return exists
+}
+
+func (s *AccountsStorage) tryExistsAccountFilePath() (bool, error) {
+	return s.backend.Exists(s.accountFilePath)
 }
 
 func (s *AccountsStorage) GetRootPath() string {
-	return s.rootPath
+	return s.backend.Location(s.rootPath)
 }
 
 func (s *AccountsStorage) GetRootUserPath() string {
-	return s.rootUserPath
+	return s.backend.Location(s.rootUserPath)
 }
 
 func (s *AccountsStorage) GetUserID() string {
@@ -131,20 +170,29 @@ func (s *AccountsStorage) Save(account *Account) error {
 		return err
 	}
 
-	return os.WriteFile(s.accountFilePath, jsonBytes, filePerm)
+	return s.backend.WriteFile(s.accountFilePath, jsonBytes)
 }
 
 func (s *AccountsStorage) LoadAccount(privateKey crypto.PrivateKey) *Account {
-	fileBytes, err := os.ReadFile(s.accountFilePath)
+	account, err := s.tryLoadAccount(privateKey)
 	if err != nil {
-		log.Fatalf("Could not load file for account %s: %v", s.GetUserID(), err)
+		log.Fatal(err)
+	}
+
+	return account
+}
+
+func (s *AccountsStorage) tryLoadAccount(privateKey crypto.PrivateKey) (*Account, error) {
+	fileBytes, err := s.backend.ReadFile(s.accountFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load file for account %s: %w", s.GetUserID(), err)
 	}
 
 	var account Account
 
 	err = json.Unmarshal(fileBytes, &account)
 	if err != nil {
-		log.Fatalf("Could not parse file for account %s: %v", s.GetUserID(), err)
+		return nil, fmt.Errorf("could not parse file for account %s: %w", s.GetUserID(), err)
 	}
 
 	account.key = privateKey
@@ -152,66 +200,179 @@ func (s *AccountsStorage) LoadAccount(privateKey crypto.PrivateKey) *Account {
 	if account.Registration == nil || account.Registration.Body.Status == "" {
 		reg, err := tryRecoverRegistration(s.ctx, privateKey)
 		if err != nil {
-			log.Fatalf("Could not load account for %s. Registration is nil: %#v", s.GetUserID(), err)
+			return nil, fmt.Errorf("could not load account for %s. Registration is nil: %w", s.GetUserID(), err)
 		}
 
 		account.Registration = reg
 
 		err = s.Save(&account)
 		if err != nil {
-			log.Fatalf("Could not save account for %s. Registration is nil: %#v", s.GetUserID(), err)
+			return nil, fmt.Errorf("could not save account for %s. Registration is nil: %w", s.GetUserID(), err)
 		}
 	}
 
-	return &account
+	return &account, nil
 }
 
 func (s *AccountsStorage) GetPrivateKey(keyType certcrypto.KeyType) crypto.PrivateKey {
-	accKeyPath := filepath.Join(s.keysPath, s.GetUserID()+".key")
+	privateKey, err := s.tryGetPrivateKey(keyType)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return privateKey
+}
+
+func (s *AccountsStorage) tryGetPrivateKey(keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	accKeyPath := s.getPrivateKeyPath()
 
-	if _, err := os.Stat(accKeyPath); os.IsNotExist(err) {
+	exists, err := s.backend.Exists(accKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
 		log.Printf("No key found for account %s. Generating a %s key.", s.GetUserID(), keyType)
-		s.createKeysFolder()
 
-		privateKey, err := generatePrivateKey(accKeyPath, keyType)
+		privateKey, err := s.generatePrivateKey(accKeyPath, keyType)
 		if err != nil {
-			log.Fatalf("Could not generate RSA private account key for account %s: %v", s.GetUserID(), err)
+			return nil, fmt.Errorf("could not generate RSA private account key for account %s: %w", s.GetUserID(), err)
 		}
 
-		log.Printf("Saved key to %s", accKeyPath)
+		log.Printf("Saved key to %s", s.backend.Location(accKeyPath))
 
-		return privateKey
+		return privateKey, nil
 	}
 
-	privateKey, err := loadPrivateKey(accKeyPath)
+	keyBytes, err := s.backend.ReadFile(accKeyPath)
 	if err != nil {
-		log.Fatalf("Could not load RSA private key from file %s: %v", accKeyPath, err)
+		return nil, fmt.Errorf("could not load RSA private key from file %s: %w", s.backend.Location(accKeyPath), err)
 	}
 
-	return privateKey
+	privateKey, err := certcrypto.ParsePEMPrivateKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not load RSA private key from file %s: %w", s.backend.Location(accKeyPath), err)
+	}
+
+	return privateKey, nil
+}
+
+// ReadPrivateKey reads the account key, without generating it when it doesn't exist.
+func (s *AccountsStorage) ReadPrivateKey() (crypto.PrivateKey, error) {
+	keyBytes, err := s.backend.ReadFile(s.getPrivateKeyPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return certcrypto.ParsePEMPrivateKey(keyBytes)
 }
 
-func (s *AccountsStorage) createKeysFolder() {
-	if err := createNonExistingFolder(s.keysPath); err != nil {
-		log.Fatalf("Could not check/create directory for account %s: %v", s.GetUserID(), err)
+// SavePrivateKey stores the account key.
+func (s *AccountsStorage) SavePrivateKey(privateKey crypto.PrivateKey) error {
+	return s.backend.WriteFile(s.getPrivateKeyPath(), certcrypto.PEMEncode(privateKey))
+}
+
+// MoveTo moves the account file, the account key, and the archived keys, to the location of another account.
+// The location of the other account must be empty.
+func (s *AccountsStorage) MoveTo(target *AccountsStorage) error {
+	keys, err := s.backend.List(s.rootUserPath + "/")
+	if err != nil {
+		return err
+	}
+
+	existing, err := target.backend.List(target.rootUserPath + "/")
+	if err != nil {
+		return err
 	}
+
+	if len(existing) > 0 {
+		return fmt.Errorf("the account %s already exists", target.GetUserID())
+	}
+
+	for _, key := range keys {
+		newKey := path.Join(target.rootUserPath, strings.TrimPrefix(key, s.rootUserPath+"/"))
+
+		switch key {
+		case s.getPrivateKeyPath():
+			newKey = target.getPrivateKeyPath()
+		case s.getNewPrivateKeyPath():
+			newKey = target.getNewPrivateKeyPath()
+		}
+
+		err = s.backend.Rename(key, newKey)
+		if err != nil {
+			return fmt.Errorf("could not move the account file %s: %w", s.backend.Location(key), err)
+		}
+	}
+
+	return nil
 }
 
-func generatePrivateKey(file string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
-	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+// SaveNewPrivateKey stores a key, next to the current account key, which is intended to replace it.
+// The key is stored before the key rollover on the ACME server to never lose it.
+func (s *AccountsStorage) SaveNewPrivateKey(privateKey crypto.PrivateKey) error {
+	return s.backend.WriteFile(s.getNewPrivateKeyPath(), certcrypto.PEMEncode(privateKey))
+}
+
+// RemoveNewPrivateKey removes the key stored by SaveNewPrivateKey.
+func (s *AccountsStorage) RemoveNewPrivateKey() error {
+	return s.backend.Remove(s.getNewPrivateKeyPath())
+}
+
+// ReplacePrivateKey moves the current account key to the archives,
+// and replaces it with the key stored by SaveNewPrivateKey.
+func (s *AccountsStorage) ReplacePrivateKey() error {
+	accKeyPath := s.getPrivateKeyPath()
+
+	date := strconv.FormatInt(time.Now().Unix(), 10)
+	archivedKeyPath := path.Join(s.archivesPath, date+"."+path.Base(accKeyPath))
+
+	err := s.backend.Rename(accKeyPath, archivedKeyPath)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("could not archive the account key: %w", err)
+	}
+
+	err = s.backend.Rename(s.getNewPrivateKeyPath(), accKeyPath)
+	if err != nil {
+		return fmt.Errorf("could not replace the account key: %w", err)
 	}
 
-	certOut, err := os.Create(file)
+	return nil
+}
+
+func (s *AccountsStorage) getPrivateKeyPath() string {
+	return path.Join(s.keysPath, s.GetUserID()+keyExt)
+}
+
+func (s *AccountsStorage) getNewPrivateKeyPath() string {
+	return s.getPrivateKeyPath() + ".new"
+}
+
+// listAccountFiles returns the paths of the account files of all the users and all the servers.
+func (s *AccountsStorage) listAccountFiles() ([]string, error) {
+	keys, err := s.backend.List(s.rootPath + "/")
 	if err != nil {
 		return nil, err
 	}
-	defer certOut.Close()
 
-	pemKey := certcrypto.PEMBlock(privateKey)
+	var files []string
+
+	for _, key := range keys {
+		if ok, _ := path.Match(path.Join(s.rootPath, "*", "*", accountFileName), key); ok {
+			files = append(files, key)
+		}
+	}
+
+	return files, nil
+}
+
+func (s *AccountsStorage) generatePrivateKey(file string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
 
-	err = pem.Encode(certOut, pemKey)
+	err = s.backend.WriteFile(file, certcrypto.PEMEncode(privateKey))
 	if err != nil {
 		return nil, err
 	}
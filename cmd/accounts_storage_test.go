@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	localstorage "github.com/go-acme/lego/v4/cmd/internal/storage"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountsStorage_MoveTo(t *testing.T) {
+	backend := localstorage.NewFileSystem(t.TempDir())
+
+	source := newTestAccountsStorage(t, backend, "foo@example.com")
+
+	privateKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	require.NoError(t, source.SavePrivateKey(privateKey))
+	require.NoError(t, source.Save(&Account{Email: "foo@example.com", Registration: &registration.Resource{URI: "https://ca.example/acct/1"}}))
+	require.NoError(t, backend.WriteFile(source.archivesPath+"/123.foo@example.com.key", []byte("old")))
+
+	target := newTestAccountsStorage(t, backend, "bar@example.com")
+
+	err = source.MoveTo(target)
+	require.NoError(t, err)
+
+	assert.False(t, source.ExistsAccountFilePath())
+	assert.True(t, target.ExistsAccountFilePath())
+
+	key, err := target.ReadPrivateKey()
+	require.NoError(t, err)
+
+	assert.Equal(t, privateKey, key)
+
+	keys, err := backend.List(target.rootUserPath + "/")
+	require.NoError(t, err)
+
+	expected := []string{
+		"accounts/ca.example/bar@example.com/account.json",
+		"accounts/ca.example/bar@example.com/archives/123.foo@example.com.key",
+		"accounts/ca.example/bar@example.com/keys/bar@example.com.key",
+	}
+
+	assert.ElementsMatch(t, expected, keys)
+}
+
+func TestAccountsStorage_MoveTo_exists(t *testing.T) {
+	backend := localstorage.NewFileSystem(t.TempDir())
+
+	source := newTestAccountsStorage(t, backend, "foo@example.com")
+	require.NoError(t, source.Save(&Account{Email: "foo@example.com"}))
+
+	target := newTestAccountsStorage(t, backend, "bar@example.com")
+	require.NoError(t, target.Save(&Account{Email: "bar@example.com"}))
+
+	err := source.MoveTo(target)
+	require.EqualError(t, err, "the account bar@example.com already exists")
+
+	assert.True(t, source.ExistsAccountFilePath())
+}
+
+func newTestAccountsStorage(t *testing.T, backend localstorage.Backend, email string) *AccountsStorage {
+	t.Helper()
+
+	accountsStorage, err := newAccountsStorage(nil, backend, "https://ca.example/directory", email)
+	require.NoError(t, err)
+
+	return accountsStorage
+}
@@ -7,14 +7,14 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
 	"github.com/go-acme/lego/v4/log"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/net/idna"
@@ -24,6 +24,7 @@ import (
 const (
 	baseCertificatesFolderName = "certificates"
 	baseArchivesFolderName     = "archives"
+	baseOrdersFolderName       = "orders"
 )
 
 const (
@@ -37,6 +38,8 @@ const (
 
 // CertificatesStorage a certificates' storage.
 //
+// The paths are relative to the root of the storage backend ("path" option for the file storage).
+//
 // rootPath:
 //
 //	./.lego/certificates/
@@ -48,9 +51,17 @@ const (
 //	./.lego/archives/
 //	     │      └── archived certificates directory
 //	     └── "path" option
+//
+// ordersPath:
+//
+//	./.lego/orders/
+//	     │      └── pending orders directory
+//	     └── "path" option
 type CertificatesStorage struct {
+	backend     storage.Backend
 	rootPath    string
 	archivePath string
+	ordersPath  string
 	pem         bool
 	pfx         bool
 	pfxPassword string
@@ -69,8 +80,10 @@ func NewCertificatesStorage(ctx *cli.Context) *CertificatesStorage {
 	}
 
 	return &CertificatesStorage{
-		rootPath:    filepath.Join(ctx.String(flgPath), baseCertificatesFolderName),
-		archivePath: filepath.Join(ctx.String(flgPath), baseArchivesFolderName),
+		backend:     newStorageBackend(ctx),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+		ordersPath:  baseOrdersFolderName,
 		pem:         ctx.Bool(flgPEM),
 		pfx:         ctx.Bool(flgPFX),
 		pfxPassword: ctx.String(flgPFXPass),
@@ -79,22 +92,34 @@ func NewCertificatesStorage(ctx *cli.Context) *CertificatesStorage {
 	}
 }
 
-func (s *CertificatesStorage) CreateRootFolder() {
-	err := createNonExistingFolder(s.rootPath)
-	if err != nil {
-		log.Fatalf("Could not check/create path: %v", err)
-	}
+func (s *CertificatesStorage) GetRootPath() string {
+	return s.backend.Location(s.rootPath)
 }
 
-func (s *CertificatesStorage) CreateArchiveFolder() {
-	err := createNonExistingFolder(s.archivePath)
+// ListDomains returns the (sanitized) domains of the stored certificates.
+func (s *CertificatesStorage) ListDomains() ([]string, error) {
+	keys, err := s.backend.List(s.rootPath + "/")
 	if err != nil {
-		log.Fatalf("Could not check/create path: %v", err)
+		return nil, err
 	}
+
+	var domains []string
+
+	for _, key := range keys {
+		if path.Dir(key) != s.rootPath || !strings.HasSuffix(key, certExt) || strings.HasSuffix(key, issuerExt) {
+			continue
+		}
+
+		domains = append(domains, strings.TrimSuffix(path.Base(key), certExt))
+	}
+
+	return domains, nil
 }
 
-func (s *CertificatesStorage) GetRootPath() string {
-	return s.rootPath
+// Lock acquires an exclusive lock related to a certificate.
+// The lock prevents several instances of lego, sharing the same storage, to obtain the same certificate at once.
+func (s *CertificatesStorage) Lock(domain string) (func() error, error) {
+	return s.backend.Lock(path.Join(s.rootPath, sanitizedDomain(domain)))
 }
 
 func (s *CertificatesStorage) SaveResource(certRes *certificate.Resource) {
@@ -151,24 +176,24 @@ func (s *CertificatesStorage) ReadResource(domain string) certificate.Resource {
 }
 
 func (s *CertificatesStorage) ExistsFile(domain, extension string) bool {
-	filePath := s.GetFileName(domain, extension)
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return false
-	} else if err != nil {
+	exists, err := s.tryExistsFile(domain, extension)
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	return true
+	return exists
+}
+
+func (s *CertificatesStorage) tryExistsFile(domain, extension string) (bool, error) {
+	return s.backend.Exists(s.getFilePath(domain, extension))
 }
 
 func (s *CertificatesStorage) ReadFile(domain, extension string) ([]byte, error) {
-	return os.ReadFile(s.GetFileName(domain, extension))
+	return s.backend.ReadFile(s.getFilePath(domain, extension))
 }
 
 func (s *CertificatesStorage) GetFileName(domain, extension string) string {
-	filename := sanitizedDomain(domain) + extension
-	return filepath.Join(s.rootPath, filename)
+	return s.backend.Location(s.getFilePath(domain, extension))
 }
 
 func (s *CertificatesStorage) ReadCertificate(domain, extension string) ([]*x509.Certificate, error) {
@@ -189,9 +214,7 @@ func (s *CertificatesStorage) WriteFile(domain, extension string, data []byte) e
 		baseFileName = sanitizedDomain(domain)
 	}
 
-	filePath := filepath.Join(s.rootPath, baseFileName+extension)
-
-	return os.WriteFile(filePath, data, filePerm)
+	return s.backend.WriteFile(path.Join(s.rootPath, baseFileName+extension), data)
 }
 
 func (s *CertificatesStorage) WriteCertificateFiles(domain string, certRes *certificate.Resource) error {
@@ -252,23 +275,23 @@ func (s *CertificatesStorage) WritePFXFile(domain string, certRes *certificate.R
 }
 
 func (s *CertificatesStorage) MoveToArchive(domain string) error {
-	baseFilename := filepath.Join(s.rootPath, sanitizedDomain(domain))
+	baseFilename := path.Join(s.rootPath, sanitizedDomain(domain))
 
-	matches, err := filepath.Glob(baseFilename + ".*")
+	matches, err := s.backend.List(baseFilename + ".")
 	if err != nil {
 		return err
 	}
 
 	for _, oldFile := range matches {
-		if strings.TrimSuffix(oldFile, filepath.Ext(oldFile)) != baseFilename && oldFile != baseFilename+issuerExt {
+		if strings.TrimSuffix(oldFile, path.Ext(oldFile)) != baseFilename && oldFile != baseFilename+issuerExt {
 			continue
 		}
 
 		date := strconv.FormatInt(time.Now().Unix(), 10)
-		filename := date + "." + filepath.Base(oldFile)
-		newFile := filepath.Join(s.archivePath, filename)
+		filename := date + "." + path.Base(oldFile)
+		newFile := path.Join(s.archivePath, filename)
 
-		err = os.Rename(oldFile, newFile)
+		err = s.backend.Rename(oldFile, newFile)
 		if err != nil {
 			return err
 		}
@@ -277,6 +300,51 @@ func (s *CertificatesStorage) MoveToArchive(domain string) error {
 	return nil
 }
 
+// SavePendingOrder saves a pending order (see certificate.Certifier.PresentOrder).
+func (s *CertificatesStorage) SavePendingOrder(domain string, pending *certificate.PendingOrder) error {
+	data, err := json.MarshalIndent(pending, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return s.backend.WriteFile(s.getPendingOrderPath(domain), data)
+}
+
+// ReadPendingOrder reads a pending order.
+func (s *CertificatesStorage) ReadPendingOrder(domain string) (*certificate.PendingOrder, error) {
+	data, err := s.backend.ReadFile(s.getPendingOrderPath(domain))
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &certificate.PendingOrder{}
+
+	err = json.Unmarshal(data, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// RemovePendingOrder removes a pending order.
+func (s *CertificatesStorage) RemovePendingOrder(domain string) error {
+	return s.backend.Remove(s.getPendingOrderPath(domain))
+}
+
+// GetPendingOrderLocation returns the location of a pending order.
+func (s *CertificatesStorage) GetPendingOrderLocation(domain string) string {
+	return s.backend.Location(s.getPendingOrderPath(domain))
+}
+
+func (s *CertificatesStorage) getPendingOrderPath(domain string) string {
+	return path.Join(s.ordersPath, sanitizedDomain(domain)+resourceExt)
+}
+
+func (s *CertificatesStorage) getFilePath(domain, extension string) string {
+	return path.Join(s.rootPath, sanitizedDomain(domain)+extension)
+}
+
 func getCertificateChain(certRes *certificate.Resource) ([]*x509.Certificate, error) {
 	chainCertPemBlock, rest := pem.Decode(certRes.IssuerCertificate)
 	if chainCertPemBlock == nil {
@@ -5,7 +5,12 @@ import (
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/resolver"
+	localstorage "github.com/go-acme/lego/v4/cmd/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -13,12 +18,9 @@ import (
 func TestCertificatesStorage_MoveToArchive(t *testing.T) {
 	domain := "example.com"
 
-	storage := CertificatesStorage{
-		rootPath:    t.TempDir(),
-		archivePath: t.TempDir(),
-	}
+	storage := newTestCertificatesStorage(t)
 
-	domainFiles := generateTestFiles(t, storage.rootPath, domain)
+	domainFiles := generateTestFiles(t, storage.GetRootPath(), domain)
 
 	err := storage.MoveToArchive(domain)
 	require.NoError(t, err)
@@ -27,11 +29,11 @@ func TestCertificatesStorage_MoveToArchive(t *testing.T) {
 		assert.NoFileExists(t, file)
 	}
 
-	root, err := os.ReadDir(storage.rootPath)
+	root, err := os.ReadDir(storage.GetRootPath())
 	require.NoError(t, err)
 	require.Empty(t, root)
 
-	archive, err := os.ReadDir(storage.archivePath)
+	archive, err := os.ReadDir(storage.backend.Location(storage.archivePath))
 	require.NoError(t, err)
 
 	require.Len(t, archive, len(domainFiles))
@@ -41,12 +43,9 @@ func TestCertificatesStorage_MoveToArchive(t *testing.T) {
 func TestCertificatesStorage_MoveToArchive_noFileRelatedToDomain(t *testing.T) {
 	domain := "example.com"
 
-	storage := CertificatesStorage{
-		rootPath:    t.TempDir(),
-		archivePath: t.TempDir(),
-	}
+	storage := newTestCertificatesStorage(t)
 
-	domainFiles := generateTestFiles(t, storage.rootPath, "example.org")
+	domainFiles := generateTestFiles(t, storage.GetRootPath(), "example.org")
 
 	err := storage.MoveToArchive(domain)
 	require.NoError(t, err)
@@ -55,11 +54,11 @@ func TestCertificatesStorage_MoveToArchive_noFileRelatedToDomain(t *testing.T) {
 		assert.FileExists(t, file)
 	}
 
-	root, err := os.ReadDir(storage.rootPath)
+	root, err := os.ReadDir(storage.GetRootPath())
 	require.NoError(t, err)
 	assert.Len(t, root, len(domainFiles))
 
-	archive, err := os.ReadDir(storage.archivePath)
+	archive, err := os.ReadDir(storage.backend.Location(storage.archivePath))
 	require.NoError(t, err)
 
 	assert.Empty(t, archive)
@@ -68,13 +67,10 @@ func TestCertificatesStorage_MoveToArchive_noFileRelatedToDomain(t *testing.T) {
 func TestCertificatesStorage_MoveToArchive_ambiguousDomain(t *testing.T) {
 	domain := "example.com"
 
-	storage := CertificatesStorage{
-		rootPath:    t.TempDir(),
-		archivePath: t.TempDir(),
-	}
+	storage := newTestCertificatesStorage(t)
 
-	domainFiles := generateTestFiles(t, storage.rootPath, domain)
-	otherDomainFiles := generateTestFiles(t, storage.rootPath, domain+".example.org")
+	domainFiles := generateTestFiles(t, storage.GetRootPath(), domain)
+	otherDomainFiles := generateTestFiles(t, storage.GetRootPath(), domain+".example.org")
 
 	err := storage.MoveToArchive(domain)
 	require.NoError(t, err)
@@ -87,17 +83,65 @@ func TestCertificatesStorage_MoveToArchive_ambiguousDomain(t *testing.T) {
 		assert.FileExists(t, file)
 	}
 
-	root, err := os.ReadDir(storage.rootPath)
+	root, err := os.ReadDir(storage.GetRootPath())
 	require.NoError(t, err)
 	require.Len(t, root, len(otherDomainFiles))
 
-	archive, err := os.ReadDir(storage.archivePath)
+	archive, err := os.ReadDir(storage.backend.Location(storage.archivePath))
 	require.NoError(t, err)
 
 	require.Len(t, archive, len(domainFiles))
 	assert.Regexp(t, `\d+\.`+regexp.QuoteMeta(domain), archive[0].Name())
 }
 
+func TestCertificatesStorage_PendingOrder(t *testing.T) {
+	storage := newTestCertificatesStorage(t)
+
+	pending := &certificate.PendingOrder{
+		Domains:        []string{"*.example.com", "example.com"},
+		OrderURL:       "https://ca.example/order/1",
+		Authorizations: []string{"https://ca.example/authz/1", "https://ca.example/authz/2"},
+		Challenges: &resolver.State{Challenges: []resolver.ChallengeState{
+			{Domain: "*.example.com", Type: challenge.DNS01, Token: "abc", ProviderState: "123"},
+			{Domain: "example.com", Type: challenge.DNS01, Token: "def"},
+		}},
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	err := storage.SavePendingOrder("*.example.com", pending)
+	require.NoError(t, err)
+
+	assert.FileExists(t, storage.GetPendingOrderLocation("*.example.com"))
+	assert.Equal(t, "_.example.com.json", filepath.Base(storage.GetPendingOrderLocation("*.example.com")))
+
+	restored, err := storage.ReadPendingOrder("*.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, pending, restored)
+
+	err = storage.RemovePendingOrder("*.example.com")
+	require.NoError(t, err)
+
+	_, err = storage.ReadPendingOrder("*.example.com")
+	require.Error(t, err)
+}
+
+func newTestCertificatesStorage(t *testing.T) *CertificatesStorage {
+	t.Helper()
+
+	storage := &CertificatesStorage{
+		backend:     localstorage.NewFileSystem(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+		ordersPath:  baseOrdersFolderName,
+	}
+
+	require.NoError(t, os.MkdirAll(storage.GetRootPath(), 0o700))
+	require.NoError(t, os.MkdirAll(storage.backend.Location(storage.archivePath), 0o700))
+
+	return storage
+}
+
 func generateTestFiles(t *testing.T, dir, domain string) []string {
 	t.Helper()
 
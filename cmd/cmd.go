@@ -6,9 +6,15 @@ import "github.com/urfave/cli/v2"
 func CreateCommands() []*cli.Command {
 	return []*cli.Command{
 		createRun(),
+		createAuthorize(),
+		createBatch(),
 		createRevoke(),
 		createRenew(),
+		createDaemon(),
 		createDNSHelp(),
+		createDNSPersist(),
+		createDNS(),
 		createList(),
+		createAccounts(),
 	}
 }
@@ -0,0 +1,484 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/urfave/cli/v2"
+)
+
+// Flag names.
+const (
+	flgNewKey   = "new-key"
+	flgNewEmail = "new-email"
+	flgYes      = "yes"
+	flgKey      = "key"
+	flgOutput   = "output"
+	flgInput    = "input"
+)
+
+// accountBundle the portable content of an account (see the export and import subcommands).
+type accountBundle struct {
+	Server  string   `json:"server"`
+	Account *Account `json:"account"`
+	// Key the account key (in PEM encoding).
+	Key string `json:"key"`
+}
+
+func createAccounts() *cli.Command {
+	return &cli.Command{
+		Name:  "accounts",
+		Usage: "Manage accounts.",
+		Subcommands: []*cli.Command{
+			createAccountsShow(),
+			createAccountsUpdateContact(),
+			createAccountsRollover(),
+			createAccountsDeactivate(),
+			createAccountsRecover(),
+			createAccountsExport(),
+			createAccountsImport(),
+		},
+	}
+}
+
+func createAccountsRollover() *cli.Command {
+	return &cli.Command{
+		Name:   "rollover",
+		Usage:  "Replace the account key by a new one (account key rollover).",
+		Action: accountsRollover,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: flgNewKey,
+				Usage: "Path to the new account key (in PEM encoding)." +
+					" By default, a new key is generated with the type defined by --" + flgKeyType + ".",
+			},
+		},
+	}
+}
+
+func accountsRollover(ctx *cli.Context) error {
+	accountsStorage, account, keyType := setupRegisteredAccount(ctx)
+
+	newKey, err := getNewAccountKey(ctx, keyType)
+	if err != nil {
+		return err
+	}
+
+	// The new key is stored before the rollover: if something goes wrong after the rollover,
+	// the key accepted by the server is still available.
+	err = accountsStorage.SaveNewPrivateKey(newKey)
+	if err != nil {
+		return fmt.Errorf("could not save the new account key: %w", err)
+	}
+
+	client := newClient(ctx, account, keyType)
+
+	err = client.Registration.RolloverKey(newKey)
+	if err != nil {
+		if errR := accountsStorage.RemoveNewPrivateKey(); errR != nil {
+			log.Warnf("Could not remove the new account key: %v", errR)
+		}
+
+		log.Fatalf("Could not rollover the key of the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	err = accountsStorage.ReplacePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("The key of the account %s has been replaced.", accountsStorage.GetUserID())
+
+	return nil
+}
+
+func getNewAccountKey(ctx *cli.Context, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	if ctx.IsSet(flgNewKey) {
+		privateKey, err := loadPrivateKey(ctx.String(flgNewKey))
+		if err != nil {
+			return nil, fmt.Errorf("load new account key: %w", err)
+		}
+
+		return privateKey, nil
+	}
+
+	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, fmt.Errorf("generate new account key: %w", err)
+	}
+
+	return privateKey, nil
+}
+
+func createAccountsShow() *cli.Command {
+	return &cli.Command{
+		Name:   "show",
+		Usage:  "Display the account information known by the ACME server.",
+		Action: accountsShow,
+	}
+}
+
+func accountsShow(ctx *cli.Context) error {
+	accountsStorage, account, keyType := setupRegisteredAccount(ctx)
+
+	client := newClient(ctx, account, keyType)
+
+	reg, err := client.Registration.QueryRegistration()
+	if err != nil {
+		log.Fatalf("Could not query the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	account.Registration = reg
+
+	err = accountsStorage.Save(account)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Account:", accountsStorage.GetUserID())
+	fmt.Println("  Server:", ctx.String(flgServer))
+	fmt.Println("  URI:", reg.URI)
+	fmt.Println("  Status:", reg.Body.Status)
+	fmt.Println("  Contact:", strings.Join(reg.Body.Contact, ", "))
+
+	if reg.Body.Orders != "" {
+		fmt.Println("  Orders:", reg.Body.Orders)
+	}
+
+	fmt.Println("  Path:", accountsStorage.GetRootUserPath())
+
+	return nil
+}
+
+func createAccountsUpdateContact() *cli.Command {
+	return &cli.Command{
+		Name:   "update-contact",
+		Usage:  "Replace the contact email address of the account.",
+		Action: accountsUpdateContact,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     flgNewEmail,
+				Usage:    "The new email address of the account. The account files are moved to the location related to this email.",
+				Required: true,
+			},
+		},
+	}
+}
+
+func accountsUpdateContact(ctx *cli.Context) error {
+	accountsStorage, account, keyType := setupRegisteredAccount(ctx)
+
+	newEmail := ctx.String(flgNewEmail)
+	if newEmail == "" {
+		log.Fatalf("The new email address of the account %s is empty.", accountsStorage.GetUserID())
+	}
+
+	targetStorage, err := newAccountsStorage(ctx, accountsStorage.backend, ctx.String(flgServer), newEmail)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(ctx, &Account{Email: newEmail, Registration: account.Registration, key: account.key}, keyType)
+
+	reg, err := client.Registration.UpdateRegistration(registration.RegisterOptions{})
+	if err != nil {
+		log.Fatalf("Could not update the contact of the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	if targetStorage.GetUserID() != accountsStorage.GetUserID() {
+		err = accountsStorage.MoveTo(targetStorage)
+		if err != nil {
+			return fmt.Errorf("the contact has been updated, but the account files cannot be moved: %w", err)
+		}
+	}
+
+	account.Email = newEmail
+	account.Registration = reg
+
+	err = targetStorage.Save(account)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("The contact of the account has been updated: use '--%s %s' to use it.", flgEmail, newEmail)
+
+	return nil
+}
+
+func createAccountsDeactivate() *cli.Command {
+	return &cli.Command{
+		Name:   "deactivate",
+		Usage:  "Deactivate the account. A deactivated account cannot be used anymore: this action is irreversible.",
+		Action: accountsDeactivate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  flgYes,
+				Usage: "Deactivate the account without confirmation.",
+			},
+		},
+	}
+}
+
+func accountsDeactivate(ctx *cli.Context) error {
+	accountsStorage, account, keyType := setupRegisteredAccount(ctx)
+
+	if !ctx.Bool(flgYes) && !confirm(fmt.Sprintf("Do you want to deactivate the account %s? y/N", accountsStorage.GetUserID())) {
+		log.Fatal("The account has not been deactivated.")
+	}
+
+	client := newClient(ctx, account, keyType)
+
+	err := client.Registration.DeleteRegistration()
+	if err != nil {
+		log.Fatalf("Could not deactivate the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	account.Registration.Body.Status = acme.StatusDeactivated
+
+	err = accountsStorage.Save(account)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("The account %s has been deactivated.", accountsStorage.GetUserID())
+
+	return nil
+}
+
+func createAccountsRecover() *cli.Command {
+	return &cli.Command{
+		Name:   "recover",
+		Usage:  "Recover an account from its key: the account is looked up on the ACME server, and stored.",
+		Action: accountsRecover,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  flgKey,
+				Usage: "Path to the account key (in PEM encoding). By default, the key already stored for the account is used.",
+			},
+		},
+	}
+}
+
+func accountsRecover(ctx *cli.Context) error {
+	accountsStorage := NewAccountsStorage(ctx)
+
+	if accountsStorage.ExistsAccountFilePath() {
+		log.Fatalf("Account %s already exists.", accountsStorage.GetUserID())
+	}
+
+	var (
+		privateKey crypto.PrivateKey
+		err        error
+	)
+
+	if ctx.IsSet(flgKey) {
+		privateKey, err = loadPrivateKey(ctx.String(flgKey))
+	} else {
+		privateKey, err = accountsStorage.ReadPrivateKey()
+	}
+
+	if err != nil {
+		log.Fatalf("Could not load the key of the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	reg, err := tryRecoverRegistration(ctx, privateKey)
+	if err != nil {
+		log.Fatalf("Could not recover the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	if ctx.IsSet(flgKey) {
+		err = accountsStorage.SavePrivateKey(privateKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = accountsStorage.Save(&Account{Email: accountsStorage.GetEmail(), Registration: reg, key: privateKey})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("The account %s has been recovered: %s", accountsStorage.GetUserID(), reg.URI)
+
+	return nil
+}
+
+func createAccountsExport() *cli.Command {
+	return &cli.Command{
+		Name:   "export",
+		Usage:  "Export the account (account key and account file) into a portable bundle (JSON).",
+		Action: accountsExport,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  flgOutput,
+				Usage: "Path to the bundle file. By default, the bundle is written to the standard output.",
+			},
+		},
+	}
+}
+
+func accountsExport(ctx *cli.Context) error {
+	accountsStorage := NewAccountsStorage(ctx)
+
+	bundle, err := exportAccount(accountsStorage, ctx.String(flgServer))
+	if err != nil {
+		log.Fatalf("Could not export the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if !ctx.IsSet(flgOutput) {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	// The bundle contains the account key.
+	err = os.WriteFile(ctx.String(flgOutput), data, 0o600)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("The account %s has been exported to %s.", accountsStorage.GetUserID(), ctx.String(flgOutput))
+
+	return nil
+}
+
+func createAccountsImport() *cli.Command {
+	return &cli.Command{
+		Name:   "import",
+		Usage:  "Import an account from a bundle created by the export subcommand. The server and the email are defined by the bundle.",
+		Action: accountsImport,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     flgInput,
+				Usage:    "Path to the bundle file.",
+				Required: true,
+			},
+		},
+	}
+}
+
+func accountsImport(ctx *cli.Context) error {
+	data, err := os.ReadFile(ctx.String(flgInput))
+	if err != nil {
+		return err
+	}
+
+	var bundle accountBundle
+
+	err = json.Unmarshal(data, &bundle)
+	if err != nil {
+		return fmt.Errorf("could not parse the bundle: %w", err)
+	}
+
+	if bundle.Account == nil {
+		return errors.New("the bundle does not contain an account")
+	}
+
+	accountsStorage, err := newAccountsStorage(ctx, newStorageBackend(ctx), bundle.Server, bundle.Account.Email)
+	if err != nil {
+		return err
+	}
+
+	err = importAccount(accountsStorage, &bundle)
+	if err != nil {
+		log.Fatalf("Could not import the account %s: %v", accountsStorage.GetUserID(), err)
+	}
+
+	log.Printf("The account %s has been imported: %s", accountsStorage.GetUserID(), accountsStorage.GetRootUserPath())
+
+	return nil
+}
+
+// setupRegisteredAccount loads an existing and registered account.
+func setupRegisteredAccount(ctx *cli.Context) (*AccountsStorage, *Account, certcrypto.KeyType) {
+	accountsStorage := NewAccountsStorage(ctx)
+
+	if !accountsStorage.ExistsAccountFilePath() {
+		log.Fatalf("Account %s does not exist. Use 'run' to register a new account.\n", accountsStorage.GetUserID())
+	}
+
+	account, keyType := setupAccount(ctx, accountsStorage)
+
+	if account.Registration == nil {
+		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", account.Email)
+	}
+
+	return accountsStorage, account, keyType
+}
+
+func exportAccount(accountsStorage *AccountsStorage, server string) (*accountBundle, error) {
+	if !accountsStorage.ExistsAccountFilePath() {
+		return nil, errors.New("the account does not exist")
+	}
+
+	data, err := accountsStorage.backend.ReadFile(accountsStorage.accountFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var account Account
+
+	err = json.Unmarshal(data, &account)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := accountsStorage.ReadPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountBundle{
+		Server:  server,
+		Account: &account,
+		Key:     string(certcrypto.PEMEncode(privateKey)),
+	}, nil
+}
+
+func importAccount(accountsStorage *AccountsStorage, bundle *accountBundle) error {
+	if accountsStorage.ExistsAccountFilePath() {
+		return errors.New("the account already exists")
+	}
+
+	privateKey, err := certcrypto.ParsePEMPrivateKey([]byte(bundle.Key))
+	if err != nil {
+		return fmt.Errorf("invalid account key: %w", err)
+	}
+
+	err = accountsStorage.SavePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	return accountsStorage.Save(bundle.Account)
+}
+
+// confirm asks a question, the default answer is no.
+func confirm(question string) bool {
+	fmt.Println(question)
+
+	text, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.TrimSpace(text) {
+	case "y", "Y", "yes":
+		return true
+	default:
+		return false
+	}
+}
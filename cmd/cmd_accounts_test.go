@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certcrypto"
+	localstorage "github.com/go-acme/lego/v4/cmd/internal/storage"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_exportAccount_importAccount(t *testing.T) {
+	source := newTestAccountsStorage(t, localstorage.NewFileSystem(t.TempDir()), "foo@example.com")
+
+	privateKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	account := &Account{
+		Email: "foo@example.com",
+		Registration: &registration.Resource{
+			URI:  "https://ca.example/acct/1",
+			Body: acme.Account{Status: acme.StatusValid, Contact: []string{"mailto:foo@example.com"}},
+		},
+	}
+
+	require.NoError(t, source.SavePrivateKey(privateKey))
+	require.NoError(t, source.Save(account))
+
+	bundle, err := exportAccount(source, "https://ca.example/directory")
+	require.NoError(t, err)
+
+	// The bundle is moved to another host.
+	raw, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	var restored accountBundle
+
+	err = json.Unmarshal(raw, &restored)
+	require.NoError(t, err)
+
+	target := newTestAccountsStorage(t, localstorage.NewFileSystem(t.TempDir()), restored.Account.Email)
+
+	err = importAccount(target, &restored)
+	require.NoError(t, err)
+
+	key, err := target.ReadPrivateKey()
+	require.NoError(t, err)
+
+	assert.Equal(t, privateKey, key)
+
+	data, err := target.backend.ReadFile(target.accountFilePath)
+	require.NoError(t, err)
+
+	var imported Account
+
+	err = json.Unmarshal(data, &imported)
+	require.NoError(t, err)
+
+	assert.Equal(t, account, &imported)
+
+	// The account cannot be imported twice.
+	err = importAccount(target, &restored)
+	require.EqualError(t, err, "the account already exists")
+}
+
+func Test_exportAccount_notExist(t *testing.T) {
+	source := newTestAccountsStorage(t, localstorage.NewFileSystem(t.TempDir()), "foo@example.com")
+
+	_, err := exportAccount(source, "https://ca.example/directory")
+	require.EqualError(t, err, "the account does not exist")
+}
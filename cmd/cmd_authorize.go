@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+func createAuthorize() *cli.Command {
+	return &cli.Command{
+		Name: "authorize",
+		Usage: "Register an account, then create and solve the authorizations of the domains ahead of time (pre-authorization)." +
+			" The next orders of the account reuse the valid authorizations. The ACME server must support pre-authorization.",
+		Before: func(ctx *cli.Context) error {
+			if len(ctx.StringSlice(flgDomains)) == 0 {
+				log.Fatal("Please specify --domains/-d")
+			}
+
+			return nil
+		},
+		Action: authorize,
+	}
+}
+
+func authorize(ctx *cli.Context) error {
+	accountsStorage := NewAccountsStorage(ctx)
+
+	account, keyType := setupAccount(ctx, accountsStorage)
+
+	client := setupClient(ctx, account, keyType)
+
+	ensureRegistration(ctx, client, account, accountsStorage)
+
+	authorizations, err := client.Certificate.Authorize(ctx.Context, ctx.StringSlice(flgDomains))
+	if err != nil {
+		return fmt.Errorf("could not authorize the domains:\n\t%w", err)
+	}
+
+	fmt.Println("The following authorizations are valid:")
+
+	for _, authz := range authorizations {
+		fmt.Println("  Domain:", authz.Identifier.Value)
+		fmt.Println("  Status:", authz.Status)
+
+		if !authz.Expires.IsZero() {
+			fmt.Println("  Expires:", authz.Expires)
+		}
+
+		fmt.Println("  URL:", authz.Location)
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/resolver"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Flag names.
+const (
+	flgBatchConfig  = "config"
+	flgBatchWorkers = "workers"
+)
+
+// batchChallenges are the challenge types allowed in the batch configuration.
+var batchChallenges = []challenge.Type{
+	challenge.HTTP01,
+	challenge.TLSALPN01,
+	challenge.DNS01,
+	challenge.DNSAccount01,
+	challenge.DNSPersist01,
+}
+
+// batchConfig is the configuration of the batch command.
+type batchConfig struct {
+	Certificates []batchCertificate `yaml:"certificates"`
+}
+
+// batchCertificate describes a certificate to obtain.
+type batchCertificate struct {
+	Domains        []string `yaml:"domains"`
+	KeyType        string   `yaml:"keyType"`
+	Profile        string   `yaml:"profile"`
+	Challenge      string   `yaml:"challenge"`
+	PreferredChain string   `yaml:"preferredChain"`
+	MustStaple     bool     `yaml:"mustStaple"`
+}
+
+// batchResult is the result of the issuance of a certificate.
+type batchResult struct {
+	domain   string
+	notAfter time.Time
+	err      error
+}
+
+func createBatch() *cli.Command {
+	return &cli.Command{
+		Name:   "batch",
+		Usage:  "Register an account, then create and install the certificates described by a configuration file, concurrently.",
+		Action: batch,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     flgBatchConfig,
+				Usage:    "Path to the configuration file (YAML) describing the certificates.",
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:  flgBatchWorkers,
+				Usage: "Maximum number of certificates obtained concurrently.",
+				Value: 4,
+			},
+			&cli.BoolFlag{
+				Name:  flgNoBundle,
+				Usage: "Do not create a certificate bundle by adding the issuers certificate to the new certificate.",
+			},
+			&cli.StringFlag{
+				Name:  flgRunHook,
+				Usage: "Define a hook. The hook is executed when a certificate is effectively created.",
+			},
+			&cli.DurationFlag{
+				Name:  flgRunHookTimeout,
+				Usage: "Define the timeout for the hook execution.",
+				Value: 2 * time.Minute,
+			},
+		},
+	}
+}
+
+func batch(ctx *cli.Context) error {
+	config, err := readBatchConfig(ctx.String(flgBatchConfig))
+	if err != nil {
+		log.Fatalf("Could not read the configuration: %v", err)
+	}
+
+	if ctx.Int(flgBatchWorkers) < 1 {
+		log.Fatalf("The number of workers must be greater than 0.")
+	}
+
+	accountsStorage := NewAccountsStorage(ctx)
+
+	account, keyType := setupAccount(ctx, accountsStorage)
+
+	client := setupClient(ctx, account, keyType)
+
+	ensureRegistration(ctx, client, account, accountsStorage)
+
+	certsStorage := NewCertificatesStorage(ctx)
+
+	results := make([]batchResult, len(config.Certificates))
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(ctx.Int(flgBatchWorkers), len(config.Certificates)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				results[i] = obtainBatchCertificate(ctx, client, certsStorage, account, config.Certificates[i])
+			}
+		}()
+	}
+
+	for i := range config.Certificates {
+		indexes <- i
+	}
+
+	close(indexes)
+
+	wg.Wait()
+
+	return reportBatch(results)
+}
+
+func obtainBatchCertificate(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, account *Account, cert batchCertificate) batchResult {
+	domain := cert.Domains[0]
+
+	unlock, err := certsStorage.Lock(domain)
+	if err != nil {
+		return batchResult{domain: domain, err: fmt.Errorf("could not lock the certificate: %w", err)}
+	}
+
+	defer releaseLock(domain, unlock)
+
+	request := certificate.ObtainRequest{
+		Domains:        cert.Domains,
+		MustStaple:     cert.MustStaple,
+		Bundle:         !ctx.Bool(flgNoBundle),
+		PreferredChain: cert.PreferredChain,
+		Profile:        cert.Profile,
+	}
+
+	if cert.KeyType != "" {
+		// The key type has already been validated.
+		keyType, _ := parseKeyType(cert.KeyType)
+
+		request.PrivateKey, err = certcrypto.GeneratePrivateKey(keyType)
+		if err != nil {
+			return batchResult{domain: domain, err: fmt.Errorf("could not generate the private key: %w", err)}
+		}
+	}
+
+	obtainCtx := ctx.Context
+	if cert.Challenge != "" {
+		obtainCtx = resolver.WithChallengeTypes(obtainCtx, challenge.Type(cert.Challenge))
+	}
+
+	certRes, err := client.Certificate.ObtainWithContext(obtainCtx, request)
+	if err != nil {
+		log.Error("Could not obtain the certificate.", log.AttrDomains, cert.Domains, log.AttrError, err)
+
+		return batchResult{domain: domain, err: err}
+	}
+
+	certsStorage.SaveResource(certRes)
+
+	result := batchResult{domain: domain}
+
+	if x509Cert, errP := certcrypto.ParsePEMCertificate(certRes.Certificate); errP == nil {
+		result.notAfter = x509Cert.NotAfter
+	}
+
+	meta := map[string]string{
+		hookEnvAccountEmail: account.Email,
+	}
+
+	addPathToMetadata(meta, certRes.Domain, certRes, certsStorage)
+
+	err = launchHook(ctx.String(flgRunHook), ctx.Duration(flgRunHookTimeout), meta)
+	if err != nil {
+		result.err = fmt.Errorf("hook: %w", err)
+	}
+
+	return result
+}
+
+// reportBatch displays the summary of the issuance.
+func reportBatch(results []batchResult) error {
+	var failed int
+
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+
+	fmt.Printf("Summary: %d certificate(s), %d obtained, %d failed.\n", len(results), len(results)-failed, failed)
+
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			fmt.Printf("  [failed] %s: %v\n", result.domain, result.err)
+		case result.notAfter.IsZero():
+			fmt.Printf("  [ok]     %s\n", result.domain)
+		default:
+			fmt.Printf("  [ok]     %s (expires %s)\n", result.domain, result.notAfter.Format(time.RFC3339))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d certificate(s) could not be obtained", failed)
+	}
+
+	return nil
+}
+
+func readBatchConfig(filename string) (*batchConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &batchConfig{}
+
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *batchConfig) validate() error {
+	if len(c.Certificates) == 0 {
+		return errors.New("no certificates")
+	}
+
+	seen := make(map[string]struct{})
+
+	for i, cert := range c.Certificates {
+		if len(cert.Domains) == 0 {
+			return fmt.Errorf("certificates[%d]: no domains", i)
+		}
+
+		// The main domain identifies the certificate in the storage.
+		if _, ok := seen[cert.Domains[0]]; ok {
+			return fmt.Errorf("certificates[%d]: duplicated certificate %s", i, cert.Domains[0])
+		}
+
+		seen[cert.Domains[0]] = struct{}{}
+
+		if cert.KeyType != "" {
+			if _, err := parseKeyType(cert.KeyType); err != nil {
+				return fmt.Errorf("certificates[%d]: %w", i, err)
+			}
+		}
+
+		if cert.Challenge != "" && !slices.Contains(batchChallenges, challenge.Type(cert.Challenge)) {
+			return fmt.Errorf("certificates[%d]: unsupported challenge: %s", i, cert.Challenge)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readBatchConfig(t *testing.T) {
+	filename := writeBatchConfig(t, `
+certificates:
+  - domains: [example.com, www.example.com]
+    keyType: rsa2048
+    profile: shortlived
+    challenge: http-01
+  - domains: ["*.example.org"]
+    challenge: dns-01
+    mustStaple: true
+`)
+
+	config, err := readBatchConfig(filename)
+	require.NoError(t, err)
+
+	expected := &batchConfig{
+		Certificates: []batchCertificate{
+			{Domains: []string{"example.com", "www.example.com"}, KeyType: "rsa2048", Profile: "shortlived", Challenge: "http-01"},
+			{Domains: []string{"*.example.org"}, Challenge: "dns-01", MustStaple: true},
+		},
+	}
+
+	assert.Equal(t, expected, config)
+}
+
+func Test_readBatchConfig_error(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		content  string
+		expected string
+	}{
+		{
+			desc:     "no certificates",
+			content:  "certificates: []",
+			expected: "no certificates",
+		},
+		{
+			desc:     "no domains",
+			content:  "certificates: [{profile: shortlived}]",
+			expected: "certificates[0]: no domains",
+		},
+		{
+			desc:     "duplicated certificate",
+			content:  "certificates: [{domains: [example.com]}, {domains: [example.com, www.example.com]}]",
+			expected: "certificates[1]: duplicated certificate example.com",
+		},
+		{
+			desc:     "invalid key type",
+			content:  "certificates: [{domains: [example.com], keyType: dsa}]",
+			expected: "certificates[0]: unsupported key type: dsa",
+		},
+		{
+			desc:     "invalid challenge",
+			content:  "certificates: [{domains: [example.com], challenge: smtp-01}]",
+			expected: "certificates[0]: unsupported challenge: smtp-01",
+		},
+		{
+			desc:     "unknown field",
+			content:  "certificates: [{domains: [example.com], foo: bar}]",
+			expected: "yaml: unmarshal errors:\n  line 1: field foo not found in type cmd.batchCertificate",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := readBatchConfig(writeBatchConfig(t, test.content))
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func Test_reportBatch(t *testing.T) {
+	results := []batchResult{
+		{domain: "example.com", notAfter: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{domain: "example.org", err: errors.New("oops")},
+	}
+
+	require.EqualError(t, reportBatch(results), "1 certificate(s) could not be obtained")
+
+	require.NoError(t, reportBatch(results[:1]))
+}
+
+func writeBatchConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "certificates.yml")
+
+	err := os.WriteFile(filename, []byte(content), 0o600)
+	require.NoError(t, err)
+
+	return filename
+}
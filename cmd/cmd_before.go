@@ -6,11 +6,16 @@ import (
 )
 
 func Before(ctx *cli.Context) error {
+	err := setupLogger(ctx)
+	if err != nil {
+		log.Fatalf("Could not configure the logs: %v", err)
+	}
+
 	if ctx.String(flgPath) == "" {
 		log.Fatalf("Could not determine current working directory. Please pass --%s.", flgPath)
 	}
 
-	err := createNonExistingFolder(ctx.String(flgPath))
+	err = createNonExistingFolder(ctx.String(flgPath))
 	if err != nil {
 		log.Fatalf("Could not check/create path: %v", err)
 	}
@@ -19,5 +24,12 @@ func Before(ctx *cli.Context) error {
 		log.Fatalf("Could not determine current working server. Please pass --%s.", flgServer)
 	}
 
+	if ctx.String(flgMetricsAddr) != "" {
+		err = startMetricsServer(ctx)
+		if err != nil {
+			log.Fatalf("Could not start the metrics server: %v", err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/urfave/cli/v2"
+)
+
+// Flag names.
+const (
+	flgCheckInterval    = "check-interval"
+	flgRetryInterval    = "retry-interval"
+	flgRetryMaxInterval = "retry-max-interval"
+)
+
+func createDaemon() *cli.Command {
+	return &cli.Command{
+		Name: "daemon",
+		Usage: "Run as a long-running process that renews all the certificates of the storage when needed." +
+			" Send SIGHUP to reload the certificates and the account.",
+		Action: daemon,
+		Before: func(ctx *cli.Context) error {
+			if ctx.Int(flgRenewDays) < 0 {
+				log.Fatalf("--%s must be positive", flgRenewDays)
+			}
+
+			if ctx.Duration(flgCheckInterval) <= 0 || ctx.Duration(flgRetryInterval) <= 0 {
+				log.Fatalf("--%s and --%s must be positive", flgCheckInterval, flgRetryInterval)
+			}
+
+			if ctx.Duration(flgRetryMaxInterval) < ctx.Duration(flgRetryInterval) {
+				log.Fatalf("--%s must be greater than --%s", flgRetryMaxInterval, flgRetryInterval)
+			}
+
+			return nil
+		},
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  flgRenewDays,
+				Value: 30,
+				Usage: "The number of days left on a certificate to renew it.",
+			},
+			&cli.BoolFlag{
+				Name:  flgRenewDynamic,
+				Value: false,
+				Usage: "Compute dynamically, based on the lifetime of the certificate(s), when to renew: use 1/3rd of the lifetime left, or 1/2 of the lifetime for short-lived certificates). This supersedes --days.",
+			},
+			&cli.BoolFlag{
+				Name:  flgARIDisable,
+				Usage: "Do not use the renewalInfo endpoint (RFC9773) to check if a certificate should be renewed.",
+			},
+			&cli.DurationFlag{
+				Name:  flgCheckInterval,
+				Usage: "The maximum duration between two checks of a certificate (renewalInfo endpoint).",
+				Value: 6 * time.Hour,
+			},
+			&cli.DurationFlag{
+				Name:  flgRetryInterval,
+				Usage: "The duration to wait before retrying a failed renewal. The duration is doubled after each consecutive failure.",
+				Value: 10 * time.Minute,
+			},
+			&cli.DurationFlag{
+				Name:  flgRetryMaxInterval,
+				Usage: "The maximum duration to wait before retrying a failed renewal.",
+				Value: 24 * time.Hour,
+			},
+			&cli.BoolFlag{
+				Name:  flgReuseKey,
+				Usage: "Used to indicate you want to reuse your current private key for the new certificate.",
+			},
+			&cli.BoolFlag{
+				Name:  flgNoBundle,
+				Usage: "Do not create a certificate bundle by adding the issuers certificate to the new certificate.",
+			},
+			&cli.BoolFlag{
+				Name:  flgMustStaple,
+				Usage: "Include the OCSP must staple TLS extension in the CSR and generated certificate.",
+			},
+			&cli.StringFlag{
+				Name: flgPreferredChain,
+				Usage: "If the CA offers multiple certificate chains, prefer the chain with an issuer matching this Subject Common Name." +
+					" If no match, the default offered chain will be used.",
+			},
+			&cli.StringFlag{
+				Name:  flgProfile,
+				Usage: "If the CA offers multiple certificate profiles (draft-ietf-acme-profiles), choose this one.",
+			},
+			&cli.BoolFlag{
+				Name:  flgAlwaysDeactivateAuthorizations,
+				Usage: "Force the authorizations to be relinquished even if the certificate request was successful.",
+			},
+			&cli.StringFlag{
+				Name:  flgRenewHook,
+				Usage: "Define a hook. The hook is executed only when the certificates are effectively renewed.",
+			},
+			&cli.DurationFlag{
+				Name:  flgRenewHookTimeout,
+				Usage: "Define the timeout for the hook execution.",
+				Value: 2 * time.Minute,
+			},
+		},
+	}
+}
+
+func daemon(ctx *cli.Context) error {
+	certsStorage := NewCertificatesStorage(ctx)
+
+	d := &renewalDaemon{
+		cliCtx:       ctx,
+		certsStorage: certsStorage,
+		schedule:     NewSchedule(certsStorage.backend),
+	}
+
+	err := d.schedule.Load()
+	if err != nil {
+		return err
+	}
+
+	err = d.reload()
+	if err != nil {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	for {
+		d.processDueEntries(ctx.Context)
+
+		err = d.schedule.Save()
+		if err != nil {
+			log.Warnf("daemon: %v", err)
+		}
+
+		delay := ctx.Duration(flgCheckInterval)
+		if next, ok := d.schedule.Next(); ok {
+			delay = max(time.Until(next), 0)
+
+			log.Infof("daemon: next check at %s", next.Format(time.RFC3339))
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Context.Done():
+			timer.Stop()
+
+			log.Infof("daemon: stopped: %v", context.Cause(ctx.Context))
+
+			return nil
+
+		case <-hup:
+			timer.Stop()
+
+			log.Infof("daemon: reloading")
+
+			err = d.reload()
+			if err != nil {
+				log.Warnf("daemon: reload failed, the previous configuration is kept: %v", err)
+			}
+
+		case <-timer.C:
+		}
+	}
+}
+
+type renewalDaemon struct {
+	cliCtx *cli.Context
+
+	certsStorage *CertificatesStorage
+	schedule     *Schedule
+
+	account *Account
+	client  *lego.Client
+}
+
+// reload (re)loads the account, the client, and the list of certificates to manage.
+// On error, the previous account and client are kept.
+func (d *renewalDaemon) reload() error {
+	accountsStorage, err := tryNewAccountsStorage(d.cliCtx)
+	if err != nil {
+		return err
+	}
+
+	account, keyType, err := trySetupAccount(d.cliCtx, accountsStorage)
+	if err != nil {
+		return err
+	}
+
+	if account.Registration == nil {
+		return fmt.Errorf("account %s is not registered, use 'run' to register a new account", account.Email)
+	}
+
+	client, err := trySetupClient(d.cliCtx, account, keyType)
+	if err != nil {
+		return err
+	}
+
+	domains, err := d.findDomains()
+	if err != nil {
+		return err
+	}
+
+	d.account = account
+	d.client = client
+
+	d.schedule.Sync(domains, time.Now())
+
+	log.Infof("daemon: %d certificate(s) managed", len(domains))
+
+	return nil
+}
+
+// findDomains returns the main domains of the certificates that can be renewed by the daemon.
+func (d *renewalDaemon) findDomains() ([]string, error) {
+	matches, err := d.certsStorage.ListDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+
+	for _, name := range matches {
+		data, err := d.certsStorage.ReadFile(name, certExt)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := certcrypto.ParsePEMCertificate(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		domain, err := certcrypto.GetCertificateMainDomain(cert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		hasKey, err := d.certsStorage.tryExistsFile(domain, keyExt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		// The private key of a certificate obtained from a CSR is unknown: the CSR cannot be rebuilt.
+		if !hasKey {
+			log.Warn("daemon: no private key found (certificate obtained from a CSR?): ignored.", log.AttrDomain, domain)
+			continue
+		}
+
+		metrics.CertificateExpiry(domain, cert.NotAfter)
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
+
+func (d *renewalDaemon) processDueEntries(ctx context.Context) {
+	for _, entry := range d.schedule.Due(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		next, err := d.process(ctx, entry.Domain)
+		if err != nil {
+			d.schedule.Fail(entry.Domain, err, time.Now(),
+				d.cliCtx.Duration(flgRetryInterval), d.cliCtx.Duration(flgRetryMaxInterval))
+
+			log.Warn("daemon: renewal failed.",
+				log.AttrDomain, entry.Domain, "attempt", entry.Failures, "nextAttempt", entry.NextRun.Format(time.RFC3339), log.AttrError, err)
+
+			continue
+		}
+
+		d.schedule.Succeed(entry.Domain, next)
+	}
+}
+
+// process checks a certificate, renews it if needed, and returns the time of the next check.
+func (d *renewalDaemon) process(ctx context.Context, domain string) (time.Time, error) {
+	// The lock prevents another instance, sharing the same storage, to renew the certificate at the same time.
+	unlock, err := d.certsStorage.Lock(domain)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer releaseLock(domain, unlock)
+
+	cert, err := d.readCertificate(domain)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The certificate may have been renewed by another instance sharing the same storage.
+	metrics.CertificateExpiry(domain, cert.NotAfter)
+
+	now := time.Now().UTC()
+
+	next := d.getNextCheck(ctx, domain, cert, now)
+	if next.After(now) {
+		return next, nil
+	}
+
+	err = d.renew(ctx, domain, cert)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cert, err = d.readCertificate(domain)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	metrics.CertificateExpiry(domain, cert.NotAfter)
+
+	now = time.Now().UTC()
+
+	next = d.getNextCheck(ctx, domain, cert, now)
+	if !next.After(now) {
+		// Avoids a renewal loop when the renewal threshold is greater than the lifetime of the certificates.
+		log.Warn("daemon: the new certificate is already due for renewal, check the renewal threshold.", log.AttrDomain, domain)
+
+		next = now.Add(d.cliCtx.Duration(flgCheckInterval))
+	}
+
+	return next, nil
+}
+
+// getNextCheck computes when the certificate should be renewed, or checked again.
+func (d *renewalDaemon) getNextCheck(ctx context.Context, domain string, cert *x509.Certificate, now time.Time) time.Time {
+	next := getDueDate(cert, d.cliCtx.Int(flgRenewDays), d.cliCtx.Bool(flgRenewDynamic))
+
+	if d.cliCtx.Bool(flgARIDisable) {
+		return next
+	}
+
+	checkInterval := d.cliCtx.Duration(flgCheckInterval)
+
+	renewalInfo, err := d.client.Certificate.GetRenewalInfoWithContext(ctx, certificate.RenewalInfoRequest{Cert: cert})
+	if err != nil {
+		if !errors.Is(err, api.ErrNoARI) {
+			log.Warn("acme: calling renewal info endpoint failed.", log.AttrDomain, domain, log.AttrError, err)
+		}
+
+		return next
+	}
+
+	if renewalTime := renewalInfo.ShouldRenewAt(now, checkInterval); renewalTime != nil {
+		if renewalInfo.ExplanationURL != "" {
+			log.Info("acme: renewalInfo endpoint provided an explanation", log.AttrDomain, domain, "explanationURL", renewalInfo.ExplanationURL)
+		}
+
+		return earliest(next, *renewalTime)
+	}
+
+	// The server recommends a polling interval.
+	return earliest(next, now.Add(max(checkInterval, renewalInfo.RetryAfter)))
+}
+
+func (d *renewalDaemon) renew(ctx context.Context, domain string, cert *x509.Certificate) error {
+	log.Info("acme: Trying renewal", log.AttrDomain, domain, "hoursRemaining", int(time.Until(cert.NotAfter).Hours()))
+
+	var privateKey crypto.PrivateKey
+
+	if d.cliCtx.Bool(flgReuseKey) {
+		keyBytes, err := d.certsStorage.ReadFile(domain, keyExt)
+		if err != nil {
+			return fmt.Errorf("load private key: %w", err)
+		}
+
+		privateKey, err = certcrypto.ParsePEMPrivateKey(keyBytes)
+		if err != nil {
+			return fmt.Errorf("parse private key: %w", err)
+		}
+	}
+
+	request := certificate.ObtainRequest{
+		Domains:                        certcrypto.ExtractDomains(cert),
+		PrivateKey:                     privateKey,
+		MustStaple:                     d.cliCtx.Bool(flgMustStaple),
+		Bundle:                         !d.cliCtx.Bool(flgNoBundle),
+		PreferredChain:                 d.cliCtx.String(flgPreferredChain),
+		Profile:                        d.cliCtx.String(flgProfile),
+		AlwaysDeactivateAuthorizations: d.cliCtx.Bool(flgAlwaysDeactivateAuthorizations),
+	}
+
+	if !d.cliCtx.Bool(flgARIDisable) {
+		replacesCertID, err := certificate.MakeARICertID(cert)
+		if err != nil {
+			return fmt.Errorf("construct the ARI CertID: %w", err)
+		}
+
+		request.ReplacesCertID = replacesCertID
+	}
+
+	certRes, err := d.client.Certificate.ObtainWithContext(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	certRes.Domain = domain
+
+	d.certsStorage.SaveResource(certRes)
+
+	meta := map[string]string{
+		hookEnvAccountEmail: d.account.Email,
+	}
+
+	addPathToMetadata(meta, domain, certRes, d.certsStorage)
+
+	// The certificate is renewed: a hook failure must not trigger a new renewal.
+	err = launchHook(d.cliCtx.String(flgRenewHook), d.cliCtx.Duration(flgRenewHookTimeout), meta)
+	if err != nil {
+		log.Warn("daemon: hook failed.", log.AttrDomain, domain, log.AttrError, err)
+	}
+
+	return nil
+}
+
+func (d *renewalDaemon) readCertificate(domain string) (*x509.Certificate, error) {
+	certificates, err := d.certsStorage.ReadCertificate(domain, certExt)
+	if err != nil {
+		return nil, fmt.Errorf("load the certificate: %w", err)
+	}
+
+	cert := certificates[0]
+
+	if cert.IsCA {
+		return nil, errors.New("certificate bundle starts with a CA certificate")
+	}
+
+	return cert, nil
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
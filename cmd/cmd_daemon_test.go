@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renewalDaemon_findDomains(t *testing.T) {
+	recorder := &expiryRecorder{expiries: make(map[string]time.Time)}
+
+	metrics.SetRecorder(recorder)
+	t.Cleanup(func() { metrics.SetRecorder(nil) })
+
+	certsStorage := newTestCertificatesStorage(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second).UTC()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	err = os.WriteFile(filepath.Join(certsStorage.GetRootPath(), "example.com"+certExt), cert, 0o600)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(certsStorage.GetRootPath(), "example.com"+keyExt), certcrypto.PEMEncode(privateKey), 0o600)
+	require.NoError(t, err)
+
+	d := &renewalDaemon{certsStorage: certsStorage}
+
+	domains, err := d.findDomains()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"example.com"}, domains)
+
+	// The expiration dates of the loaded certificates are recorded.
+	assert.Equal(t, map[string]time.Time{"example.com": notAfter}, recorder.expiries)
+}
+
+type expiryRecorder struct {
+	metrics.NoopRecorder
+
+	expiries map[string]time.Time
+}
+
+func (r *expiryRecorder) CertificateExpiry(domain string, notAfter time.Time) {
+	r.expiries[domain] = notAfter
+}
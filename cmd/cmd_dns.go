@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01/dnsserver"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+// Flag names.
+const (
+	flgDNSZone        = "zone"
+	flgDNSListen      = "listen"
+	flgDNSNameserver  = "nameserver"
+	flgDNSAdmin       = "admin"
+	flgDNSTTL         = "ttl"
+	flgDNSRecordsDir  = "records-dir"
+	flgDNSAPI         = "api"
+	flgDNSAPIAccounts = "api-accounts"
+)
+
+func createDNS() *cli.Command {
+	return &cli.Command{
+		Name:  "dns",
+		Usage: "Built-in DNS server for the delegated DNS-01 challenges.",
+		Subcommands: []*cli.Command{
+			createDNSServe(),
+		},
+	}
+}
+
+func createDNSServe() *cli.Command {
+	return &cli.Command{
+		Name: "serve",
+		Usage: "Run an authoritative DNS server for a challenge zone." +
+			" The `_acme-challenge` records of the domains must be CNAMEs pointing inside the zone, and the zone must be delegated to the server.",
+		Action: dnsServe,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     flgDNSZone,
+				Usage:    "The challenge zone (e.g. acme.example.com).",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  flgDNSListen,
+				Usage: "The address (UDP and TCP) of the DNS server.",
+				Value: ":53",
+			},
+			&cli.StringFlag{
+				Name:  flgDNSNameserver,
+				Usage: "The hostname of the DNS server, used in the NS and SOA records. By default, ns.<zone>.",
+			},
+			&cli.StringFlag{
+				Name:  flgDNSAdmin,
+				Usage: "The mailbox of the administrator, used in the SOA record. By default, hostmaster.<zone>.",
+			},
+			&cli.UintFlag{
+				Name:  flgDNSTTL,
+				Usage: "The TTL of the records.",
+				Value: dnsserver.DefaultTTL,
+			},
+			&cli.StringFlag{
+				Name: flgDNSRecordsDir,
+				Usage: "The directory of the TXT records, shared with the 'dnsserver' DNS provider." +
+					" By default, the records are only kept in memory (only the records created through the API are served).",
+			},
+			&cli.StringFlag{
+				Name:  flgDNSAPI,
+				Usage: "Enable the acme-dns compatible HTTP API on the address (e.g. :8053), used by the 'acme-dns' DNS provider.",
+			},
+			&cli.StringFlag{
+				Name:  flgDNSAPIAccounts,
+				Usage: "The file of the accounts of the HTTP API. By default, <path>/dns/accounts.json.",
+			},
+		},
+	}
+}
+
+func dnsServe(ctx *cli.Context) error {
+	var store dnsserver.Store = dnsserver.NewMemoryStore()
+	if ctx.IsSet(flgDNSRecordsDir) {
+		store = dnsserver.DirStore(ctx.String(flgDNSRecordsDir))
+	}
+
+	server, err := dnsserver.NewServer(store, dnsserver.Options{
+		Zone:       ctx.String(flgDNSZone),
+		Nameserver: ctx.String(flgDNSNameserver),
+		Admin:      ctx.String(flgDNSAdmin),
+		TTL:        uint32(ctx.Uint(flgDNSTTL)), //nolint:gosec // the TTL is a 32 bits value.
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if ctx.IsSet(flgDNSAPI) {
+		err = startDNSAPI(ctx, server.Zone(), store)
+		if err != nil {
+			log.Fatalf("Could not start the API: %v", err)
+		}
+	}
+
+	return server.ListenAndServe(ctx.Context, ctx.String(flgDNSListen))
+}
+
+// startDNSAPI starts the acme-dns compatible HTTP API.
+func startDNSAPI(ctx *cli.Context, zone string, store dnsserver.Store) error {
+	accountsFile := ctx.String(flgDNSAPIAccounts)
+	if accountsFile == "" {
+		accountsFile = filepath.Join(ctx.String(flgPath), "dns", "accounts.json")
+	}
+
+	api, err := dnsserver.NewAPI(zone, store, accountsFile)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", ctx.String(flgDNSAPI))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Infof("API available on http://%s", listener.Addr())
+
+	go func() {
+		errS := server.Serve(listener)
+		if errS != nil && !errors.Is(errS, http.ErrServerClosed) {
+			log.Warnf("The API server has stopped: %v", errS)
+		}
+	}()
+
+	go func() {
+		<-ctx.Context.Done()
+
+		_ = server.Close()
+	}()
+
+	return nil
+}
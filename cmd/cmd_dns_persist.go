@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/challenge/dnspersist01"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/providers/dns"
+	"github.com/urfave/cli/v2"
+)
+
+// Flag names.
+const (
+	flgWildcard     = "wildcard"
+	flgPersistUntil = "persist-until"
+)
+
+func createDNSPersist() *cli.Command {
+	flags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  flgWildcard,
+			Usage: "Allow the issuance of wildcard certificates (policy=wildcard).",
+		},
+		&cli.TimestampFlag{
+			Name:   flgPersistUntil,
+			Usage:  "Set the time after which the record is no longer valid (RFC3339 format). By default, the record doesn't expire.",
+			Layout: time.RFC3339,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "dns-persist",
+		Usage: "Manage the persistent TXT records of the DNS-PERSIST-01 challenge.",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "record",
+				Usage:  "Display the TXT records binding the domains to the account.",
+				Action: dnsPersistRecord,
+				Flags:  flags,
+			},
+			{
+				Name: "setup",
+				Usage: "Create the TXT records binding the domains to the account, using the DNS provider defined by --" + flgDNS + "." +
+					" The provider must support arbitrary TXT records: " + strings.Join(recordProviders, ", ") + ".",
+				Action: dnsPersistSetup,
+				Flags:  flags,
+			},
+		},
+	}
+}
+
+func dnsPersistRecord(ctx *cli.Context) error {
+	record := getDNSPersistRecord(ctx)
+
+	for _, domain := range ctx.StringSlice(flgDomains) {
+		fmt.Printf("%s IN TXT %q\n", dnspersist01.GetRecordFQDN(domain), record.Value())
+	}
+
+	return nil
+}
+
+func dnsPersistSetup(ctx *cli.Context) error {
+	if !ctx.IsSet(flgDNS) {
+		log.Fatalf("A DNS provider is required: use --%s.", flgDNS)
+	}
+
+	err := checkRecordProvider(ctx.String(flgDNS), "the setup of the DNS-PERSIST-01 records")
+	if err != nil {
+		return err
+	}
+
+	provider, err := dns.NewDNSChallengeProviderByName(ctx.String(flgDNS))
+	if err != nil {
+		return err
+	}
+
+	record := getDNSPersistRecord(ctx)
+
+	for _, domain := range ctx.StringSlice(flgDomains) {
+		fqdn := dnspersist01.GetRecordFQDN(domain)
+
+		err = dns01.PresentRecord(provider, domain, dns01.Record{FQDN: fqdn, Value: record.Value()})
+		if err != nil {
+			log.Fatalf("Could not create the TXT record %s: %v", fqdn, err)
+		}
+
+		log.Info("The DNS-PERSIST-01 record has been created.", log.AttrDomain, domain, "fqdn", fqdn, "value", record.Value())
+	}
+
+	return nil
+}
+
+// getDNSPersistRecord returns the record binding the domains to the current account.
+func getDNSPersistRecord(ctx *cli.Context) dnspersist01.Record {
+	if len(ctx.StringSlice(flgDomains)) == 0 {
+		log.Fatalf("Please specify --%s or -d", flgDomains)
+	}
+
+	if !ctx.IsSet(flgDNSPersistIssuer) {
+		log.Fatalf("The issuer domain name of the CA is required: use --%s.", flgDNSPersistIssuer)
+	}
+
+	account, _ := setupAccount(ctx, NewAccountsStorage(ctx))
+
+	if account.Registration == nil {
+		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", account.Email)
+	}
+
+	return dnspersist01.Record{
+		IssuerDomainName: ctx.String(flgDNSPersistIssuer),
+		AccountURI:       account.Registration.URI,
+		Wildcard:         ctx.Bool(flgWildcard),
+		PersistUntil:     getTime(ctx, flgPersistUntil),
+	}
+}
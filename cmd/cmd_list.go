@@ -1,21 +1,35 @@
 package cmd
 
 import (
+	"cmp"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/log"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
 )
 
+// Flag names.
 const (
-	flgAccounts = "accounts"
-	flgNames    = "names"
+	flgAccounts   = "accounts"
+	flgNames      = "names"
+	flgListFormat = "format"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
 )
 
 func createList() *cli.Command {
@@ -23,6 +37,15 @@ func createList() *cli.Command {
 		Name:   "list",
 		Usage:  "Display certificates and accounts information.",
 		Action: list,
+		Before: func(ctx *cli.Context) error {
+			switch ctx.String(flgListFormat) {
+			case formatText, formatJSON, formatYAML:
+			default:
+				log.Fatalf("Invalid format: %s. Supported: %s, %s, %s.", ctx.String(flgListFormat), formatText, formatJSON, formatYAML)
+			}
+
+			return nil
+		},
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:    flgAccounts,
@@ -32,7 +55,17 @@ func createList() *cli.Command {
 			&cli.BoolFlag{
 				Name:    flgNames,
 				Aliases: []string{"n"},
-				Usage:   "Display certificate common names only.",
+				Usage:   "Display certificate common names only. Only with the text format.",
+			},
+			&cli.StringFlag{
+				Name:  flgListFormat,
+				Usage: "Output format. Supported: text, json, yaml.",
+				Value: formatText,
+			},
+			&cli.IntFlag{
+				Name:  flgRenewDays,
+				Value: 30,
+				Usage: "The number of days left on a certificate to consider it due for renewal.",
 			},
 			// fake email, needed by NewAccountsStorage
 			&cli.StringFlag{
@@ -44,7 +77,47 @@ func createList() *cli.Command {
 	}
 }
 
+// listOutput the structured output of the list command.
+type listOutput struct {
+	Accounts     []accountInfo     `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+	Certificates []certificateInfo `json:"certificates" yaml:"certificates"`
+}
+
+type accountInfo struct {
+	Email  string `json:"email" yaml:"email"`
+	Server string `json:"server" yaml:"server"`
+	URI    string `json:"uri" yaml:"uri"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+type certificateInfo struct {
+	Name          string           `json:"name" yaml:"name"`
+	Domains       []string         `json:"domains" yaml:"domains"`
+	IPs           []string         `json:"ips,omitempty" yaml:"ips,omitempty"`
+	NotBefore     time.Time        `json:"notBefore" yaml:"notBefore"`
+	NotAfter      time.Time        `json:"notAfter" yaml:"notAfter"`
+	Issuer        string           `json:"issuer" yaml:"issuer"`
+	ARICertID     string           `json:"ariCertId,omitempty" yaml:"ariCertId,omitempty"`
+	Profile       string           `json:"profile,omitempty" yaml:"profile,omitempty"`
+	DueForRenewal bool             `json:"dueForRenewal" yaml:"dueForRenewal"`
+	Paths         certificatePaths `json:"paths" yaml:"paths"`
+}
+
+type certificatePaths struct {
+	Certificate string `json:"certificate" yaml:"certificate"`
+	PrivateKey  string `json:"privateKey,omitempty" yaml:"privateKey,omitempty"`
+	Issuer      string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	PEM         string `json:"pem,omitempty" yaml:"pem,omitempty"`
+	PFX         string `json:"pfx,omitempty" yaml:"pfx,omitempty"`
+	Resource    string `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
 func list(ctx *cli.Context) error {
+	format := ctx.String(flgListFormat)
+	if format != formatText {
+		return listStructured(ctx, format)
+	}
+
 	if ctx.Bool(flgAccounts) && !ctx.Bool(flgNames) {
 		if err := listAccount(ctx); err != nil {
 			return err
@@ -54,17 +127,47 @@ func list(ctx *cli.Context) error {
 	return listCertificates(ctx)
 }
 
-func listCertificates(ctx *cli.Context) error {
-	certsStorage := NewCertificatesStorage(ctx)
+func listStructured(ctx *cli.Context, format string) error {
+	var output listOutput
 
-	matches, err := filepath.Glob(filepath.Join(certsStorage.GetRootPath(), "*.crt"))
+	if ctx.Bool(flgAccounts) {
+		accounts, err := readAccountsInfo(ctx)
+		if err != nil {
+			return err
+		}
+
+		output.Accounts = accounts
+	}
+
+	certificates, err := readCertificatesInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Always display an array, even if there are no certificates.
+	output.Certificates = append([]certificateInfo{}, certificates...)
+
+	switch format {
+	case formatYAML:
+		return yaml.NewEncoder(os.Stdout).Encode(output)
+
+	default:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(output)
+	}
+}
+
+func listCertificates(ctx *cli.Context) error {
+	certificates, err := readCertificatesInfo(ctx)
 	if err != nil {
 		return err
 	}
 
 	names := ctx.Bool(flgNames)
 
-	if len(matches) == 0 {
+	if len(certificates) == 0 {
 		if !names {
 			fmt.Println("No certificates found.")
 		}
@@ -76,92 +179,191 @@ func listCertificates(ctx *cli.Context) error {
 		fmt.Println("Found the following certs:")
 	}
 
-	for _, filename := range matches {
-		if strings.HasSuffix(filename, issuerExt) {
-			continue
+	for _, info := range certificates {
+		if names {
+			fmt.Println(info.Name)
+		} else {
+			fmt.Println("  Certificate Name:", info.Name)
+			fmt.Println("    Domains:", strings.Join(info.Domains, ", "))
+
+			if len(info.IPs) > 0 {
+				fmt.Println("    IPs:", strings.Join(info.IPs, ", "))
+			}
+
+			fmt.Println("    Expiry Date:", info.NotAfter)
+			fmt.Println("    Certificate Path:", info.Paths.Certificate)
+			fmt.Println()
 		}
+	}
+
+	return nil
+}
+
+func listAccount(ctx *cli.Context) error {
+	accounts, err := readAccountsInfo(ctx)
+	if err != nil {
+		return err
+	}
 
-		data, err := os.ReadFile(filename)
+	if len(accounts) == 0 {
+		fmt.Println("No accounts found.")
+		return nil
+	}
+
+	fmt.Println("Found the following accounts:")
+
+	for _, info := range accounts {
+		fmt.Println("  Email:", info.Email)
+		fmt.Println("  Server:", info.Server)
+		fmt.Println("  Path:", info.Path)
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func readCertificatesInfo(ctx *cli.Context) ([]certificateInfo, error) {
+	certsStorage := NewCertificatesStorage(ctx)
+
+	matches, err := certsStorage.ListDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	var certificates []certificateInfo
+
+	for _, domain := range matches {
+		data, err := certsStorage.ReadFile(domain, certExt)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		pCert, err := certcrypto.ParsePEMCertificate(data)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		name, err := certcrypto.GetCertificateMainDomain(pCert)
+		info, err := newCertificateInfo(certsStorage, domain, pCert, ctx.Int(flgRenewDays))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if names {
-			fmt.Println(name)
-		} else {
-			fmt.Println("  Certificate Name:", name)
-			fmt.Println("    Domains:", strings.Join(pCert.DNSNames, ", "))
+		certificates = append(certificates, info)
+	}
 
-			if len(pCert.IPAddresses) > 0 {
-				fmt.Println("    IPs:", formatIPAddresses(pCert.IPAddresses))
-			}
+	return certificates, nil
+}
 
-			fmt.Println("    Expiry Date:", pCert.NotAfter)
-			fmt.Println("    Certificate Path:", filename)
-			fmt.Println()
+func newCertificateInfo(certsStorage *CertificatesStorage, domain string, pCert *x509.Certificate, days int) (certificateInfo, error) {
+	name, err := certcrypto.GetCertificateMainDomain(pCert)
+	if err != nil {
+		return certificateInfo{}, err
+	}
+
+	info := certificateInfo{
+		Name:          name,
+		Domains:       pCert.DNSNames,
+		IPs:           formatIPAddresses(pCert.IPAddresses),
+		NotBefore:     pCert.NotBefore,
+		NotAfter:      pCert.NotAfter,
+		Issuer:        cmp.Or(pCert.Issuer.CommonName, pCert.Issuer.String()),
+		DueForRenewal: isDueForRenewal(pCert, days, time.Now()),
+		Paths: certificatePaths{
+			Certificate: certsStorage.GetFileName(domain, certExt),
+		},
+	}
+
+	// The ARI CertID requires the Authority Key Identifier extension.
+	if certID, errID := certificate.MakeARICertID(pCert); errID == nil {
+		info.ARICertID = certID
+	}
+
+	optionalPaths := map[string]*string{
+		keyExt:      &info.Paths.PrivateKey,
+		issuerExt:   &info.Paths.Issuer,
+		pemExt:      &info.Paths.PEM,
+		pfxExt:      &info.Paths.PFX,
+		resourceExt: &info.Paths.Resource,
+	}
+
+	for ext, value := range optionalPaths {
+		if certsStorage.ExistsFile(domain, ext) {
+			*value = certsStorage.GetFileName(domain, ext)
 		}
 	}
 
-	return nil
+	if info.Paths.Resource != "" {
+		raw, err := certsStorage.ReadFile(domain, resourceExt)
+		if err != nil {
+			return certificateInfo{}, err
+		}
+
+		var resource certificate.Resource
+
+		err = json.Unmarshal(raw, &resource)
+		if err != nil {
+			return certificateInfo{}, fmt.Errorf("%s: %w", info.Paths.Resource, err)
+		}
+
+		info.Profile = resource.Profile
+	}
+
+	return info, nil
 }
 
-func listAccount(ctx *cli.Context) error {
+func readAccountsInfo(ctx *cli.Context) ([]accountInfo, error) {
 	accountsStorage := NewAccountsStorage(ctx)
 
-	matches, err := filepath.Glob(filepath.Join(accountsStorage.GetRootPath(), "*", "*", "*.json"))
+	matches, err := accountsStorage.listAccountFiles()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(matches) == 0 {
-		fmt.Println("No accounts found.")
-		return nil
-	}
-
-	fmt.Println("Found the following accounts:")
+	var accounts []accountInfo
 
 	for _, filename := range matches {
-		data, err := os.ReadFile(filename)
+		data, err := accountsStorage.backend.ReadFile(filename)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var account Account
 
 		err = json.Unmarshal(data, &account)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		uri, err := url.Parse(account.Registration.URI)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		fmt.Println("  Email:", account.Email)
-		fmt.Println("  Server:", uri.Host)
-		fmt.Println("  Path:", filepath.Dir(filename))
-		fmt.Println()
+		accounts = append(accounts, accountInfo{
+			Email:  account.Email,
+			Server: uri.Host,
+			URI:    account.Registration.URI,
+			Path:   accountsStorage.backend.Location(path.Dir(filename)),
+		})
 	}
 
-	return nil
+	return accounts, nil
+}
+
+// isDueForRenewal uses the same rule as the renew command.
+func isDueForRenewal(x509Cert *x509.Certificate, days int, now time.Time) bool {
+	if days < 0 {
+		return true
+	}
+
+	return int(x509Cert.NotAfter.Sub(now).Hours()/24.0) <= days
 }
 
-func formatIPAddresses(ipAddresses []net.IP) string {
+func formatIPAddresses(ipAddresses []net.IP) []string {
 	var ips []string
 	for _, ip := range ipAddresses {
 		ips = append(ips, ip.String())
 	}
 
-	return strings.Join(ips, ", ")
+	return ips
 }
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newCertificateInfo(t *testing.T) {
+	certsStorage := newTestCertificatesStorage(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1234),
+		Subject:        pkix.Name{CommonName: "example.com"},
+		DNSNames:       []string{"example.com", "*.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("192.0.2.1")},
+		NotBefore:      notBefore,
+		NotAfter:       notBefore.Add(90 * 24 * time.Hour),
+		AuthorityKeyId: []byte{0x01, 0x02, 0x03},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+
+	pCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	require.NoError(t, certsStorage.WriteFile("example.com", certExt, certcrypto.PEMEncode(certcrypto.DERCertificateBytes(der))))
+	require.NoError(t, certsStorage.WriteFile("example.com", keyExt, certcrypto.PEMEncode(privateKey)))
+	require.NoError(t, certsStorage.WriteFile("example.com", resourceExt, []byte(`{"domain":"example.com","profile":"shortlived"}`)))
+
+	info, err := newCertificateInfo(certsStorage, "example.com", pCert, 30)
+	require.NoError(t, err)
+
+	expected := certificateInfo{
+		Name:          "example.com",
+		Domains:       []string{"example.com", "*.example.com"},
+		IPs:           []string{"192.0.2.1"},
+		NotBefore:     notBefore,
+		NotAfter:      notBefore.Add(90 * 24 * time.Hour),
+		Issuer:        "example.com",
+		ARICertID:     "AQID.BNI",
+		Profile:       "shortlived",
+		DueForRenewal: true,
+		Paths: certificatePaths{
+			Certificate: certsStorage.GetFileName("example.com", certExt),
+			PrivateKey:  certsStorage.GetFileName("example.com", keyExt),
+			Resource:    certsStorage.GetFileName("example.com", resourceExt),
+		},
+	}
+
+	assert.Equal(t, expected, info)
+}
+
+func Test_isDueForRenewal(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc     string
+		notAfter time.Time
+		days     int
+		expected bool
+	}{
+		{
+			desc:     "expired",
+			notAfter: now.Add(-time.Hour),
+			days:     30,
+			expected: true,
+		},
+		{
+			desc:     "30 days left",
+			notAfter: now.Add(30 * 24 * time.Hour),
+			days:     30,
+			expected: true,
+		},
+		{
+			desc:     "31 days left",
+			notAfter: now.Add(31*24*time.Hour + time.Second),
+			days:     30,
+			expected: false,
+		},
+		{
+			desc:     "always",
+			notAfter: now.Add(90 * 24 * time.Hour),
+			days:     -1,
+			expected: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			due := isDueForRenewal(&x509.Certificate{NotAfter: test.notAfter}, test.days, now)
+			assert.Equal(t, test.expected, due)
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/wait"
 	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli/v2"
 )
@@ -161,6 +162,13 @@ func renewForDomains(ctx *cli.Context, account *Account, keyType certcrypto.KeyT
 	domains := ctx.StringSlice(flgDomains)
 	domain := domains[0]
 
+	unlock, err := certsStorage.Lock(domain)
+	if err != nil {
+		log.Fatalf("Could not lock the certificate for domain %s: %v", domain, err)
+	}
+
+	defer releaseLock(domain, unlock)
+
 	// load the cert resource from files.
 	// We store the certificate, private key and metadata in different files
 	// as web servers would not be able to work with a combined file.
@@ -187,8 +195,12 @@ func renewForDomains(ctx *cli.Context, account *Account, keyType certcrypto.KeyT
 
 			// Figure out if we need to sleep before renewing.
 			if ariRenewalTime.After(now) {
-				log.Infof("[%s] Sleeping %s until renewal time %s", domain, ariRenewalTime.Sub(now), ariRenewalTime)
-				time.Sleep(ariRenewalTime.Sub(now))
+				log.Info("Sleeping until renewal time.", log.AttrDomain, domain, "duration", ariRenewalTime.Sub(now), "renewalTime", ariRenewalTime)
+
+				err = wait.Sleep(ctx.Context, ariRenewalTime.Sub(now))
+				if err != nil {
+					return err
+				}
 			}
 		}
 
@@ -213,7 +225,7 @@ func renewForDomains(ctx *cli.Context, account *Account, keyType certcrypto.KeyT
 
 	// This is just meant to be informal for the user.
 	timeLeft := cert.NotAfter.Sub(time.Now().UTC())
-	log.Infof("[%s] acme: Trying renewal with %d hours remaining", domain, int(timeLeft.Hours()))
+	log.Info("acme: Trying renewal", log.AttrDomain, domain, "hoursRemaining", int(timeLeft.Hours()))
 
 	var privateKey crypto.PrivateKey
 
@@ -239,7 +251,11 @@ func renewForDomains(ctx *cli.Context, account *Account, keyType certcrypto.KeyT
 		sleepTime := time.Duration(rnd.Int63n(int64(jitter)))
 
 		log.Infof("renewal: random delay of %s", sleepTime)
-		time.Sleep(sleepTime)
+
+		err = wait.Sleep(ctx.Context, sleepTime)
+		if err != nil {
+			return err
+		}
 	}
 
 	renewalDomains := slices.Clone(domains)
@@ -263,9 +279,10 @@ func renewForDomains(ctx *cli.Context, account *Account, keyType certcrypto.KeyT
 		request.ReplacesCertID = replacesCertID
 	}
 
-	certRes, err := client.Certificate.Obtain(request)
+	certRes, err := client.Certificate.ObtainWithContext(ctx.Context, request)
 	if err != nil {
-		log.Fatal(err)
+		// The error is returned (instead of calling log.Fatal) to release the lock.
+		return err
 	}
 
 	certRes.Domain = domain
@@ -288,6 +305,13 @@ func renewForCSR(ctx *cli.Context, account *Account, keyType certcrypto.KeyType,
 		log.Fatalf("Error: %v", err)
 	}
 
+	unlock, err := certsStorage.Lock(domain)
+	if err != nil {
+		log.Fatalf("Could not lock the certificate for domain %s: %v", domain, err)
+	}
+
+	defer releaseLock(domain, unlock)
+
 	// load the cert resource from files.
 	// We store the certificate, private key and metadata in different files
 	// as web servers would not be able to work with a combined file.
@@ -314,8 +338,12 @@ func renewForCSR(ctx *cli.Context, account *Account, keyType certcrypto.KeyType,
 
 			// Figure out if we need to sleep before renewing.
 			if ariRenewalTime.After(now) {
-				log.Infof("[%s] Sleeping %s until renewal time %s", domain, ariRenewalTime.Sub(now), ariRenewalTime)
-				time.Sleep(ariRenewalTime.Sub(now))
+				log.Info("Sleeping until renewal time.", log.AttrDomain, domain, "duration", ariRenewalTime.Sub(now), "renewalTime", ariRenewalTime)
+
+				err = wait.Sleep(ctx.Context, ariRenewalTime.Sub(now))
+				if err != nil {
+					return err
+				}
 			}
 		}
 
@@ -335,7 +363,7 @@ func renewForCSR(ctx *cli.Context, account *Account, keyType certcrypto.KeyType,
 
 	// This is just meant to be informal for the user.
 	timeLeft := cert.NotAfter.Sub(time.Now().UTC())
-	log.Infof("[%s] acme: Trying renewal with %d hours remaining", domain, int(timeLeft.Hours()))
+	log.Info("acme: Trying renewal", log.AttrDomain, domain, "hoursRemaining", int(timeLeft.Hours()))
 
 	request := certificate.ObtainForCSRRequest{
 		CSR:                            csr,
@@ -351,9 +379,10 @@ func renewForCSR(ctx *cli.Context, account *Account, keyType certcrypto.KeyType,
 		request.ReplacesCertID = replacesCertID
 	}
 
-	certRes, err := client.Certificate.ObtainForCSR(request)
+	certRes, err := client.Certificate.ObtainForCSRWithContext(ctx.Context, request)
 	if err != nil {
-		log.Fatal(err)
+		// The error is returned (instead of calling log.Fatal) to release the lock.
+		return err
 	}
 
 	certsStorage.SaveResource(certRes)
@@ -381,47 +410,61 @@ func needRenewal(x509Cert *x509.Certificate, domain string, days int, dynamic bo
 		return true
 	}
 
-	log.Printf("[%s] The certificate expires in %d days, the number of days defined to perform the renewal is %d: no renewal.",
-		domain, notAfter, days)
+	log.Info("The certificate expires after the number of days defined to perform the renewal: no renewal.",
+		log.AttrDomain, domain, "daysRemaining", notAfter, "days", days)
 
 	return false
 }
 
 func needRenewalDynamic(x509Cert *x509.Certificate, domain string, now time.Time) bool {
-	lifetime := x509Cert.NotAfter.Sub(x509Cert.NotBefore)
-
-	var divisor int64 = 3
-	if lifetime.Round(24*time.Hour).Hours()/24.0 <= 10 {
-		divisor = 2
-	}
-
-	dueDate := x509Cert.NotAfter.Add(-1 * time.Duration(lifetime.Nanoseconds()/divisor))
+	dueDate := getDynamicDueDate(x509Cert)
 
 	if dueDate.Before(now) {
 		return true
 	}
 
-	log.Infof("[%s] The certificate expires at %s, the renewal can be performed in %s: no renewal.",
-		domain, x509Cert.NotAfter.Format(time.RFC3339), dueDate.Sub(now))
+	log.Info("The certificate renewal is not due yet: no renewal.",
+		log.AttrDomain, domain, "notAfter", x509Cert.NotAfter.Format(time.RFC3339), "dueIn", dueDate.Sub(now))
 
 	return false
 }
 
+// getDueDate returns the date from which the certificate should be renewed.
+func getDueDate(x509Cert *x509.Certificate, days int, dynamic bool) time.Time {
+	if dynamic {
+		return getDynamicDueDate(x509Cert)
+	}
+
+	return x509Cert.NotAfter.Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// getDynamicDueDate returns the date when 1/3rd of the lifetime is left (1/2 for short-lived certificates).
+func getDynamicDueDate(x509Cert *x509.Certificate) time.Time {
+	lifetime := x509Cert.NotAfter.Sub(x509Cert.NotBefore)
+
+	var divisor int64 = 3
+	if lifetime.Round(24*time.Hour).Hours()/24.0 <= 10 {
+		divisor = 2
+	}
+
+	return x509Cert.NotAfter.Add(-1 * time.Duration(lifetime.Nanoseconds()/divisor))
+}
+
 // getARIRenewalTime checks if the certificate needs to be renewed using the renewalInfo endpoint.
 func getARIRenewalTime(ctx *cli.Context, cert *x509.Certificate, domain string, client *lego.Client) *time.Time {
 	if cert.IsCA {
 		log.Fatalf("[%s] Certificate bundle starts with a CA certificate", domain)
 	}
 
-	renewalInfo, err := client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+	renewalInfo, err := client.Certificate.GetRenewalInfoWithContext(ctx.Context, certificate.RenewalInfoRequest{Cert: cert})
 	if err != nil {
 		if errors.Is(err, api.ErrNoARI) {
 			// The server does not advertise a renewal info endpoint.
-			log.Warnf("[%s] acme: %v", domain, err)
+			log.Warn("acme: renewal info not available.", log.AttrDomain, domain, log.AttrError, err)
 			return nil
 		}
 
-		log.Warnf("[%s] acme: calling renewal info endpoint: %v", domain, err)
+		log.Warn("acme: calling renewal info endpoint failed.", log.AttrDomain, domain, log.AttrError, err)
 
 		return nil
 	}
@@ -430,14 +473,14 @@ func getARIRenewalTime(ctx *cli.Context, cert *x509.Certificate, domain string,
 
 	renewalTime := renewalInfo.ShouldRenewAt(now, ctx.Duration(flgARIWaitToRenewDuration))
 	if renewalTime == nil {
-		log.Infof("[%s] acme: renewalInfo endpoint indicates that renewal is not needed", domain)
+		log.Info("acme: renewalInfo endpoint indicates that renewal is not needed", log.AttrDomain, domain)
 		return nil
 	}
 
-	log.Infof("[%s] acme: renewalInfo endpoint indicates that renewal is needed", domain)
+	log.Info("acme: renewalInfo endpoint indicates that renewal is needed", log.AttrDomain, domain)
 
 	if renewalInfo.ExplanationURL != "" {
-		log.Infof("[%s] acme: renewalInfo endpoint provided an explanation: %s", domain, renewalInfo.ExplanationURL)
+		log.Info("acme: renewalInfo endpoint provided an explanation", log.AttrDomain, domain, "explanationURL", renewalInfo.ExplanationURL)
 	}
 
 	return renewalTime
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"crypto"
+	"os"
+
 	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/log"
 	"github.com/urfave/cli/v2"
@@ -8,8 +11,10 @@ import (
 
 // Flag names.
 const (
-	flgKeep   = "keep"
-	flgReason = "reason"
+	flgKeep    = "keep"
+	flgReason  = "reason"
+	flgCertKey = "cert-key"
+	flgCert    = "cert"
 )
 
 func createRevoke() *cli.Command {
@@ -33,21 +38,48 @@ func createRevoke() *cli.Command {
 					" 9 (privilegeWithdrawn), or 10 (aACompromise).",
 				Value: acme.CRLReasonUnspecified,
 			},
+			&cli.StringFlag{
+				Name: flgCertKey,
+				Usage: "Path to the private key of the certificate (in PEM encoding)." +
+					" The revocation request is signed with this key instead of the account key: no account is required.",
+			},
+			&cli.StringFlag{
+				Name: flgCert,
+				Usage: "Path to the certificate to revoke (in PEM encoding)." +
+					" Only with --" + flgCertKey + ". By default, the certificates of the domains are read from the storage.",
+			},
 		},
 	}
 }
 
 func revoke(ctx *cli.Context) error {
-	account, keyType := setupAccount(ctx, NewAccountsStorage(ctx))
-
-	if account.Registration == nil {
-		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", account.Email)
+	if ctx.IsSet(flgCert) && !ctx.IsSet(flgCertKey) {
+		log.Fatalf("--%s requires --%s.", flgCert, flgCertKey)
 	}
 
-	client := newClient(ctx, account, keyType)
+	revokeCert := setupRevoke(ctx)
+
+	reason := ctx.Uint(flgReason)
+
+	if ctx.IsSet(flgCert) {
+		log.Printf("Trying to revoke certificate %s", ctx.String(flgCert))
+
+		certBytes, err := os.ReadFile(ctx.String(flgCert))
+		if err != nil {
+			log.Fatalf("Error while revoking the certificate %s\n\t%v", ctx.String(flgCert), err)
+		}
+
+		err = revokeCert(certBytes, &reason)
+		if err != nil {
+			log.Fatalf("Error while revoking the certificate %s\n\t%v", ctx.String(flgCert), err)
+		}
+
+		log.Println("Certificate was revoked.")
+
+		return nil
+	}
 
 	certsStorage := NewCertificatesStorage(ctx)
-	certsStorage.CreateRootFolder()
 
 	for _, domain := range ctx.StringSlice(flgDomains) {
 		log.Printf("Trying to revoke certificate for domain %s", domain)
@@ -57,9 +89,7 @@ func revoke(ctx *cli.Context) error {
 			log.Fatalf("Error while revoking the certificate for domain %s\n\t%v", domain, err)
 		}
 
-		reason := ctx.Uint(flgReason)
-
-		err = client.Certificate.RevokeWithReason(certBytes, &reason)
+		err = revokeCert(certBytes, &reason)
 		if err != nil {
 			log.Fatalf("Error while revoking the certificate for domain %s\n\t%v", domain, err)
 		}
@@ -70,8 +100,6 @@ func revoke(ctx *cli.Context) error {
 			return nil
 		}
 
-		certsStorage.CreateArchiveFolder()
-
 		err = certsStorage.MoveToArchive(domain)
 		if err != nil {
 			return err
@@ -82,3 +110,38 @@ func revoke(ctx *cli.Context) error {
 
 	return nil
 }
+
+// setupRevoke returns the function revoking a certificate:
+// with the certificate key (--cert-key), or with the account key.
+func setupRevoke(ctx *cli.Context) func(cert []byte, reason *uint) error {
+	if ctx.IsSet(flgCertKey) {
+		privateKey, err := loadPrivateKey(ctx.String(flgCertKey))
+		if err != nil {
+			log.Fatalf("Could not load the certificate key %s: %v", ctx.String(flgCertKey), err)
+		}
+
+		certKey, ok := privateKey.(crypto.Signer)
+		if !ok {
+			log.Fatalf("Unsupported certificate key %s.", ctx.String(flgCertKey))
+		}
+
+		// The certificate key is only used to create the client, no account is registered nor loaded.
+		client := newAnonymousClient(ctx, &Account{key: privateKey}, getKeyType(ctx))
+
+		return func(cert []byte, reason *uint) error {
+			return client.Certificate.RevokeWithCertificateKey(ctx.Context, cert, certKey, reason)
+		}
+	}
+
+	account, keyType := setupAccount(ctx, NewAccountsStorage(ctx))
+
+	if account.Registration == nil {
+		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", account.Email)
+	}
+
+	client := newClient(ctx, account, keyType)
+
+	return func(cert []byte, reason *uint) error {
+		return client.Certificate.RevokeWithReasonWithContext(ctx.Context, cert, reason)
+	}
+}
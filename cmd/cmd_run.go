@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/go-acme/lego/v4/log"
@@ -26,6 +28,8 @@ const (
 	flgAlwaysDeactivateAuthorizations = "always-deactivate-authorizations"
 	flgRunHook                        = "run-hook"
 	flgRunHookTimeout                 = "run-hook-timeout"
+	flgPresentOnly                    = "present-only"
+	flgResume                         = "resume"
 )
 
 func createRun() *cli.Command {
@@ -45,6 +49,14 @@ func createRun() *cli.Command {
 				log.Fatal("Please specify --domains/-d (or --csr/-c if you already have a CSR)")
 			}
 
+			if ctx.Bool(flgPresentOnly) && ctx.Bool(flgResume) {
+				log.Fatalf("Please specify either --%s or --%s, but not both", flgPresentOnly, flgResume)
+			}
+
+			if hasCsr && (ctx.Bool(flgPresentOnly) || ctx.Bool(flgResume)) {
+				log.Fatalf("The flags --%s and --%s are not supported with --csr/-c", flgPresentOnly, flgResume)
+			}
+
 			return nil
 		},
 		Action: run,
@@ -94,6 +106,15 @@ func createRun() *cli.Command {
 				Usage: "Define the timeout for the hook execution.",
 				Value: 2 * time.Minute,
 			},
+			&cli.BoolFlag{
+				Name: flgPresentOnly,
+				Usage: "Create the order and present the DNS challenges, without validating them." +
+					" The pending order is saved, and can be resumed later (--resume), by another instance sharing the same storage.",
+			},
+			&cli.BoolFlag{
+				Name:  flgResume,
+				Usage: "Resume a pending order (see --present-only): validate and clean up the challenges, then obtain the certificate.",
+			},
 		},
 	}
 }
@@ -116,28 +137,39 @@ func run(ctx *cli.Context) error {
 
 	client := setupClient(ctx, account, keyType)
 
-	if account.Registration == nil {
-		reg, err := register(ctx, client)
-		if err != nil {
-			log.Fatalf("Could not complete registration\n\t%v", err)
-		}
+	ensureRegistration(ctx, client, account, accountsStorage)
 
-		account.Registration = reg
-		if err = accountsStorage.Save(account); err != nil {
-			log.Fatal(err)
-		}
+	certsStorage := NewCertificatesStorage(ctx)
 
-		fmt.Printf(rootPathWarningMessage, accountsStorage.GetRootPath())
+	domain, err := getMainDomain(ctx)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	certsStorage := NewCertificatesStorage(ctx)
-	certsStorage.CreateRootFolder()
+	unlock, err := certsStorage.Lock(domain)
+	if err != nil {
+		log.Fatalf("Could not lock the certificate for domain %s: %v", domain, err)
+	}
+
+	defer releaseLock(domain, unlock)
+
+	if ctx.Bool(flgPresentOnly) {
+		return presentOrder(ctx, client, certsStorage, domain)
+	}
+
+	var cert *certificate.Resource
+
+	if ctx.Bool(flgResume) {
+		cert, err = resumeOrder(ctx, client, certsStorage, domain)
+	} else {
+		cert, err = obtainCertificate(ctx, client)
+	}
 
-	cert, err := obtainCertificate(ctx, client)
 	if err != nil {
 		// Make sure to return a non-zero exit code if ObtainSANCertificate returned at least one error.
 		// Due to us not returning partial certificate we can just exit here instead of at the end.
-		log.Fatalf("Could not obtain certificates:\n\t%v", err)
+		// The error is returned (instead of calling log.Fatal) to release the lock.
+		return fmt.Errorf("could not obtain certificates:\n\t%w", err)
 	}
 
 	certsStorage.SaveResource(cert)
@@ -151,6 +183,25 @@ func run(ctx *cli.Context) error {
 	return launchHook(ctx.String(flgRunHook), ctx.Duration(flgRunHookTimeout), meta)
 }
 
+// ensureRegistration registers the account if it is not already registered.
+func ensureRegistration(ctx *cli.Context, client *lego.Client, account *Account, accountsStorage *AccountsStorage) {
+	if account.Registration != nil {
+		return
+	}
+
+	reg, err := register(ctx, client)
+	if err != nil {
+		log.Fatalf("Could not complete registration\n\t%v", err)
+	}
+
+	account.Registration = reg
+	if err = accountsStorage.Save(account); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf(rootPathWarningMessage, accountsStorage.GetRootPath())
+}
+
 func handleTOS(ctx *cli.Context, client *lego.Client) bool {
 	// Check for a global accept override
 	if ctx.Bool(flgAcceptTOS) {
@@ -205,33 +256,116 @@ func register(ctx *cli.Context, client *lego.Client) (*registration.Resource, er
 	return client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
 }
 
+// getMainDomain returns the main domain of the certificate to obtain.
+func getMainDomain(ctx *cli.Context) (string, error) {
+	domains := ctx.StringSlice(flgDomains)
+	if len(domains) > 0 {
+		return domains[0], nil
+	}
+
+	csr, err := readCSRFile(ctx.String(flgCSR))
+	if err != nil {
+		return "", err
+	}
+
+	return certcrypto.GetCSRMainDomain(csr)
+}
+
+// releaseLock releases the lock of a certificate.
+func releaseLock(domain string, unlock func() error) {
+	err := unlock()
+	if err != nil {
+		log.Warn("Could not release the lock of the certificate.", log.AttrDomain, domain, log.AttrError, err)
+	}
+}
+
+// presentOrder creates an order, presents the challenges, and saves the pending order.
+func presentOrder(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, domain string) error {
+	request, err := newObtainRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending, err := client.Certificate.PresentOrder(ctx.Context, request)
+	if err != nil {
+		return fmt.Errorf("could not present the challenges:\n\t%w", err)
+	}
+
+	err = certsStorage.SavePendingOrder(domain, pending)
+	if err != nil {
+		return fmt.Errorf("could not save the pending order: %w", err)
+	}
+
+	log.Infof("The challenges are presented, the pending order is saved in %s: use --%s to obtain the certificate.",
+		certsStorage.GetPendingOrderLocation(domain), flgResume)
+
+	return nil
+}
+
+// resumeOrder resumes a pending order, and removes it.
+func resumeOrder(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, domain string) (*certificate.Resource, error) {
+	pending, err := certsStorage.ReadPendingOrder(domain)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the pending order: %w", err)
+	}
+
+	request, err := newObtainRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := client.Certificate.ResumeOrder(ctx.Context, pending, request)
+	if errors.Is(err, certificate.ErrChallengesNotCleanedUp) {
+		log.Warn("The pending order is kept: it can be resumed again.", log.AttrDomain, domain)
+
+		return nil, err
+	}
+
+	// The challenges are cleaned up even if the order fails: the pending order cannot be resumed twice.
+	errR := certsStorage.RemovePendingOrder(domain)
+	if errR != nil {
+		log.Warn("Could not remove the pending order.", log.AttrDomain, domain, log.AttrError, errR)
+	}
+
+	return cert, err
+}
+
+func newObtainRequest(ctx *cli.Context) (certificate.ObtainRequest, error) {
+	request := certificate.ObtainRequest{
+		Domains:                        ctx.StringSlice(flgDomains),
+		MustStaple:                     ctx.Bool(flgMustStaple),
+		NotBefore:                      getTime(ctx, flgNotBefore),
+		NotAfter:                       getTime(ctx, flgNotAfter),
+		Bundle:                         !ctx.Bool(flgNoBundle),
+		PreferredChain:                 ctx.String(flgPreferredChain),
+		Profile:                        ctx.String(flgProfile),
+		AlwaysDeactivateAuthorizations: ctx.Bool(flgAlwaysDeactivateAuthorizations),
+	}
+
+	if ctx.IsSet(flgPrivateKey) {
+		var err error
+
+		request.PrivateKey, err = loadPrivateKey(ctx.String(flgPrivateKey))
+		if err != nil {
+			return certificate.ObtainRequest{}, fmt.Errorf("load private key: %w", err)
+		}
+	}
+
+	return request, nil
+}
+
 func obtainCertificate(ctx *cli.Context, client *lego.Client) (*certificate.Resource, error) {
 	bundle := !ctx.Bool(flgNoBundle)
 
 	domains := ctx.StringSlice(flgDomains)
 	if len(domains) > 0 {
 		// obtain a certificate, generating a new private key
-		request := certificate.ObtainRequest{
-			Domains:                        domains,
-			MustStaple:                     ctx.Bool(flgMustStaple),
-			NotBefore:                      getTime(ctx, flgNotBefore),
-			NotAfter:                       getTime(ctx, flgNotAfter),
-			Bundle:                         bundle,
-			PreferredChain:                 ctx.String(flgPreferredChain),
-			Profile:                        ctx.String(flgProfile),
-			AlwaysDeactivateAuthorizations: ctx.Bool(flgAlwaysDeactivateAuthorizations),
-		}
-
-		if ctx.IsSet(flgPrivateKey) {
-			var err error
-
-			request.PrivateKey, err = loadPrivateKey(ctx.String(flgPrivateKey))
-			if err != nil {
-				return nil, fmt.Errorf("load private key: %w", err)
-			}
+		request, err := newObtainRequest(ctx)
+		if err != nil {
+			return nil, err
 		}
 
-		return client.Certificate.Obtain(request)
+		return client.Certificate.ObtainWithContext(ctx.Context, request)
 	}
 
 	// read the CSR
@@ -260,5 +394,5 @@ func obtainCertificate(ctx *cli.Context, client *lego.Client) (*certificate.Reso
 		}
 	}
 
-	return client.Certificate.ObtainForCSR(request)
+	return client.Certificate.ObtainForCSRWithContext(ctx.Context, request)
 }
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
+)
+
+const baseDaemonScheduleFileName = "daemon_schedule.json"
+
+// ScheduleEntry the renewal state of a certificate.
+type ScheduleEntry struct {
+	// Domain the main domain of the certificate.
+	Domain string `json:"domain"`
+	// NextRun the next time the certificate will be checked (and renewed if needed).
+	NextRun time.Time `json:"nextRun"`
+	// Failures the number of consecutive failed renewals.
+	Failures int `json:"failures,omitempty"`
+	// LastError the error of the last failed renewal.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Schedule the renewal schedule of the daemon.
+//
+// The path is relative to the root of the storage backend ("path" option for the file storage).
+//
+// filePath:
+//
+//	./.lego/daemon_schedule.json
+//	     │      └── schedule file
+//	     └── "path" option
+type Schedule struct {
+	backend  storage.Backend
+	filePath string
+	entries  map[string]*ScheduleEntry
+}
+
+// NewSchedule creates a new schedule.
+func NewSchedule(backend storage.Backend) *Schedule {
+	return &Schedule{
+		backend:  backend,
+		filePath: baseDaemonScheduleFileName,
+		entries:  make(map[string]*ScheduleEntry),
+	}
+}
+
+// Load reads the persisted schedule, if it exists.
+func (s *Schedule) Load() error {
+	raw, err := s.backend.ReadFile(s.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("read schedule: %w", err)
+	}
+
+	var entries []*ScheduleEntry
+
+	err = json.Unmarshal(raw, &entries)
+	if err != nil {
+		return fmt.Errorf("unmarshal schedule: %w", err)
+	}
+
+	s.entries = make(map[string]*ScheduleEntry)
+
+	for _, entry := range entries {
+		s.entries[entry.Domain] = entry
+	}
+
+	return nil
+}
+
+// Save persists the schedule.
+func (s *Schedule) Save() error {
+	raw, err := json.MarshalIndent(s.Entries(), "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshal schedule: %w", err)
+	}
+
+	err = s.backend.WriteFile(s.filePath, raw)
+	if err != nil {
+		return fmt.Errorf("write schedule: %w", err)
+	}
+
+	return nil
+}
+
+// Sync aligns the schedule with the list of managed certificates:
+// the new certificates are checked immediately, the entries of the removed certificates are dropped.
+func (s *Schedule) Sync(domains []string, now time.Time) {
+	for domain := range s.entries {
+		if !slices.Contains(domains, domain) {
+			delete(s.entries, domain)
+		}
+	}
+
+	for _, domain := range domains {
+		if _, ok := s.entries[domain]; !ok {
+			s.entries[domain] = &ScheduleEntry{Domain: domain, NextRun: now}
+		}
+	}
+}
+
+// Get returns the entry related to a domain.
+func (s *Schedule) Get(domain string) *ScheduleEntry {
+	return s.entries[domain]
+}
+
+// Entries returns the entries sorted by next run.
+func (s *Schedule) Entries() []*ScheduleEntry {
+	var entries []*ScheduleEntry
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b *ScheduleEntry) int {
+		if c := a.NextRun.Compare(b.NextRun); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Domain, b.Domain)
+	})
+
+	return entries
+}
+
+// Due returns the entries to process.
+func (s *Schedule) Due(now time.Time) []*ScheduleEntry {
+	var due []*ScheduleEntry
+
+	for _, entry := range s.Entries() {
+		if entry.NextRun.After(now) {
+			break
+		}
+
+		due = append(due, entry)
+	}
+
+	return due
+}
+
+// Next returns the next time an entry should be processed.
+func (s *Schedule) Next() (time.Time, bool) {
+	entries := s.Entries()
+	if len(entries) == 0 {
+		return time.Time{}, false
+	}
+
+	return entries[0].NextRun, true
+}
+
+// Succeed records a successful check or renewal.
+func (s *Schedule) Succeed(domain string, next time.Time) {
+	entry := s.getOrCreate(domain)
+
+	entry.NextRun = next
+	entry.Failures = 0
+	entry.LastError = ""
+}
+
+// Fail records a failed renewal, the next run is delayed by an exponential backoff.
+func (s *Schedule) Fail(domain string, err error, now time.Time, minInterval, maxInterval time.Duration) {
+	entry := s.getOrCreate(domain)
+
+	entry.Failures++
+	entry.LastError = err.Error()
+	entry.NextRun = now.Add(backoff(entry.Failures, minInterval, maxInterval))
+}
+
+func (s *Schedule) getOrCreate(domain string) *ScheduleEntry {
+	entry, ok := s.entries[domain]
+	if !ok {
+		entry = &ScheduleEntry{Domain: domain}
+		s.entries[domain] = entry
+	}
+
+	return entry
+}
+
+// backoff computes the delay before the next attempt: minInterval * 2^(failures-1), bounded by maxInterval.
+func backoff(failures int, minInterval, maxInterval time.Duration) time.Duration {
+	delay := minInterval
+
+	for i := 1; i < failures; i++ {
+		delay *= 2
+
+		if delay >= maxInterval {
+			return maxInterval
+		}
+	}
+
+	return min(delay, maxInterval)
+}
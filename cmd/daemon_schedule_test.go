@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_persistence(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	schedule := NewSchedule(storage.NewFileSystem(dir))
+	schedule.Sync([]string{"example.com", "example.org"}, now)
+	schedule.Succeed("example.com", now.Add(48*time.Hour))
+	schedule.Fail("example.org", errors.New("oops"), now, time.Minute, time.Hour)
+
+	err := schedule.Save()
+	require.NoError(t, err)
+
+	loaded := NewSchedule(storage.NewFileSystem(dir))
+
+	err = loaded.Load()
+	require.NoError(t, err)
+
+	expected := []*ScheduleEntry{
+		{Domain: "example.org", NextRun: now.Add(time.Minute), Failures: 1, LastError: "oops"},
+		{Domain: "example.com", NextRun: now.Add(48 * time.Hour)},
+	}
+
+	assert.Equal(t, expected, loaded.Entries())
+}
+
+func TestSchedule_Sync(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	schedule := NewSchedule(storage.NewFileSystem(t.TempDir()))
+	schedule.Sync([]string{"a.example.com", "b.example.com"}, now)
+	schedule.Succeed("a.example.com", now.Add(time.Hour))
+
+	schedule.Sync([]string{"a.example.com", "c.example.com"}, now.Add(time.Minute))
+
+	expected := []*ScheduleEntry{
+		{Domain: "c.example.com", NextRun: now.Add(time.Minute)},
+		{Domain: "a.example.com", NextRun: now.Add(time.Hour)},
+	}
+
+	assert.Equal(t, expected, schedule.Entries())
+
+	due := schedule.Due(now.Add(30 * time.Minute))
+	require.Len(t, due, 1)
+	assert.Equal(t, "c.example.com", due[0].Domain)
+
+	next, ok := schedule.Next()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(time.Minute), next)
+}
+
+func Test_backoff(t *testing.T) {
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 1, expected: 10 * time.Minute},
+		{failures: 2, expected: 20 * time.Minute},
+		{failures: 3, expected: 40 * time.Minute},
+		{failures: 4, expected: 80 * time.Minute},
+		{failures: 5, expected: 2 * time.Hour},
+		{failures: 100, expected: 2 * time.Hour},
+	}
+
+	for _, test := range testCases {
+		t.Run(strconv.Itoa(test.failures), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, backoff(test.failures, 10*time.Minute, 2*time.Hour))
+		})
+	}
+}
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge/http01"
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/urfave/cli/v2"
 	"software.sslmate.com/src/go-pkcs12"
@@ -17,6 +19,7 @@ const (
 	flgAcceptTOS                = "accept-tos"
 	flgEmail                    = "email"
 	flgDisableCommonName        = "disable-cn"
+	flgCAACheck                 = "caa-check"
 	flgCSR                      = "csr"
 	flgEAB                      = "eab"
 	flgKID                      = "kid"
@@ -24,6 +27,11 @@ const (
 	flgKeyType                  = "key-type"
 	flgFilename                 = "filename"
 	flgPath                     = "path"
+	flgStorage                  = "storage"
+	flgStorageS3Bucket          = "storage.s3-bucket"
+	flgStorageS3Prefix          = "storage.s3-prefix"
+	flgStorageS3Endpoint        = "storage.s3-endpoint"
+	flgStorageS3Region          = "storage.s3-region"
 	flgHTTP                     = "http"
 	flgHTTPPort                 = "http.port"
 	flgHTTPDelay                = "http.delay"
@@ -31,10 +39,17 @@ const (
 	flgHTTPWebroot              = "http.webroot"
 	flgHTTPMemcachedHost        = "http.memcached-host"
 	flgHTTPS3Bucket             = "http.s3-bucket"
+	flgHTTPSelfCheck            = "http.self-check"
+	flgHTTPSelfCheckTarget      = "http.self-check-target"
+	flgHTTPSelfCheckResolver    = "http.self-check-resolver"
+	flgHTTPSelfCheckTimeout     = "http.self-check-timeout"
 	flgTLS                      = "tls"
 	flgTLSPort                  = "tls.port"
 	flgTLSDelay                 = "tls.delay"
 	flgDNS                      = "dns"
+	flgDNSAccount               = "dns.account"
+	flgDNSPersist               = "dns-persist"
+	flgDNSPersistIssuer         = "dns-persist.issuer"
 	flgDNSDisableCP             = "dns.disable-cp"
 	flgDNSPropagationWait       = "dns.propagation-wait"
 	flgDNSPropagationDisableANS = "dns.propagation-disable-ans"
@@ -43,6 +58,7 @@ const (
 	flgHTTPTimeout              = "http-timeout"
 	flgTLSSkipVerify            = "tls-skip-verify"
 	flgDNSTimeout               = "dns-timeout"
+	flgDomainChallenge          = "domain-challenge"
 	flgPEM                      = "pem"
 	flgPFX                      = "pfx"
 	flgPFXPass                  = "pfx.pass"
@@ -50,6 +66,13 @@ const (
 	flgCertTimeout              = "cert.timeout"
 	flgOverallRequestLimit      = "overall-request-limit"
 	flgUserAgent                = "user-agent"
+	flgRateLimit                = "ratelimit"
+	flgRateLimitConfig          = "ratelimit.config"
+	flgRateLimitMaxDelay        = "ratelimit.max-delay"
+	flgRateLimitMaxRetryAfter   = "ratelimit.max-retry-after"
+	flgMetricsAddr              = "metrics-addr"
+	flgLogLevel                 = "log-level"
+	flgLogFormat                = "log-format"
 )
 
 const (
@@ -58,10 +81,18 @@ const (
 	envEABKID      = "LEGO_EAB_KID"
 	envEmail       = "LEGO_EMAIL"
 	envPath        = "LEGO_PATH"
+	envStorage     = "LEGO_STORAGE"
+	envS3Bucket    = "LEGO_STORAGE_S3_BUCKET"
+	envS3Prefix    = "LEGO_STORAGE_S3_PREFIX"
+	envS3Endpoint  = "LEGO_STORAGE_S3_ENDPOINT"
+	envS3Region    = "LEGO_STORAGE_S3_REGION"
 	envPFX         = "LEGO_PFX"
 	envPFXFormat   = "LEGO_PFX_FORMAT"
 	envPFXPassword = "LEGO_PFX_PASSWORD"
 	envServer      = "LEGO_SERVER"
+	envMetricsAddr = "LEGO_METRICS_ADDR"
+	envLogLevel    = "LEGO_LOG_LEVEL"
+	envLogFormat   = "LEGO_LOG_FORMAT"
 )
 
 func CreateFlags(defaultPath string) []cli.Flag {
@@ -93,6 +124,11 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:  flgDisableCommonName,
 			Usage: "Disable the use of the common name in the CSR.",
 		},
+		&cli.BoolFlag{
+			Name: flgCAACheck,
+			Usage: "Check the CAA records of the domains (including the accounturi and validationmethods parameters) before creating the orders." +
+				" The resolvers defined by '--" + flgDNSResolvers + "' are used.",
+		},
 		&cli.StringFlag{
 			Name:    flgCSR,
 			Aliases: []string{"c"},
@@ -129,6 +165,32 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Usage:   "Directory to use for storing the data.",
 			Value:   defaultPath,
 		},
+		&cli.StringFlag{
+			Name:    flgStorage,
+			EnvVars: []string{envStorage},
+			Usage:   "Storage backend for the accounts and the certificates. Supported: file (inside --path), s3.",
+			Value:   storageFile,
+		},
+		&cli.StringFlag{
+			Name:    flgStorageS3Bucket,
+			EnvVars: []string{envS3Bucket},
+			Usage:   "Set the S3 bucket name to use with the s3 storage.",
+		},
+		&cli.StringFlag{
+			Name:    flgStorageS3Prefix,
+			EnvVars: []string{envS3Prefix},
+			Usage:   "Set a prefix for the keys of the objects stored with the s3 storage.",
+		},
+		&cli.StringFlag{
+			Name:    flgStorageS3Endpoint,
+			EnvVars: []string{envS3Endpoint},
+			Usage:   "Set the endpoint of an S3-compatible object store (path-style addressing) to use with the s3 storage.",
+		},
+		&cli.StringFlag{
+			Name:    flgStorageS3Region,
+			EnvVars: []string{envS3Region},
+			Usage:   "Set the region to use with the s3 storage. By default, the region from the AWS configuration is used.",
+		},
 		&cli.BoolFlag{
 			Name:  flgHTTP,
 			Usage: "Use the HTTP-01 challenge to solve challenges. Can be mixed with other types of challenges.",
@@ -161,6 +223,24 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:  flgHTTPS3Bucket,
 			Usage: "Set the S3 bucket name to use for HTTP-01 based challenges. Challenges will be written to the S3 bucket.",
 		},
+		&cli.BoolFlag{
+			Name: flgHTTPSelfCheck,
+			Usage: "Check, from the local machine, that the key authorization is served on http://<domain>/.well-known/acme-challenge/<token>" +
+				" before asking the CA to validate the HTTP-01 challenge.",
+		},
+		&cli.StringFlag{
+			Name:  flgHTTPSelfCheckTarget,
+			Usage: "Send the self-check requests to this address instead of the address of the domain. Supported: host:port.",
+		},
+		&cli.StringFlag{
+			Name:  flgHTTPSelfCheckResolver,
+			Usage: "Set the DNS resolver used to resolve the domains during the self-check. Supported: host:port.",
+		},
+		&cli.DurationFlag{
+			Name:  flgHTTPSelfCheckTimeout,
+			Usage: "Duration during which the self-check is retried.",
+			Value: http01.DefaultSelfCheckTimeout,
+		},
 		&cli.BoolFlag{
 			Name:  flgTLS,
 			Usage: "Use the TLS-ALPN-01 challenge to solve challenges. Can be mixed with other types of challenges.",
@@ -179,6 +259,22 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:  flgDNS,
 			Usage: "Solve a DNS-01 challenge using the specified provider. Can be mixed with other types of challenges. Run 'lego dnshelp' for help on usage.",
 		},
+		&cli.BoolFlag{
+			Name: flgDNSAccount,
+			Usage: "Solve a DNS-ACCOUNT-01 challenge, instead of a DNS-01 challenge, using the provider defined by --dns." +
+				" The TXT record is account-scoped (_<label>._acme-challenge.<domain>), several accounts can validate the same domain concurrently." +
+				" The provider must support arbitrary TXT records: " + strings.Join(recordProviders, ", ") + ".",
+		},
+		&cli.BoolFlag{
+			Name: flgDNSPersist,
+			Usage: "Use the DNS-PERSIST-01 challenge to solve challenges. Can be mixed with other types of challenges." +
+				" The persistent TXT records must be created beforehand (see 'lego dns-persist')." +
+				" No DNS provider is needed.",
+		},
+		&cli.StringFlag{
+			Name:  flgDNSPersistIssuer,
+			Usage: "Set the issuer domain name of the CA used in the DNS-PERSIST-01 records. By default, the issuer domain names offered by the CA are accepted.",
+		},
 		&cli.BoolFlag{
 			Name:  flgDNSDisableCP,
 			Usage: fmt.Sprintf("(deprecated) use %s instead.", flgDNSPropagationDisableANS),
@@ -199,7 +295,7 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name: flgDNSResolvers,
 			Usage: "Set the resolvers to use for performing (recursive) CNAME resolving and apex domain determination." +
 				" For DNS-01 challenge verification, the authoritative DNS server is queried directly." +
-				" Supported: host:port." +
+				" Supported: host:port, tls://host[:port] (DNS over TLS), https://host[:port]/path (DNS over HTTPS)." +
 				" The default is to use the system resolvers, or Google's DNS resolvers if the system's cannot be determined.",
 		},
 		&cli.IntFlag{
@@ -210,6 +306,12 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:  flgTLSSkipVerify,
 			Usage: "Skip the TLS verification of the ACME server.",
 		},
+		&cli.StringSliceFlag{
+			Name: flgDomainChallenge,
+			Usage: "Use a specific challenge for the domains matching a pattern: '<pattern>=http', '<pattern>=tls', or '<pattern>=dns:<provider>'." +
+				" The pattern is a domain (example.com), or a zone (*.example.com) matching the subdomains and the wildcard domain." +
+				" The most specific pattern is used. The other domains use the other challenge options. Can be specified multiple times.",
+		},
 		&cli.IntFlag{
 			Name:  flgDNSTimeout,
 			Usage: "Set the DNS timeout value to a specific value in seconds. Used only when performing authoritative name server queries.",
@@ -246,10 +348,46 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Usage: "ACME overall requests limit.",
 			Value: certificate.DefaultOverallRequestLimit,
 		},
+		&cli.BoolFlag{
+			Name: flgRateLimit,
+			Usage: "Record the orders, the certificates, and the failed validations in a ledger (inside the storage)," +
+				" and refuse the orders which would exceed the known rate limits of the CA (see --" + flgRateLimitConfig + ", --" + flgRateLimitMaxDelay + ").",
+		},
+		&cli.StringFlag{
+			Name: flgRateLimitConfig,
+			Usage: "Path to a YAML file defining the rate limits by CA (servers.<directory URL>)." +
+				" By default, the known limits of the CA are used (Let's Encrypt).",
+		},
+		&cli.DurationFlag{
+			Name:  flgRateLimitMaxDelay,
+			Usage: "The maximum duration to wait when a known rate limit is reached, instead of refusing the order.",
+		},
+		&cli.DurationFlag{
+			Name: flgRateLimitMaxRetryAfter,
+			Usage: "The maximum duration to wait before retrying an order rejected with a rateLimited error, as requested by the CA (Retry-After)." +
+				" By default, the order is not retried.",
+		},
 		&cli.StringFlag{
 			Name:  flgUserAgent,
 			Usage: "Add to the user-agent sent to the CA to identify an application embedding lego-cli",
 		},
+		&cli.StringFlag{
+			Name:    flgMetricsAddr,
+			EnvVars: []string{envMetricsAddr},
+			Usage:   "Set the address (ex: ':9090') of an HTTP server exposing the metrics in Prometheus format on /metrics, and a health check on /health.",
+		},
+		&cli.StringFlag{
+			Name:    flgLogLevel,
+			EnvVars: []string{envLogLevel},
+			Usage:   "Set the minimum level of the logs. Supported: debug, info, warn, error.",
+			Value:   "info",
+		},
+		&cli.StringFlag{
+			Name:    flgLogFormat,
+			EnvVars: []string{envLogFormat},
+			Usage:   "Set the format of the logs. Supported: text, json.",
+			Value:   logFormatText,
+		},
 	}
 }
 
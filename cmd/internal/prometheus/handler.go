@@ -0,0 +1,22 @@
+package prometheus
+
+import (
+	"net/http"
+)
+
+// ContentType the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns an HTTP handler which exposes the metrics of the registry.
+// The optional collect function is called before each exposition, to update the metrics which are not recorded as they occur.
+func Handler(registry *Registry, collect func(*Registry)) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if collect != nil {
+			collect(registry)
+		}
+
+		rw.Header().Set("Content-Type", ContentType)
+
+		_, _ = registry.WriteTo(rw)
+	})
+}
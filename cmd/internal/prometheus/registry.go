@@ -0,0 +1,309 @@
+// Package prometheus exposes the metrics of lego in the Prometheus text format.
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/metrics"
+)
+
+// DefaultBuckets the buckets (in seconds) of the histograms.
+// The range is large because some operations (DNS propagation, validations) can take minutes.
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var _ metrics.Recorder = (*Registry)(nil)
+
+// Registry stores the metrics of lego, and implements metrics.Recorder.
+type Registry struct {
+	metrics.NoopRecorder
+
+	mu       sync.Mutex
+	families []*family
+
+	acmeRequests         *family
+	acmeRequestsDuration *family
+	acmeErrors           *family
+	badNonceRetries      *family
+	orders               *family
+	ordersDuration       *family
+	challengeAttempts    *family
+	challengesDuration   *family
+	propagationChecks    *family
+	propagationDuration  *family
+	providerCalls        *family
+	providerDuration     *family
+	certificateExpiry    *family
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry() *Registry {
+	r := &Registry{}
+
+	r.acmeRequests = r.register("lego_acme_requests_total", typeCounter,
+		"Number of requests to the ACME server.", "resource", "code")
+	r.acmeRequestsDuration = r.register("lego_acme_request_duration_seconds", typeHistogram,
+		"Duration of the requests to the ACME server.", "resource")
+	r.acmeErrors = r.register("lego_acme_errors_total", typeCounter,
+		"Number of problems returned by the ACME server, by problem type.", "resource", "type")
+	r.badNonceRetries = r.register("lego_acme_bad_nonce_retries_total", typeCounter,
+		"Number of requests retried because of a badNonce error.")
+	r.orders = r.register("lego_orders_total", typeCounter,
+		"Number of completed orders.", "result")
+	r.ordersDuration = r.register("lego_order_duration_seconds", typeHistogram,
+		"Duration of the orders, from the creation to the download of the certificate.", "result")
+	r.challengeAttempts = r.register("lego_challenge_attempts_total", typeCounter,
+		"Number of attempts to solve a challenge.", "type", "result")
+	r.challengesDuration = r.register("lego_challenge_attempt_duration_seconds", typeHistogram,
+		"Duration of the attempts to solve a challenge.", "type")
+	r.propagationChecks = r.register("lego_dns_propagation_checks_total", typeCounter,
+		"Number of checks of the propagation of DNS records.", "result")
+	r.propagationDuration = r.register("lego_dns_propagation_check_duration_seconds", typeHistogram,
+		"Duration of the checks of the propagation of DNS records.")
+	r.providerCalls = r.register("lego_provider_calls_total", typeCounter,
+		"Number of calls to the challenge providers.", "provider", "operation", "result")
+	r.providerDuration = r.register("lego_provider_call_duration_seconds", typeHistogram,
+		"Duration of the calls to the challenge providers.", "provider", "operation")
+	r.certificateExpiry = r.register("lego_certificate_expiry_timestamp_seconds", typeGauge,
+		"Expiration date of the certificates, as a Unix timestamp.", "domain")
+
+	return r
+}
+
+func (r *Registry) ACMERequest(resource string, statusCode int, duration time.Duration) {
+	r.add(r.acmeRequests, 1, resource, strconv.Itoa(statusCode))
+	r.observe(r.acmeRequestsDuration, duration, resource)
+}
+
+func (r *Registry) ACMEError(resource, problemType string) {
+	r.add(r.acmeErrors, 1, resource, problemType)
+}
+
+func (r *Registry) BadNonceRetry() {
+	r.add(r.badNonceRetries, 1)
+}
+
+func (r *Registry) Order(success bool, duration time.Duration) {
+	r.add(r.orders, 1, result(success))
+	r.observe(r.ordersDuration, duration, result(success))
+}
+
+func (r *Registry) ChallengeAttempt(challengeType string, success bool, duration time.Duration) {
+	r.add(r.challengeAttempts, 1, challengeType, result(success))
+	r.observe(r.challengesDuration, duration, challengeType)
+}
+
+func (r *Registry) PropagationCheck(success bool, duration time.Duration) {
+	r.add(r.propagationChecks, 1, result(success))
+	r.observe(r.propagationDuration, duration)
+}
+
+func (r *Registry) ProviderCall(provider, operation string, success bool, duration time.Duration) {
+	r.add(r.providerCalls, 1, provider, operation, result(success))
+	r.observe(r.providerDuration, duration, provider, operation)
+}
+
+func (r *Registry) CertificateExpiry(domain string, notAfter time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.certificateExpiry.get(domain).value = float64(notAfter.Unix())
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, f := range r.families {
+		f.write(bw)
+	}
+
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+func (r *Registry) register(name, typ, help string, labels ...string) *family {
+	f := &family{
+		name:   name,
+		typ:    typ,
+		help:   help,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+
+	if len(labels) == 0 {
+		// Exposes the series without labels even if nothing has been recorded yet.
+		f.get()
+	}
+
+	r.families = append(r.families, f)
+
+	return f
+}
+
+func (r *Registry) add(f *family, value float64, labelValues ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f.get(labelValues...).value += value
+}
+
+func (r *Registry) observe(f *family, duration time.Duration, labelValues ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := f.get(labelValues...)
+
+	value := duration.Seconds()
+
+	for i, bound := range DefaultBuckets {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+
+	s.count++
+	s.value += value
+}
+
+type family struct {
+	name   string
+	typ    string
+	help   string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	// value is the value of the counters and the gauges, and the sum of the histograms.
+	value float64
+
+	// buckets and count are only used by the histograms.
+	// The buckets are cumulative.
+	buckets []uint64
+	count   uint64
+}
+
+func (f *family) get(labelValues ...string) *series {
+	key := strings.Join(labelValues, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+
+		if f.typ == typeHistogram {
+			s.buckets = make([]uint64, len(DefaultBuckets))
+		}
+
+		f.series[key] = s
+	}
+
+	return s
+}
+
+func (f *family) write(w *bufio.Writer) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+
+		if f.typ != typeHistogram {
+			writeSample(w, f.name, f.labels, s.labelValues, s.value)
+			continue
+		}
+
+		labels := append(slices.Clone(f.labels), "le")
+
+		for i, bound := range DefaultBuckets {
+			writeSample(w, f.name+"_bucket", labels, append(slices.Clone(s.labelValues), formatFloat(bound)), float64(s.buckets[i]))
+		}
+
+		writeSample(w, f.name+"_bucket", labels, append(slices.Clone(s.labelValues), "+Inf"), float64(s.count))
+		writeSample(w, f.name+"_sum", f.labels, s.labelValues, s.value)
+		writeSample(w, f.name+"_count", f.labels, s.labelValues, float64(s.count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, value float64) {
+	_, _ = w.WriteString(name)
+
+	if len(labels) > 0 {
+		_ = w.WriteByte('{')
+
+		for i, label := range labels {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+
+			_, _ = fmt.Fprintf(w, "%s=\"%s\"", label, labelValueEscaper.Replace(labelValues[i]))
+		}
+
+		_ = w.WriteByte('}')
+	}
+
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatFloat(value))
+	_ = w.WriteByte('\n')
+}
+
+// labelValueEscaper escapes the label values: the Prometheus text format only escapes the backslash, the double-quote, and the line feed.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func result(success bool) string {
+	if success {
+		return "success"
+	}
+
+	return "failure"
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
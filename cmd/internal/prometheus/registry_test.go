@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.ACMERequest("newOrder", http.StatusCreated, 200*time.Millisecond)
+	registry.ACMERequest("newOrder", http.StatusCreated, 2*time.Second)
+	registry.ACMEError("newOrder", "urn:ietf:params:acme:error:rateLimited")
+	registry.BadNonceRetry()
+	registry.ProviderCall("cloudflare", "present", true, 3*time.Second)
+	registry.CertificateExpiry(`example.com"\`, time.Unix(1700000000, 0))
+
+	buf := &bytes.Buffer{}
+
+	_, err := registry.WriteTo(buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+
+	assert.Contains(t, output, "# TYPE lego_acme_requests_total counter\n")
+	assert.Contains(t, output, `lego_acme_requests_total{resource="newOrder",code="201"} 2`+"\n")
+	assert.Contains(t, output, "# TYPE lego_acme_request_duration_seconds histogram\n")
+	assert.Contains(t, output, `lego_acme_request_duration_seconds_bucket{resource="newOrder",le="0.25"} 1`+"\n")
+	assert.Contains(t, output, `lego_acme_request_duration_seconds_bucket{resource="newOrder",le="2.5"} 2`+"\n")
+	assert.Contains(t, output, `lego_acme_request_duration_seconds_bucket{resource="newOrder",le="+Inf"} 2`+"\n")
+	assert.Contains(t, output, `lego_acme_request_duration_seconds_sum{resource="newOrder"} 2.2`+"\n")
+	assert.Contains(t, output, `lego_acme_request_duration_seconds_count{resource="newOrder"} 2`+"\n")
+	assert.Contains(t, output, `lego_acme_errors_total{resource="newOrder",type="urn:ietf:params:acme:error:rateLimited"} 1`+"\n")
+	assert.Contains(t, output, "lego_acme_bad_nonce_retries_total 1\n")
+	assert.Contains(t, output, `lego_provider_calls_total{provider="cloudflare",operation="present",result="success"} 1`+"\n")
+	assert.Contains(t, output, `lego_provider_call_duration_seconds_count{provider="cloudflare",operation="present"} 1`+"\n")
+	assert.Contains(t, output, `lego_certificate_expiry_timestamp_seconds{domain="example.com\"\\"} 1.7e+09`+"\n")
+
+	// series without labels are always exposed.
+	assert.Contains(t, output, "lego_dns_propagation_check_duration_seconds_count 0\n")
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+
+	handler := Handler(registry, func(r *Registry) {
+		r.CertificateExpiry("example.com", time.Unix(1700000000, 0))
+	})
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `lego_certificate_expiry_timestamp_seconds{domain="example.com"} 1.7e+09`+"\n")
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	dirPerm  os.FileMode = 0o700
+	filePerm os.FileMode = 0o600
+)
+
+var _ Backend = (*FileSystem)(nil)
+
+// FileSystem a storage backend based on a local directory.
+type FileSystem struct {
+	rootPath string
+	lockTTL  time.Duration
+}
+
+// NewFileSystem creates a new FileSystem.
+func NewFileSystem(rootPath string) *FileSystem {
+	return &FileSystem{rootPath: rootPath, lockTTL: DefaultLockTTL}
+}
+
+func (f *FileSystem) ReadFile(key string) ([]byte, error) {
+	return os.ReadFile(f.Location(key))
+}
+
+func (f *FileSystem) WriteFile(key string, data []byte) error {
+	filename := f.Location(key)
+
+	err := os.MkdirAll(filepath.Dir(filename), dirPerm)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, filePerm)
+}
+
+func (f *FileSystem) Exists(key string) (bool, error) {
+	_, err := os.Stat(f.Location(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (f *FileSystem) Remove(key string) error {
+	return os.Remove(f.Location(key))
+}
+
+func (f *FileSystem) Rename(oldKey, newKey string) error {
+	newFilename := f.Location(newKey)
+
+	err := os.MkdirAll(filepath.Dir(newFilename), dirPerm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Location(oldKey), newFilename)
+}
+
+func (f *FileSystem) List(prefix string) ([]string, error) {
+	var keys []string
+
+	// Only the deepest directory of the prefix is walked.
+	start := f.Location(path.Dir(prefix + "_"))
+
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == start && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.rootPath, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Lock creates a lock file, the creation of the file is atomic.
+// A lock file older than the lock TTL is considered stale and is atomically replaced:
+// the process whose token is in the lock file holds the lock.
+// The lock file is refreshed periodically until the lock is released.
+func (f *FileSystem) Lock(name string) (func() error, error) {
+	info, err := newLockInfo(f.lockTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := f.Location(lockKey(name))
+
+	err = os.MkdirAll(filepath.Dir(filename), dirPerm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = createExclusive(filename, content)
+	if errors.Is(err, fs.ErrExist) {
+		err = f.replaceStaleLock(name, filename, info)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("create lock: %w", err)
+	}
+
+	stopRefresh := refreshLock(name, f.lockTTL, func() error {
+		return f.refreshLock(filename, info)
+	})
+
+	return func() error {
+		stopRefresh()
+
+		current, err := f.readLock(filename)
+		if err != nil {
+			return err
+		}
+
+		if current.Token != info.Token {
+			// The lock has expired and has been taken by another process.
+			return nil
+		}
+
+		return os.Remove(filename)
+	}, nil
+}
+
+// replaceStaleLock replaces an expired lock.
+// The lock file is replaced atomically (rename),
+// then read again: if several processes replace the same stale lock, only the last one holds the lock.
+func (f *FileSystem) replaceStaleLock(name, filename string, info *lockInfo) error {
+	current, err := f.readLock(filename)
+	if err != nil {
+		return err
+	}
+
+	if !current.expired() {
+		return lockedError(name, current)
+	}
+
+	err = writeLock(filename, info)
+	if err != nil {
+		return fmt.Errorf("replace stale lock: %w", err)
+	}
+
+	current, err = f.readLock(filename)
+	if err != nil {
+		return err
+	}
+
+	if current.Token != info.Token {
+		return lockedError(name, current)
+	}
+
+	return nil
+}
+
+// refreshLock extends the expiration of a held lock.
+func (f *FileSystem) refreshLock(filename string, info *lockInfo) error {
+	current, err := f.readLock(filename)
+	if err != nil {
+		return err
+	}
+
+	if current.Token != info.Token {
+		return errLockLost
+	}
+
+	refreshed := *info
+	refreshed.Expires = time.Now().Add(f.lockTTL).UTC()
+
+	return writeLock(filename, &refreshed)
+}
+
+func (f *FileSystem) Location(key string) string {
+	return filepath.Join(f.rootPath, filepath.FromSlash(key))
+}
+
+func (f *FileSystem) readLock(filename string) (*lockInfo, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read lock: %w", err)
+	}
+
+	var info lockInfo
+
+	err = json.Unmarshal(raw, &info)
+	if err != nil {
+		// Partially written or corrupted lock: the modification time is used to detect stale locks.
+		stat, errS := os.Stat(filename)
+		if errS != nil {
+			return nil, fmt.Errorf("read lock: %w", errS)
+		}
+
+		return &lockInfo{Owner: "unknown", Expires: stat.ModTime().Add(f.lockTTL)}, nil
+	}
+
+	return &info, nil
+}
+
+func createExclusive(filename string, content []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+
+	return errors.Join(err, file.Close())
+}
+
+// writeLock writes a lock file atomically: the content is written in a temporary file, which is renamed.
+func writeLock(filename string, info *lockInfo) error {
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + "." + info.Token + ".tmp"
+
+	err = os.WriteFile(tmp, content, filePerm)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, filename)
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+
+	return nil
+}
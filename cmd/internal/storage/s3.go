@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const defaultS3Timeout = 30 * time.Second
+
+var _ Backend = (*S3)(nil)
+
+// S3Config the configuration of the S3 backend.
+type S3Config struct {
+	// Bucket the name of the bucket.
+	Bucket string
+	// Prefix an optional prefix added to all the keys.
+	Prefix string
+	// Endpoint an optional endpoint, used for S3-compatible object stores (MinIO, Ceph, etc.).
+	// When defined, the path-style addressing is used.
+	Endpoint string
+	// Region an optional region, by default the region is read from the AWS configuration.
+	Region string
+}
+
+// S3 a storage backend based on an S3-compatible object store.
+// The credentials are read from the default AWS configuration (environment variables, shared files, etc.).
+//
+// The locks rely on conditional writes (If-None-Match).
+type S3 struct {
+	client  *s3.Client
+	bucket  string
+	prefix  string
+	lockTTL time.Duration
+	timeout time.Duration
+}
+
+// NewS3 creates a new S3.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket name missing")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultS3Timeout)
+	defer cancel()
+
+	awsConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s3: unable to create AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if cfg.Region != "" {
+			o.Region = cfg.Region
+		}
+
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+
+		// The checksums are not supported by all the S3-compatible object stores.
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+
+	return &S3{
+		client:  client,
+		bucket:  cfg.Bucket,
+		prefix:  strings.Trim(cfg.Prefix, "/"),
+		lockTTL: DefaultLockTTL,
+		timeout: defaultS3Timeout,
+	}, nil
+}
+
+func (b *S3) ReadFile(key string) ([]byte, error) {
+	content, _, err := b.read(key)
+
+	return content, err
+}
+
+func (b *S3) WriteFile(key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("s3: put %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (b *S3) Exists(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	_, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("s3: head %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (b *S3) Remove(key string) error {
+	return b.remove(key, nil)
+}
+
+func (b *S3) Rename(oldKey, newKey string) error {
+	content, err := b.ReadFile(oldKey)
+	if err != nil {
+		return err
+	}
+
+	err = b.WriteFile(newKey, content)
+	if err != nil {
+		return err
+	}
+
+	return b.Remove(oldKey)
+}
+
+func (b *S3) List(prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.objectKey(prefix)),
+	})
+
+	var keys []string
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("s3: list %s: %w", prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, b.relativeKey(aws.ToString(object.Key)))
+		}
+	}
+
+	return keys, nil
+}
+
+// Lock creates a lock object with a conditional write: the creation fails if the object already exists.
+// A lock older than the lock TTL is considered stale and is replaced.
+// The lock object is refreshed periodically (conditional write) until the lock is released.
+func (b *S3) Lock(name string) (func() error, error) {
+	info, err := newLockInfo(b.lockTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKey(name)
+
+	etag, err := b.createExclusive(key, content)
+	if errors.Is(err, os.ErrExist) {
+		raw, currentETag, errR := b.read(key)
+		if errR != nil && !errors.Is(errR, os.ErrNotExist) {
+			return nil, errR
+		}
+
+		current := &lockInfo{}
+		if errR == nil {
+			// A corrupted lock is considered stale.
+			_ = json.Unmarshal(raw, current)
+		}
+
+		if !current.expired() {
+			return nil, lockedError(name, current)
+		}
+
+		// Stale lock: it is only removed if it has not been modified in the meantime.
+		if errR == nil {
+			err = b.remove(key, currentETag)
+			if err != nil && !isPreconditionFailed(err) {
+				return nil, fmt.Errorf("remove stale lock: %w", err)
+			}
+		}
+
+		etag, err = b.createExclusive(key, content)
+	}
+
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%s: %w", name, ErrLocked)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("create lock: %w", err)
+	}
+
+	var mu sync.Mutex
+
+	stopRefresh := refreshLock(name, b.lockTTL, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		refreshed := *info
+		refreshed.Expires = time.Now().Add(b.lockTTL).UTC()
+
+		data, errM := json.Marshal(refreshed)
+		if errM != nil {
+			return errM
+		}
+
+		newETag, errP := b.replace(key, data, etag)
+		if errP != nil {
+			return errP
+		}
+
+		etag = newETag
+
+		return nil
+	})
+
+	return func() error {
+		stopRefresh()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		err := b.remove(key, etag)
+		if err != nil && !isPreconditionFailed(err) && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}, nil
+}
+
+func (b *S3) Location(key string) string {
+	return "s3://" + b.bucket + "/" + b.objectKey(key)
+}
+
+func (b *S3) read(key string) ([]byte, *string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	output, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, fmt.Errorf("s3: get %s: %w", key, os.ErrNotExist)
+		}
+
+		return nil, nil, fmt.Errorf("s3: get %s: %w", key, err)
+	}
+
+	defer func() { _ = output.Body.Close() }()
+
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("s3: read %s: %w", key, err)
+	}
+
+	return content, output.ETag, nil
+}
+
+func (b *S3) createExclusive(key string, data []byte) (*string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	output, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.objectKey(key)),
+		Body:        bytes.NewReader(data),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return nil, fmt.Errorf("s3: put %s: %w", key, os.ErrExist)
+		}
+
+		return nil, fmt.Errorf("s3: put %s: %w", key, err)
+	}
+
+	return output.ETag, nil
+}
+
+// replace replaces the content of an object, only if it has not been modified since the read of the ETag.
+func (b *S3) replace(key string, data []byte, etag *string) (*string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	output, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:  aws.String(b.bucket),
+		Key:     aws.String(b.objectKey(key)),
+		Body:    bytes.NewReader(data),
+		IfMatch: etag,
+	})
+	if err != nil {
+		if isPreconditionFailed(err) || isNotFound(err) {
+			return nil, errLockLost
+		}
+
+		return nil, fmt.Errorf("s3: put %s: %w", key, err)
+	}
+
+	return output.ETag, nil
+}
+
+func (b *S3) remove(key string, etag *string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(b.bucket),
+		Key:     aws.String(b.objectKey(key)),
+		IfMatch: etag,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("s3: delete %s: %w", key, os.ErrNotExist)
+		}
+
+		return fmt.Errorf("s3: delete %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (b *S3) objectKey(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+
+	return b.prefix + "/" + key
+}
+
+func (b *S3) relativeKey(objectKey string) string {
+	if b.prefix == "" {
+		return objectKey
+	}
+
+	return strings.TrimPrefix(objectKey, b.prefix+"/")
+}
+
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+
+	var notFound *types.NotFound
+
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound) || hasStatusCode(err, http.StatusNotFound)
+}
+
+func isPreconditionFailed(err error) bool {
+	// Some S3-compatible object stores return a 409 Conflict when a concurrent conditional write is in progress.
+	return hasStatusCode(err, http.StatusPreconditionFailed) || hasStatusCode(err, http.StatusConflict)
+}
+
+func hasStatusCode(err error, code int) bool {
+	var respErr *awshttp.ResponseError
+
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == code
+}
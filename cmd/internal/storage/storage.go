@@ -0,0 +1,126 @@
+// Package storage provides the backends used to store the data of lego (accounts, certificates).
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-acme/lego/v4/log"
+)
+
+// ErrLocked is returned when a lock is already held by another process.
+var ErrLocked = errors.New("locked by another process")
+
+// errLockLost is returned when refreshing a lock which has been taken by another process.
+var errLockLost = errors.New("the lock has been taken by another process")
+
+// DefaultLockTTL the default duration after which a lock is considered stale.
+// A held lock is refreshed periodically (see refreshLock): only the locks of the dead processes become stale.
+const DefaultLockTTL = time.Hour
+
+// Backend a storage backend.
+//
+// A key is a slash-separated path relative to the root of the storage (ex: "certificates/example.com.crt").
+// The errors related to missing keys wrap [os.ErrNotExist].
+type Backend interface {
+	// ReadFile reads the content of a key.
+	ReadFile(key string) ([]byte, error)
+	// WriteFile writes the content of a key, the parent "directories" are created if needed.
+	WriteFile(key string, data []byte) error
+	// Exists checks if a key exists.
+	Exists(key string) (bool, error)
+	// Remove removes a key.
+	Remove(key string) error
+	// Rename moves the content of a key to another key.
+	Rename(oldKey, newKey string) error
+	// List returns all the keys with the given prefix.
+	List(prefix string) ([]string, error)
+	// Lock acquires an exclusive lock.
+	// Returns an error wrapping ErrLocked if the lock is already held by another process.
+	// The lock is released by calling the returned function.
+	Lock(name string) (func() error, error)
+	// Location returns the human-readable location of a key (file path, URL).
+	Location(key string) string
+}
+
+// lockInfo the content of a lock.
+type lockInfo struct {
+	Owner   string    `json:"owner"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+func newLockInfo(ttl time.Duration) (*lockInfo, error) {
+	raw := make([]byte, 16)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		return nil, fmt.Errorf("generate lock token: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &lockInfo{
+		Owner:   hostname + ":" + strconv.Itoa(os.Getpid()),
+		Token:   hex.EncodeToString(raw),
+		Expires: time.Now().Add(ttl).UTC(),
+	}, nil
+}
+
+func (l *lockInfo) expired() bool {
+	return time.Now().After(l.Expires)
+}
+
+// refreshLock refreshes a lock every third of the lock TTL, until the returned function is called.
+func refreshLock(name string, ttl time.Duration, refresh func() error) func() {
+	interval := ttl / 3
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+
+			case <-ticker.C:
+				err := refresh()
+				if errors.Is(err, errLockLost) {
+					log.Warn("The lock has been taken by another process.", "lock", name)
+					return
+				}
+
+				if err != nil {
+					log.Warn("Could not refresh the lock.", "lock", name, log.AttrError, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func lockKey(name string) string {
+	return "locks/" + name + ".lock"
+}
+
+func lockedError(name string, info *lockInfo) error {
+	return fmt.Errorf("%s: %w (owner: %s, until %s)", name, ErrLocked, info.Owner, info.Expires.Format(time.RFC3339))
+}
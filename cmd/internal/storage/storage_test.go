@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSystem(t *testing.T) {
+	testBackend(t, NewFileSystem(t.TempDir()))
+}
+
+func TestFileSystem_Lock_stale(t *testing.T) {
+	backend := NewFileSystem(t.TempDir())
+	backend.lockTTL = -time.Minute
+
+	_, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	// The previous lock is expired.
+	unlock, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, unlock())
+}
+
+func TestFileSystem_Lock_refresh(t *testing.T) {
+	backend := NewFileSystem(t.TempDir())
+	backend.lockTTL = 300 * time.Millisecond
+
+	testLockRefresh(t, backend)
+}
+
+func TestFileSystem_Lock_staleReplaced(t *testing.T) {
+	backend := NewFileSystem(t.TempDir())
+	backend.lockTTL = -time.Minute
+
+	unlockStale, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	backend.lockTTL = time.Hour
+
+	unlock, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	// The lock of the stale process is not released by it: the lock is held by another process.
+	require.NoError(t, unlockStale())
+
+	_, err = backend.Lock("example.com")
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, unlock())
+
+	// No temporary file is left.
+	keys, err := backend.List("locks/")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestS3(t *testing.T) {
+	testBackend(t, setupS3(t, "lego"))
+}
+
+func TestS3_Lock_stale(t *testing.T) {
+	backend := setupS3(t, "")
+	backend.lockTTL = -time.Minute
+
+	_, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	// The previous lock is expired.
+	unlock, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, unlock())
+}
+
+func TestS3_Lock_refresh(t *testing.T) {
+	backend := setupS3(t, "")
+	backend.lockTTL = 300 * time.Millisecond
+
+	testLockRefresh(t, backend)
+}
+
+func TestErrLocked(t *testing.T) {
+	err := lockedError("example.com", &lockInfo{Owner: "host:1", Expires: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+	require.ErrorIs(t, err, ErrLocked)
+	assert.EqualError(t, err, "example.com: locked by another process (owner: host:1, until 2025-01-01T00:00:00Z)")
+}
+
+func testBackend(t *testing.T, backend Backend) {
+	t.Helper()
+
+	t.Run("read/write", func(t *testing.T) {
+		_, err := backend.ReadFile("certificates/example.com.crt")
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		exists, err := backend.Exists("certificates/example.com.crt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		err = backend.WriteFile("certificates/example.com.crt", []byte("cert"))
+		require.NoError(t, err)
+
+		exists, err = backend.Exists("certificates/example.com.crt")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		content, err := backend.ReadFile("certificates/example.com.crt")
+		require.NoError(t, err)
+		assert.Equal(t, "cert", string(content))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		err := backend.WriteFile("certificates/example.org.crt", []byte("cert"))
+		require.NoError(t, err)
+
+		err = backend.WriteFile("accounts/example.com/foo@example.com/account.json", []byte("{}"))
+		require.NoError(t, err)
+
+		keys, err := backend.List("certificates/")
+		require.NoError(t, err)
+
+		slices.Sort(keys)
+		assert.Equal(t, []string{"certificates/example.com.crt", "certificates/example.org.crt"}, keys)
+
+		keys, err = backend.List("accounts/")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"accounts/example.com/foo@example.com/account.json"}, keys)
+
+		keys, err = backend.List("missing/")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("rename/remove", func(t *testing.T) {
+		err := backend.Rename("certificates/example.org.crt", "archives/123.example.org.crt")
+		require.NoError(t, err)
+
+		exists, err := backend.Exists("certificates/example.org.crt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		content, err := backend.ReadFile("archives/123.example.org.crt")
+		require.NoError(t, err)
+		assert.Equal(t, "cert", string(content))
+
+		err = backend.Remove("archives/123.example.org.crt")
+		require.NoError(t, err)
+
+		exists, err = backend.Exists("archives/123.example.org.crt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		unlock, err := backend.Lock("example.com")
+		require.NoError(t, err)
+
+		_, err = backend.Lock("example.com")
+		require.ErrorIs(t, err, ErrLocked)
+
+		// Another lock is not impacted.
+		unlockOther, err := backend.Lock("example.org")
+		require.NoError(t, err)
+		require.NoError(t, unlockOther())
+
+		require.NoError(t, unlock())
+
+		unlock, err = backend.Lock("example.com")
+		require.NoError(t, err)
+		require.NoError(t, unlock())
+	})
+}
+
+// testLockRefresh checks that a held lock is not considered stale after its TTL.
+func testLockRefresh(t *testing.T, backend Backend) {
+	t.Helper()
+
+	unlock, err := backend.Lock("example.com")
+	require.NoError(t, err)
+
+	time.Sleep(time.Second)
+
+	_, err = backend.Lock("example.com")
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, unlock())
+
+	unlock, err = backend.Lock("example.com")
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
+
+func setupS3(t *testing.T, prefix string) *S3 {
+	t.Helper()
+
+	server := httptest.NewServer(newFakeS3("bucket"))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	backend, err := NewS3(S3Config{Bucket: "bucket", Prefix: prefix, Endpoint: server.URL})
+	require.NoError(t, err)
+
+	return backend
+}
+
+// fakeS3 a minimal in-memory S3-compatible object store (path-style).
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, ok := strings.CutPrefix(req.URL.Path, "/"+f.bucket)
+	if !ok {
+		writeS3Error(rw, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	key = strings.TrimPrefix(key, "/")
+
+	if key == "" && req.Method == http.MethodGet {
+		f.list(rw, req.URL.Query().Get("prefix"))
+		return
+	}
+
+	content, exists := f.objects[key]
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			writeS3Error(rw, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		rw.Header().Set("ETag", etag(content))
+
+		if req.Method == http.MethodGet {
+			_, _ = rw.Write(content)
+		}
+
+	case http.MethodPut:
+		if req.Header.Get("If-None-Match") == "*" && exists {
+			writeS3Error(rw, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		if match := req.Header.Get("If-Match"); match != "" && (!exists || match != etag(content)) {
+			writeS3Error(rw, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeS3Error(rw, http.StatusInternalServerError, "InternalError")
+			return
+		}
+
+		f.objects[key] = body
+
+		rw.Header().Set("ETag", etag(body))
+
+	case http.MethodDelete:
+		if match := req.Header.Get("If-Match"); match != "" && (!exists || match != etag(content)) {
+			writeS3Error(rw, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		delete(f.objects, key)
+
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(rw, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) list(rw http.ResponseWriter, prefix string) {
+	type object struct {
+		Key string `xml:"Key"`
+	}
+
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string   `xml:"Name"`
+		Prefix      string   `xml:"Prefix"`
+		IsTruncated bool     `xml:"IsTruncated"`
+		Contents    []object `xml:"Contents"`
+	}{Name: f.bucket, Prefix: prefix}
+
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, object{Key: key})
+		}
+	}
+
+	slices.SortFunc(result.Contents, func(a, b object) int { return strings.Compare(a.Key, b.Key) })
+
+	rw.Header().Set("Content-Type", "application/xml")
+
+	_ = xml.NewEncoder(rw).Encode(result)
+}
+
+func writeS3Error(rw http.ResponseWriter, status int, code string) {
+	rw.Header().Set("Content-Type", "application/xml")
+	rw.WriteHeader(status)
+
+	_, _ = io.WriteString(rw, "<Error><Code>"+code+"</Code><Message>"+code+"</Message></Error>")
+}
+
+func etag(content []byte) string {
+	sum := md5.Sum(content)
+
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
@@ -3,10 +3,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"syscall"
 
 	"github.com/go-acme/lego/v4/cmd"
 	"github.com/go-acme/lego/v4/log"
@@ -38,7 +41,12 @@ func main() {
 
 	app.Commands = cmd.CreateCommands()
 
-	err = app.Run(os.Args)
+	// The context is canceled on interruption: the ongoing operations are stopped,
+	// and the challenges already presented are cleaned up.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = app.RunContext(ctx, os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-acme/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// setupLogger configures the logger according to the log level and the log format.
+func setupLogger(ctx *cli.Context) error {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(ctx.String(flgLogLevel)))
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", ctx.String(flgLogLevel))
+	}
+
+	switch ctx.String(flgLogFormat) {
+	case logFormatText:
+		log.SetDefault(slog.New(log.NewStdHandler(level)))
+
+	case logFormatJSON:
+		logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+		log.SetDefault(logger)
+
+		// The unstructured entries are also written as JSON.
+		log.Logger = log.NewStdLogger(logger)
+
+	default:
+		return fmt.Errorf("invalid log format: %s. Supported: %s, %s", ctx.String(flgLogFormat), logFormatText, logFormatJSON)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/cmd/internal/prometheus"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/metrics"
+	"github.com/urfave/cli/v2"
+)
+
+// startMetricsServer starts an HTTP server exposing the metrics in the Prometheus text format, and a health check.
+func startMetricsServer(ctx *cli.Context) error {
+	registry := prometheus.NewRegistry()
+
+	metrics.SetRecorder(registry)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler(registry, collectCertificatesExpiry(NewCertificatesStorage(ctx))))
+	mux.HandleFunc("/health", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = rw.Write([]byte("OK"))
+	})
+
+	listener, err := net.Listen("tcp", ctx.String(flgMetricsAddr))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Infof("Metrics available on http://%s/metrics", listener.Addr())
+
+	go func() {
+		errS := server.Serve(listener)
+		if errS != nil && !errors.Is(errS, http.ErrServerClosed) {
+			log.Warnf("The metrics server has stopped: %v", errS)
+		}
+	}()
+
+	go func() {
+		<-ctx.Context.Done()
+
+		_ = server.Close()
+	}()
+
+	return nil
+}
+
+// collectCertificatesExpiry updates the expiration dates of the stored certificates.
+func collectCertificatesExpiry(certsStorage *CertificatesStorage) func(*prometheus.Registry) {
+	return func(registry *prometheus.Registry) {
+		domains, err := certsStorage.ListDomains()
+		if err != nil {
+			log.Warnf("Could not list the certificates for the metrics: %v", err)
+			return
+		}
+
+		for _, domain := range domains {
+			certificates, err := certsStorage.ReadCertificate(domain, certExt)
+			if err != nil || len(certificates) == 0 {
+				continue
+			}
+
+			// Uses the same label as the certificates obtained by the current process.
+			name, err := certcrypto.GetCertificateMainDomain(certificates[0])
+			if err != nil {
+				name = domain
+			}
+
+			registry.CertificateExpiry(name, certificates[0].NotAfter)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/ratelimit"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const baseRateLimitFolderName = "ratelimit"
+
+// rateLimitConfig the rate limits of the ACME servers (--ratelimit.config).
+type rateLimitConfig struct {
+	// Servers the rate limits by directory URL.
+	Servers map[string]ratelimit.Limits `yaml:"servers"`
+}
+
+// setupRateLimiter creates the rate limiter of the client, nil if the rate limits are not handled.
+func setupRateLimiter(ctx *cli.Context) (*ratelimit.Limiter, error) {
+	if !ctx.Bool(flgRateLimit) && ctx.Duration(flgRateLimitMaxRetryAfter) <= 0 {
+		return nil, nil
+	}
+
+	config := ratelimit.Config{
+		MaxDelay:      ctx.Duration(flgRateLimitMaxDelay),
+		MaxRetryAfter: ctx.Duration(flgRateLimitMaxRetryAfter),
+	}
+
+	if !ctx.Bool(flgRateLimit) {
+		return ratelimit.NewLimiter(nil, config), nil
+	}
+
+	limits, err := getRateLimits(ctx.String(flgServer), ctx.String(flgRateLimitConfig))
+	if err != nil {
+		return nil, fmt.Errorf("could not load the rate limits: %w", err)
+	}
+
+	config.Limits = limits
+
+	serverURL, err := url.Parse(ctx.String(flgServer))
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := tryNewStorageBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path.Join(baseRateLimitFolderName, strings.NewReplacer(":", "_").Replace(serverURL.Host)+".json")
+
+	return ratelimit.NewLimiter(ratelimit.NewLedger(&ledgerStore{backend: backend, key: key}), config), nil
+}
+
+// getRateLimits returns the rate limits of the server: from the configuration file if any, or the known limits.
+func getRateLimits(server, filename string) (ratelimit.Limits, error) {
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return ratelimit.Limits{}, err
+		}
+
+		config := &rateLimitConfig{}
+
+		err = yaml.UnmarshalStrict(data, config)
+		if err != nil {
+			return ratelimit.Limits{}, fmt.Errorf("parse %s: %w", filename, err)
+		}
+
+		if limits, ok := config.Servers[server]; ok {
+			return limits, nil
+		}
+	}
+
+	limits, ok := ratelimit.KnownLimits(server)
+	if !ok {
+		log.Warn("No known rate limits for the server: the events are recorded, but no request is limited.", "server", server)
+	}
+
+	return limits, nil
+}
+
+// ledgerStore a ratelimit.Store based on the storage backend.
+type ledgerStore struct {
+	backend storage.Backend
+	key     string
+}
+
+func (s *ledgerStore) Load() ([]byte, error) {
+	data, err := s.backend.ReadFile(s.key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (s *ledgerStore) Save(data []byte) error {
+	return s.backend.WriteFile(s.key, data)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getRateLimits(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ratelimit.yml")
+
+	content := `
+servers:
+  https://ca.example/directory:
+    newOrders:
+      count: 100
+      window: 3h
+    duplicateCertificates:
+      count: 2
+      window: 168h
+`
+
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+
+	testCases := []struct {
+		desc     string
+		server   string
+		filename string
+		expected ratelimit.Limits
+	}{
+		{
+			desc:     "from the configuration file",
+			server:   "https://ca.example/directory",
+			filename: filename,
+			expected: ratelimit.Limits{
+				NewOrders:             ratelimit.Limit{Count: 100, Window: 3 * time.Hour},
+				DuplicateCertificates: ratelimit.Limit{Count: 2, Window: 7 * 24 * time.Hour},
+			},
+		},
+		{
+			desc:     "known limits",
+			server:   lego.LEDirectoryProduction,
+			filename: filename,
+			expected: ratelimit.LetsEncrypt,
+		},
+		{
+			desc:     "known limits without configuration file",
+			server:   lego.LEDirectoryStaging,
+			expected: ratelimit.LetsEncryptStaging,
+		},
+		{
+			desc:   "unknown server",
+			server: "https://ca.example/directory",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			limits, err := getRateLimits(test.server, test.filename)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, limits)
+		})
+	}
+}
+
+func Test_checkRetry_rateLimited(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		handleRateLimits bool
+		expectedRetry    bool
+		assertErr        func(t *testing.T, err error)
+	}{
+		{
+			desc:             "rate limits handled",
+			handleRateLimits: true,
+			assertErr: func(t *testing.T, err error) {
+				t.Helper()
+
+				var rateLimitedErr *acme.RateLimitedError
+				require.ErrorAs(t, err, &rateLimitedErr)
+
+				assert.Equal(t, "120", rateLimitedErr.RetryAfter)
+			},
+		},
+		{
+			desc:          "rate limits not handled",
+			expectedRetry: true,
+			assertErr: func(t *testing.T, err error) {
+				t.Helper()
+
+				var problem *acme.ProblemDetails
+				require.ErrorAs(t, err, &problem)
+
+				assert.Equal(t, acme.RateLimitedErr, problem.Type)
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "https://ca.example/acme/new-order", http.NoBody)
+
+			resp := &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": []string{"120"}},
+				Body:       io.NopCloser(strings.NewReader(`{"type":"urn:ietf:params:acme:error:rateLimited","detail":"too many new orders","status":429}`)),
+				Request:    req,
+			}
+
+			retry, err := newCheckRetry(test.handleRateLimits)(t.Context(), resp, nil)
+
+			assert.Equal(t, test.expectedRetry, retry)
+			test.assertErr(t, err)
+		})
+	}
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/cmd/internal/storage"
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/go-acme/lego/v4/log"
 	"github.com/go-acme/lego/v4/registration"
@@ -21,32 +23,110 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-const filePerm os.FileMode = 0o600
-
 // setupClient creates a new client with challenge settings.
 func setupClient(ctx *cli.Context, account *Account, keyType certcrypto.KeyType) *lego.Client {
-	client := newClient(ctx, account, keyType)
-
-	setupChallenges(ctx, client)
+	client, err := trySetupClient(ctx, account, keyType)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	return client
 }
 
-func setupAccount(ctx *cli.Context, accountsStorage *AccountsStorage) (*Account, certcrypto.KeyType) {
-	keyType := getKeyType(ctx)
-	privateKey := accountsStorage.GetPrivateKey(keyType)
+// trySetupClient creates a new client with challenge settings.
+// Unlike setupClient, it returns the errors (e.g. the reload of the daemon).
+func trySetupClient(ctx *cli.Context, account *Account, keyType certcrypto.KeyType) (*lego.Client, error) {
+	client, err := tryNewClient(ctx, account, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setupChallenges(ctx, client)
+	if err != nil {
+		return nil, err
+	}
 
-	var account *Account
-	if accountsStorage.ExistsAccountFilePath() {
-		account = accountsStorage.LoadAccount(privateKey)
-	} else {
-		account = &Account{Email: accountsStorage.GetEmail(), key: privateKey}
+	return client, nil
+}
+
+func setupAccount(ctx *cli.Context, accountsStorage *AccountsStorage) (*Account, certcrypto.KeyType) {
+	account, keyType, err := trySetupAccount(ctx, accountsStorage)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	return account, keyType
 }
 
+// trySetupAccount loads the account, or creates it (not registered).
+// Unlike setupAccount, it returns the errors (e.g. the reload of the daemon).
+func trySetupAccount(ctx *cli.Context, accountsStorage *AccountsStorage) (*Account, certcrypto.KeyType, error) {
+	keyType, err := parseKeyType(ctx.String(flgKeyType))
+	if err != nil {
+		return nil, "", err
+	}
+
+	privateKey, err := accountsStorage.tryGetPrivateKey(keyType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exists, err := accountsStorage.tryExistsAccountFilePath()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !exists {
+		return &Account{Email: accountsStorage.GetEmail(), key: privateKey}, keyType, nil
+	}
+
+	account, err := accountsStorage.tryLoadAccount(privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return account, keyType, nil
+}
+
 func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyType) *lego.Client {
+	client, err := tryNewClient(ctx, acc, keyType)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return client
+}
+
+func tryNewClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyType) (*lego.Client, error) {
+	client, err := tryNewAnonymousClient(ctx, acc, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.GetExternalAccountRequired() && !ctx.IsSet(flgEAB) {
+		return nil, fmt.Errorf("server requires External Account Binding. Use --%s with --%s and --%s", flgEAB, flgKID, flgHMAC)
+	}
+
+	return client, nil
+}
+
+// newAnonymousClient creates a client without the account checks:
+// only for the requests which don't rely on an account (ex: revocation with the certificate key).
+func newAnonymousClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyType) *lego.Client {
+	client, err := tryNewAnonymousClient(ctx, acc, keyType)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return client
+}
+
+func tryNewAnonymousClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyType) (*lego.Client, error) {
+	rateLimiter, err := setupRateLimiter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	config := lego.NewConfig(acc)
 	config.CADirURL = ctx.String(flgServer)
 
@@ -55,9 +135,20 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 		Timeout:             time.Duration(ctx.Int(flgCertTimeout)) * time.Second,
 		OverallRequestLimit: ctx.Int(flgOverallRequestLimit),
 		DisableCommonName:   ctx.Bool(flgDisableCommonName),
+		CheckCAA:            ctx.Bool(flgCAACheck),
+		RateLimiter:         rateLimiter,
 	}
 	config.UserAgent = getUserAgent(ctx)
 
+	// The default resolver is used by the zone lookups of the DNS providers, and by the CAA checks.
+	if len(ctx.StringSlice(flgDNSResolvers)) > 0 {
+		dns01.SetRecursiveNameservers(ctx.StringSlice(flgDNSResolvers))
+	}
+
+	if ctx.IsSet(flgDNSTimeout) {
+		dns01.DefaultResolver().SetTimeout(time.Duration(ctx.Int(flgDNSTimeout)) * time.Second)
+	}
+
 	if ctx.IsSet(flgHTTPTimeout) {
 		config.HTTPClient.Timeout = time.Duration(ctx.Int(flgHTTPTimeout)) * time.Second
 	}
@@ -74,7 +165,7 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = 5
 	retryClient.HTTPClient = config.HTTPClient
-	retryClient.CheckRetry = checkRetry
+	retryClient.CheckRetry = newCheckRetry(config.Certificate.RateLimiter != nil)
 	retryClient.Logger = nil
 
 	if _, v := os.LookupEnv("LEGO_DEBUG_ACME_HTTP_CLIENT"); v {
@@ -85,37 +176,39 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 
 	client, err := lego.NewClient(config)
 	if err != nil {
-		log.Fatalf("Could not create client: %v", err)
-	}
-
-	if client.GetExternalAccountRequired() && !ctx.IsSet(flgEAB) {
-		log.Fatalf("Server requires External Account Binding. Use --%s with --%s and --%s.", flgEAB, flgKID, flgHMAC)
+		return nil, fmt.Errorf("could not create client: %w", err)
 	}
 
-	return client
+	return client, nil
 }
 
 // getKeyType the type from which private keys should be generated.
 func getKeyType(ctx *cli.Context) certcrypto.KeyType {
-	keyType := ctx.String(flgKeyType)
+	keyType, err := parseKeyType(ctx.String(flgKeyType))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return keyType
+}
+
+func parseKeyType(keyType string) (certcrypto.KeyType, error) {
 	switch strings.ToUpper(keyType) {
 	case "RSA2048":
-		return certcrypto.RSA2048
+		return certcrypto.RSA2048, nil
 	case "RSA3072":
-		return certcrypto.RSA3072
+		return certcrypto.RSA3072, nil
 	case "RSA4096":
-		return certcrypto.RSA4096
+		return certcrypto.RSA4096, nil
 	case "RSA8192":
-		return certcrypto.RSA8192
+		return certcrypto.RSA8192, nil
 	case "EC256":
-		return certcrypto.EC256
+		return certcrypto.EC256, nil
 	case "EC384":
-		return certcrypto.EC384
+		return certcrypto.EC384, nil
 	}
 
-	log.Fatalf("Unsupported KeyType: %s", keyType)
-
-	return ""
+	return "", fmt.Errorf("unsupported key type: %s", keyType)
 }
 
 func getUserAgent(ctx *cli.Context) string {
@@ -132,6 +225,43 @@ func createNonExistingFolder(path string) error {
 	return nil
 }
 
+const (
+	storageFile = "file"
+	storageS3   = "s3"
+)
+
+// newStorageBackend creates the storage backend of the accounts and the certificates.
+func newStorageBackend(ctx *cli.Context) storage.Backend {
+	backend, err := tryNewStorageBackend(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return backend
+}
+
+func tryNewStorageBackend(ctx *cli.Context) (storage.Backend, error) {
+	switch ctx.String(flgStorage) {
+	case storageFile:
+		return storage.NewFileSystem(ctx.String(flgPath)), nil
+
+	case storageS3:
+		backend, err := storage.NewS3(storage.S3Config{
+			Bucket:   ctx.String(flgStorageS3Bucket),
+			Prefix:   ctx.String(flgStorageS3Prefix),
+			Endpoint: ctx.String(flgStorageS3Endpoint),
+			Region:   ctx.String(flgStorageS3Region),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create the storage: %w", err)
+		}
+
+		return backend, nil
+	}
+
+	return nil, fmt.Errorf("unsupported storage: %s", ctx.String(flgStorage))
+}
+
 func readCSRFile(filename string) (*x509.CertificateRequest, error) {
 	bytes, err := os.ReadFile(filename)
 	if err != nil {
@@ -165,7 +295,16 @@ func readCSRFile(filename string) (*x509.CertificateRequest, error) {
 	return x509.ParseCertificateRequest(raw)
 }
 
-func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+// newCheckRetry creates the retry policy of the HTTP client.
+// When the rate limits are handled (rate limiter),
+// the rate-limited requests are not retried by the HTTP client: the waiting is driven by the rate limiter.
+func newCheckRetry(handleRateLimits bool) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return checkRetry(ctx, resp, err, handleRateLimits)
+	}
+}
+
+func checkRetry(ctx context.Context, resp *http.Response, err error, handleRateLimits bool) (bool, error) {
 	rt, err := retryablehttp.ErrorPropagatedRetryPolicy(ctx, resp, err)
 	if err != nil {
 		return rt, err
@@ -201,6 +340,19 @@ func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, erro
 				}
 			}
 
+		case acme.RateLimitedErr:
+			// The request is not retried: the waiting is driven by the Retry-After (see --ratelimit.max-retry-after).
+			if handleRateLimits && errorDetails.HTTPStatus == http.StatusTooManyRequests {
+				return false, &acme.RateLimitedError{
+					ProblemDetails: errorDetails,
+					RetryAfter:     resp.Header.Get("Retry-After"),
+				}
+			}
+
+			log.Warnf("retry: %v", errorDetails)
+
+			return rt, errorDetails
+
 		default:
 			log.Warnf("retry: %v", errorDetails)
 
@@ -3,11 +3,14 @@ package cmd
 import (
 	"fmt"
 	"net"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/challenge/dnspersist01"
 	"github.com/go-acme/lego/v4/challenge/http01"
 	"github.com/go-acme/lego/v4/challenge/tlsalpn01"
 	"github.com/go-acme/lego/v4/lego"
@@ -19,66 +22,117 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-func setupChallenges(ctx *cli.Context, client *lego.Client) {
-	if !ctx.Bool(flgHTTP) && !ctx.Bool(flgTLS) && !ctx.IsSet(flgDNS) {
-		log.Fatalf("No challenge selected. You must specify at least one challenge: `--%s`, `--%s`, `--%s`.", flgHTTP, flgTLS, flgDNS)
+func setupChallenges(ctx *cli.Context, client *lego.Client) error {
+	if !ctx.Bool(flgHTTP) && !ctx.Bool(flgTLS) && !ctx.IsSet(flgDNS) && !ctx.Bool(flgDNSPersist) && !ctx.IsSet(flgDomainChallenge) {
+		return fmt.Errorf("no challenge selected. You must specify at least one challenge: `--%s`, `--%s`, `--%s`, `--%s`, `--%s`",
+			flgHTTP, flgTLS, flgDNS, flgDNSPersist, flgDomainChallenge)
 	}
 
+	// The built-in servers are shared by all the domains (and by the concurrent orders of the batch command).
+	httpProvider := sync.OnceValues(func() (challenge.Provider, error) {
+		return setupHTTPProvider(ctx)
+	})
+
+	tlsProvider := sync.OnceValues(func() (challenge.Provider, error) {
+		return setupTLSProvider(ctx)
+	})
+
 	if ctx.Bool(flgHTTP) {
-		err := client.Challenge.SetHTTP01Provider(setupHTTPProvider(ctx), http01.SetDelay(ctx.Duration(flgHTTPDelay)))
+		provider, err := httpProvider()
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+
+		err = client.Challenge.SetHTTP01Provider(provider, httpChallengeOptions(ctx)...)
+		if err != nil {
+			return err
 		}
 	}
 
 	if ctx.Bool(flgTLS) {
-		err := client.Challenge.SetTLSALPN01Provider(setupTLSProvider(ctx), tlsalpn01.SetDelay(ctx.Duration(flgTLSDelay)))
+		provider, err := tlsProvider()
+		if err != nil {
+			return err
+		}
+
+		err = client.Challenge.SetTLSALPN01Provider(provider, tlsalpn01.SetDelay(ctx.Duration(flgTLSDelay)))
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
 
 	if ctx.IsSet(flgDNS) {
 		err := setupDNS(ctx, client)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+	}
+
+	if ctx.Bool(flgDNSPersist) {
+		err := client.Challenge.SetDNSPersist01(
+			dnspersist01.CondOption(ctx.IsSet(flgDNSPersistIssuer),
+				dnspersist01.SetIssuerDomainName(ctx.String(flgDNSPersistIssuer))),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ctx.IsSet(flgDomainChallenge) {
+		err := setupDomainChallenges(ctx, client, httpProvider, tlsProvider)
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
+}
+
+func httpChallengeOptions(ctx *cli.Context) []http01.ChallengeOption {
+	return []http01.ChallengeOption{
+		http01.SetDelay(ctx.Duration(flgHTTPDelay)),
+		http01.CondOption(ctx.Bool(flgHTTPSelfCheck),
+			http01.SelfCheck(http01.SelfCheckOptions{
+				Target:   ctx.String(flgHTTPSelfCheckTarget),
+				Resolver: ctx.String(flgHTTPSelfCheckResolver),
+				Timeout:  ctx.Duration(flgHTTPSelfCheckTimeout),
+			})),
+	}
 }
 
 //nolint:gocyclo // the complexity is expected.
-func setupHTTPProvider(ctx *cli.Context) challenge.Provider {
+func setupHTTPProvider(ctx *cli.Context) (challenge.Provider, error) {
 	switch {
 	case ctx.IsSet(flgHTTPWebroot):
 		ps, err := webroot.NewHTTPProvider(ctx.String(flgHTTPWebroot))
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		return ps
+		return ps, nil
 	case ctx.IsSet(flgHTTPMemcachedHost):
 		ps, err := memcached.NewMemcachedProvider(ctx.StringSlice(flgHTTPMemcachedHost))
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		return ps
+		return ps, nil
 	case ctx.IsSet(flgHTTPS3Bucket):
 		ps, err := s3.NewHTTPProvider(ctx.String(flgHTTPS3Bucket))
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		return ps
+		return ps, nil
 	case ctx.IsSet(flgHTTPPort):
 		iface := ctx.String(flgHTTPPort)
 		if !strings.Contains(iface, ":") {
-			log.Fatalf("The --%s switch only accepts interface:port or :port for its argument.", flgHTTPPort)
+			return nil, fmt.Errorf("the --%s switch only accepts interface:port or :port for its argument", flgHTTPPort)
 		}
 
 		host, port, err := net.SplitHostPort(iface)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
 		srv := http01.NewProviderServer(host, port)
@@ -86,51 +140,47 @@ func setupHTTPProvider(ctx *cli.Context) challenge.Provider {
 			srv.SetProxyHeader(header)
 		}
 
-		return srv
-	case ctx.Bool(flgHTTP):
+		return srv, nil
+	default:
 		srv := http01.NewProviderServer("", "")
 		if header := ctx.String(flgHTTPProxyHeader); header != "" {
 			srv.SetProxyHeader(header)
 		}
 
-		return srv
-	default:
-		log.Fatal("Invalid HTTP challenge options.")
-		return nil
+		return srv, nil
 	}
 }
 
-func setupTLSProvider(ctx *cli.Context) challenge.Provider {
+func setupTLSProvider(ctx *cli.Context) (challenge.Provider, error) {
 	switch {
 	case ctx.IsSet(flgTLSPort):
 		iface := ctx.String(flgTLSPort)
 		if !strings.Contains(iface, ":") {
-			log.Fatalf("The --%s switch only accepts interface:port or :port for its argument.", flgTLSPort)
+			return nil, fmt.Errorf("the --%s switch only accepts interface:port or :port for its argument", flgTLSPort)
 		}
 
 		host, port, err := net.SplitHostPort(iface)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		return tlsalpn01.NewProviderServer(host, port)
-	case ctx.Bool(flgTLS):
-		return tlsalpn01.NewProviderServer("", "")
+		return tlsalpn01.NewProviderServer(host, port), nil
 	default:
-		log.Fatal("Invalid HTTP challenge options.")
-		return nil
+		return tlsalpn01.NewProviderServer("", ""), nil
 	}
 }
 
 func setupDNS(ctx *cli.Context, client *lego.Client) error {
-	err := checkPropagationExclusiveOptions(ctx)
-	if err != nil {
-		return err
+	if ctx.Bool(flgDNSAccount) {
+		err := checkRecordProvider(ctx.String(flgDNS), "the DNS-ACCOUNT-01 challenge (--"+flgDNSAccount+")")
+		if err != nil {
+			return err
+		}
 	}
 
-	wait := ctx.Duration(flgDNSPropagationWait)
-	if wait < 0 {
-		return fmt.Errorf("'%s' cannot be negative", flgDNSPropagationWait)
+	opts, err := dnsChallengeOptions(ctx)
+	if err != nil {
+		return err
 	}
 
 	provider, err := dns.NewDNSChallengeProviderByName(ctx.String(flgDNS))
@@ -138,9 +188,60 @@ func setupDNS(ctx *cli.Context, client *lego.Client) error {
 		return err
 	}
 
+	checkProviderState(ctx, ctx.String(flgDNS), provider)
+
+	if ctx.Bool(flgDNSAccount) {
+		return client.Challenge.SetDNSAccount01Provider(provider, opts...)
+	}
+
+	return client.Challenge.SetDNS01Provider(provider, opts...)
+}
+
+// checkProviderState warns when the challenges are presented in advance (--present-only)
+// with a DNS provider which doesn't save its state (challenge.ProviderState):
+// if the provider keeps a state between Present and CleanUp (e.g. the IDs of the records),
+// the records cannot be cleaned up when the order is resumed by another process.
+func checkProviderState(ctx *cli.Context, name string, provider challenge.Provider) {
+	if !ctx.Bool(flgPresentOnly) {
+		return
+	}
+
+	if _, ok := provider.(challenge.ProviderState); ok {
+		return
+	}
+
+	log.Warn("The DNS provider doesn't save its state: if it keeps the IDs of the records in memory, "+
+		"the records may not be cleaned up when the order is resumed by another process.", log.AttrProvider, name)
+}
+
+// recordProviders the DNS providers able to manage arbitrary TXT records (dns01.RecordProvider).
+// Only these providers support the DNS-ACCOUNT-01 challenge, and the setup of the DNS-PERSIST-01 records.
+var recordProviders = []string{"dnsserver", "exec", "gandiv5", "httpreq", "manual", "rfc2136"}
+
+// checkRecordProvider checks that a DNS provider is able to manage arbitrary TXT records.
+func checkRecordProvider(name, feature string) error {
+	if slices.Contains(recordProviders, name) {
+		return nil
+	}
+
+	return fmt.Errorf("the DNS provider %q doesn't support %s: the supported providers are %s",
+		name, feature, strings.Join(recordProviders, ", "))
+}
+
+func dnsChallengeOptions(ctx *cli.Context) ([]dns01.ChallengeOption, error) {
+	err := checkPropagationExclusiveOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := ctx.Duration(flgDNSPropagationWait)
+	if wait < 0 {
+		return nil, fmt.Errorf("'%s' cannot be negative", flgDNSPropagationWait)
+	}
+
 	servers := ctx.StringSlice(flgDNSResolvers)
 
-	err = client.Challenge.SetDNS01Provider(provider,
+	return []dns01.ChallengeOption{
 		dns01.CondOption(len(servers) > 0,
 			dns01.AddRecursiveNameservers(dns01.ParseNameservers(ctx.StringSlice(flgDNSResolvers)))),
 
@@ -157,9 +258,121 @@ func setupDNS(ctx *cli.Context, client *lego.Client) error {
 
 		dns01.CondOption(ctx.IsSet(flgDNSTimeout),
 			dns01.AddDNSTimeout(time.Duration(ctx.Int(flgDNSTimeout))*time.Second)),
-	)
+	}, nil
+}
+
+// domainChallenge the challenge of the domains matching a pattern (--domain-challenge).
+type domainChallenge struct {
+	pattern  string
+	kind     string
+	provider string
+}
+
+// The kinds of domain challenges.
+const (
+	domainChallengeHTTP = "http"
+	domainChallengeTLS  = "tls"
+	domainChallengeDNS  = "dns"
+)
+
+// parseDomainChallenge parses `<pattern>=http`, `<pattern>=tls`, or `<pattern>=dns:<provider>`.
+func parseDomainChallenge(value string) (domainChallenge, error) {
+	pattern, kind, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(pattern) == "" {
+		return domainChallenge{}, fmt.Errorf("invalid domain challenge %q: the format is <pattern>=<challenge>", value)
+	}
+
+	dc := domainChallenge{pattern: strings.TrimSpace(pattern)}
+
+	dc.kind, dc.provider, _ = strings.Cut(strings.TrimSpace(kind), ":")
+
+	switch dc.kind {
+	case domainChallengeHTTP, domainChallengeTLS:
+		if dc.provider != "" {
+			return domainChallenge{}, fmt.Errorf("invalid domain challenge %q: no provider expected for %s", value, dc.kind)
+		}
+
+	case domainChallengeDNS:
+		if dc.provider == "" {
+			return domainChallenge{}, fmt.Errorf("invalid domain challenge %q: missing DNS provider (dns:<provider>)", value)
+		}
+
+	default:
+		return domainChallenge{}, fmt.Errorf("invalid domain challenge %q: unsupported challenge %q (http, tls, dns:<provider>)", value, dc.kind)
+	}
+
+	return dc, nil
+}
+
+// setupDomainChallenges defines the challenges of the domains matching a pattern (--domain-challenge).
+func setupDomainChallenges(ctx *cli.Context, client *lego.Client, httpProvider, tlsProvider func() (challenge.Provider, error)) error {
+	dnsProviders := make(map[string]challenge.Provider)
+
+	for _, value := range ctx.StringSlice(flgDomainChallenge) {
+		dc, err := parseDomainChallenge(value)
+		if err != nil {
+			return err
+		}
+
+		switch dc.kind {
+		case domainChallengeHTTP:
+			var provider challenge.Provider
+
+			provider, err = httpProvider()
+			if err == nil {
+				err = client.Challenge.SetHTTP01ProviderFor(dc.pattern, provider, httpChallengeOptions(ctx)...)
+			}
+
+		case domainChallengeTLS:
+			var provider challenge.Provider
+
+			provider, err = tlsProvider()
+			if err == nil {
+				err = client.Challenge.SetTLSALPN01ProviderFor(dc.pattern, provider, tlsalpn01.SetDelay(ctx.Duration(flgTLSDelay)))
+			}
+
+		case domainChallengeDNS:
+			err = setupDomainDNS(ctx, client, dc, dnsProviders)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", value, err)
+		}
+	}
+
+	return nil
+}
+
+func setupDomainDNS(ctx *cli.Context, client *lego.Client, dc domainChallenge, providers map[string]challenge.Provider) error {
+	if ctx.Bool(flgDNSAccount) {
+		err := checkRecordProvider(dc.provider, "the DNS-ACCOUNT-01 challenge (--"+flgDNSAccount+")")
+		if err != nil {
+			return err
+		}
+	}
+
+	opts, err := dnsChallengeOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	provider, ok := providers[dc.provider]
+	if !ok {
+		provider, err = dns.NewDNSChallengeProviderByName(dc.provider)
+		if err != nil {
+			return err
+		}
+
+		checkProviderState(ctx, dc.provider, provider)
+
+		providers[dc.provider] = provider
+	}
+
+	if ctx.Bool(flgDNSAccount) {
+		return client.Challenge.SetDNSAccount01ProviderFor(dc.pattern, provider, opts...)
+	}
 
-	return err
+	return client.Challenge.SetDNS01ProviderFor(dc.pattern, provider, opts...)
 }
 
 func checkPropagationExclusiveOptions(ctx *cli.Context) error {
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/providers/dns/dnsserver"
+	"github.com/go-acme/lego/v4/providers/dns/exec"
+	"github.com/go-acme/lego/v4/providers/dns/gandiv5"
+	"github.com/go-acme/lego/v4/providers/dns/httpreq"
+	"github.com/go-acme/lego/v4/providers/dns/manual"
+	"github.com/go-acme/lego/v4/providers/dns/rfc2136"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDomainChallenge(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected domainChallenge
+	}{
+		{
+			desc:     "http",
+			value:    "example.com=http",
+			expected: domainChallenge{pattern: "example.com", kind: domainChallengeHTTP},
+		},
+		{
+			desc:     "tls",
+			value:    " app.example.com = tls ",
+			expected: domainChallenge{pattern: "app.example.com", kind: domainChallengeTLS},
+		},
+		{
+			desc:     "dns",
+			value:    "*.example.com=dns:cloudflare",
+			expected: domainChallenge{pattern: "*.example.com", kind: domainChallengeDNS, provider: "cloudflare"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			dc, err := parseDomainChallenge(test.value)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, dc)
+		})
+	}
+}
+
+func Test_parseDomainChallenge_errors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected string
+	}{
+		{
+			desc:     "missing challenge",
+			value:    "example.com",
+			expected: `invalid domain challenge "example.com": the format is <pattern>=<challenge>`,
+		},
+		{
+			desc:     "missing pattern",
+			value:    "=http",
+			expected: `invalid domain challenge "=http": the format is <pattern>=<challenge>`,
+		},
+		{
+			desc:     "missing DNS provider",
+			value:    "example.com=dns",
+			expected: `invalid domain challenge "example.com=dns": missing DNS provider (dns:<provider>)`,
+		},
+		{
+			desc:     "unexpected provider",
+			value:    "example.com=http:webroot",
+			expected: `invalid domain challenge "example.com=http:webroot": no provider expected for http`,
+		},
+		{
+			desc:     "unsupported challenge",
+			value:    "example.com=foo",
+			expected: `invalid domain challenge "example.com=foo": unsupported challenge "foo" (http, tls, dns:<provider>)`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseDomainChallenge(test.value)
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func Test_recordProviders(t *testing.T) {
+	providers := map[string]challenge.Provider{
+		"dnsserver": (*dnsserver.DNSProvider)(nil),
+		"exec":      (*exec.DNSProvider)(nil),
+		"gandiv5":   (*gandiv5.DNSProvider)(nil),
+		"httpreq":   (*httpreq.DNSProvider)(nil),
+		"manual":    (*manual.DNSProvider)(nil),
+		"rfc2136":   (*rfc2136.DNSProvider)(nil),
+	}
+
+	assert.ElementsMatch(t, recordProviders, slices.Collect(maps.Keys(providers)))
+
+	for name, provider := range providers {
+		assert.Implements(t, (*dns01.RecordProvider)(nil), provider, name)
+	}
+}
+
+func Test_checkRecordProvider(t *testing.T) {
+	require.NoError(t, checkRecordProvider("rfc2136", "the DNS-ACCOUNT-01 challenge"))
+
+	err := checkRecordProvider("route53", "the DNS-ACCOUNT-01 challenge")
+	require.EqualError(t, err, `the DNS provider "route53" doesn't support the DNS-ACCOUNT-01 challenge: `+
+		`the supported providers are dnsserver, exec, gandiv5, httpreq, manual, rfc2136`)
+}
@@ -61,6 +61,7 @@ func allDNSCodes() string {
 		"dnsimple",
 		"dnsmadeeasy",
 		"dnspod",
+		"dnsserver",
 		"dode",
 		"domeneshop",
 		"dreamhost",
@@ -1289,6 +1290,25 @@ func displayDNSHelp(w io.Writer, name string) error {
 		ew.writeln()
 		ew.writeln(`More information: https://go-acme.github.io/lego/dns/dnspod`)
 
+	case "dnsserver":
+		// generated from: providers/dns/dnsserver/dnsserver.toml
+		ew.writeln(`Configuration for Built-in DNS server.`)
+		ew.writeln(`Code:	'dnsserver'`)
+		ew.writeln(`Since:	'v4.35.0'`)
+		ew.writeln()
+
+		ew.writeln(`Credentials:`)
+		ew.writeln(`	- "DNSSERVER_RECORDS_DIR":	The records directory, shared with the DNS server ('lego dns serve --records-dir')`)
+		ew.writeln(`	- "DNSSERVER_ZONE":	The challenge zone served by the DNS server`)
+		ew.writeln()
+
+		ew.writeln(`Additional Configuration:`)
+		ew.writeln(`	- "DNSSERVER_POLLING_INTERVAL":	Time between DNS propagation check in seconds (Default: 2)`)
+		ew.writeln(`	- "DNSSERVER_PROPAGATION_TIMEOUT":	Maximum waiting time for DNS propagation in seconds (Default: 60)`)
+
+		ew.writeln()
+		ew.writeln(`More information: https://go-acme.github.io/lego/dns/dnsserver`)
+
 	case "dode":
 		// generated from: providers/dns/dode/dode.toml
 		ew.writeln(`Configuration for Domain Offensive (do.de).`)
@@ -2643,6 +2663,18 @@ func displayDNSHelp(w io.Writer, name string) error {
 		ew.writeln(`Since:	'v0.3.0'`)
 		ew.writeln()
 
+		ew.writeln(`Additional Configuration:`)
+		ew.writeln(`	- "MANUAL_CONFIRM_ADDRESS":	The address of the HTTP server receiving the confirmations, 'POST /confirm/<id>' (non-interactive mode).`)
+		ew.writeln(`	- "MANUAL_CONFIRM_FILE":	The file signaling the creation of a record, it must contain the ID of the record (non-interactive mode).`)
+		ew.writeln(`	- "MANUAL_CONFIRM_TIMEOUT":	Maximum waiting time for a confirmation in seconds (Default: 3600)`)
+		ew.writeln(`	- "MANUAL_HTTP_TIMEOUT":	Webhook request timeout in seconds (Default: 30)`)
+		ew.writeln(`	- "MANUAL_POLLING_INTERVAL":	Time between DNS propagation check in seconds (Default: 2)`)
+		ew.writeln(`	- "MANUAL_PROPAGATION_TIMEOUT":	Maximum waiting time for DNS propagation in seconds (Default: 60)`)
+		ew.writeln(`	- "MANUAL_RECORDS_FILE":	The file where the records are written (JSON). Enables the non-interactive mode.`)
+		ew.writeln(`	- "MANUAL_SEQUENCE_INTERVAL":	Time between sequential requests in seconds (Default: 60)`)
+		ew.writeln(`	- "MANUAL_TTL":	The TTL of the TXT record used for the DNS challenge in seconds (Default: 120)`)
+		ew.writeln(`	- "MANUAL_WEBHOOK_URL":	The URL where the records are sent (JSON, POST). Enables the non-interactive mode.`)
+
 		ew.writeln()
 		ew.writeln(`More information: https://go-acme.github.io/lego/dns/manual`)
 
@@ -54,9 +54,22 @@ func generate() error {
 	for _, args := range [][]string{
 		{"lego", "help"},
 		{"lego", "help", "run"},
+		{"lego", "help", "authorize"},
+		{"lego", "help", "batch"},
 		{"lego", "help", "renew"},
+		{"lego", "help", "daemon"},
 		{"lego", "help", "revoke"},
 		{"lego", "help", "list"},
+		{"lego", "accounts", "help", "show"},
+		{"lego", "accounts", "help", "update-contact"},
+		{"lego", "accounts", "help", "rollover"},
+		{"lego", "accounts", "help", "deactivate"},
+		{"lego", "accounts", "help", "recover"},
+		{"lego", "accounts", "help", "export"},
+		{"lego", "accounts", "help", "import"},
+		{"lego", "dns-persist", "help", "record"},
+		{"lego", "dns-persist", "help", "setup"},
+		{"lego", "dns", "help", "serve"},
 		{"lego", "dnshelp"},
 	} {
 		content, err := run(app, args)
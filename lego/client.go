@@ -59,6 +59,8 @@ func NewClient(config *Config) (*Client, error) {
 		Timeout:             config.Certificate.Timeout,
 		OverallRequestLimit: config.Certificate.OverallRequestLimit,
 		DisableCommonName:   config.Certificate.DisableCommonName,
+		CheckCAA:            config.Certificate.CheckCAA,
+		RateLimiter:         config.Certificate.RateLimiter,
 	}
 
 	certifier := certificate.NewCertifier(core, prober, options)
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/ratelimit"
 	"github.com/go-acme/lego/v4/registration"
 )
 
@@ -65,6 +66,13 @@ type CertificateConfig struct {
 	Timeout             time.Duration
 	OverallRequestLimit int
 	DisableCommonName   bool
+
+	// CheckCAA enables the check of the CAA records of the domains before the creation of the orders.
+	CheckCAA bool
+
+	// RateLimiter checks the known rate limits of the ACME server before the creation of the orders (optional).
+	// See ratelimit.NewLimiter.
+	RateLimiter *ratelimit.Limiter
 }
 
 // createDefaultHTTPClient Creates an HTTP client with a reasonable timeout value
@@ -2,6 +2,7 @@ package log
 
 import (
 	"log"
+	"log/slog"
 	"os"
 )
 
@@ -48,12 +49,14 @@ func Printf(format string, args ...any) {
 	Logger.Printf(format, args...)
 }
 
-// Warnf writes a log entry.
+// Warnf writes a log entry at the warning level.
+// It uses the structured logger (see Default).
 func Warnf(format string, args ...any) {
-	Printf("[WARN] "+format, args...)
+	logf(slog.LevelWarn, format, args...)
 }
 
-// Infof writes a log entry.
+// Infof writes a log entry at the info level.
+// It uses the structured logger (see Default).
 func Infof(format string, args ...any) {
-	Printf("[INFO] "+format, args...)
+	logf(slog.LevelInfo, format, args...)
 }
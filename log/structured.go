@@ -0,0 +1,267 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Keys of the attributes of the log entries.
+const (
+	AttrDomain        = "domain"
+	AttrDomains       = "domains"
+	AttrOrderURL      = "order"
+	AttrAuthzURL      = "authz"
+	AttrChallengeType = "challenge"
+	AttrProvider      = "provider"
+	AttrError         = "error"
+)
+
+var structured atomic.Pointer[slog.Logger]
+
+// SetDefault defines the structured logger used by lego.
+// A nil logger restores the default behavior: the entries are written through Logger, without the debug entries.
+func SetDefault(logger *slog.Logger) {
+	structured.Store(logger)
+}
+
+// Default returns the structured logger used by lego.
+func Default() *slog.Logger {
+	if logger := structured.Load(); logger != nil {
+		return logger
+	}
+
+	return slog.New(NewStdHandler(slog.LevelInfo))
+}
+
+// Debug writes a log entry at the debug level.
+// The args are key-value pairs or slog.Attr, like with slog.
+func Debug(msg string, args ...any) {
+	Default().Debug(msg, args...)
+}
+
+// Info writes a log entry at the info level.
+// The args are key-value pairs or slog.Attr, like with slog.
+func Info(msg string, args ...any) {
+	Default().Info(msg, args...)
+}
+
+// Warn writes a log entry at the warning level.
+// The args are key-value pairs or slog.Attr, like with slog.
+func Warn(msg string, args ...any) {
+	Default().Warn(msg, args...)
+}
+
+// Error writes a log entry at the error level.
+// The args are key-value pairs or slog.Attr, like with slog.
+func Error(msg string, args ...any) {
+	Default().Error(msg, args...)
+}
+
+// Debugf writes a log entry at the debug level.
+func Debugf(format string, args ...any) {
+	logf(slog.LevelDebug, format, args...)
+}
+
+func logf(level slog.Level, format string, args ...any) {
+	logger := Default()
+
+	if !logger.Enabled(context.Background(), level) {
+		return
+	}
+
+	logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+// StdHandler is a slog.Handler writing the entries through Logger, in the historical format of lego:
+//
+//	[INFO] [example.com] acme: message key=value
+type StdHandler struct {
+	level  slog.Leveler
+	attrs  []slog.Attr
+	groups []string
+}
+
+// NewStdHandler creates a new StdHandler.
+func NewStdHandler(level slog.Leveler) *StdHandler {
+	return &StdHandler{level: level}
+}
+
+// Enabled implements slog.Handler.
+func (h *StdHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *StdHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := slices.Clone(h.attrs)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, h.qualify(attr))
+		return true
+	})
+
+	var (
+		prefix string
+		fields []string
+	)
+
+	for _, attr := range attrs {
+		// The domains are displayed as a prefix, like the historical entries.
+		if p, ok := domainsPrefix(attr); ok && prefix == "" {
+			prefix = p
+			continue
+		}
+
+		fields = appendAttr(fields, "", attr)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("[" + levelName(record.Level) + "] ")
+	sb.WriteString(prefix)
+	sb.WriteString(record.Message)
+
+	for _, field := range fields {
+		sb.WriteString(" " + field)
+	}
+
+	Logger.Print(sb.String())
+
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *StdHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = slices.Clone(h.attrs)
+
+	for _, attr := range attrs {
+		h2.attrs = append(h2.attrs, h.qualify(attr))
+	}
+
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *StdHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(slices.Clone(h.groups), name)
+
+	return &h2
+}
+
+func (h *StdHandler) qualify(attr slog.Attr) slog.Attr {
+	if len(h.groups) == 0 {
+		return attr
+	}
+
+	return slog.Attr{Key: strings.Join(h.groups, ".") + "." + attr.Key, Value: attr.Value}
+}
+
+func appendAttr(fields []string, prefix string, attr slog.Attr) []string {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		for _, a := range value.Group() {
+			fields = appendAttr(fields, prefix+attr.Key+".", a)
+		}
+
+		return fields
+	}
+
+	if attr.Key == "" {
+		return fields
+	}
+
+	return append(fields, prefix+attr.Key+"="+quote(value.String()))
+}
+
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+func domainsPrefix(attr slog.Attr) (string, bool) {
+	switch attr.Key {
+	case AttrDomain:
+		return "[" + attr.Value.Resolve().String() + "] ", true
+
+	case AttrDomains:
+		if domains, ok := attr.Value.Resolve().Any().([]string); ok {
+			return "[" + strings.Join(domains, ", ") + "] ", true
+		}
+	}
+
+	return "", false
+}
+
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// NewStdLogger creates a StdLogger which writes the entries through a structured logger, at the info level.
+// The Fatal methods write the entries at the error level, then exit.
+//
+// It allows to redirect the unstructured entries (Print, Fatal, etc.) to the structured logger.
+// The structured logger must not use a StdHandler.
+func NewStdLogger(logger *slog.Logger) StdLogger {
+	return &slogAdapter{logger: logger}
+}
+
+type slogAdapter struct {
+	logger *slog.Logger
+}
+
+func (a *slogAdapter) Fatal(args ...any) {
+	a.logger.Error(trimNewline(fmt.Sprint(args...)))
+	os.Exit(1)
+}
+
+func (a *slogAdapter) Fatalln(args ...any) {
+	a.logger.Error(trimNewline(fmt.Sprintln(args...)))
+	os.Exit(1)
+}
+
+func (a *slogAdapter) Fatalf(format string, args ...any) {
+	a.logger.Error(trimNewline(fmt.Sprintf(format, args...)))
+	os.Exit(1)
+}
+
+func (a *slogAdapter) Print(args ...any) {
+	a.logger.Info(trimNewline(fmt.Sprint(args...)))
+}
+
+func (a *slogAdapter) Println(args ...any) {
+	a.logger.Info(trimNewline(fmt.Sprintln(args...)))
+}
+
+func (a *slogAdapter) Printf(format string, args ...any) {
+	a.logger.Info(trimNewline(fmt.Sprintf(format, args...)))
+}
+
+// trimNewline removes the trailing line feed, added by the standard logger if missing.
+func trimNewline(msg string) string {
+	return strings.TrimSuffix(msg, "\n")
+}
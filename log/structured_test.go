@@ -0,0 +1,128 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	stdlog "log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	previous := Logger
+	Logger = stdlog.New(buf, "", 0)
+
+	t.Cleanup(func() {
+		Logger = previous
+		SetDefault(nil)
+	})
+
+	return buf
+}
+
+func TestStdHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		log      func(logger *slog.Logger)
+		expected string
+	}{
+		{
+			desc: "message only",
+			log: func(logger *slog.Logger) {
+				logger.Info("acme: message")
+			},
+			expected: "[INFO] acme: message\n",
+		},
+		{
+			desc: "domain",
+			log: func(logger *slog.Logger) {
+				logger.Warn("acme: message", AttrDomain, "example.com", AttrError, errors.New("oops"))
+			},
+			expected: "[WARN] [example.com] acme: message error=oops\n",
+		},
+		{
+			desc: "domains",
+			log: func(logger *slog.Logger) {
+				logger.Info("acme: message", AttrDomains, []string{"example.com", "example.org"}, AttrChallengeType, "dns-01")
+			},
+			expected: "[INFO] [example.com, example.org] acme: message challenge=dns-01\n",
+		},
+		{
+			desc: "quoted value",
+			log: func(logger *slog.Logger) {
+				logger.Info("acme: message", "value", "a b")
+			},
+			expected: "[INFO] acme: message value=\"a b\"\n",
+		},
+		{
+			desc: "with attributes and group",
+			log: func(logger *slog.Logger) {
+				logger.With(AttrProvider, "cloudflare").WithGroup("g").Info("acme: message", "a", 1)
+			},
+			expected: "[INFO] acme: message provider=cloudflare g.a=1\n",
+		},
+		{
+			desc: "debug disabled",
+			log: func(logger *slog.Logger) {
+				logger.Debug("acme: message")
+			},
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			buf := setupTestLogger(t)
+
+			test.log(slog.New(NewStdHandler(slog.LevelInfo)))
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestInfof_default(t *testing.T) {
+	buf := setupTestLogger(t)
+
+	Infof("[%s] acme: message", "example.com")
+	Debugf("hidden")
+
+	assert.Equal(t, "[INFO] [example.com] acme: message\n", buf.String())
+}
+
+func TestSetDefault_json(t *testing.T) {
+	setupTestLogger(t)
+
+	buf := &bytes.Buffer{}
+
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	SetDefault(logger)
+	Logger = NewStdLogger(logger)
+
+	Debug("acme: message", AttrDomain, "example.com")
+	Printf("unstructured %s\n", "message")
+
+	dec := json.NewDecoder(buf)
+
+	var entry map[string]any
+
+	require.NoError(t, dec.Decode(&entry))
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "acme: message", entry["msg"])
+	assert.Equal(t, "example.com", entry[AttrDomain])
+
+	entry = nil
+
+	require.NoError(t, dec.Decode(&entry))
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "unstructured message", entry["msg"])
+}
@@ -0,0 +1,153 @@
+// Package metrics provides an optional hook to collect metrics about the operations of lego.
+package metrics
+
+import (
+	"path"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Names of the provider operations.
+const (
+	OperationPresent = "present"
+	OperationCleanUp = "cleanup"
+)
+
+// Recorder receives the measurements of the operations performed by lego.
+//
+// The methods can be called concurrently.
+// Implementations should embed NoopRecorder to stay compatible with future additions to the interface.
+type Recorder interface {
+	// ACMERequest is called after each request to the ACME server.
+	// The resource is the name of the requested ACME resource (ex: "newOrder", "authz", "finalize").
+	// The status code is 0 if no response was received.
+	ACMERequest(resource string, statusCode int, duration time.Duration)
+
+	// ACMEError is called for each problem document returned by the ACME server.
+	// The problem type is the "type" field of the problem (ex: "urn:ietf:params:acme:error:badNonce").
+	ACMEError(resource, problemType string)
+
+	// BadNonceRetry is called each time a request is retried because of a badNonce error.
+	BadNonceRetry()
+
+	// Order is called when an order is completed, successfully or not.
+	Order(success bool, duration time.Duration)
+
+	// ChallengeAttempt is called after each attempt to solve a challenge.
+	ChallengeAttempt(challengeType string, success bool, duration time.Duration)
+
+	// PropagationCheck is called after each check of the propagation of a DNS record.
+	PropagationCheck(success bool, duration time.Duration)
+
+	// ProviderCall is called after each call to the Present or CleanUp method of a challenge provider.
+	ProviderCall(provider, operation string, success bool, duration time.Duration)
+
+	// CertificateExpiry is called when a certificate is obtained, or loaded (e.g. by the daemon of the CLI).
+	CertificateExpiry(domain string, notAfter time.Time)
+}
+
+// NoopRecorder a Recorder which does nothing.
+type NoopRecorder struct{}
+
+func (NoopRecorder) ACMERequest(string, int, time.Duration) {}
+
+func (NoopRecorder) ACMEError(string, string) {}
+
+func (NoopRecorder) BadNonceRetry() {}
+
+func (NoopRecorder) Order(bool, time.Duration) {}
+
+func (NoopRecorder) ChallengeAttempt(string, bool, time.Duration) {}
+
+func (NoopRecorder) PropagationCheck(bool, time.Duration) {}
+
+func (NoopRecorder) ProviderCall(string, string, bool, time.Duration) {}
+
+func (NoopRecorder) CertificateExpiry(string, time.Time) {}
+
+type holder struct {
+	recorder Recorder
+}
+
+var current atomic.Pointer[holder]
+
+// SetRecorder defines the recorder used by lego.
+// A nil recorder disables the metrics.
+func SetRecorder(recorder Recorder) {
+	if recorder == nil {
+		current.Store(nil)
+		return
+	}
+
+	current.Store(&holder{recorder: recorder})
+}
+
+// GetRecorder returns the recorder used by lego.
+func GetRecorder() Recorder {
+	h := current.Load()
+	if h == nil {
+		return NoopRecorder{}
+	}
+
+	return h.recorder
+}
+
+// ACMERequest records a request to the ACME server.
+func ACMERequest(resource string, statusCode int, duration time.Duration) {
+	GetRecorder().ACMERequest(resource, statusCode, duration)
+}
+
+// ACMEError records a problem document returned by the ACME server.
+func ACMEError(resource, problemType string) {
+	GetRecorder().ACMEError(resource, problemType)
+}
+
+// BadNonceRetry records a retry caused by a badNonce error.
+func BadNonceRetry() {
+	GetRecorder().BadNonceRetry()
+}
+
+// Order records a completed order.
+func Order(success bool, duration time.Duration) {
+	GetRecorder().Order(success, duration)
+}
+
+// ChallengeAttempt records an attempt to solve a challenge.
+func ChallengeAttempt(challengeType string, success bool, duration time.Duration) {
+	GetRecorder().ChallengeAttempt(challengeType, success, duration)
+}
+
+// PropagationCheck records a check of the propagation of a DNS record.
+func PropagationCheck(success bool, duration time.Duration) {
+	GetRecorder().PropagationCheck(success, duration)
+}
+
+// ProviderCall records a call to a challenge provider.
+func ProviderCall(provider, operation string, success bool, duration time.Duration) {
+	GetRecorder().ProviderCall(provider, operation, success, duration)
+}
+
+// CertificateExpiry records the expiration date of an obtained, or loaded, certificate.
+func CertificateExpiry(domain string, notAfter time.Time) {
+	GetRecorder().CertificateExpiry(domain, notAfter)
+}
+
+// ProviderName returns the name of a provider, based on the name of its package.
+// Ex: the name of *cloudflare.DNSProvider is "cloudflare".
+func ProviderName(provider any) string {
+	t := reflect.TypeOf(provider)
+	if t == nil {
+		return ""
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+
+	return path.Base(t.PkgPath())
+}
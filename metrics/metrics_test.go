@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProvider struct{}
+
+type fakeRecorder struct {
+	NoopRecorder
+
+	calls []string
+}
+
+func (r *fakeRecorder) ProviderCall(provider, operation string, _ bool, _ time.Duration) {
+	r.calls = append(r.calls, provider+":"+operation)
+}
+
+func TestProviderName(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		provider any
+		expected string
+	}{
+		{
+			desc:     "pointer",
+			provider: &fakeProvider{},
+			expected: "metrics",
+		},
+		{
+			desc:     "value",
+			provider: fakeProvider{},
+			expected: "metrics",
+		},
+		{
+			desc:     "nil",
+			provider: nil,
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ProviderName(test.provider))
+		})
+	}
+}
+
+func TestSetRecorder(t *testing.T) {
+	recorder := &fakeRecorder{}
+
+	SetRecorder(recorder)
+	t.Cleanup(func() { SetRecorder(nil) })
+
+	ProviderCall("cloudflare", OperationPresent, true, time.Second)
+
+	assert.Equal(t, []string{"cloudflare:present"}, recorder.calls)
+
+	SetRecorder(nil)
+
+	ProviderCall("cloudflare", OperationCleanUp, true, time.Second)
+
+	assert.Equal(t, []string{"cloudflare:present"}, recorder.calls)
+	assert.Equal(t, NoopRecorder{}, GetRecorder())
+}
@@ -0,0 +1,195 @@
+package acmeserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/acme"
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+type account struct {
+	id         string
+	key        *jose.JSONWebKey
+	thumbprint string
+	status     string
+	contact    []string
+}
+
+func (a *account) toACME() acme.Account {
+	return acme.Account{
+		Status:  a.status,
+		Contact: a.contact,
+	}
+}
+
+// handleNewAccount creates an account, or finds the account of the key.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3
+func (s *Server) handleNewAccount(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	var msg acme.Account
+
+	err := json.Unmarshal(r.payload, &msg)
+	if err != nil {
+		return malformed("invalid account: %v", err)
+	}
+
+	keyID, err := thumbprint(r.jwk)
+	if err != nil {
+		return newProblem(errBadPublicKey, http.StatusBadRequest, "invalid key: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if acc := s.accountByThumbprint(keyID); acc != nil {
+		rw.Header().Set("Location", baseURL(req)+pathAccount+acc.id)
+		writeJSON(rw, http.StatusOK, acc.toACME())
+
+		return nil
+	}
+
+	if msg.OnlyReturnExisting {
+		return newProblem(errAccountDoesNotExist, http.StatusBadRequest, "no account exists with the provided key")
+	}
+
+	acc := &account{
+		id:         newID(),
+		key:        r.jwk,
+		thumbprint: keyID,
+		status:     acme.StatusValid,
+		contact:    msg.Contact,
+	}
+
+	s.accounts[acc.id] = acc
+
+	rw.Header().Set("Location", baseURL(req)+pathAccount+acc.id)
+	writeJSON(rw, http.StatusCreated, acc.toACME())
+
+	return nil
+}
+
+// handleAccount gets, updates, or deactivates an account.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.2
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.6
+func (s *Server) handleAccount(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.account.id != req.PathValue("id") {
+		return unauthorized("the account doesn't match the signer of the request")
+	}
+
+	if !r.isPostAsGet() {
+		var msg acme.Account
+
+		err := json.Unmarshal(r.payload, &msg)
+		if err != nil {
+			return malformed("invalid account: %v", err)
+		}
+
+		switch msg.Status {
+		case "":
+			if msg.Contact != nil {
+				r.account.contact = msg.Contact
+			}
+
+		case acme.StatusDeactivated:
+			r.account.status = acme.StatusDeactivated
+
+		default:
+			return malformed("invalid account status: %q", msg.Status)
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, r.account.toACME())
+
+	return nil
+}
+
+// handleKeyChange changes the key of an account.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+func (s *Server) handleKeyChange(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	inner, err := jose.ParseSigned(string(r.payload), signatureAlgorithms)
+	if err != nil {
+		return malformed("invalid inner JWS: %v", err)
+	}
+
+	if len(inner.Signatures) != 1 {
+		return malformed("the inner JWS must have exactly one signature")
+	}
+
+	header := inner.Signatures[0].Protected
+
+	if header.JSONWebKey == nil {
+		return malformed("the inner JWS must contain a jwk header")
+	}
+
+	if url, _ := header.ExtraHeaders["url"].(string); url != baseURL(req)+req.URL.Path {
+		return malformed("the url header of the inner JWS %q doesn't match the request URL", url)
+	}
+
+	payload, err := inner.Verify(header.JSONWebKey)
+	if err != nil {
+		return malformed("invalid inner JWS signature: %v", err)
+	}
+
+	var msg acme.KeyChangeMessage
+
+	err = json.Unmarshal(payload, &msg)
+	if err != nil {
+		return malformed("invalid key change: %v", err)
+	}
+
+	var oldKey jose.JSONWebKey
+
+	err = oldKey.UnmarshalJSON(msg.OldKey)
+	if err != nil {
+		return malformed("invalid old key: %v", err)
+	}
+
+	oldKeyID, err := thumbprint(&oldKey)
+	if err != nil {
+		return malformed("invalid old key: %v", err)
+	}
+
+	newKeyID, err := thumbprint(header.JSONWebKey)
+	if err != nil {
+		return newProblem(errBadPublicKey, http.StatusBadRequest, "invalid new key: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg.Account != baseURL(req)+pathAccount+r.account.id {
+		return unauthorized("the account of the key change doesn't match the signer of the request")
+	}
+
+	if oldKeyID != r.account.thumbprint {
+		return unauthorized("the old key doesn't match the key of the account")
+	}
+
+	if other := s.accountByThumbprint(newKeyID); other != nil {
+		rw.Header().Set("Location", baseURL(req)+pathAccount+other.id)
+
+		return newProblem(errMalformed, http.StatusConflict, "the new key is already used by an account")
+	}
+
+	r.account.key = header.JSONWebKey
+	r.account.thumbprint = newKeyID
+
+	writeJSON(rw, http.StatusOK, r.account.toACME())
+
+	return nil
+}
+
+// accountByThumbprint returns the account related to a key.
+// The caller must hold the lock.
+func (s *Server) accountByThumbprint(keyID string) *account {
+	for _, acc := range s.accounts {
+		if acc.thumbprint == keyID {
+			return acc
+		}
+	}
+
+	return nil
+}
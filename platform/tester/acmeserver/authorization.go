@@ -0,0 +1,268 @@
+package acmeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+type authorization struct {
+	id           string
+	accountID    string
+	identifier   acme.Identifier
+	wildcard     bool
+	status       string
+	expires      time.Time
+	challengeIDs []string
+}
+
+// currentStatus returns the status of the authorization, taking the expiration into account.
+func (a *authorization) currentStatus() string {
+	if (a.status == acme.StatusPending || a.status == acme.StatusValid) && time.Now().After(a.expires) {
+		return acme.StatusExpired
+	}
+
+	return a.status
+}
+
+type authzChallenge struct {
+	id        string
+	authzID   string
+	typ       string
+	token     string
+	status    string
+	validated time.Time
+	err       *acme.ProblemDetails
+}
+
+// handleNewAuthz creates an authorization (pre-authorization).
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.1
+func (s *Server) handleNewAuthz(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	var msg acme.NewAuthzMessage
+
+	err := json.Unmarshal(r.payload, &msg)
+	if err != nil {
+		return malformed("invalid authorization: %v", err)
+	}
+
+	value, wildcard, problem := checkIdentifier(msg.Identifier)
+	if problem != nil {
+		return problem
+	}
+
+	if wildcard {
+		return newProblem(errRejectedIdentifier, http.StatusBadRequest, "a wildcard identifier cannot be pre-authorized: %q", msg.Identifier.Value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authz := s.newAuthorization(r.account.id, acme.Identifier{Type: msg.Identifier.Type, Value: value}, false)
+
+	rw.Header().Set("Location", baseURL(req)+pathAuthz+authz.id)
+	writeJSON(rw, http.StatusCreated, s.authorizationToACME(baseURL(req), authz))
+
+	return nil
+}
+
+// handleAuthorization gets, or deactivates, an authorization.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.5
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.5.2
+func (s *Server) handleAuthorization(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authz, ok := s.authorizations[req.PathValue("id")]
+	if !ok {
+		return notFound("unknown authorization")
+	}
+
+	if authz.accountID != r.account.id {
+		return unauthorized("the authorization doesn't belong to the account")
+	}
+
+	if !r.isPostAsGet() {
+		var msg acme.Authorization
+
+		err := json.Unmarshal(r.payload, &msg)
+		if err != nil {
+			return malformed("invalid authorization: %v", err)
+		}
+
+		if msg.Status != acme.StatusDeactivated {
+			return malformed("invalid authorization status: %q", msg.Status)
+		}
+
+		switch authz.currentStatus() {
+		case acme.StatusPending, acme.StatusValid:
+			authz.status = acme.StatusDeactivated
+
+		default:
+			return malformed("the authorization is %s", authz.currentStatus())
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, s.authorizationToACME(baseURL(req), authz))
+
+	return nil
+}
+
+// handleChallenge gets a challenge, or responds to a challenge.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.5.1
+func (s *Server) handleChallenge(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	s.mu.Lock()
+
+	chlg, ok := s.challenges[req.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		return notFound("unknown challenge")
+	}
+
+	authz := s.authorizations[chlg.authzID]
+
+	if authz.accountID != r.account.id {
+		s.mu.Unlock()
+		return unauthorized("the challenge doesn't belong to the account")
+	}
+
+	if r.isPostAsGet() || chlg.status != acme.StatusPending || authz.currentStatus() != acme.StatusPending {
+		defer s.mu.Unlock()
+
+		s.writeChallenge(rw, req, authz, chlg)
+
+		return nil
+	}
+
+	chlg.status = acme.StatusProcessing
+
+	toValidate := Challenge{
+		Type:             chlg.typ,
+		Identifier:       authz.identifier,
+		Wildcard:         authz.wildcard,
+		Token:            chlg.token,
+		KeyAuthorization: chlg.token + "." + r.account.thumbprint,
+	}
+
+	s.mu.Unlock()
+
+	// The lock is released during the validation:
+	// the validator can use the methods of the server.
+	var err error
+	if s.validator != nil {
+		err = s.validator(toValidate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		chlg.status = acme.StatusInvalid
+		chlg.err = newProblem(errIncorrectResponse, http.StatusForbidden, "%v", err)
+		authz.status = acme.StatusInvalid
+	} else {
+		chlg.status = acme.StatusValid
+		chlg.validated = time.Now()
+		authz.status = acme.StatusValid
+		authz.expires = time.Now().Add(authorizationLifetime)
+	}
+
+	s.writeChallenge(rw, req, authz, chlg)
+
+	return nil
+}
+
+// writeChallenge writes a challenge, with a link to its authorization.
+// The caller must hold the lock.
+func (s *Server) writeChallenge(rw http.ResponseWriter, req *http.Request, authz *authorization, chlg *authzChallenge) {
+	rw.Header().Add("Link", "<"+baseURL(req)+pathAuthz+authz.id+`>;rel="up"`)
+
+	writeJSON(rw, http.StatusOK, challengeToACME(baseURL(req), chlg))
+}
+
+// newAuthorization creates a pending authorization with the challenges supported by the identifier.
+// The caller must hold the lock.
+func (s *Server) newAuthorization(accountID string, ident acme.Identifier, wildcard bool) *authorization {
+	authz := &authorization{
+		id:         newID(),
+		accountID:  accountID,
+		identifier: ident,
+		wildcard:   wildcard,
+		status:     acme.StatusPending,
+		expires:    time.Now().Add(pendingAuthorizationLifetime),
+	}
+
+	for _, typ := range challengeTypes(ident, wildcard) {
+		chlg := &authzChallenge{
+			id:      newID(),
+			authzID: authz.id,
+			typ:     string(typ),
+			token:   newToken(),
+			status:  acme.StatusPending,
+		}
+
+		s.challenges[chlg.id] = chlg
+		authz.challengeIDs = append(authz.challengeIDs, chlg.id)
+	}
+
+	s.authorizations[authz.id] = authz
+
+	return authz
+}
+
+// reusableAuthorization returns a valid authorization of the account for the identifier, if any.
+// The caller must hold the lock.
+func (s *Server) reusableAuthorization(accountID, value string, wildcard bool) *authorization {
+	for _, authz := range s.authorizations {
+		if authz.accountID == accountID && authz.identifier.Value == value && authz.wildcard == wildcard &&
+			authz.currentStatus() == acme.StatusValid {
+			return authz
+		}
+	}
+
+	return nil
+}
+
+// authorizationToACME returns the ACME representation of an authorization.
+// The caller must hold the lock.
+func (s *Server) authorizationToACME(base string, authz *authorization) acme.Authorization {
+	result := acme.Authorization{
+		Status:     authz.currentStatus(),
+		Expires:    authz.expires,
+		Identifier: authz.identifier,
+		Wildcard:   authz.wildcard,
+	}
+
+	for _, id := range authz.challengeIDs {
+		result.Challenges = append(result.Challenges, challengeToACME(base, s.challenges[id]))
+	}
+
+	return result
+}
+
+func challengeToACME(base string, chlg *authzChallenge) acme.Challenge {
+	return acme.Challenge{
+		Type:      chlg.typ,
+		URL:       base + pathChallenge + chlg.id,
+		Status:    chlg.status,
+		Validated: chlg.validated,
+		Error:     chlg.err,
+		Token:     chlg.token,
+	}
+}
+
+// challengeTypes returns the challenge types supported by an identifier.
+func challengeTypes(ident acme.Identifier, wildcard bool) []challenge.Type {
+	switch {
+	case wildcard:
+		return []challenge.Type{challenge.DNS01}
+
+	case ident.Type == "ip":
+		return []challenge.Type{challenge.HTTP01, challenge.TLSALPN01}
+
+	default:
+		return []challenge.Type{challenge.HTTP01, challenge.DNS01, challenge.TLSALPN01}
+	}
+}
@@ -0,0 +1,254 @@
+package acmeserver
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certificate"
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// renewalInfoRetryAfter the polling interval of the renewal information.
+const renewalInfoRetryAfter = 6 * time.Hour
+
+type issuedCertificate struct {
+	id        string
+	accountID string
+	cert      *x509.Certificate
+	ariID     string
+	replaced  bool
+	revoked   bool
+	revokedAt time.Time
+	window    *acme.Window
+}
+
+func newCertificate(accountID string, cert *x509.Certificate) (*issuedCertificate, error) {
+	ariID, err := certificate.MakeARICertID(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuedCertificate{
+		id:        newID(),
+		accountID: accountID,
+		cert:      cert,
+		ariID:     ariID,
+	}, nil
+}
+
+// renewalWindow returns the suggested renewal window of the certificate.
+// https://www.rfc-editor.org/rfc/rfc9773.html#section-4.2
+func (c *issuedCertificate) renewalWindow() acme.Window {
+	switch {
+	case c.window != nil:
+		return *c.window
+
+	case c.revoked:
+		// A revoked certificate must be renewed immediately.
+		return acme.Window{Start: c.revokedAt.Add(-time.Hour), End: c.revokedAt}
+
+	default:
+		// From two thirds to five sixths of the lifetime.
+		lifetime := c.cert.NotAfter.Sub(c.cert.NotBefore)
+
+		return acme.Window{
+			Start: c.cert.NotBefore.Add(lifetime * 2 / 3),
+			End:   c.cert.NotBefore.Add(lifetime * 5 / 6),
+		}
+	}
+}
+
+// handleCertificate downloads a certificate chain.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.2
+func (s *Server) handleCertificate(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByID(req.PathValue("id"))
+	if c == nil {
+		return notFound("unknown certificate")
+	}
+
+	if c.accountID != r.account.id {
+		return unauthorized("the certificate doesn't belong to the account")
+	}
+
+	rw.Header().Set("Content-Type", "application/pem-certificate-chain")
+
+	_, _ = rw.Write(s.ca.chain(c.cert))
+
+	return nil
+}
+
+// handleRevokeCert revokes a certificate.
+// The request must be signed by the account which owns the certificate, or by the key of the certificate.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.6
+func (s *Server) handleRevokeCert(rw http.ResponseWriter, _ *http.Request, r *request) *acme.ProblemDetails {
+	var msg acme.RevokeCertMessage
+
+	err := json.Unmarshal(r.payload, &msg)
+	if err != nil {
+		return malformed("invalid revocation: %v", err)
+	}
+
+	der, err := base64.RawURLEncoding.DecodeString(msg.Certificate)
+	if err != nil {
+		return malformed("invalid certificate encoding: %v", err)
+	}
+
+	if msg.Reason != nil && (*msg.Reason == 7 || *msg.Reason > acme.CRLReasonAACompromise) {
+		return newProblem(errBadRevocationReason, http.StatusBadRequest, "unsupported revocation reason: %d", *msg.Reason)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByDER(der)
+	if c == nil {
+		return notFound("unknown certificate")
+	}
+
+	if r.account != nil && c.accountID != r.account.id {
+		return unauthorized("the certificate doesn't belong to the account")
+	}
+
+	if r.jwk != nil {
+		certKeyID, err := thumbprint(&jose.JSONWebKey{Key: c.cert.PublicKey})
+		if err != nil {
+			return newProblem(errBadPublicKey, http.StatusBadRequest, "invalid certificate key: %v", err)
+		}
+
+		jwkID, err := thumbprint(r.jwk)
+		if err != nil || jwkID != certKeyID {
+			return unauthorized("the request is not signed by the key of the certificate")
+		}
+	}
+
+	if c.revoked {
+		return newProblem(errAlreadyRevoked, http.StatusBadRequest, "the certificate is already revoked")
+	}
+
+	c.revoked = true
+	c.revokedAt = time.Now()
+
+	rw.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+// handleRenewalInfo gets the renewal information of a certificate.
+// https://www.rfc-editor.org/rfc/rfc9773.html#section-4.1
+func (s *Server) handleRenewalInfo(rw http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByARIID(req.PathValue("id"))
+	if c == nil {
+		writeProblem(rw, notFound("unknown certificate"))
+		return
+	}
+
+	rw.Header().Set("Retry-After", strconv.Itoa(int(renewalInfoRetryAfter.Seconds())))
+
+	writeJSON(rw, http.StatusOK, acme.RenewalInfoResponse{SuggestedWindow: c.renewalWindow()})
+}
+
+// Root returns the root certificate of the CA.
+func (s *Server) Root() *x509.Certificate {
+	return s.ca.root
+}
+
+// Certificates returns the issued certificates, in the order of issuance.
+func (s *Server) Certificates() []*x509.Certificate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var certs []*x509.Certificate
+
+	for _, c := range s.certificates {
+		certs = append(certs, c.cert)
+	}
+
+	return certs
+}
+
+// IsRevoked returns true if the certificate has been revoked.
+func (s *Server) IsRevoked(cert *x509.Certificate) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByDER(cert.Raw)
+
+	return c != nil && c.revoked
+}
+
+// IsReplaced returns true if the certificate has been replaced by another certificate (RFC 9773).
+func (s *Server) IsReplaced(cert *x509.Certificate) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByDER(cert.Raw)
+
+	return c != nil && c.replaced
+}
+
+// SetRenewalWindow overrides the suggested renewal window of a certificate (RFC 9773).
+// By default, the window starts at two thirds of the lifetime of the certificate, and ends at five sixths.
+func (s *Server) SetRenewalWindow(cert *x509.Certificate, start, end time.Time) error {
+	if !start.Before(end) {
+		return errors.New("the start of the renewal window must be before its end")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.certificateByDER(cert.Raw)
+	if c == nil {
+		return fmt.Errorf("unknown certificate: %s", cert.SerialNumber)
+	}
+
+	c.window = &acme.Window{Start: start, End: end}
+
+	return nil
+}
+
+// The caller must hold the lock.
+func (s *Server) certificateByID(id string) *issuedCertificate {
+	for _, c := range s.certificates {
+		if c.id == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// The caller must hold the lock.
+func (s *Server) certificateByARIID(ariID string) *issuedCertificate {
+	for _, c := range s.certificates {
+		if c.ariID == ariID {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// The caller must hold the lock.
+func (s *Server) certificateByDER(der []byte) *issuedCertificate {
+	for _, c := range s.certificates {
+		if bytes.Equal(c.cert.Raw, der) {
+			return c
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package acmeserver
+
+import (
+	"crypto"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-acme/lego/v4/acme"
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+const maxBodySize = 1024 * 1024
+
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// signedWith the keys accepted to sign the requests of an endpoint.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-6.2
+type signedWith int
+
+const (
+	// signedWithKID the requests must be signed by an existing account.
+	signedWithKID signedWith = iota
+	// signedWithJWK the requests must be signed by an embedded JWK.
+	signedWithJWK
+	// signedWithAny the requests can be signed by an existing account or by an embedded JWK.
+	signedWithAny
+)
+
+// request a verified POST request.
+type request struct {
+	// payload the verified payload (empty for POST-as-GET requests).
+	payload []byte
+
+	// account the account which signed the request, if signed with a key identifier.
+	account *account
+
+	// jwk the key which signed the request, if signed with an embedded JWK.
+	jwk *jose.JSONWebKey
+}
+
+// postHandler handles a verified POST request.
+type postHandler func(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails
+
+// post verifies the JWS of the requests before calling the handler.
+func (s *Server) post(signed signedWith, handler postHandler) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		s.addNonce(rw)
+
+		r, problem := s.verify(req, signed)
+		if problem != nil {
+			writeProblem(rw, problem)
+			return
+		}
+
+		problem = handler(rw, req, r)
+		if problem != nil {
+			writeProblem(rw, problem)
+		}
+	}
+}
+
+// verify verifies the JWS of a request.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-6.3
+func (s *Server) verify(req *http.Request, signed signedWith) (*request, *acme.ProblemDetails) {
+	if ct := req.Header.Get("Content-Type"); ct != "application/jose+json" {
+		return nil, malformed("invalid content type: %q", ct)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, maxBodySize))
+	if err != nil {
+		return nil, malformed("unable to read the request body: %v", err)
+	}
+
+	jws, err := jose.ParseSigned(string(body), signatureAlgorithms)
+	if err != nil {
+		return nil, newProblem(errBadSignatureAlgorithm, http.StatusBadRequest, "invalid JWS: %v", err)
+	}
+
+	if len(jws.Signatures) != 1 {
+		return nil, malformed("the JWS must have exactly one signature")
+	}
+
+	header := jws.Signatures[0].Protected
+
+	if !s.useNonce(header.Nonce) {
+		return nil, newProblem(acme.BadNonceErr, http.StatusBadRequest, "invalid nonce: %q", header.Nonce)
+	}
+
+	if url, _ := header.ExtraHeaders["url"].(string); url != baseURL(req)+req.URL.Path {
+		return nil, unauthorized("the url header %q doesn't match the request URL", url)
+	}
+
+	switch {
+	case header.JSONWebKey != nil && header.KeyID != "":
+		return nil, malformed("the jwk and kid headers are mutually exclusive")
+
+	case header.JSONWebKey != nil:
+		if signed == signedWithKID {
+			return nil, malformed("the request must be signed with a key identifier (kid)")
+		}
+
+		payload, err := jws.Verify(header.JSONWebKey)
+		if err != nil {
+			return nil, malformed("invalid JWS signature: %v", err)
+		}
+
+		return &request{payload: payload, jwk: header.JSONWebKey}, nil
+
+	case header.KeyID != "":
+		if signed == signedWithJWK {
+			return nil, malformed("the request must be signed with a JWK")
+		}
+
+		acc, key, problem := s.lookupAccount(req, header.KeyID)
+		if problem != nil {
+			return nil, problem
+		}
+
+		payload, err := jws.Verify(key)
+		if err != nil {
+			return nil, malformed("invalid JWS signature: %v", err)
+		}
+
+		return &request{payload: payload, account: acc}, nil
+
+	default:
+		return nil, malformed("the request must contain a jwk or a kid header")
+	}
+}
+
+// lookupAccount returns the account, and its current key, identified by a key identifier (the URL of the account).
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.6
+func (s *Server) lookupAccount(req *http.Request, kid string) (*account, *jose.JSONWebKey, *acme.ProblemDetails) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, found := strings.CutPrefix(kid, baseURL(req)+pathAccount)
+
+	acc, ok := s.accounts[id]
+	if !found || !ok {
+		return nil, nil, newProblem(errAccountDoesNotExist, http.StatusBadRequest, "unknown account: %s", kid)
+	}
+
+	if acc.status != acme.StatusValid {
+		return nil, nil, unauthorized("the account is %s", acc.status)
+	}
+
+	return acc, acc.key, nil
+}
+
+// isPostAsGet returns true if the payload is empty.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-6.3
+func (r *request) isPostAsGet() bool {
+	return len(r.payload) == 0
+}
+
+// thumbprint returns the JWK thumbprint (RFC 7638) of a key.
+func thumbprint(key *jose.JSONWebKey) (string, error) {
+	raw, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,348 @@
+package acmeserver
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+)
+
+type order struct {
+	id          string
+	accountID   string
+	status      string
+	expires     time.Time
+	identifiers []acme.Identifier
+	profile     string
+	notBefore   time.Time
+	notAfter    time.Time
+	replaces    string
+	authzIDs    []string
+	certID      string
+}
+
+// handleNewOrder creates an order.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4
+func (s *Server) handleNewOrder(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	var msg acme.Order
+
+	err := json.Unmarshal(r.payload, &msg)
+	if err != nil {
+		return malformed("invalid order: %v", err)
+	}
+
+	if len(msg.Identifiers) == 0 {
+		return malformed("the order must contain at least one identifier")
+	}
+
+	if msg.Profile != "" {
+		if _, ok := s.profiles[msg.Profile]; !ok {
+			return newProblem(errInvalidProfile, http.StatusBadRequest, "unknown profile: %q", msg.Profile)
+		}
+	}
+
+	o := &order{
+		id:        newID(),
+		accountID: r.account.id,
+		status:    acme.StatusPending,
+		expires:   time.Now().Add(orderLifetime),
+		profile:   msg.Profile,
+		replaces:  msg.Replaces,
+	}
+
+	o.notBefore, err = parseTime(msg.NotBefore)
+	if err != nil {
+		return malformed("invalid notBefore: %v", err)
+	}
+
+	o.notAfter, err = parseTime(msg.NotAfter)
+	if err != nil {
+		return malformed("invalid notAfter: %v", err)
+	}
+
+	for _, ident := range msg.Identifiers {
+		if _, _, problem := checkIdentifier(ident); problem != nil {
+			return problem
+		}
+
+		if !slices.Contains(o.identifiers, ident) {
+			o.identifiers = append(o.identifiers, ident)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o.replaces != "" {
+		// https://www.rfc-editor.org/rfc/rfc9773.html#section-5
+		replaced := s.certificateByARIID(o.replaces)
+		if replaced == nil || replaced.accountID != r.account.id {
+			return malformed("unknown replaced certificate: %s", o.replaces)
+		}
+
+		if replaced.replaced {
+			return newProblem(acme.AlreadyReplacedErr, http.StatusConflict, "the certificate %s has already been replaced", o.replaces)
+		}
+	}
+
+	for _, ident := range o.identifiers {
+		value, wildcard, _ := checkIdentifier(ident)
+
+		authz := s.reusableAuthorization(r.account.id, value, wildcard)
+		if authz == nil {
+			authz = s.newAuthorization(r.account.id, acme.Identifier{Type: ident.Type, Value: value}, wildcard)
+		}
+
+		o.authzIDs = append(o.authzIDs, authz.id)
+	}
+
+	s.orders[o.id] = o
+
+	rw.Header().Set("Location", baseURL(req)+pathOrder+o.id)
+	writeJSON(rw, http.StatusCreated, s.orderToACME(baseURL(req), o))
+
+	return nil
+}
+
+// handleOrder gets an order.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.1.3
+func (s *Server) handleOrder(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[req.PathValue("id")]
+	if !ok {
+		return notFound("unknown order")
+	}
+
+	if o.accountID != r.account.id {
+		return unauthorized("the order doesn't belong to the account")
+	}
+
+	writeJSON(rw, http.StatusOK, s.orderToACME(baseURL(req), o))
+
+	return nil
+}
+
+// handleFinalize finalizes an order, and issues the certificate.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4
+func (s *Server) handleFinalize(rw http.ResponseWriter, req *http.Request, r *request) *acme.ProblemDetails {
+	var msg acme.CSRMessage
+
+	err := json.Unmarshal(r.payload, &msg)
+	if err != nil {
+		return malformed("invalid finalization: %v", err)
+	}
+
+	der, err := base64.RawURLEncoding.DecodeString(msg.Csr)
+	if err != nil {
+		return newProblem(errBadCSR, http.StatusBadRequest, "invalid CSR encoding: %v", err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return newProblem(errBadCSR, http.StatusBadRequest, "invalid CSR: %v", err)
+	}
+
+	err = csr.CheckSignature()
+	if err != nil {
+		return newProblem(errBadCSR, http.StatusBadRequest, "invalid CSR signature: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[req.PathValue("id")]
+	if !ok {
+		return notFound("unknown order")
+	}
+
+	if o.accountID != r.account.id {
+		return unauthorized("the order doesn't belong to the account")
+	}
+
+	if status := s.orderStatus(o); status != acme.StatusReady {
+		return newProblem(errOrderNotReady, http.StatusForbidden, "the order is %s", status)
+	}
+
+	if !slices.Equal(csrIdentifiers(csr), orderIdentifiers(o)) {
+		return newProblem(errBadCSR, http.StatusBadRequest, "the CSR identifiers don't match the order identifiers")
+	}
+
+	notBefore := o.notBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+
+	notAfter := o.notAfter
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(s.lifetime)
+	}
+
+	cert, err := s.ca.issue(csr, notBefore, notAfter)
+	if err != nil {
+		return newProblem(errServerInternal, http.StatusInternalServerError, "unable to issue the certificate: %v", err)
+	}
+
+	issued, err := newCertificate(r.account.id, cert)
+	if err != nil {
+		return newProblem(errServerInternal, http.StatusInternalServerError, "unable to issue the certificate: %v", err)
+	}
+
+	s.certificates = append(s.certificates, issued)
+
+	if o.replaces != "" {
+		if replaced := s.certificateByARIID(o.replaces); replaced != nil {
+			replaced.replaced = true
+		}
+	}
+
+	o.status = acme.StatusValid
+	o.certID = issued.id
+
+	writeJSON(rw, http.StatusOK, s.orderToACME(baseURL(req), o))
+
+	return nil
+}
+
+// orderStatus returns the status of an order.
+// The status of a pending order depends on the status of its authorizations.
+// The caller must hold the lock.
+func (s *Server) orderStatus(o *order) string {
+	if o.status != acme.StatusPending {
+		return o.status
+	}
+
+	if time.Now().After(o.expires) {
+		return acme.StatusInvalid
+	}
+
+	status := acme.StatusReady
+
+	for _, id := range o.authzIDs {
+		switch s.authorizations[id].currentStatus() {
+		case acme.StatusValid:
+		case acme.StatusPending:
+			status = acme.StatusPending
+		default:
+			return acme.StatusInvalid
+		}
+	}
+
+	return status
+}
+
+// orderToACME returns the ACME representation of an order.
+// The caller must hold the lock.
+func (s *Server) orderToACME(base string, o *order) acme.Order {
+	ord := acme.Order{
+		Status:      s.orderStatus(o),
+		Expires:     o.expires.Format(time.RFC3339),
+		Identifiers: o.identifiers,
+		Profile:     o.profile,
+		Finalize:    base + pathOrder + o.id + "/finalize",
+		Replaces:    o.replaces,
+	}
+
+	if !o.notBefore.IsZero() {
+		ord.NotBefore = o.notBefore.Format(time.RFC3339)
+	}
+
+	if !o.notAfter.IsZero() {
+		ord.NotAfter = o.notAfter.Format(time.RFC3339)
+	}
+
+	for _, id := range o.authzIDs {
+		ord.Authorizations = append(ord.Authorizations, base+pathAuthz+id)
+	}
+
+	if o.certID != "" {
+		ord.Certificate = base + pathCertificate + o.certID
+	}
+
+	return ord
+}
+
+// checkIdentifier checks an identifier,
+// and returns the value of the identifier without the wildcard prefix.
+func checkIdentifier(ident acme.Identifier) (string, bool, *acme.ProblemDetails) {
+	switch ident.Type {
+	case "dns":
+		value, wildcard := strings.CutPrefix(ident.Value, "*.")
+
+		if value == "" || strings.Contains(value, "*") || net.ParseIP(value) != nil {
+			return "", false, newProblem(errRejectedIdentifier, http.StatusBadRequest, "invalid DNS identifier: %q", ident.Value)
+		}
+
+		return value, wildcard, nil
+
+	case "ip":
+		if net.ParseIP(ident.Value) == nil {
+			return "", false, newProblem(errRejectedIdentifier, http.StatusBadRequest, "invalid IP identifier: %q", ident.Value)
+		}
+
+		return ident.Value, false, nil
+
+	default:
+		return "", false, newProblem(errUnsupportedIdentifier, http.StatusBadRequest, "unsupported identifier type: %q", ident.Type)
+	}
+}
+
+// orderIdentifiers returns the sorted values of the identifiers of an order.
+func orderIdentifiers(o *order) []string {
+	var values []string
+
+	for _, ident := range o.identifiers {
+		if ident.Type == "ip" {
+			values = append(values, net.ParseIP(ident.Value).String())
+			continue
+		}
+
+		values = append(values, strings.ToLower(ident.Value))
+	}
+
+	slices.Sort(values)
+
+	return slices.Compact(values)
+}
+
+// csrIdentifiers returns the sorted values of the identifiers of a CSR.
+func csrIdentifiers(csr *x509.CertificateRequest) []string {
+	var values []string
+
+	if csr.Subject.CommonName != "" {
+		cn := csr.Subject.CommonName
+
+		if ip := net.ParseIP(cn); ip != nil {
+			cn = ip.String()
+		}
+
+		values = append(values, strings.ToLower(cn))
+	}
+
+	for _, name := range csr.DNSNames {
+		values = append(values, strings.ToLower(name))
+	}
+
+	for _, ip := range csr.IPAddresses {
+		values = append(values, ip.String())
+	}
+
+	slices.Sort(values)
+
+	return slices.Compact(values)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,48 @@
+package acmeserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/acme"
+)
+
+// Errors types.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-6.7
+const (
+	errNS                    = "urn:ietf:params:acme:error:"
+	errAccountDoesNotExist   = errNS + "accountDoesNotExist"
+	errAlreadyRevoked        = errNS + "alreadyRevoked"
+	errBadCSR                = errNS + "badCSR"
+	errBadPublicKey          = errNS + "badPublicKey"
+	errBadRevocationReason   = errNS + "badRevocationReason"
+	errBadSignatureAlgorithm = errNS + "badSignatureAlgorithm"
+	errIncorrectResponse     = errNS + "incorrectResponse"
+	errInvalidProfile        = errNS + "invalidProfile"
+	errMalformed             = errNS + "malformed"
+	errOrderNotReady         = errNS + "orderNotReady"
+	errRejectedIdentifier    = errNS + "rejectedIdentifier"
+	errServerInternal        = errNS + "serverInternal"
+	errUnauthorized          = errNS + "unauthorized"
+	errUnsupportedIdentifier = errNS + "unsupportedIdentifier"
+)
+
+func newProblem(typ string, status int, format string, args ...any) *acme.ProblemDetails {
+	return &acme.ProblemDetails{
+		Type:       typ,
+		Detail:     fmt.Sprintf(format, args...),
+		HTTPStatus: status,
+	}
+}
+
+func malformed(format string, args ...any) *acme.ProblemDetails {
+	return newProblem(errMalformed, http.StatusBadRequest, format, args...)
+}
+
+func unauthorized(format string, args ...any) *acme.ProblemDetails {
+	return newProblem(errUnauthorized, http.StatusForbidden, format, args...)
+}
+
+func notFound(format string, args ...any) *acme.ProblemDetails {
+	return newProblem(errMalformed, http.StatusNotFound, format, args...)
+}
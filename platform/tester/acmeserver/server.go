@@ -0,0 +1,281 @@
+// Package acmeserver provides an in-process, in-memory ACME server (RFC 8555) for the tests of the lego consumers.
+//
+// The server implements the directory, the nonces, the accounts (including the key rollover),
+// the orders, the authorizations (including the pre-authorizations), the challenges, the finalization,
+// the renewal information (RFC 9773), and the revocation.
+// The certificates are signed by a throwaway CA, generated for each server.
+//
+// The challenges are validated automatically, or by a [Validator].
+//
+//	server := acmeserver.New()
+//	ts := server.BuildHTTPS(t)
+//
+//	config := lego.NewConfig(user)
+//	config.CADirURL = ts.URL + acmeserver.DirectoryPath
+//	config.HTTPClient = ts.Client()
+package acmeserver
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+)
+
+// DirectoryPath the path of the directory.
+const DirectoryPath = "/directory"
+
+const (
+	pathNewNonce    = "/new-nonce"
+	pathNewAccount  = "/new-account"
+	pathAccount     = "/account/"
+	pathKeyChange   = "/key-change"
+	pathNewOrder    = "/new-order"
+	pathOrder       = "/order/"
+	pathNewAuthz    = "/new-authz"
+	pathAuthz       = "/authz/"
+	pathChallenge   = "/chall/"
+	pathCertificate = "/cert/"
+	pathRevokeCert  = "/revoke-cert"
+	pathRenewalInfo = "/renewal-info"
+)
+
+const (
+	defaultCertificateLifetime = 90 * 24 * time.Hour
+
+	authorizationLifetime        = 30 * 24 * time.Hour
+	pendingAuthorizationLifetime = 7 * 24 * time.Hour
+	orderLifetime                = 7 * 24 * time.Hour
+)
+
+// Challenge a challenge to validate.
+type Challenge struct {
+	// Type the challenge type (e.g. http-01).
+	Type string
+
+	// Identifier the identifier of the authorization.
+	// The value of a wildcard identifier doesn't contain the wildcard prefix.
+	Identifier acme.Identifier
+
+	// Wildcard is true if the authorization is related to a wildcard identifier.
+	Wildcard bool
+
+	// Token the token of the challenge.
+	Token string
+
+	// KeyAuthorization the key authorization expected by the server.
+	// https://www.rfc-editor.org/rfc/rfc8555.html#section-8.1
+	KeyAuthorization string
+}
+
+// Validator validates a challenge.
+// The challenge is valid if the returned error is nil.
+type Validator func(chlg Challenge) error
+
+// Option configures the server.
+type Option func(*Server)
+
+// WithValidator defines the function used to validate the challenges.
+// By default, all the challenges are valid.
+func WithValidator(validator Validator) Option {
+	return func(s *Server) {
+		s.validator = validator
+	}
+}
+
+// WithCertificateLifetime defines the lifetime of the certificates (90 days by default).
+func WithCertificateLifetime(lifetime time.Duration) Option {
+	return func(s *Server) {
+		s.lifetime = lifetime
+	}
+}
+
+// WithProfiles defines the certificate profiles advertised by the directory.
+// The orders with an unknown profile are rejected.
+func WithProfiles(profiles map[string]string) Option {
+	return func(s *Server) {
+		s.profiles = profiles
+	}
+}
+
+// WithCAAIdentities defines the CAA identities advertised by the directory.
+func WithCAAIdentities(identities ...string) Option {
+	return func(s *Server) {
+		s.caaIdentities = identities
+	}
+}
+
+// Server an in-memory ACME server.
+type Server struct {
+	mux *http.ServeMux
+
+	validator     Validator
+	lifetime      time.Duration
+	profiles      map[string]string
+	caaIdentities []string
+
+	ca *authority
+
+	mu             sync.Mutex
+	nonces         map[string]struct{}
+	accounts       map[string]*account
+	orders         map[string]*order
+	authorizations map[string]*authorization
+	challenges     map[string]*authzChallenge
+	certificates   []*issuedCertificate
+}
+
+// New creates a new Server.
+func New(opts ...Option) *Server {
+	ca, err := newAuthority()
+	if err != nil {
+		panic(fmt.Sprintf("acmeserver: %v", err))
+	}
+
+	s := &Server{
+		mux:            http.NewServeMux(),
+		lifetime:       defaultCertificateLifetime,
+		ca:             ca,
+		nonces:         make(map[string]struct{}),
+		accounts:       make(map[string]*account),
+		orders:         make(map[string]*order),
+		authorizations: make(map[string]*authorization),
+		challenges:     make(map[string]*authzChallenge),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET "+DirectoryPath, s.handleDirectory)
+	s.mux.HandleFunc("HEAD "+pathNewNonce, s.handleNewNonce)
+	s.mux.HandleFunc("GET "+pathNewNonce, s.handleNewNonce)
+	s.mux.Handle("POST "+pathNewAccount, s.post(signedWithJWK, s.handleNewAccount))
+	s.mux.Handle("POST "+pathAccount+"{id}", s.post(signedWithKID, s.handleAccount))
+	s.mux.Handle("POST "+pathKeyChange, s.post(signedWithKID, s.handleKeyChange))
+	s.mux.Handle("POST "+pathNewOrder, s.post(signedWithKID, s.handleNewOrder))
+	s.mux.Handle("POST "+pathOrder+"{id}", s.post(signedWithKID, s.handleOrder))
+	s.mux.Handle("POST "+pathOrder+"{id}/finalize", s.post(signedWithKID, s.handleFinalize))
+	s.mux.Handle("POST "+pathNewAuthz, s.post(signedWithKID, s.handleNewAuthz))
+	s.mux.Handle("POST "+pathAuthz+"{id}", s.post(signedWithKID, s.handleAuthorization))
+	s.mux.Handle("POST "+pathChallenge+"{id}", s.post(signedWithKID, s.handleChallenge))
+	s.mux.Handle("POST "+pathCertificate+"{id}", s.post(signedWithKID, s.handleCertificate))
+	s.mux.Handle("POST "+pathRevokeCert, s.post(signedWithAny, s.handleRevokeCert))
+	s.mux.HandleFunc("GET "+pathRenewalInfo+"/{id}", s.handleRenewalInfo)
+
+	return s
+}
+
+// BuildHTTPS starts a TLS test server, closed at the end of the test.
+// The client of the test server ([httptest.Server.Client]) trusts the server.
+func (s *Server) BuildHTTPS(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(s)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(rw, req)
+}
+
+func (s *Server) handleDirectory(rw http.ResponseWriter, req *http.Request) {
+	base := baseURL(req)
+
+	writeJSON(rw, http.StatusOK, acme.Directory{
+		NewNonceURL:   base + pathNewNonce,
+		NewAccountURL: base + pathNewAccount,
+		NewOrderURL:   base + pathNewOrder,
+		NewAuthzURL:   base + pathNewAuthz,
+		RevokeCertURL: base + pathRevokeCert,
+		KeyChangeURL:  base + pathKeyChange,
+		RenewalInfo:   base + pathRenewalInfo,
+		Meta: acme.Meta{
+			CaaIdentities: s.caaIdentities,
+			Profiles:      s.profiles,
+		},
+	})
+}
+
+func (s *Server) handleNewNonce(rw http.ResponseWriter, req *http.Request) {
+	s.addNonce(rw)
+
+	rw.Header().Set("Cache-Control", "no-store")
+
+	if req.Method == http.MethodGet {
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// addNonce adds a new nonce to the response.
+func (s *Server) addNonce(rw http.ResponseWriter) {
+	nonce := newToken()
+
+	s.mu.Lock()
+	s.nonces[nonce] = struct{}{}
+	s.mu.Unlock()
+
+	rw.Header().Set("Replay-Nonce", nonce)
+}
+
+// useNonce consumes a nonce.
+func (s *Server) useNonce(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.nonces[nonce]
+	delete(s.nonces, nonce)
+
+	return ok
+}
+
+func baseURL(req *http.Request) string {
+	if req.TLS == nil {
+		return "http://" + req.Host
+	}
+
+	return "https://" + req.Host
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	_ = json.NewEncoder(rw).Encode(body)
+}
+
+func writeProblem(rw http.ResponseWriter, problem *acme.ProblemDetails) {
+	rw.Header().Set("Content-Type", "application/problem+json")
+	rw.WriteHeader(problem.HTTPStatus)
+
+	_ = json.NewEncoder(rw).Encode(problem)
+}
+
+// newToken returns a random base64url value with 128 bits of entropy.
+func newToken() string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(16))
+}
+
+// newID returns a random identifier for the resources.
+func newID() string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(9))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+
+	// The error is always nil (crypto/rand).
+	_, _ = rand.Read(b)
+
+	return b
+}
@@ -0,0 +1,348 @@
+package acmeserver_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/platform/tester/acmeserver"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_obtain(t *testing.T) {
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	resource, err := client.Certificate.Obtain(certificate.ObtainRequest{
+		Domains: []string{"example.com", "www.example.com", "192.0.2.1"},
+		Bundle:  true,
+	})
+	require.NoError(t, err)
+
+	cert, err := certcrypto.ParsePEMCertificate(resource.Certificate)
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com", cert.Subject.CommonName)
+	assert.ElementsMatch(t, []string{"example.com", "www.example.com"}, cert.DNSNames)
+	require.Len(t, cert.IPAddresses, 1)
+	assert.Equal(t, "192.0.2.1", cert.IPAddresses[0].String())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Root())
+
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "www.example.com", Roots: roots})
+	require.NoError(t, err)
+
+	certs := server.Certificates()
+	require.Len(t, certs, 1)
+	assert.Equal(t, cert.Raw, certs[0].Raw)
+}
+
+func TestServer_obtain_profile(t *testing.T) {
+	server := acmeserver.New(
+		acmeserver.WithProfiles(map[string]string{"shortlived": "6 days"}),
+		acmeserver.WithCertificateLifetime(6*24*time.Hour),
+	)
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	resource, err := client.Certificate.Obtain(certificate.ObtainRequest{
+		Domains: []string{"example.com"},
+		Profile: "shortlived",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "shortlived", resource.Profile)
+
+	cert, err := certcrypto.ParsePEMCertificate(resource.Certificate)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6*24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+
+	_, err = client.Certificate.Obtain(certificate.ObtainRequest{
+		Domains: []string{"example.com"},
+		Profile: "unknown",
+	})
+	require.ErrorContains(t, err, "unknown profile")
+}
+
+func TestServer_validator(t *testing.T) {
+	provider := &recordingProvider{}
+
+	server := acmeserver.New(acmeserver.WithValidator(func(chlg acmeserver.Challenge) error {
+		if chlg.Identifier.Value == "invalid.example.com" {
+			return errors.New("the key authorization is not served")
+		}
+
+		if provider.keyAuth(chlg.Token) != chlg.KeyAuthorization {
+			return errors.New("unexpected key authorization")
+		}
+
+		return nil
+	}))
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	err := client.Challenge.SetHTTP01Provider(provider)
+	require.NoError(t, err)
+
+	_, err = client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	_, err = client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"invalid.example.com"}})
+	require.ErrorContains(t, err, "the key authorization is not served")
+
+	assert.Len(t, server.Certificates(), 1)
+}
+
+func TestServer_preAuthorization(t *testing.T) {
+	provider := &recordingProvider{}
+
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	err := client.Challenge.SetHTTP01Provider(provider)
+	require.NoError(t, err)
+
+	authzs, err := client.Certificate.Authorize(t.Context(), []string{"example.com"})
+	require.NoError(t, err)
+
+	require.Len(t, authzs, 1)
+	assert.Equal(t, acme.StatusValid, authzs[0].Status)
+
+	// The valid authorization is reused by the order.
+	_, err = client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, provider.presented())
+}
+
+func TestServer_renewalInfo(t *testing.T) {
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	resource, err := client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	cert, err := certcrypto.ParsePEMCertificate(resource.Certificate)
+	require.NoError(t, err)
+
+	info, err := client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+	require.NoError(t, err)
+
+	assert.Equal(t, 6*time.Hour, info.RetryAfter)
+	assert.Nil(t, info.ShouldRenewAt(time.Now(), time.Hour))
+
+	err = server.SetRenewalWindow(cert, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	info, err = client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+	require.NoError(t, err)
+
+	assert.NotNil(t, info.ShouldRenewAt(time.Now(), time.Hour))
+
+	certID, err := certificate.MakeARICertID(cert)
+	require.NoError(t, err)
+
+	renewal := certificate.ObtainRequest{Domains: []string{"example.com"}, ReplacesCertID: certID}
+
+	_, err = client.Certificate.Obtain(renewal)
+	require.NoError(t, err)
+
+	assert.True(t, server.IsReplaced(cert))
+
+	// The certificate has already been replaced: the order is retried without the replaces field.
+	_, err = client.Certificate.Obtain(renewal)
+	require.NoError(t, err)
+
+	assert.Len(t, server.Certificates(), 3)
+}
+
+func TestServer_revoke(t *testing.T) {
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	resource, err := client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	cert, err := certcrypto.ParsePEMCertificate(resource.Certificate)
+	require.NoError(t, err)
+
+	err = client.Certificate.Revoke(resource.Certificate)
+	require.NoError(t, err)
+
+	assert.True(t, server.IsRevoked(cert))
+
+	info, err := client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+	require.NoError(t, err)
+
+	assert.NotNil(t, info.ShouldRenewAt(time.Now(), time.Hour))
+
+	err = client.Certificate.Revoke(resource.Certificate)
+	require.ErrorContains(t, err, "alreadyRevoked")
+}
+
+func TestServer_revoke_certificateKey(t *testing.T) {
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, _ := setupClient(t, ts)
+
+	resource, err := client.Certificate.Obtain(certificate.ObtainRequest{Domains: []string{"example.com"}})
+	require.NoError(t, err)
+
+	certKey, err := certcrypto.ParsePEMPrivateKey(resource.PrivateKey)
+	require.NoError(t, err)
+
+	// Another account, without authorization on the certificate.
+	other, _ := setupClient(t, ts)
+
+	err = other.Certificate.Revoke(resource.Certificate)
+	require.ErrorContains(t, err, "the certificate doesn't belong to the account")
+
+	err = other.Certificate.RevokeWithCertificateKey(t.Context(), resource.Certificate, certKey.(crypto.Signer), nil)
+	require.NoError(t, err)
+
+	cert, err := certcrypto.ParsePEMCertificate(resource.Certificate)
+	require.NoError(t, err)
+
+	assert.True(t, server.IsRevoked(cert))
+}
+
+func TestServer_account(t *testing.T) {
+	server := acmeserver.New()
+	ts := server.BuildHTTPS(t)
+
+	client, user := setupClient(t, ts)
+
+	reg, err := client.Registration.UpdateRegistration(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, acme.StatusValid, reg.Body.Status)
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	err = client.Registration.RolloverKey(newKey)
+	require.NoError(t, err)
+
+	// The account is found with the new key.
+	recovered := &fakeUser{privateKey: newKey}
+
+	client, err = lego.NewClient(newConfig(ts, recovered))
+	require.NoError(t, err)
+
+	recovered.registration, err = client.Registration.ResolveAccountByKey()
+	require.NoError(t, err)
+
+	assert.Equal(t, user.registration.URI, recovered.registration.URI)
+
+	client, err = lego.NewClient(newConfig(ts, recovered))
+	require.NoError(t, err)
+
+	err = client.Registration.DeleteRegistration()
+	require.NoError(t, err)
+
+	_, err = client.Registration.QueryRegistration()
+	require.ErrorContains(t, err, "the account is deactivated")
+}
+
+func setupClient(t *testing.T, ts *httptest.Server) (*lego.Client, *fakeUser) {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	user := &fakeUser{privateKey: privateKey}
+
+	client, err := lego.NewClient(newConfig(ts, user))
+	require.NoError(t, err)
+
+	err = client.Challenge.SetHTTP01Provider(&recordingProvider{})
+	require.NoError(t, err)
+
+	user.registration, err = client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	require.NoError(t, err)
+
+	return client, user
+}
+
+func newConfig(ts *httptest.Server, user *fakeUser) *lego.Config {
+	config := lego.NewConfig(user)
+	config.CADirURL = ts.URL + acmeserver.DirectoryPath
+	config.HTTPClient = ts.Client()
+	config.Certificate.KeyType = certcrypto.EC256
+
+	return config
+}
+
+type fakeUser struct {
+	privateKey   crypto.PrivateKey
+	registration *registration.Resource
+}
+
+func (f *fakeUser) GetEmail() string                        { return "" }
+func (f *fakeUser) GetRegistration() *registration.Resource { return f.registration }
+func (f *fakeUser) GetPrivateKey() crypto.PrivateKey        { return f.privateKey }
+
+// recordingProvider a challenge provider which records the key authorizations.
+type recordingProvider struct {
+	mu       sync.Mutex
+	keyAuths map[string]string
+	count    int
+}
+
+func (p *recordingProvider) Present(_, token, keyAuth string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keyAuths == nil {
+		p.keyAuths = make(map[string]string)
+	}
+
+	p.keyAuths[token] = keyAuth
+	p.count++
+
+	return nil
+}
+
+func (p *recordingProvider) CleanUp(_, _, _ string) error {
+	return nil
+}
+
+func (p *recordingProvider) keyAuth(token string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.keyAuths[token]
+}
+
+func (p *recordingProvider) presented() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.count
+}
@@ -23,6 +23,7 @@ func MockACMEServer() *servermock.Builder[*httptest.Server] {
 				NewNonceURL:   serverURL + "/nonce",
 				NewAccountURL: serverURL + "/account",
 				NewOrderURL:   serverURL + "/newOrder",
+				NewAuthzURL:   serverURL + "/newAuthz",
 				RevokeCertURL: serverURL + "/revokeCert",
 				KeyChangeURL:  serverURL + "/keyChange",
 				RenewalInfo:   serverURL + "/renewalInfo",
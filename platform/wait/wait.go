@@ -11,6 +11,11 @@ import (
 
 // For polls the given function 'f', once every 'interval', up to 'timeout'.
 func For(msg string, timeout, interval time.Duration, f func() (bool, error)) error {
+	return ForWithContext(context.Background(), msg, timeout, interval, f)
+}
+
+// ForWithContext polls the given function 'f', once every 'interval', up to 'timeout' or until the context is canceled.
+func ForWithContext(ctx context.Context, msg string, timeout, interval time.Duration, f func() (bool, error)) error {
 	log.Infof("Wait for %s [timeout: %s, interval: %s]", msg, timeout, interval)
 
 	var lastErr error
@@ -25,6 +30,12 @@ func For(msg string, timeout, interval time.Duration, f func() (bool, error)) er
 			}
 
 			return fmt.Errorf("%s: time limit exceeded: last error: %w", msg, lastErr)
+		case <-ctx.Done():
+			if lastErr == nil {
+				return fmt.Errorf("%s: %w", msg, ctx.Err())
+			}
+
+			return fmt.Errorf("%s: %w: last error: %w", msg, ctx.Err(), lastErr)
 		default:
 		}
 
@@ -37,7 +48,26 @@ func For(msg string, timeout, interval time.Duration, f func() (bool, error)) er
 			lastErr = err
 		}
 
-		time.Sleep(interval)
+		// The cancellation of the context is handled at the beginning of the loop.
+		_ = Sleep(ctx, interval)
+	}
+}
+
+// Sleep pauses the current goroutine for at least the duration d, or until the context is canceled.
+// It returns the context error if the context is canceled before the end of the duration.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
@@ -1,6 +1,7 @@
 package wait
 
 import (
+	"context"
 	"errors"
 	"sync/atomic"
 	"testing"
@@ -119,3 +120,33 @@ func TestFor_stop_with_error(t *testing.T) {
 
 	require.EqualValues(t, 1, io.Load())
 }
+
+func TestForWithContext_canceled(t *testing.T) {
+	var io atomic.Int64
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	c := make(chan error)
+
+	go func() {
+		c <- ForWithContext(ctx, "test", 3*time.Second, 1*time.Second, func() (bool, error) {
+			io.Add(1)
+
+			cancel()
+
+			return false, errors.New("oops")
+		})
+	}()
+
+	timeout := time.After(2 * time.Second)
+
+	select {
+	case <-timeout:
+		t.Fatal("timeout exceeded")
+	case err := <-c:
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualError(t, err, "test: context canceled: last error: oops")
+	}
+
+	require.EqualValues(t, 1, io.Load())
+}
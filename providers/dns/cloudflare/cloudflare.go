@@ -228,6 +228,28 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
+// SaveState returns the ID of the record created for the token.
+func (d *DNSProvider) SaveState(token string) (string, error) {
+	d.recordIDsMu.Lock()
+	defer d.recordIDsMu.Unlock()
+
+	recordID, ok := d.recordIDs[token]
+	if !ok {
+		return "", fmt.Errorf("cloudflare: unknown record ID for token '%s'", token)
+	}
+
+	return recordID, nil
+}
+
+// RestoreState restores the ID of the record created for the token.
+func (d *DNSProvider) RestoreState(token, state string) error {
+	d.recordIDsMu.Lock()
+	d.recordIDs[token] = state
+	d.recordIDsMu.Unlock()
+
+	return nil
+}
+
 func altEnvName(v string) string {
 	return strings.ReplaceAll(v, envNamespace, altEnvNamespace)
 }
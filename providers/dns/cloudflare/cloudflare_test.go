@@ -364,3 +364,37 @@ func TestDNSProvider_CleanUp(t *testing.T) {
 	err := provider.CleanUp("example.com", token, "123d==")
 	require.NoError(t, err)
 }
+
+func TestDNSProvider_state(t *testing.T) {
+	provider := mockBuilder().
+		Route("GET /zones",
+			servermock.ResponseFromInternal("zones.json")).
+		Route("POST /zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records",
+			servermock.ResponseFromInternal("create_record.json")).
+		Build(t)
+
+	err := provider.Present("example.com", "abc", "123d==")
+	require.NoError(t, err)
+
+	state, err := provider.SaveState("abc")
+	require.NoError(t, err)
+
+	assert.Equal(t, "023e105f4ecef8ad9ca31a8372d0c353", state)
+
+	// The challenge is cleaned up by another provider (e.g. another process).
+	provider = mockBuilder().
+		Route("GET /zones",
+			servermock.ResponseFromInternal("zones.json")).
+		Route("DELETE /zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records/023e105f4ecef8ad9ca31a8372d0c353",
+			servermock.ResponseFromInternal("delete_record.json")).
+		Build(t)
+
+	err = provider.RestoreState("abc", state)
+	require.NoError(t, err)
+
+	err = provider.CleanUp("example.com", "abc", "123d==")
+	require.NoError(t, err)
+
+	_, err = provider.SaveState("abc")
+	require.Error(t, err)
+}
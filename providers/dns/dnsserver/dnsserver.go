@@ -0,0 +1,112 @@
+// Package dnsserver implements a DNS provider for solving the DNS-01 challenge using the built-in DNS server of lego (`lego dns serve`).
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	legodns "github.com/go-acme/lego/v4/challenge/dns01/dnsserver"
+	"github.com/go-acme/lego/v4/platform/config/env"
+)
+
+// Environment variables names.
+const (
+	envNamespace = "DNSSERVER_"
+
+	EnvZone       = envNamespace + "ZONE"
+	EnvRecordsDir = envNamespace + "RECORDS_DIR"
+
+	EnvPropagationTimeout = envNamespace + "PROPAGATION_TIMEOUT"
+	EnvPollingInterval    = envNamespace + "POLLING_INTERVAL"
+)
+
+var (
+	_ challenge.ProviderTimeout = (*DNSProvider)(nil)
+	_ dns01.RecordProvider      = (*DNSProvider)(nil)
+)
+
+// Config is used to configure the creation of the DNSProvider.
+type Config struct {
+	Zone       string
+	RecordsDir string
+
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+}
+
+// NewDefaultConfig returns a default configuration for the DNSProvider.
+func NewDefaultConfig() *Config {
+	return &Config{
+		PropagationTimeout: env.GetOrDefaultSecond(EnvPropagationTimeout, dns01.DefaultPropagationTimeout),
+		PollingInterval:    env.GetOrDefaultSecond(EnvPollingInterval, dns01.DefaultPollingInterval),
+	}
+}
+
+// DNSProvider implements the challenge.Provider interface.
+type DNSProvider struct {
+	config   *Config
+	provider *legodns.Provider
+}
+
+// NewDNSProvider returns a DNSProvider instance configured for the built-in DNS server.
+// The records are written in the directory DNSSERVER_RECORDS_DIR,
+// shared with the DNS server (`lego dns serve --records-dir`).
+func NewDNSProvider() (*DNSProvider, error) {
+	values, err := env.Get(EnvZone, EnvRecordsDir)
+	if err != nil {
+		return nil, fmt.Errorf("dnsserver: %w", err)
+	}
+
+	config := NewDefaultConfig()
+	config.Zone = values[EnvZone]
+	config.RecordsDir = values[EnvRecordsDir]
+
+	return NewDNSProviderConfig(config)
+}
+
+// NewDNSProviderConfig return a DNSProvider instance configured for the built-in DNS server.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("dnsserver: the configuration of the DNS provider is nil")
+	}
+
+	if config.RecordsDir == "" {
+		return nil, errors.New("dnsserver: missing records directory")
+	}
+
+	provider, err := legodns.NewProvider(config.Zone, legodns.DirStore(config.RecordsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DNSProvider{config: config, provider: provider}, nil
+}
+
+// Present creates a TXT record to fulfill the dns-01 challenge.
+func (d *DNSProvider) Present(domain, token, keyAuth string) error {
+	return d.provider.Present(domain, token, keyAuth)
+}
+
+// CleanUp removes the TXT record matching the specified parameters.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	return d.provider.CleanUp(domain, token, keyAuth)
+}
+
+// PresentRecord creates an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) PresentRecord(domain string, info dns01.ChallengeInfo) error {
+	return d.provider.PresentRecord(domain, info)
+}
+
+// CleanUpRecord removes an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) CleanUpRecord(domain string, info dns01.ChallengeInfo) error {
+	return d.provider.CleanUpRecord(domain, info)
+}
+
+// Timeout returns the timeout and interval to use when checking for DNS propagation.
+// Adjusting here to cope with spikes in propagation times.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	return d.config.PropagationTimeout, d.config.PollingInterval
+}
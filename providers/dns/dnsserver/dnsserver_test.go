@@ -0,0 +1,111 @@
+package dnsserver
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/platform/tester"
+	"github.com/stretchr/testify/require"
+)
+
+var envTest = tester.NewEnvTest(EnvZone, EnvRecordsDir)
+
+func TestNewDNSProvider(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		envVars  map[string]string
+		expected string
+	}{
+		{
+			desc: "success",
+			envVars: map[string]string{
+				EnvZone:       "acme.example.com",
+				EnvRecordsDir: t.TempDir(),
+			},
+		},
+		{
+			desc: "missing zone",
+			envVars: map[string]string{
+				EnvRecordsDir: t.TempDir(),
+			},
+			expected: "dnsserver: some credentials information are missing: DNSSERVER_ZONE",
+		},
+		{
+			desc: "missing records directory",
+			envVars: map[string]string{
+				EnvZone: "acme.example.com",
+			},
+			expected: "dnsserver: some credentials information are missing: DNSSERVER_RECORDS_DIR",
+		},
+		{
+			desc:     "missing credentials",
+			envVars:  map[string]string{},
+			expected: "dnsserver: some credentials information are missing: DNSSERVER_ZONE,DNSSERVER_RECORDS_DIR",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			defer envTest.RestoreEnv()
+
+			envTest.ClearEnv()
+
+			envTest.Apply(test.envVars)
+
+			p, err := NewDNSProvider()
+
+			if test.expected == "" {
+				require.NoError(t, err)
+				require.NotNil(t, p)
+				require.NotNil(t, p.config)
+				require.NotNil(t, p.provider)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewDNSProviderConfig(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		zone       string
+		recordsDir string
+		expected   string
+	}{
+		{
+			desc:       "success",
+			zone:       "acme.example.com",
+			recordsDir: t.TempDir(),
+		},
+		{
+			desc:       "invalid zone",
+			zone:       "",
+			recordsDir: t.TempDir(),
+			expected:   `dnsserver: invalid zone: ""`,
+		},
+		{
+			desc:     "missing records directory",
+			zone:     "acme.example.com",
+			expected: "dnsserver: missing records directory",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			config := NewDefaultConfig()
+			config.Zone = test.zone
+			config.RecordsDir = test.recordsDir
+
+			p, err := NewDNSProviderConfig(config)
+
+			if test.expected == "" {
+				require.NoError(t, err)
+				require.NotNil(t, p)
+				require.NotNil(t, p.config)
+				require.NotNil(t, p.provider)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
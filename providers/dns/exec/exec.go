@@ -28,7 +28,10 @@ const (
 	EnvSequenceInterval   = envNamespace + "SEQUENCE_INTERVAL"
 )
 
-var _ challenge.ProviderTimeout = (*DNSProvider)(nil)
+var (
+	_ challenge.ProviderTimeout = (*DNSProvider)(nil)
+	_ dns01.RecordProvider      = (*DNSProvider)(nil)
+)
 
 // Config Provider configuration.
 type Config struct {
@@ -98,6 +101,35 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
+// PresentRecord creates an arbitrary TXT record (see dns01.RecordProvider).
+// The RAW mode is not supported: the program expects a token and a key authorization.
+func (d *DNSProvider) PresentRecord(_ string, info dns01.ChallengeInfo) error {
+	if d.config.Mode == "RAW" {
+		return errors.New("exec: the RAW mode doesn't support arbitrary records")
+	}
+
+	err := d.execute(context.Background(), "present", info.EffectiveFQDN, info.Value)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
+// CleanUpRecord removes an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) CleanUpRecord(_ string, info dns01.ChallengeInfo) error {
+	if d.config.Mode == "RAW" {
+		return errors.New("exec: the RAW mode doesn't support arbitrary records")
+	}
+
+	err := d.execute(context.Background(), "cleanup", info.EffectiveFQDN, info.Value)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
 // Timeout returns the timeout and interval to use when checking for DNS propagation.
 // Adjusting here to cope with spikes in propagation times.
 func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
@@ -111,14 +143,16 @@ func (d *DNSProvider) Sequential() time.Duration {
 }
 
 func (d *DNSProvider) run(ctx context.Context, command, domain, token, keyAuth string) error {
-	var args []string
 	if d.config.Mode == "RAW" {
-		args = []string{command, "--", domain, token, keyAuth}
-	} else {
-		info := dns01.GetChallengeInfo(domain, keyAuth)
-		args = []string{command, info.EffectiveFQDN, info.Value}
+		return d.execute(ctx, command, "--", domain, token, keyAuth)
 	}
 
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	return d.execute(ctx, command, info.EffectiveFQDN, info.Value)
+}
+
+func (d *DNSProvider) execute(ctx context.Context, args ...string) error {
 	cmd := exec.CommandContext(ctx, d.config.Program, args...)
 
 	stdout, err := cmd.StdoutPipe()
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-acme/lego/v4/challenge/dns01"
 	"github.com/go-acme/lego/v4/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -161,3 +162,45 @@ func TestDNSProvider_CleanUp(t *testing.T) {
 		})
 	}
 }
+
+func TestDNSProvider_PresentRecord(t *testing.T) {
+	backupLogger := log.Logger
+
+	defer func() {
+		log.Logger = backupLogger
+	}()
+
+	logRecorder := &LogRecorder{}
+	log.Logger = logRecorder
+
+	var message string
+
+	logRecorder.On("Println", mock.Anything).Run(func(args mock.Arguments) {
+		message = args.String(0)
+	})
+
+	info := dns01.ChallengeInfo{
+		FQDN:          "_validation-persist.domain.",
+		EffectiveFQDN: "_validation-persist.domain.",
+		Value:         "ca.example; accounturi=https://ca.example/acct/1",
+	}
+
+	provider, err := NewDNSProviderConfig(&Config{Program: "echo"})
+	require.NoError(t, err)
+
+	err = provider.PresentRecord("domain", info)
+	require.NoError(t, err)
+
+	assert.Equal(t, "present _validation-persist.domain. ca.example; accounturi=https://ca.example/acct/1", strings.TrimSpace(message))
+
+	err = provider.CleanUpRecord("domain", info)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cleanup _validation-persist.domain. ca.example; accounturi=https://ca.example/acct/1", strings.TrimSpace(message))
+
+	provider, err = NewDNSProviderConfig(&Config{Program: "echo", Mode: "RAW"})
+	require.NoError(t, err)
+
+	err = provider.PresentRecord("domain", info)
+	require.EqualError(t, err, "exec: the RAW mode doesn't support arbitrary records")
+}
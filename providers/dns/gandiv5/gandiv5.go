@@ -33,7 +33,10 @@ const (
 
 const minTTL = 300
 
-var _ challenge.ProviderTimeout = (*DNSProvider)(nil)
+var (
+	_ challenge.ProviderTimeout = (*DNSProvider)(nil)
+	_ dns01.RecordProvider      = (*DNSProvider)(nil)
+)
 
 // inProgressInfo contains information about an in-progress challenge.
 type inProgressInfo struct {
@@ -134,8 +137,16 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 
 // Present creates a TXT record using the specified parameters.
 func (d *DNSProvider) Present(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.PresentRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
 
+// CleanUp removes the TXT record matching the specified parameters.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	return d.CleanUpRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentRecord creates an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) PresentRecord(domain string, info dns01.ChallengeInfo) error {
 	// find authZone
 	authZone, err := d.findZoneByFqdn(info.EffectiveFQDN)
 	if err != nil {
@@ -168,10 +179,8 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 	return nil
 }
 
-// CleanUp removes the TXT record matching the specified parameters.
-func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
-
+// CleanUpRecord removes an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) CleanUpRecord(_ string, info dns01.ChallengeInfo) error {
 	// acquire lock and retrieve authZone
 	d.inProgressMu.Lock()
 	defer d.inProgressMu.Unlock()
@@ -32,7 +32,10 @@ const (
 	EnvHTTPTimeout        = envNamespace + "HTTP_TIMEOUT"
 )
 
-var _ challenge.ProviderTimeout = (*DNSProvider)(nil)
+var (
+	_ challenge.ProviderTimeout = (*DNSProvider)(nil)
+	_ dns01.RecordProvider      = (*DNSProvider)(nil)
+)
 
 type message struct {
 	FQDN  string `json:"fqdn"`
@@ -133,18 +136,7 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 		return nil
 	}
 
-	info := dns01.GetChallengeInfo(domain, keyAuth)
-	msg := &message{
-		FQDN:  info.EffectiveFQDN,
-		Value: info.Value,
-	}
-
-	err := d.doPost(ctx, "/present", msg)
-	if err != nil {
-		return fmt.Errorf("httpreq: %w", err)
-	}
-
-	return nil
+	return d.PresentRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
 }
 
 // CleanUp removes the TXT record matching the specified parameters.
@@ -166,13 +158,31 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 		return nil
 	}
 
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.CleanUpRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentRecord creates an arbitrary TXT record (see dns01.RecordProvider).
+// The RAW mode is not supported: the server expects a token and a key authorization.
+func (d *DNSProvider) PresentRecord(_ string, info dns01.ChallengeInfo) error {
+	return d.postRecord(context.Background(), "/present", info)
+}
+
+// CleanUpRecord removes an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) CleanUpRecord(_ string, info dns01.ChallengeInfo) error {
+	return d.postRecord(context.Background(), "/cleanup", info)
+}
+
+func (d *DNSProvider) postRecord(ctx context.Context, uri string, info dns01.ChallengeInfo) error {
+	if d.config.Mode == "RAW" {
+		return errors.New("httpreq: the RAW mode doesn't support arbitrary records")
+	}
+
 	msg := &message{
 		FQDN:  info.EffectiveFQDN,
 		Value: info.Value,
 	}
 
-	err := d.doPost(ctx, "/cleanup", msg)
+	err := d.doPost(ctx, uri, msg)
 	if err != nil {
 		return fmt.Errorf("httpreq: %w", err)
 	}
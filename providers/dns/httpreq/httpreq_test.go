@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/go-acme/lego/v4/challenge/dns01"
 	"github.com/go-acme/lego/v4/platform/tester"
 	"github.com/go-acme/lego/v4/platform/tester/servermock"
 	"github.com/stretchr/testify/require"
@@ -224,6 +225,30 @@ func TestNewDNSProvider_Cleanup(t *testing.T) {
 	}
 }
 
+func TestNewDNSProvider_PresentRecord(t *testing.T) {
+	envTest.RestoreEnv()
+
+	info := dns01.ChallengeInfo{
+		FQDN:          "_validation-persist.domain.",
+		EffectiveFQDN: "_validation-persist.domain.",
+		Value:         "ca.example; accounturi=https://ca.example/acct/1",
+	}
+
+	p := mockBuilder("").
+		Route("/present",
+			servermock.RawStringResponse("lego"),
+			servermock.CheckRequestJSONBody(`{"fqdn":"_validation-persist.domain.","value":"ca.example; accounturi=https://ca.example/acct/1"}`)).
+		Build(t)
+
+	err := p.PresentRecord("domain", info)
+	require.NoError(t, err)
+
+	p = mockBuilder("RAW").Build(t)
+
+	err = p.PresentRecord("domain", info)
+	require.EqualError(t, err, "httpreq: the RAW mode doesn't support arbitrary records")
+}
+
 func mockBuilder(mode string) *servermock.Builder[*DNSProvider] {
 	return servermock.NewBuilder(
 		func(server *httptest.Server) (*DNSProvider, error) {
@@ -1,13 +1,453 @@
+// Package manual implements a DNS provider for solving the DNS-01 challenge manually.
 package manual
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/config/env"
+)
+
+// Environment variables names.
+const (
+	envNamespace = "MANUAL_"
+
+	EnvRecordsFile    = envNamespace + "RECORDS_FILE"
+	EnvWebhookURL     = envNamespace + "WEBHOOK_URL"
+	EnvConfirmFile    = envNamespace + "CONFIRM_FILE"
+	EnvConfirmAddress = envNamespace + "CONFIRM_ADDRESS"
+	EnvConfirmTimeout = envNamespace + "CONFIRM_TIMEOUT"
+
+	EnvTTL                = envNamespace + "TTL"
+	EnvPropagationTimeout = envNamespace + "PROPAGATION_TIMEOUT"
+	EnvPollingInterval    = envNamespace + "POLLING_INTERVAL"
+	EnvSequenceInterval   = envNamespace + "SEQUENCE_INTERVAL"
+	EnvHTTPTimeout        = envNamespace + "HTTP_TIMEOUT"
+)
+
+// Actions of the messages.
+const (
+	ActionPresent = "present"
+	ActionCleanUp = "cleanup"
 )
 
-// DNSProvider is an implementation of the ChallengeProvider interface.
-type DNSProvider = dns01.DNSProviderManual
+const dnsTemplate = `%s %d IN TXT %q`
+
+// confirmPath is the path of the HTTP callback, followed by the ID of the record.
+const confirmPath = "/confirm/"
+
+var (
+	_ challenge.ProviderTimeout = (*DNSProvider)(nil)
+	_ dns01.RecordProvider      = (*DNSProvider)(nil)
+)
+
+// Config is used to configure the creation of the DNSProvider.
+type Config struct {
+	// RecordsFile the file where the records are written (JSON).
+	RecordsFile string
+	// WebhookURL the URL where the records are sent (JSON, POST).
+	WebhookURL string
+
+	// ConfirmFile the file signaling the creation of a record: the file must contain the ID of the record.
+	// The file is removed before sending each record, and after each confirmation.
+	ConfirmFile string
+	// ConfirmAddress the address of the HTTP server receiving the confirmations (`POST /confirm/<id>`).
+	ConfirmAddress string
+	// ConfirmTimeout the maximum waiting time for a confirmation.
+	ConfirmTimeout time.Duration
+
+	TTL                int
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+	SequenceInterval   time.Duration
+	HTTPClient         *http.Client
+}
+
+// NewDefaultConfig returns a default configuration for the DNSProvider.
+func NewDefaultConfig() *Config {
+	return &Config{
+		ConfirmTimeout:     env.GetOrDefaultSecond(EnvConfirmTimeout, time.Hour),
+		TTL:                env.GetOrDefaultInt(EnvTTL, dns01.DefaultTTL),
+		PropagationTimeout: env.GetOrDefaultSecond(EnvPropagationTimeout, dns01.DefaultPropagationTimeout),
+		PollingInterval:    env.GetOrDefaultSecond(EnvPollingInterval, dns01.DefaultPollingInterval),
+		SequenceInterval:   env.GetOrDefaultSecond(EnvSequenceInterval, dns01.DefaultPropagationTimeout),
+		HTTPClient: &http.Client{
+			Timeout: env.GetOrDefaultSecond(EnvHTTPTimeout, 30*time.Second),
+		},
+	}
+}
+
+// Message the instructions sent to the records file, and to the webhook.
+type Message struct {
+	// Action `present` (the record must be created), or `cleanup` (the record can be removed).
+	Action string `json:"action"`
+
+	// ID identifies the record: used to confirm the creation of the record (`POST /confirm/<id>`).
+	ID string `json:"id"`
+
+	Domain string `json:"domain"`
+	Zone   string `json:"zone"`
+	FQDN   string `json:"fqdn"`
+	Value  string `json:"value"`
+	TTL    int    `json:"ttl"`
+}
+
+// DNSProvider implements the challenge.Provider interface.
+//
+// By default, the instructions are displayed, and the confirmations are read from the standard input.
+// With a records file or a webhook, the provider is non-interactive:
+// the instructions are written to the file and/or sent to the webhook,
+// and the confirmations are signaled by a file or an HTTP callback.
+//
+// DNSProvider is no longer an alias of dns01.DNSProviderManual:
+// the deprecated dns01.DNSProviderManual is still available, but it is a distinct type.
+type DNSProvider struct {
+	config *Config
+
+	mu sync.Mutex
+}
 
 // NewDNSProvider returns a DNSProvider instance.
+// The provider is interactive, unless MANUAL_RECORDS_FILE or MANUAL_WEBHOOK_URL are defined.
 func NewDNSProvider() (*DNSProvider, error) {
-	return &DNSProvider{}, nil
+	config := NewDefaultConfig()
+	config.RecordsFile = env.GetOrFile(EnvRecordsFile)
+	config.WebhookURL = env.GetOrFile(EnvWebhookURL)
+	config.ConfirmFile = env.GetOrFile(EnvConfirmFile)
+	config.ConfirmAddress = env.GetOrFile(EnvConfirmAddress)
+
+	return NewDNSProviderConfig(config)
+}
+
+// NewDNSProviderConfig return a DNSProvider instance configured for the manual resolution.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("manual: the configuration of the DNS provider is nil")
+	}
+
+	interactive := config.RecordsFile == "" && config.WebhookURL == ""
+	hasConfirmation := config.ConfirmFile != "" || config.ConfirmAddress != ""
+
+	switch {
+	case interactive && hasConfirmation:
+		return nil, errors.New("manual: a confirmation requires a records file or a webhook URL")
+	case !interactive && !hasConfirmation:
+		return nil, errors.New("manual: a records file or a webhook URL requires a confirmation file or a confirmation address")
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &DNSProvider{config: config}, nil
+}
+
+// Present creates a TXT record to fulfill the dns-01 challenge.
+func (d *DNSProvider) Present(domain, token, keyAuth string) error {
+	return d.PresentRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// CleanUp removes the TXT record matching the specified parameters.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	return d.CleanUpRecord(domain, dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentRecord creates an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) PresentRecord(domain string, info dns01.ChallengeInfo) error {
+	msg, err := d.newMessage(ActionPresent, domain, info)
+	if err != nil {
+		return err
+	}
+
+	if d.isInteractive() {
+		return d.presentInteractive(msg)
+	}
+
+	// Only one record is waiting for a confirmation at once.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.ConfirmTimeout)
+	defer cancel()
+
+	var confirmed <-chan struct{}
+
+	if d.config.ConfirmAddress != "" {
+		var server *http.Server
+
+		server, confirmed, err = d.startConfirmServer(msg.ID)
+		if err != nil {
+			return fmt.Errorf("manual: %w", err)
+		}
+
+		defer func() { _ = server.Shutdown(context.WithoutCancel(ctx)) }()
+	}
+
+	// A leftover confirmation file must not confirm the record.
+	err = removeConfirmFile(d.config.ConfirmFile)
+	if err != nil {
+		return fmt.Errorf("manual: %w", err)
+	}
+
+	err = d.send(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("manual: %w", err)
+	}
+
+	log.Info("manual: waiting for the confirmation of the record.", log.AttrDomain, domain, "fqdn", msg.FQDN, "id", msg.ID)
+
+	err = d.waitConfirmation(ctx, msg.ID, confirmed)
+	if err != nil {
+		return fmt.Errorf("manual: %w", err)
+	}
+
+	return nil
+}
+
+// CleanUpRecord removes an arbitrary TXT record (see dns01.RecordProvider).
+func (d *DNSProvider) CleanUpRecord(domain string, info dns01.ChallengeInfo) error {
+	msg, err := d.newMessage(ActionCleanUp, domain, info)
+	if err != nil {
+		return err
+	}
+
+	if d.isInteractive() {
+		fmt.Printf("lego: You can now remove this TXT record from your %s zone:\n", msg.Zone)
+		fmt.Printf(dnsTemplate+"\n", msg.FQDN, msg.TTL, "...")
+
+		return nil
+	}
+
+	err = d.send(context.Background(), msg)
+	if err != nil {
+		return fmt.Errorf("manual: %w", err)
+	}
+
+	return nil
+}
+
+// Timeout returns the timeout and interval to use when checking for DNS propagation.
+// Adjusting here to cope with spikes in propagation times.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	return d.config.PropagationTimeout, d.config.PollingInterval
+}
+
+// Sequential All DNS challenges for this provider will be resolved sequentially.
+// Returns the interval between each iteration.
+func (d *DNSProvider) Sequential() time.Duration {
+	return d.config.SequenceInterval
+}
+
+func (d *DNSProvider) isInteractive() bool {
+	return d.config.RecordsFile == "" && d.config.WebhookURL == ""
+}
+
+func (d *DNSProvider) newMessage(action, domain string, info dns01.ChallengeInfo) (Message, error) {
+	authZone, err := dns01.FindZoneByFqdn(info.EffectiveFQDN)
+	if err != nil {
+		return Message{}, fmt.Errorf("manual: could not find zone: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(info.EffectiveFQDN + info.Value))
+
+	return Message{
+		Action: action,
+		ID:     hex.EncodeToString(sum[:8]),
+		Domain: domain,
+		Zone:   authZone,
+		FQDN:   info.EffectiveFQDN,
+		Value:  info.Value,
+		TTL:    d.config.TTL,
+	}, nil
+}
+
+func (d *DNSProvider) presentInteractive(msg Message) error {
+	fmt.Printf("lego: Please create the following TXT record in your %s zone:\n", msg.Zone)
+	fmt.Printf(dnsTemplate+"\n", msg.FQDN, msg.TTL, msg.Value)
+	fmt.Printf("lego: Press 'Enter' when you are done\n")
+
+	_, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("manual: %w", err)
+	}
+
+	return nil
+}
+
+// send writes the message to the records file, and sends it to the webhook.
+func (d *DNSProvider) send(ctx context.Context, msg Message) error {
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if d.config.RecordsFile != "" {
+		err = writeFile(d.config.RecordsFile, data)
+		if err != nil {
+			return fmt.Errorf("write records file: %w", err)
+		}
+	}
+
+	if d.config.WebhookURL != "" {
+		err = d.callWebhook(ctx, data)
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *DNSProvider) callWebhook(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, string(raw))
+	}
+
+	return nil
+}
+
+// startConfirmServer starts the HTTP server receiving the confirmation of the record.
+func (d *DNSProvider) startConfirmServer(id string) (*http.Server, <-chan struct{}, error) {
+	listener, err := net.Listen("tcp", d.config.ConfirmAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not start the confirmation server: %w", err)
+	}
+
+	confirmed := make(chan struct{}, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+confirmPath+"{id}", func(rw http.ResponseWriter, req *http.Request) {
+		if req.PathValue("id") != id {
+			http.Error(rw, "unknown record", http.StatusNotFound)
+			return
+		}
+
+		select {
+		case confirmed <- struct{}{}:
+		default:
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		errS := server.Serve(listener)
+		if errS != nil && !errors.Is(errS, http.ErrServerClosed) {
+			log.Warn("manual: the confirmation server failed.", log.AttrError, errS)
+		}
+	}()
+
+	return server, confirmed, nil
+}
+
+// waitConfirmation waits for the confirmation file containing the ID of the record, or for the HTTP callback.
+func (d *DNSProvider) waitConfirmation(ctx context.Context, id string, confirmed <-chan struct{}) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		ok, err := d.checkConfirmFile(id)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no confirmation after %s", d.config.ConfirmTimeout)
+
+		case <-confirmed:
+			return nil
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkConfirmFile returns true if the confirmation file contains the ID of the record.
+// The file is removed: the next record needs a new confirmation.
+func (d *DNSProvider) checkConfirmFile(id string) (bool, error) {
+	if d.config.ConfirmFile == "" {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(d.config.ConfirmFile)
+	if err != nil {
+		return false, nil
+	}
+
+	if strings.TrimSpace(string(data)) != id {
+		return false, nil
+	}
+
+	err = removeConfirmFile(d.config.ConfirmFile)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func removeConfirmFile(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	err := os.Remove(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove the confirmation file: %w", err)
+	}
+
+	return nil
+}
+
+func writeFile(name string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(name), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, 0o600)
 }
@@ -1,14 +1,26 @@
 package manual
 
 import (
+	"encoding/json"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/platform/tester/dnsmock"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDNSProviderManual(t *testing.T) {
+	mockDNS(t)
+
 	backupStdin := os.Stdin
 
 	defer func() { os.Stdin = backupStdin }()
@@ -59,3 +71,298 @@ func TestDNSProviderManual(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDNSProviderConfig(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		config   *Config
+		expected string
+	}{
+		{
+			desc:   "interactive",
+			config: &Config{},
+		},
+		{
+			desc:   "records file",
+			config: &Config{RecordsFile: "records.json", ConfirmFile: "confirm"},
+		},
+		{
+			desc:   "webhook",
+			config: &Config{WebhookURL: "https://example.com/hook", ConfirmAddress: ":8080"},
+		},
+		{
+			desc:     "no confirmation",
+			config:   &Config{WebhookURL: "https://example.com/hook"},
+			expected: "manual: a records file or a webhook URL requires a confirmation file or a confirmation address",
+		},
+		{
+			desc:     "confirmation without records",
+			config:   &Config{ConfirmFile: "confirm"},
+			expected: "manual: a confirmation requires a records file or a webhook URL",
+		},
+		{
+			desc:     "nil config",
+			expected: "manual: the configuration of the DNS provider is nil",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			p, err := NewDNSProviderConfig(test.config)
+
+			if test.expected == "" {
+				require.NoError(t, err)
+				require.NotNil(t, p)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestDNSProvider_file(t *testing.T) {
+	mockDNS(t)
+
+	dir := t.TempDir()
+
+	config := NewDefaultConfig()
+	config.RecordsFile = filepath.Join(dir, "records", "records.json")
+	config.ConfirmFile = filepath.Join(dir, "confirm")
+	config.ConfirmTimeout = 10 * time.Second
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			if id := readID(config.RecordsFile); id != "" {
+				_ = os.WriteFile(config.ConfirmFile, []byte(id+"\n"), 0o600)
+				return
+			}
+
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	msg := readMessage(t, config.RecordsFile)
+
+	expected := Message{
+		Action: ActionPresent,
+		ID:     msg.ID,
+		Domain: "example.com",
+		Zone:   "example.com.",
+		FQDN:   "_acme-challenge.example.com.",
+		Value:  dns01.GetChallengeInfo("example.com", "keyAuth").Value,
+		TTL:    dns01.DefaultTTL,
+	}
+
+	assert.Equal(t, expected, msg)
+	assert.NotEmpty(t, msg.ID)
+
+	// The confirmation file is consumed.
+	assert.NoFileExists(t, config.ConfirmFile)
+
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	expected.Action = ActionCleanUp
+
+	assert.Equal(t, expected, readMessage(t, config.RecordsFile))
+}
+
+func TestDNSProvider_webhook(t *testing.T) {
+	mockDNS(t)
+
+	config := NewDefaultConfig()
+	config.ConfirmAddress = freeAddress(t)
+	config.ConfirmTimeout = 10 * time.Second
+
+	var messages []Message
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var msg Message
+
+		err := json.NewDecoder(req.Body).Decode(&msg)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		messages = append(messages, msg)
+
+		if msg.Action == ActionPresent {
+			go func() {
+				// An unknown record is not confirmed.
+				resp, errP := http.Post("http://"+config.ConfirmAddress+"/confirm/unknown", "", nil)
+				if errP == nil {
+					_ = resp.Body.Close()
+				}
+
+				resp, errP = http.Post("http://"+config.ConfirmAddress+"/confirm/"+msg.ID, "", nil)
+				if errP == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	config.WebhookURL = server.URL
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+
+	assert.Equal(t, ActionPresent, messages[0].Action)
+	assert.Equal(t, ActionCleanUp, messages[1].Action)
+	assert.Equal(t, messages[0].ID, messages[1].ID)
+	assert.Equal(t, "_acme-challenge.example.com.", messages[1].FQDN)
+}
+
+func TestDNSProvider_Present_timeout(t *testing.T) {
+	mockDNS(t)
+
+	dir := t.TempDir()
+
+	config := NewDefaultConfig()
+	config.RecordsFile = filepath.Join(dir, "records.json")
+	config.ConfirmFile = filepath.Join(dir, "confirm")
+	config.ConfirmTimeout = 100 * time.Millisecond
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.EqualError(t, err, "manual: no confirmation after 100ms")
+}
+
+func TestDNSProvider_Present_leftoverConfirmFile(t *testing.T) {
+	mockDNS(t)
+
+	dir := t.TempDir()
+
+	config := NewDefaultConfig()
+	config.RecordsFile = filepath.Join(dir, "records.json")
+	config.ConfirmFile = filepath.Join(dir, "confirm")
+	config.ConfirmTimeout = 1500 * time.Millisecond
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	msg, err := provider.newMessage(ActionPresent, "example.com", dns01.GetChallengeInfo("example.com", "keyAuth"))
+	require.NoError(t, err)
+
+	// A confirmation written before the record is sent is ignored.
+	err = os.WriteFile(config.ConfirmFile, []byte(msg.ID), 0o600)
+	require.NoError(t, err)
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.EqualError(t, err, "manual: no confirmation after 1.5s")
+}
+
+func TestDNSProvider_Present_confirmFileUnknownID(t *testing.T) {
+	mockDNS(t)
+
+	dir := t.TempDir()
+
+	config := NewDefaultConfig()
+	config.RecordsFile = filepath.Join(dir, "records.json")
+	config.ConfirmFile = filepath.Join(dir, "confirm")
+	config.ConfirmTimeout = 1500 * time.Millisecond
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			if id := readID(config.RecordsFile); id != "" {
+				_ = os.WriteFile(config.ConfirmFile, []byte("unknown"), 0o600)
+				return
+			}
+
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	err = provider.Present("example.com", "token", "keyAuth")
+	require.EqualError(t, err, "manual: no confirmation after 1.5s")
+
+	// The confirmation of another record is not consumed.
+	assert.FileExists(t, config.ConfirmFile)
+}
+
+func mockDNS(t *testing.T) {
+	t.Helper()
+
+	addr := dnsmock.NewServer().
+		Query("_acme-challenge.example.com. CNAME", dnsmock.Noop).
+		Query("_acme-challenge.example.com. SOA", dnsmock.Error(dns.RcodeNameError)).
+		Query("example.com. SOA", dnsmock.SOA("")).
+		Build(t)
+
+	original := dns01.DefaultResolver().Nameservers()
+
+	dns01.SetRecursiveNameservers([]string{addr.String()})
+	dns01.ClearFqdnCache()
+
+	t.Cleanup(func() {
+		dns01.SetRecursiveNameservers(original)
+		dns01.ClearFqdnCache()
+	})
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+
+	require.NoError(t, listener.Close())
+
+	return addr
+}
+
+func readMessage(t *testing.T, filename string) Message {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	var msg Message
+
+	err = json.Unmarshal(data, &msg)
+	require.NoError(t, err)
+
+	return msg
+}
+
+// readID returns the ID of the record written in the records file, if any.
+func readID(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+
+	var msg Message
+
+	err = json.Unmarshal(data, &msg)
+	if err != nil {
+		return ""
+	}
+
+	return msg.ID
+}
@@ -55,6 +55,7 @@ import (
 	"github.com/go-acme/lego/v4/providers/dns/dnsimple"
 	"github.com/go-acme/lego/v4/providers/dns/dnsmadeeasy"
 	"github.com/go-acme/lego/v4/providers/dns/dnspod"
+	"github.com/go-acme/lego/v4/providers/dns/dnsserver"
 	"github.com/go-acme/lego/v4/providers/dns/dode"
 	"github.com/go-acme/lego/v4/providers/dns/domeneshop"
 	"github.com/go-acme/lego/v4/providers/dns/dreamhost"
@@ -302,6 +303,8 @@ func NewDNSChallengeProviderByName(name string) (challenge.Provider, error) {
 		return dnsmadeeasy.NewDNSProvider()
 	case "dnspod":
 		return dnspod.NewDNSProvider()
+	case "dnsserver":
+		return dnsserver.NewDNSProvider()
 	case "dode":
 		return dode.NewDNSProvider()
 	case "domeneshop", "domainnameshop":
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of events.
+const (
+	KindOrder            = "order"
+	KindCertificate      = "certificate"
+	KindFailedValidation = "failedValidation"
+)
+
+// Event an event counted by the rate limits.
+type Event struct {
+	Kind string `json:"kind"`
+	// Account the account URL.
+	Account string `json:"account,omitempty"`
+	// Domains the sorted domains related to the event.
+	Domains []string  `json:"domains"`
+	Time    time.Time `json:"time"`
+}
+
+// Store persists the content of a ledger.
+type Store interface {
+	// Load returns the stored content, nil if nothing is stored.
+	Load() ([]byte, error)
+	// Save replaces the stored content.
+	Save(data []byte) error
+}
+
+// Ledger the persisted list of the events counted by the rate limits.
+// The content of the store is loaded before each operation:
+// a ledger can be shared by several processes, as long as they don't add events at the same time.
+type Ledger struct {
+	mu    sync.Mutex
+	store Store
+}
+
+// NewLedger creates a Ledger.
+func NewLedger(store Store) *Ledger {
+	return &Ledger{store: store}
+}
+
+// Events returns the events of the ledger.
+func (l *Ledger) Events() ([]Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.load()
+}
+
+// Add adds an event, and removes the events older than the retention.
+func (l *Ledger) Add(event Event, retention time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	events = slices.DeleteFunc(events, func(e Event) bool {
+		return event.Time.Sub(e.Time) > retention
+	})
+
+	events = append(events, event)
+
+	data, err := json.MarshalIndent(events, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = l.store.Save(data)
+	if err != nil {
+		return fmt.Errorf("ratelimit: save the ledger: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Ledger) load() ([]Event, error) {
+	data, err := l.store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: load the ledger: %w", err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var events []Event
+
+	err = json.Unmarshal(data, &events)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: parse the ledger: %w", err)
+	}
+
+	return events, nil
+}
+
+// FileStore a Store based on a file.
+type FileStore struct {
+	filename string
+}
+
+// NewFileStore creates a FileStore.
+func NewFileStore(filename string) *FileStore {
+	return &FileStore{filename: filename}
+}
+
+func (f *FileStore) Load() ([]byte, error) {
+	data, err := os.ReadFile(f.filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (f *FileStore) Save(data []byte) error {
+	err := os.MkdirAll(filepath.Dir(f.filename), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.filename, data, 0o600)
+}
+
+// normalizeDomains returns the sorted, lowercase, and unique domains.
+func normalizeDomains(domains []string) []string {
+	var result []string
+
+	for _, domain := range domains {
+		result = append(result, strings.ToLower(strings.TrimSuffix(domain, ".")))
+	}
+
+	slices.Sort(result)
+
+	return slices.Compact(result)
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger_Add(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "ratelimit", "ledger.json"))
+
+	ledger := NewLedger(store)
+
+	events, err := ledger.Events()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	old := Event{Kind: KindOrder, Account: "a", Domains: []string{"example.com"}, Time: now.Add(-48 * time.Hour)}
+	recent := Event{Kind: KindCertificate, Account: "a", Domains: []string{"example.com"}, Time: now.Add(-time.Hour)}
+	event := Event{Kind: KindFailedValidation, Account: "a", Domains: []string{"example.org"}, Time: now}
+
+	require.NoError(t, ledger.Add(old, 24*time.Hour))
+	require.NoError(t, ledger.Add(recent, 24*time.Hour))
+	require.NoError(t, ledger.Add(event, 24*time.Hour))
+
+	// The ledger is persisted.
+	events, err = NewLedger(store).Events()
+	require.NoError(t, err)
+
+	// The old event is removed.
+	assert.Equal(t, []Event{recent, event}, events)
+}
+
+func Test_normalizeDomains(t *testing.T) {
+	domains := normalizeDomains([]string{"www.Example.com", "example.com.", "example.com", "*.example.com"})
+
+	assert.Equal(t, []string{"*.example.com", "example.com", "www.example.com"}, domains)
+}
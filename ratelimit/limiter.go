@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/log"
+	"github.com/go-acme/lego/v4/platform/wait"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Names of the limits.
+const (
+	LimitNewOrders             = "newOrders"
+	LimitCertificatesPerDomain = "certificatesPerDomain"
+	LimitDuplicateCertificates = "duplicateCertificates"
+	LimitFailedValidations     = "failedValidations"
+)
+
+// Config the configuration of a Limiter.
+type Config struct {
+	// Limits the known rate limits of the ACME server.
+	Limits Limits
+	// MaxDelay the maximum time to wait when a known limit is reached.
+	// When the time to wait is longer, the request is refused.
+	MaxDelay time.Duration
+	// MaxRetryAfter the maximum time to wait before retrying a request rejected with a rateLimited error,
+	// as requested by the server (Retry-After).
+	// When 0, or when the time to wait is longer, the request is not retried.
+	MaxRetryAfter time.Duration
+}
+
+// ExceededError the error returned when a request would exceed a known limit.
+type ExceededError struct {
+	// Name the name of the limit.
+	Name  string
+	Limit Limit
+	// Key the account or the domain(s) related to the limit.
+	Key string
+	// RetryAt the time when the request would not exceed the limit anymore.
+	RetryAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("ratelimit: the limit %s (%d per %s) would be exceeded for %s, retry after %s",
+		e.Name, e.Limit.Count, e.Limit.Window, e.Key, e.RetryAt.Format(time.RFC3339))
+}
+
+// Limiter checks the known limits before the creation of the orders, and records the related events in a ledger.
+// A nil Limiter does nothing.
+type Limiter struct {
+	ledger *Ledger
+	config Config
+
+	now func() time.Time
+}
+
+// NewLimiter creates a Limiter.
+// The ledger can be nil: only the rateLimited errors are handled (see Config.MaxRetryAfter).
+func NewLimiter(ledger *Ledger, config Config) *Limiter {
+	return &Limiter{
+		ledger: ledger,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Wait checks if a new order of the account for the domains would exceed a known limit.
+// If so, it waits until the order is possible, or returns an ExceededError if the time to wait is longer than Config.MaxDelay.
+func (l *Limiter) Wait(ctx context.Context, account string, domains []string) error {
+	if l == nil || l.ledger == nil {
+		return nil
+	}
+
+	events, err := l.ledger.Events()
+	if err != nil {
+		return err
+	}
+
+	now := l.now()
+
+	errE := l.check(events, account, normalizeDomains(domains), now)
+	if errE == nil {
+		return nil
+	}
+
+	delay := errE.RetryAt.Sub(now)
+	if delay > l.config.MaxDelay {
+		return errE
+	}
+
+	log.Info("ratelimit: a known limit is reached; waiting.",
+		log.AttrDomains, domains, "limit", errE.Name, "delay", delay.Round(time.Second))
+
+	return wait.Sleep(ctx, delay)
+}
+
+// RetryAfter returns the time to wait before retrying a request rejected with a rateLimited error,
+// and whether the request should be retried (see Config.MaxRetryAfter).
+func (l *Limiter) RetryAfter(err error) (time.Duration, bool) {
+	if l == nil || l.config.MaxRetryAfter <= 0 {
+		return 0, false
+	}
+
+	var rle *acme.RateLimitedError
+	if !errors.As(err, &rle) {
+		return 0, false
+	}
+
+	delay, errP := api.ParseRetryAfter(rle.RetryAfter)
+	if errP != nil || delay <= 0 || delay > l.config.MaxRetryAfter {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// RecordOrder records the creation of an order.
+func (l *Limiter) RecordOrder(account string, domains []string) {
+	l.record(Event{Kind: KindOrder, Account: account, Domains: normalizeDomains(domains)})
+}
+
+// RecordCertificate records the issuance of a certificate.
+func (l *Limiter) RecordCertificate(account string, domains []string) {
+	l.record(Event{Kind: KindCertificate, Account: account, Domains: normalizeDomains(domains)})
+}
+
+// RecordFailedValidation records the failed validation of a domain.
+func (l *Limiter) RecordFailedValidation(account, domain string) {
+	l.record(Event{Kind: KindFailedValidation, Account: account, Domains: normalizeDomains([]string{domain})})
+}
+
+func (l *Limiter) record(event Event) {
+	if l == nil || l.ledger == nil {
+		return
+	}
+
+	event.Time = l.now().UTC()
+
+	// The events are kept at least 7 days: the limits can be changed later.
+	err := l.ledger.Add(event, max(l.config.Limits.retention(), 7*24*time.Hour))
+	if err != nil {
+		log.Warn("ratelimit: unable to record the event.", "kind", event.Kind, log.AttrError, err)
+	}
+}
+
+// check returns the limit which would be exceeded by a new order, with the latest RetryAt.
+func (l *Limiter) check(events []Event, account string, domains []string, now time.Time) *ExceededError {
+	var result *ExceededError
+
+	keep := func(errE *ExceededError) {
+		if errE != nil && (result == nil || errE.RetryAt.After(result.RetryAt)) {
+			result = errE
+		}
+	}
+
+	limits := l.config.Limits
+
+	keep(exceeded(LimitNewOrders, account, limits.NewOrders, now, events, func(e Event) bool {
+		return e.Kind == KindOrder && e.Account == account
+	}))
+
+	keep(exceeded(LimitDuplicateCertificates, strings.Join(domains, ","), limits.DuplicateCertificates, now, events, func(e Event) bool {
+		return e.Kind == KindCertificate && slices.Equal(e.Domains, domains)
+	}))
+
+	var registeredDomains []string
+
+	for _, domain := range domains {
+		keep(exceeded(LimitFailedValidations, domain, limits.FailedValidations, now, events, func(e Event) bool {
+			return e.Kind == KindFailedValidation && e.Account == account && slices.Contains(e.Domains, domain)
+		}))
+
+		registeredDomains = append(registeredDomains, registeredDomain(domain))
+	}
+
+	slices.Sort(registeredDomains)
+
+	for _, rd := range slices.Compact(registeredDomains) {
+		keep(exceeded(LimitCertificatesPerDomain, rd, limits.CertificatesPerDomain, now, events, func(e Event) bool {
+			return e.Kind == KindCertificate && slices.ContainsFunc(e.Domains, func(domain string) bool {
+				return registeredDomain(domain) == rd
+			})
+		}))
+	}
+
+	return result
+}
+
+// exceeded returns an ExceededError if the matching events in the window of the limit reach the count of the limit.
+func exceeded(name, key string, limit Limit, now time.Time, events []Event, match func(Event) bool) *ExceededError {
+	if !limit.enabled() {
+		return nil
+	}
+
+	var times []time.Time
+
+	for _, event := range events {
+		if match(event) && now.Sub(event.Time) < limit.Window {
+			times = append(times, event.Time)
+		}
+	}
+
+	if len(times) < limit.Count {
+		return nil
+	}
+
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	// The request is possible when only Count-1 events remain in the window.
+	return &ExceededError{
+		Name:    name,
+		Limit:   limit,
+		Key:     key,
+		RetryAt: times[len(times)-limit.Count].Add(limit.Window),
+	}
+}
+
+// registeredDomain returns the registered domain (eTLD+1) of a domain.
+func registeredDomain(domain string) string {
+	domain = strings.TrimPrefix(domain, "*.")
+
+	if net.ParseIP(domain) != nil {
+		return domain
+	}
+
+	rd, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+
+	return rd
+}
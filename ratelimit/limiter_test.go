@@ -0,0 +1,284 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Wait(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc     string
+		limits   Limits
+		events   []Event
+		domains  []string
+		expected *ExceededError
+	}{
+		{
+			desc:    "no events",
+			limits:  LetsEncrypt,
+			domains: []string{"example.com"},
+		},
+		{
+			desc:   "duplicate certificates",
+			limits: Limits{DuplicateCertificates: Limit{Count: 2, Window: 24 * time.Hour}},
+			events: []Event{
+				{Kind: KindCertificate, Account: "a", Domains: []string{"example.com", "www.example.com"}, Time: now.Add(-3 * time.Hour)},
+				{Kind: KindCertificate, Account: "b", Domains: []string{"example.com", "www.example.com"}, Time: now.Add(-2 * time.Hour)},
+				{Kind: KindCertificate, Account: "a", Domains: []string{"example.com"}, Time: now.Add(-time.Hour)},
+			},
+			domains: []string{"www.example.com", "example.com"},
+			expected: &ExceededError{
+				Name:    LimitDuplicateCertificates,
+				Limit:   Limit{Count: 2, Window: 24 * time.Hour},
+				Key:     "example.com,www.example.com",
+				RetryAt: now.Add(21 * time.Hour),
+			},
+		},
+		{
+			desc:   "duplicate certificates: other set of domains",
+			limits: Limits{DuplicateCertificates: Limit{Count: 2, Window: 24 * time.Hour}},
+			events: []Event{
+				{Kind: KindCertificate, Account: "a", Domains: []string{"example.com", "www.example.com"}, Time: now.Add(-3 * time.Hour)},
+				{Kind: KindCertificate, Account: "b", Domains: []string{"example.com", "www.example.com"}, Time: now.Add(-2 * time.Hour)},
+			},
+			domains: []string{"example.com"},
+		},
+		{
+			desc:   "certificates per registered domain",
+			limits: Limits{CertificatesPerDomain: Limit{Count: 2, Window: 24 * time.Hour}},
+			events: []Event{
+				{Kind: KindCertificate, Account: "a", Domains: []string{"a.example.co.uk"}, Time: now.Add(-3 * time.Hour)},
+				{Kind: KindCertificate, Account: "b", Domains: []string{"b.example.co.uk"}, Time: now.Add(-2 * time.Hour)},
+				{Kind: KindCertificate, Account: "a", Domains: []string{"other.co.uk"}, Time: now.Add(-time.Hour)},
+			},
+			domains: []string{"c.example.co.uk"},
+			expected: &ExceededError{
+				Name:    LimitCertificatesPerDomain,
+				Limit:   Limit{Count: 2, Window: 24 * time.Hour},
+				Key:     "example.co.uk",
+				RetryAt: now.Add(21 * time.Hour),
+			},
+		},
+		{
+			desc:   "events outside of the window",
+			limits: Limits{CertificatesPerDomain: Limit{Count: 2, Window: 24 * time.Hour}},
+			events: []Event{
+				{Kind: KindCertificate, Account: "a", Domains: []string{"a.example.com"}, Time: now.Add(-30 * time.Hour)},
+				{Kind: KindCertificate, Account: "b", Domains: []string{"b.example.com"}, Time: now.Add(-2 * time.Hour)},
+			},
+			domains: []string{"c.example.com"},
+		},
+		{
+			desc:   "new orders per account",
+			limits: Limits{NewOrders: Limit{Count: 2, Window: 3 * time.Hour}},
+			events: []Event{
+				{Kind: KindOrder, Account: "a", Domains: []string{"a.example.com"}, Time: now.Add(-2 * time.Hour)},
+				{Kind: KindOrder, Account: "a", Domains: []string{"b.example.org"}, Time: now.Add(-time.Hour)},
+				{Kind: KindOrder, Account: "b", Domains: []string{"c.example.net"}, Time: now.Add(-time.Hour)},
+			},
+			domains: []string{"example.com"},
+			expected: &ExceededError{
+				Name:    LimitNewOrders,
+				Limit:   Limit{Count: 2, Window: 3 * time.Hour},
+				Key:     "a",
+				RetryAt: now.Add(time.Hour),
+			},
+		},
+		{
+			desc:   "failed validations",
+			limits: Limits{FailedValidations: Limit{Count: 1, Window: time.Hour}},
+			events: []Event{
+				{Kind: KindFailedValidation, Account: "a", Domains: []string{"www.example.com"}, Time: now.Add(-30 * time.Minute)},
+			},
+			domains: []string{"example.com", "www.example.com"},
+			expected: &ExceededError{
+				Name:    LimitFailedValidations,
+				Limit:   Limit{Count: 1, Window: time.Hour},
+				Key:     "www.example.com",
+				RetryAt: now.Add(30 * time.Minute),
+			},
+		},
+		{
+			desc: "several limits: the latest",
+			limits: Limits{
+				NewOrders:         Limit{Count: 1, Window: 3 * time.Hour},
+				FailedValidations: Limit{Count: 1, Window: time.Hour},
+			},
+			events: []Event{
+				{Kind: KindOrder, Account: "a", Domains: []string{"example.com"}, Time: now.Add(-time.Hour)},
+				{Kind: KindFailedValidation, Account: "a", Domains: []string{"example.com"}, Time: now.Add(-time.Hour + time.Minute)},
+			},
+			domains: []string{"example.com"},
+			expected: &ExceededError{
+				Name:    LimitNewOrders,
+				Limit:   Limit{Count: 1, Window: 3 * time.Hour},
+				Key:     "a",
+				RetryAt: now.Add(2 * time.Hour),
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ledger := NewLedger(NewFileStore(filepath.Join(t.TempDir(), "ledger.json")))
+
+			for _, event := range test.events {
+				require.NoError(t, ledger.Add(event, 30*24*time.Hour))
+			}
+
+			limiter := NewLimiter(ledger, Config{Limits: test.limits})
+			limiter.now = func() time.Time { return now }
+
+			err := limiter.Wait(t.Context(), "a", test.domains)
+
+			if test.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var errE *ExceededError
+
+			require.ErrorAs(t, err, &errE)
+
+			assert.Equal(t, test.expected, errE)
+		})
+	}
+}
+
+func TestLimiter_Wait_delay(t *testing.T) {
+	now := time.Now()
+
+	ledger := NewLedger(NewFileStore(filepath.Join(t.TempDir(), "ledger.json")))
+
+	limit := Limit{Count: 1, Window: time.Hour}
+
+	require.NoError(t, ledger.Add(Event{Kind: KindOrder, Account: "a", Time: now.Add(-limit.Window + 50*time.Millisecond)}, limit.Window))
+
+	limiter := NewLimiter(ledger, Config{Limits: Limits{NewOrders: limit}, MaxDelay: time.Second})
+	limiter.now = func() time.Time { return now }
+
+	err := limiter.Wait(t.Context(), "a", []string{"example.com"})
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, time.Since(now), 50*time.Millisecond)
+
+	// The context is canceled.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	err = limiter.Wait(ctx, "a", []string{"example.com"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLimiter_record(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	ledger := NewLedger(NewFileStore(filepath.Join(t.TempDir(), "ledger.json")))
+
+	limiter := NewLimiter(ledger, Config{Limits: LetsEncrypt})
+	limiter.now = func() time.Time { return now }
+
+	limiter.RecordOrder("a", []string{"www.example.com", "example.com"})
+	limiter.RecordFailedValidation("a", "www.example.com")
+	limiter.RecordCertificate("a", []string{"www.example.com", "example.com"})
+
+	events, err := ledger.Events()
+	require.NoError(t, err)
+
+	expected := []Event{
+		{Kind: KindOrder, Account: "a", Domains: []string{"example.com", "www.example.com"}, Time: now},
+		{Kind: KindFailedValidation, Account: "a", Domains: []string{"www.example.com"}, Time: now},
+		{Kind: KindCertificate, Account: "a", Domains: []string{"example.com", "www.example.com"}, Time: now},
+	}
+
+	assert.Equal(t, expected, events)
+}
+
+func TestLimiter_nil(t *testing.T) {
+	var limiter *Limiter
+
+	require.NoError(t, limiter.Wait(t.Context(), "a", []string{"example.com"}))
+
+	limiter.RecordOrder("a", []string{"example.com"})
+
+	_, ok := limiter.RetryAfter(&acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}, RetryAfter: "10"})
+	assert.False(t, ok)
+}
+
+func TestLimiter_RetryAfter(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		maxRetryAfter time.Duration
+		err           error
+		expected      time.Duration
+		retry         bool
+	}{
+		{
+			desc:          "rate limited",
+			maxRetryAfter: time.Hour,
+			err:           &acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}, RetryAfter: "120"},
+			expected:      2 * time.Minute,
+			retry:         true,
+		},
+		{
+			desc:          "wrapped",
+			maxRetryAfter: time.Hour,
+			err:           fmt.Errorf("oops: %w", &acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}, RetryAfter: "120"}),
+			expected:      2 * time.Minute,
+			retry:         true,
+		},
+		{
+			desc:          "too long",
+			maxRetryAfter: time.Minute,
+			err:           &acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}, RetryAfter: "120"},
+		},
+		{
+			desc:          "no Retry-After",
+			maxRetryAfter: time.Hour,
+			err:           &acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}},
+		},
+		{
+			desc: "disabled",
+			err:  &acme.RateLimitedError{ProblemDetails: &acme.ProblemDetails{}, RetryAfter: "120"},
+		},
+		{
+			desc:          "other error",
+			maxRetryAfter: time.Hour,
+			err:           &acme.ProblemDetails{Type: acme.BadNonceErr},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			limiter := NewLimiter(nil, Config{MaxRetryAfter: test.maxRetryAfter})
+
+			delay, retry := limiter.RetryAfter(test.err)
+
+			assert.Equal(t, test.retry, retry)
+			assert.Equal(t, test.expected, delay)
+		})
+	}
+}
+
+func TestKnownLimits(t *testing.T) {
+	limits, ok := KnownLimits("https://acme-v02.api.letsencrypt.org/directory")
+	require.True(t, ok)
+
+	assert.Equal(t, LetsEncrypt, limits)
+
+	_, ok = KnownLimits("https://ca.example/directory")
+	assert.False(t, ok)
+}
@@ -0,0 +1,65 @@
+// Package ratelimit provides a local accounting of the rate limits of the ACME servers.
+package ratelimit
+
+import (
+	"strings"
+	"time"
+)
+
+// Limit a number of events allowed during a sliding window.
+// The zero value disables the limit.
+type Limit struct {
+	Count  int           `json:"count" yaml:"count"`
+	Window time.Duration `json:"window" yaml:"window"`
+}
+
+func (l Limit) enabled() bool {
+	return l.Count > 0 && l.Window > 0
+}
+
+// Limits the rate limits of an ACME server.
+type Limits struct {
+	// NewOrders the number of orders per account.
+	NewOrders Limit `json:"newOrders" yaml:"newOrders"`
+	// CertificatesPerDomain the number of certificates per registered domain (ex: example.com for www.example.com).
+	CertificatesPerDomain Limit `json:"certificatesPerDomain" yaml:"certificatesPerDomain"`
+	// DuplicateCertificates the number of certificates for the exact same set of domains.
+	DuplicateCertificates Limit `json:"duplicateCertificates" yaml:"duplicateCertificates"`
+	// FailedValidations the number of failed validations per domain and per account.
+	FailedValidations Limit `json:"failedValidations" yaml:"failedValidations"`
+}
+
+// retention the duration of the longest window.
+func (l Limits) retention() time.Duration {
+	return max(l.NewOrders.Window, l.CertificatesPerDomain.Window, l.DuplicateCertificates.Window, l.FailedValidations.Window)
+}
+
+// Let's Encrypt rate limits.
+// https://letsencrypt.org/docs/rate-limits/
+var (
+	LetsEncrypt = Limits{
+		NewOrders:             Limit{Count: 300, Window: 3 * time.Hour},
+		CertificatesPerDomain: Limit{Count: 50, Window: 7 * 24 * time.Hour},
+		DuplicateCertificates: Limit{Count: 5, Window: 7 * 24 * time.Hour},
+		FailedValidations:     Limit{Count: 5, Window: time.Hour},
+	}
+
+	LetsEncryptStaging = Limits{
+		NewOrders:             Limit{Count: 1500, Window: 3 * time.Hour},
+		CertificatesPerDomain: Limit{Count: 30000, Window: 7 * 24 * time.Hour},
+		DuplicateCertificates: Limit{Count: 30000, Window: 7 * 24 * time.Hour},
+		FailedValidations:     Limit{Count: 200, Window: time.Hour},
+	}
+)
+
+// KnownLimits returns the known rate limits of an ACME server, from its directory URL.
+func KnownLimits(caDirURL string) (Limits, bool) {
+	switch strings.TrimSuffix(caDirURL, "/") {
+	case "https://acme-v02.api.letsencrypt.org/directory":
+		return LetsEncrypt, true
+	case "https://acme-staging-v02.api.letsencrypt.org/directory":
+		return LetsEncryptStaging, true
+	default:
+		return Limits{}, false
+	}
+}
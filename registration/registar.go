@@ -1,6 +1,7 @@
 package registration
 
 import (
+	"crypto"
 	"errors"
 	"net/http"
 
@@ -154,6 +155,20 @@ func (r *Registrar) DeleteRegistration() error {
 	return r.core.Accounts.Deactivate(r.user.GetRegistration().URI)
 }
 
+// RolloverKey replaces the key of the client's user registration on the ACME server by newKey.
+//
+// On success, the following requests of the client are signed with newKey:
+// it's the responsibility of the caller to store newKey and to provide it through the User interface.
+func (r *Registrar) RolloverKey(newKey crypto.PrivateKey) error {
+	if r == nil || r.user == nil || r.user.GetRegistration() == nil {
+		return errors.New("acme: cannot rollover the key of a nil client or user")
+	}
+
+	log.Infof("acme: Rolling over account key for %s", r.user.GetRegistration().URI)
+
+	return r.core.Accounts.KeyChange(r.user.GetRegistration().URI, newKey)
+}
+
 // ResolveAccountByKey will attempt to look up an account using the given account key
 // and return its registration resource.
 func (r *Registrar) ResolveAccountByKey() (*Resource, error) {
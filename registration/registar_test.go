@@ -3,7 +3,9 @@ package registration
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/platform/tester"
 	"github.com/go-acme/lego/v4/platform/tester/servermock"
+	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -45,3 +48,86 @@ func TestRegistrar_ResolveAccountByKey(t *testing.T) {
 
 	assert.Equal(t, "valid", res.Body.Status, "Unexpected account status")
 }
+
+func TestRegistrar_RolloverKey(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err, "Could not generate test key")
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err, "Could not generate test key")
+
+	server := tester.MockACMEServer().
+		Route("POST /keyChange",
+			http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				raw, err := io.ReadAll(req.Body)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				outer, err := jose.ParseSigned(string(raw), []jose.SignatureAlgorithm{jose.RS256})
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				innerRaw, err := outer.Verify(oldKey.Public())
+				if err != nil {
+					http.Error(rw, "outer: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				inner, err := jose.ParseSigned(string(innerRaw), []jose.SignatureAlgorithm{jose.RS256})
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if inner.Signatures[0].Protected.Nonce != "" {
+					http.Error(rw, "inner: unexpected nonce", http.StatusBadRequest)
+					return
+				}
+
+				payload, err := inner.Verify(newKey.Public())
+				if err != nil {
+					http.Error(rw, "inner: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				var msg acme.KeyChangeMessage
+
+				err = json.Unmarshal(payload, &msg)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				var oldJWK jose.JSONWebKey
+
+				err = oldJWK.UnmarshalJSON(msg.OldKey)
+				if err != nil {
+					http.Error(rw, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if msg.Account != outer.Signatures[0].Protected.KeyID || !oldKey.PublicKey.Equal(oldJWK.Key) {
+					http.Error(rw, "invalid key change message", http.StatusBadRequest)
+					return
+				}
+			})).
+		BuildHTTPS(t)
+
+	user := mockUser{
+		email:      "test@test.com",
+		regres:     &Resource{URI: server.URL + "/account/1"},
+		privatekey: oldKey,
+	}
+
+	core, err := api.New(server.Client(), "lego-test", server.URL+"/dir", user.regres.URI, oldKey)
+	require.NoError(t, err)
+
+	registrar := NewRegistrar(core, user)
+
+	err = registrar.RolloverKey(newKey)
+	require.NoError(t, err)
+}